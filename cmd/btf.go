@@ -0,0 +1,151 @@
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"os"
+
+	"github.com/spf13/cobra"
+
+	"github.com/viveksb007/gobpftool/pkg/btf"
+	bpferrors "github.com/viveksb007/gobpftool/pkg/errors"
+	"github.com/viveksb007/gobpftool/pkg/output"
+)
+
+// btfService is the BTF service of the btf subcommands. init sets it to
+// the kernel-backed btf.NewService; tests swap in a mock with
+// withMockBTFService
+var btfService btf.Service
+
+// btfCmd represents the btf command
+var btfCmd = &cobra.Command{
+	Use:   "btf",
+	Short: "Inspect BTF objects",
+	Long: `Inspect BTF (BPF Type Format) objects loaded in the kernel.
+
+Available commands:
+  list    List loaded BTF objects
+  dump    Dump the types of a BTF object as C declarations
+  help    Display help for btf commands`,
+	Run: func(cmd *cobra.Command, args []string) {
+		// If no subcommand is provided, show help
+		cmd.Help()
+	},
+}
+
+// btfListCmd represents the btf list command
+var btfListCmd = &cobra.Command{
+	Use:     "list",
+	Aliases: []string{"show"},
+	Short:   "List loaded BTF objects",
+	Long: `List the BTF objects loaded in the kernel: vmlinux, kernel modules and
+the BTF that came with loaded programs and maps.
+
+  gobpftool btf list
+  gobpftool -j btf list`,
+	RunE: runBTFList,
+}
+
+// runBTFList handles the btf list command
+func runBTFList(cmd *cobra.Command, args []string) error {
+	if len(args) != 0 {
+		printError("Error: invalid arguments. Use 'gobpftool btf list'")
+		return fmt.Errorf("invalid arguments")
+	}
+
+	objects, err := btfService.List()
+	if err != nil {
+		return btfError(err, "listing BTF objects")
+	}
+
+	outputObjects := make([]output.BTFInfo, len(objects))
+	for i, obj := range objects {
+		outputObjects[i] = output.BTFInfo{ID: obj.ID, Name: obj.Name, Size: obj.Size, Kernel: obj.Kernel}
+	}
+
+	formatter := newFormatter(resolveFormat(GetGlobalFlags()))
+	fmt.Fprint(resultWriter(cmd), formatter.FormatBTFs(outputObjects))
+	return nil
+}
+
+// btfDumpCmd represents the btf dump command
+var btfDumpCmd = &cobra.Command{
+	Use:   "dump id ID",
+	Short: "Dump the types of a BTF object as C declarations",
+	Long: `Dump the named types of a BTF object as C declarations.
+
+The output is best-effort C meant for reading; it isn't guaranteed to
+compile. Module BTF only lists the types the module adds to vmlinux.
+
+  gobpftool btf dump id 1
+  gobpftool -j btf dump id 42`,
+	RunE: runBTFDump,
+}
+
+// runBTFDump handles the btf dump command
+func runBTFDump(cmd *cobra.Command, args []string) error {
+	if len(args) != 2 || args[0] != "id" {
+		printError("Error: invalid arguments. Use 'gobpftool btf dump id <ID>'")
+		return fmt.Errorf("invalid arguments")
+	}
+
+	id, err := parseID(args[1])
+	if err != nil {
+		printErrorf("Error: %v", err)
+		return err
+	}
+
+	types, err := btfService.Dump(id)
+	if err != nil {
+		return btfError(err, fmt.Sprintf("dumping BTF object %d", id))
+	}
+
+	outputTypes := make([]output.BTFType, len(types))
+	for i, t := range types {
+		outputTypes[i] = output.BTFType{ID: t.ID, Kind: t.Kind, Name: t.Name, C: t.C}
+	}
+
+	formatter := newFormatter(resolveFormat(GetGlobalFlags()))
+	fmt.Fprint(resultWriter(cmd), formatter.FormatBTFTypes(outputTypes))
+	return nil
+}
+
+// btfError reports err like handleError, noting when the kernel doesn't
+// give access to BTF objects at all
+func btfError(err error, context string) error {
+	wrapped := handleError(err, context)
+	if errors.Is(err, bpferrors.ErrNotAvailable) && resolveFormat(GetGlobalFlags()) == output.FormatPlain {
+		fmt.Fprintln(os.Stderr, "BTF objects need a kernel built with CONFIG_DEBUG_INFO_BTF (Linux 5.4 or later)")
+	}
+	return wrapped
+}
+
+// btfHelpCmd represents the btf help command
+var btfHelpCmd = &cobra.Command{
+	Use:   "help",
+	Short: "Display help for btf commands",
+	Long: `Display help information for btf commands.
+
+Available btf commands:
+  list    List loaded BTF objects
+  dump    Dump the types of a BTF object as C declarations
+  help    Display this help message
+
+Examples:
+  gobpftool btf list                            # List BTF objects
+  gobpftool btf dump id 1                       # Dump the types of BTF object 1`,
+	Run: func(cmd *cobra.Command, args []string) {
+		btfCmd.Help()
+	},
+}
+
+func init() {
+	// Initialize the BTF service
+	btfService = btf.NewService()
+
+	btfCmd.AddCommand(btfListCmd)
+	btfCmd.AddCommand(btfDumpCmd)
+	btfCmd.AddCommand(btfHelpCmd)
+
+	rootCmd.AddCommand(btfCmd)
+}
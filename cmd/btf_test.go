@@ -0,0 +1,145 @@
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
+	"github.com/viveksb007/gobpftool/pkg/btf"
+	bpferrors "github.com/viveksb007/gobpftool/pkg/errors"
+)
+
+// mockBTFService is a mock implementation of btf.Service for testing
+type mockBTFService struct {
+	objects []btf.BTFInfo
+	types   map[uint32][]btf.TypeDecl
+	err     error // returned by List when set
+}
+
+func (m *mockBTFService) List() ([]btf.BTFInfo, error) {
+	if m.err != nil {
+		return nil, m.err
+	}
+	return m.objects, nil
+}
+
+func (m *mockBTFService) GetByID(id uint32) (*btf.BTFInfo, error) {
+	for _, obj := range m.objects {
+		if obj.ID == id {
+			return &obj, nil
+		}
+	}
+	return nil, fmt.Errorf("BTF object with ID %d: %w", id, bpferrors.ErrNotFound)
+}
+
+func (m *mockBTFService) Dump(id uint32) ([]btf.TypeDecl, error) {
+	if _, err := m.GetByID(id); err != nil {
+		return nil, err
+	}
+	return m.types[id], nil
+}
+
+// withMockBTFService swaps in a mock BTF service for the duration of a test
+func withMockBTFService(t *testing.T, svc *mockBTFService) {
+	t.Helper()
+	orig := btfService
+	btfService = svc
+	t.Cleanup(func() { btfService = orig })
+}
+
+func newTestBTFService() *mockBTFService {
+	return &mockBTFService{
+		objects: []btf.BTFInfo{
+			{ID: 1, Name: "vmlinux", Size: 5000000, Kernel: true},
+			{ID: 7, Size: 512},
+		},
+		types: map[uint32][]btf.TypeDecl{
+			7: {
+				{ID: 2, Kind: "typedef", Name: "__u32", C: "typedef unsigned int __u32;"},
+				{ID: 3, Kind: "struct", Name: "event", C: "struct event {\n\t__u32 pid;\n};"},
+			},
+		},
+	}
+}
+
+func TestBTFList(t *testing.T) {
+	withMockBTFService(t, newTestBTFService())
+
+	tests := []struct {
+		args []string
+		want string
+	}{
+		{
+			args: []string{"btf", "list"},
+			want: "1: name [vmlinux]  size 5000000B  kernel\n7: name <anon>  size 512B",
+		},
+		{
+			args: []string{"-j", "btf", "show"},
+			want: `{"btfs":[{"id":1,"name":"vmlinux","size":5000000,"kernel":true},{"id":7,"size":512,"kernel":false}]}`,
+		},
+	}
+
+	for _, tt := range tests {
+		got, err := executeCommandStdout(t, tt.args...)
+		if err != nil {
+			t.Fatalf("%v error = %v", tt.args, err)
+		}
+		if got != tt.want {
+			t.Errorf("%v = %q, want %q", tt.args, got, tt.want)
+		}
+	}
+}
+
+func TestBTFList_NotAvailable(t *testing.T) {
+	svc := newTestBTFService()
+	svc.err = fmt.Errorf("kernel BTF access %w", bpferrors.ErrNotAvailable)
+	withMockBTFService(t, svc)
+
+	stderr, err := executeCommandStderr(t, "btf", "list")
+	if !errors.Is(err, bpferrors.ErrNotAvailable) {
+		t.Errorf("btf list error = %v, want %v", err, bpferrors.ErrNotAvailable)
+	}
+	if want := "CONFIG_DEBUG_INFO_BTF"; !strings.Contains(stderr, want) {
+		t.Errorf("stderr = %q, want it to mention %s", stderr, want)
+	}
+}
+
+func TestBTFDump(t *testing.T) {
+	withMockBTFService(t, newTestBTFService())
+
+	got, err := executeCommandStdout(t, "btf", "dump", "id", "7")
+	if err != nil {
+		t.Fatalf("btf dump error = %v", err)
+	}
+	if want := "typedef unsigned int __u32;\n\nstruct event {\n\t__u32 pid;\n};"; got != want {
+		t.Errorf("btf dump = %q, want %q", got, want)
+	}
+
+	if err := executeCommand("btf", "dump", "id", "8"); !errors.Is(err, bpferrors.ErrNotFound) {
+		t.Errorf("btf dump of a missing object error = %v, want %v", err, bpferrors.ErrNotFound)
+	}
+	if err := executeCommand("btf", "dump", "id", "x"); !errors.Is(err, bpferrors.ErrInvalidID) {
+		t.Errorf("btf dump with a bad ID error = %v, want %v", err, bpferrors.ErrInvalidID)
+	}
+	if err := executeCommand("btf", "dump", "7"); err == nil {
+		t.Error("btf dump without 'id' succeeded")
+	}
+}
+
+func TestHelpGlobalFlags(t *testing.T) {
+	for _, help := range []*cobra.Command{mapHelpCmd, progHelpCmd, btfHelpCmd} {
+		_, flags, ok := strings.Cut(help.Long, "\nGlobal flags:\n")
+		if !ok {
+			t.Errorf("%s help has no global flags", help.Parent().Name())
+			continue
+		}
+		rootCmd.PersistentFlags().VisitAll(func(f *pflag.Flag) {
+			if !strings.Contains(flags, "--"+f.Name+" ") {
+				t.Errorf("%s help global flags miss --%s", help.Parent().Name(), f.Name)
+			}
+		})
+	}
+}
@@ -0,0 +1,61 @@
+package cmd
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/spf13/cobra"
+)
+
+// completeProgID suggests the IDs of the loaded programs for the argument
+// after "id", described by the program name. Nothing is suggested when
+// the programs can't be listed, e.g. without root.
+func completeProgID(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+	if len(args) != 1 || args[0] != "id" {
+		return nil, cobra.ShellCompDirectiveNoFileComp
+	}
+
+	programs, err := progService.List()
+	if err != nil {
+		return nil, cobra.ShellCompDirectiveNoFileComp
+	}
+
+	var candidates []string
+	for _, p := range programs {
+		candidates = appendIDCandidate(candidates, p.ID, p.Name, toComplete)
+	}
+	return candidates, cobra.ShellCompDirectiveNoFileComp
+}
+
+// completeMapID suggests the IDs of the loaded maps for the argument after
+// "id", described by the map name. Nothing is suggested when the maps
+// can't be listed, e.g. without root.
+func completeMapID(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+	if len(args) != 1 || args[0] != "id" {
+		return nil, cobra.ShellCompDirectiveNoFileComp
+	}
+
+	mapInfos, err := mapService.List()
+	if err != nil {
+		return nil, cobra.ShellCompDirectiveNoFileComp
+	}
+
+	var candidates []string
+	for _, m := range mapInfos {
+		candidates = appendIDCandidate(candidates, m.ID, m.Name, toComplete)
+	}
+	return candidates, cobra.ShellCompDirectiveNoFileComp
+}
+
+// appendIDCandidate adds id to candidates when it starts with toComplete,
+// in cobra's "value<TAB>description" form when the object has a name.
+func appendIDCandidate(candidates []string, id uint32, name, toComplete string) []string {
+	value := fmt.Sprint(id)
+	if !strings.HasPrefix(value, toComplete) {
+		return candidates
+	}
+	if name == "" {
+		return append(candidates, value)
+	}
+	return append(candidates, value+"\t"+name)
+}
@@ -0,0 +1,76 @@
+package cmd
+
+import (
+	"bytes"
+	"fmt"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/spf13/cobra"
+
+	bpferrors "github.com/viveksb007/gobpftool/pkg/errors"
+	"github.com/viveksb007/gobpftool/pkg/prog"
+)
+
+func TestCompleteProgID(t *testing.T) {
+	withMockProgService(t, []prog.ProgramInfo{
+		{ID: 12, Name: "xdp_fw"},
+		{ID: 3, Name: "trace_open"},
+		{ID: 15},
+	})
+
+	tests := []struct {
+		args       []string
+		toComplete string
+		want       []string
+	}{
+		{args: []string{"id"}, want: []string{"12\txdp_fw", "3\ttrace_open", "15"}},
+		{args: []string{"id"}, toComplete: "1", want: []string{"12\txdp_fw", "15"}},
+		{args: []string{"name"}, want: nil},
+		{args: []string{"id", "12"}, want: nil},
+	}
+
+	for _, tt := range tests {
+		got, directive := completeProgID(progShowCmd, tt.args, tt.toComplete)
+		if !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("completeProgID(%v, %q) = %q, want %q", tt.args, tt.toComplete, got, tt.want)
+		}
+		if directive != cobra.ShellCompDirectiveNoFileComp {
+			t.Errorf("completeProgID(%v, %q) directive = %v, want no file completion", tt.args, tt.toComplete, directive)
+		}
+	}
+
+	// Without permission to list there's nothing to suggest
+	progService.(*mockProgService).err = fmt.Errorf("listing: %w", bpferrors.ErrPermission)
+	if got, _ := completeProgID(progShowCmd, []string{"id"}, ""); got != nil {
+		t.Errorf("completeProgID() without permission = %q, want nothing", got)
+	}
+}
+
+func TestCompleteMapID(t *testing.T) {
+	withMockMapService(t, newTestMapService())
+
+	got, _ := completeMapID(mapDumpCmd, []string{"id"}, "")
+	if want := []string{"1\tcounts", "2\tempty"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("completeMapID() = %q, want %q", got, want)
+	}
+}
+
+func TestCompletionCommand(t *testing.T) {
+	withMockProgService(t, []prog.ProgramInfo{{ID: 7, Name: "xdp_fw"}})
+
+	ResetFlags()
+	cmd := GetRootCmd()
+	buf := &bytes.Buffer{}
+	cmd.SetOut(buf)
+	cmd.SetErr(&bytes.Buffer{})
+	cmd.SetArgs([]string{cobra.ShellCompRequestCmd, "prog", "show", "id", ""})
+
+	if err := cmd.Execute(); err != nil {
+		t.Fatalf("completion error = %v", err)
+	}
+	if want := "7\txdp_fw\n:4\n"; !strings.HasPrefix(buf.String(), want) {
+		t.Errorf("completion output = %q, want prefix %q", buf.String(), want)
+	}
+}
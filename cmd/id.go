@@ -0,0 +1,34 @@
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"strconv"
+
+	bpferrors "github.com/viveksb007/gobpftool/pkg/errors"
+)
+
+// parseID parses the ID argument of an "id" identifier. Anything but a
+// decimal number from 0 to 2^32-1 returns an error wrapping
+// ErrInvalidID that names the offending input, so every subcommand
+// reports bad IDs the same way
+func parseID(arg string) (uint32, error) {
+	id, err := strconv.ParseUint(arg, 10, 32)
+	if err == nil {
+		return uint32(id), nil
+	}
+
+	reason := "not a decimal number"
+	switch {
+	case arg == "":
+		reason = "empty"
+	case errors.Is(err, strconv.ErrRange):
+		reason = fmt.Sprintf("larger than %d", uint32(math.MaxUint32))
+	default:
+		if n, intErr := strconv.ParseInt(arg, 10, 64); intErr == nil && n < 0 {
+			reason = "negative"
+		}
+	}
+	return 0, fmt.Errorf("%w %q: %s", bpferrors.ErrInvalidID, arg, reason)
+}
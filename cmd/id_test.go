@@ -0,0 +1,46 @@
+package cmd
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	bpferrors "github.com/viveksb007/gobpftool/pkg/errors"
+)
+
+func TestParseID(t *testing.T) {
+	tests := []struct {
+		arg     string
+		want    uint32
+		wantErr string
+	}{
+		{arg: "0", want: 0},
+		{arg: "42", want: 42},
+		{arg: "4294967295", want: 4294967295},
+		{arg: "-1", wantErr: "negative"},
+		{arg: "4294967296", wantErr: "larger than 4294967295"},
+		{arg: "99999999999999999999", wantErr: "larger than 4294967295"},
+		{arg: "", wantErr: "empty"},
+		{arg: "abc", wantErr: "not a decimal number"},
+		{arg: "0x10", wantErr: "not a decimal number"},
+		{arg: " 7", wantErr: "not a decimal number"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.arg, func(t *testing.T) {
+			got, err := parseID(tt.arg)
+			if tt.wantErr == "" {
+				if err != nil || got != tt.want {
+					t.Errorf("parseID(%q) = %d, %v, want %d", tt.arg, got, err, tt.want)
+				}
+				return
+			}
+			if !errors.Is(err, bpferrors.ErrInvalidID) {
+				t.Fatalf("parseID(%q) error = %v, want %v", tt.arg, err, bpferrors.ErrInvalidID)
+			}
+			if !strings.Contains(err.Error(), tt.arg) || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("parseID(%q) error = %q, want the input and %q", tt.arg, err, tt.wantErr)
+			}
+		})
+	}
+}
@@ -0,0 +1,70 @@
+package cmd
+
+import (
+	"fmt"
+
+	"github.com/spf13/cobra"
+	"golang.org/x/sys/unix"
+
+	"github.com/viveksb007/gobpftool/internal/bpffs"
+	"github.com/viveksb007/gobpftool/internal/utils"
+	"github.com/viveksb007/gobpftool/pkg/output"
+)
+
+// readCapEff reads the effective capabilities of the process; tests swap
+// it to simulate missing privileges
+var readCapEff = utils.ReadCapEff
+
+// infoCmd represents the info command
+var infoCmd = &cobra.Command{
+	Use:     "info",
+	Aliases: []string{"summary"},
+	Short:   "Show an overview of the BPF environment",
+	Long: `Print a quick sanity check of the BPF environment: the kernel release,
+whether the BPF filesystem is mounted, how many programs and maps are
+loaded, and whether the process has CAP_BPF and CAP_SYS_ADMIN.
+
+Counting programs and maps needs privileges; without them the counts
+are unknown.
+
+  gobpftool info
+  gobpftool -j info          # {"kernel_release":...,"programs":...}`,
+	RunE: runInfo,
+}
+
+func init() {
+	rootCmd.AddCommand(infoCmd)
+}
+
+// runInfo handles the info command
+func runInfo(cmd *cobra.Command, args []string) error {
+	info := output.SystemInfo{BPFFSRoot: bpffs.GetScanner().Root()}
+
+	var uname unix.Utsname
+	if err := unix.Uname(&uname); err == nil {
+		info.KernelRelease = unix.ByteSliceToString(uname.Release[:])
+	}
+	info.BPFFSMounted = bpffs.CheckMounted(info.BPFFSRoot) == nil
+
+	// Counts are only reported when both listings succeed
+	programs, progErr := progService.ListWithSkipped(cmd.Context())
+	mapInfos, mapErr := mapService.ListWithSkipped(cmd.Context())
+	for _, err := range []error{progErr, mapErr} {
+		if err != nil && interrupted(err) {
+			return handleError(err, "counting programs and maps")
+		}
+	}
+	if progErr == nil && mapErr == nil {
+		info.Programs, info.Maps = len(programs.Programs), len(mapInfos.Maps)
+		info.CountsKnown = true
+	}
+
+	if caps, err := readCapEff(); err == nil {
+		info.CapBPF = utils.HasCap(caps, utils.CapBPF)
+		info.CapSysAdmin = utils.HasCap(caps, utils.CapSysAdmin)
+	}
+
+	formatter := newFormatter(resolveFormat(GetGlobalFlags()))
+	fmt.Fprintln(resultWriter(cmd), formatter.FormatSystemInfo(info))
+	return nil
+}
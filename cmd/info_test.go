@@ -0,0 +1,85 @@
+package cmd
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"github.com/viveksb007/gobpftool/internal/utils"
+	bpferrors "github.com/viveksb007/gobpftool/pkg/errors"
+	"github.com/viveksb007/gobpftool/pkg/prog"
+)
+
+// withCapEff makes the info command see the capability mask caps
+func withCapEff(t *testing.T, caps uint64) {
+	t.Helper()
+	orig := readCapEff
+	readCapEff = func() (uint64, error) { return caps, nil }
+	t.Cleanup(func() { readCapEff = orig })
+}
+
+func TestInfo(t *testing.T) {
+	withMockProgService(t, []prog.ProgramInfo{{ID: 1, Type: "xdp"}, {ID: 2, Type: "xdp"}, {ID: 3, Type: "kprobe"}})
+	withMockMapService(t, newTestMapService())
+	withCapEff(t, 1<<utils.CapBPF)
+
+	got, err := executeCommandStdout(t, "-j", "info")
+	if err != nil {
+		t.Fatalf("info error = %v", err)
+	}
+	var parsed struct {
+		KernelRelease string `json:"kernel_release"`
+		BPFFSRoot     string `json:"bpffs_root"`
+		Programs      *int   `json:"programs"`
+		Maps          *int   `json:"maps"`
+		CapBPF        bool   `json:"cap_bpf"`
+		CapSysAdmin   bool   `json:"cap_sys_admin"`
+	}
+	if err := json.Unmarshal([]byte(got), &parsed); err != nil {
+		t.Fatalf("failed to parse %s: %v", got, err)
+	}
+	if parsed.Programs == nil || *parsed.Programs != 3 || parsed.Maps == nil || *parsed.Maps != 2 {
+		t.Errorf("info = %s, want 3 programs and 2 maps", got)
+	}
+	if !parsed.CapBPF || parsed.CapSysAdmin {
+		t.Errorf("info = %s, want only CAP_BPF", got)
+	}
+	if parsed.KernelRelease == "" || parsed.BPFFSRoot != "/sys/fs/bpf" {
+		t.Errorf("info = %s, want the kernel release and bpffs root", got)
+	}
+
+	got, err = executeCommandStdout(t, "summary")
+	if err != nil {
+		t.Fatalf("summary error = %v", err)
+	}
+	for _, want := range []string{"programs: 3\n", "maps: 2\n", "capabilities: CAP_BPF yes, CAP_SYS_ADMIN no"} {
+		if !strings.Contains(got, want) {
+			t.Errorf("summary = %q, want it to contain %q", got, want)
+		}
+	}
+}
+
+func TestInfoUnprivileged(t *testing.T) {
+	withMockProgService(t, nil)
+	progService.(*mockProgService).err = bpferrors.ErrPermission
+	withMockMapService(t, newTestMapService())
+	withCapEff(t, 0)
+
+	got, err := executeCommandStdout(t, "info")
+	if err != nil {
+		t.Fatalf("info error = %v", err)
+	}
+	for _, want := range []string{"programs: unknown\n", "maps: unknown\n", "CAP_BPF no, CAP_SYS_ADMIN no"} {
+		if !strings.Contains(got, want) {
+			t.Errorf("info = %q, want it to contain %q", got, want)
+		}
+	}
+
+	got, err = executeCommandStdout(t, "-j", "info")
+	if err != nil {
+		t.Fatalf("info -j error = %v", err)
+	}
+	if strings.Contains(got, `"programs"`) || strings.Contains(got, `"maps"`) {
+		t.Errorf("info -j = %s, want the counts left out", got)
+	}
+}
@@ -1,10 +1,17 @@
 package cmd
 
 import (
+	"bufio"
+	"bytes"
+	"context"
+	"errors"
 	"fmt"
+	"io"
+	"math"
 	"os"
-	"strconv"
+	"slices"
 	"strings"
+	"time"
 
 	"github.com/spf13/cobra"
 
@@ -14,8 +21,95 @@ import (
 	"github.com/viveksb007/gobpftool/pkg/output"
 )
 
+// mapService is the map service every map subcommand goes through. init
+// sets it to the kernel-backed maps.NewService; tests swap in a mock with
+// withMockMapService
 var mapService maps.Service
 
+// mapInfoTTL is how long a command reuses the info it read for a map, so
+// e.g. resolving a name and then dumping the map doesn't reopen every map
+const mapInfoTTL = 2 * time.Second
+
+// mapShowFlags holds the flags for the map show command
+var mapShowFlags struct {
+	maps.Filter
+	Sort    string // --sort
+	Reverse bool   // --reverse
+	Count   bool   // --count
+	Columns string // --columns
+	Memlock string // --memlock-min
+	IDRange string // --id-range
+	Index   int    // --index
+	Entries bool   // --entries-count
+	MaxScan int    // --max-scan
+}
+
+// mapDecode is set by --decode on map dump and map lookup
+var mapDecode bool
+
+// mapCPU is set by --cpu on map dump and map lookup
+var mapCPU int
+
+// mapValueAs is set by --value-as on map dump and map lookup
+var mapValueAs string
+
+// mapKeyAs is set by --key-as on map dump and map lookup
+var mapKeyAs string
+
+// mapKeyDec, mapKeyEndian and mapKeyFile are set by --key-dec, --key-endian
+// and --key-file on map lookup and map update
+var (
+	mapKeyDec    uint64
+	mapKeyEndian string
+	mapKeyFile   string
+)
+
+// mapValueFile is set by --value-file on map update
+var mapValueFile string
+
+// mapWatch is set by --watch on map dump
+var mapWatch time.Duration
+
+// mapDiffInterval is set by --interval on map diff
+var mapDiffInterval time.Duration
+
+// mapDumpCSV is set by --csv on map dump
+var mapDumpCSV bool
+
+// mapDumpJSONL is set by --jsonl on map dump
+var mapDumpJSONL bool
+
+// mapDumpOffset and mapDumpLimit are set by --offset and --limit on map dump
+var mapDumpOffset, mapDumpLimit int
+
+// mapDumpKeyPrefix is set by --key-prefix on map dump
+var mapDumpKeyPrefix []byte
+
+// mapDumpSortKeys is set by --sort-keys on map dump
+var mapDumpSortKeys bool
+
+// mapKeysOnly and mapValuesOnly are set by --keys-only and --values-only on map dump
+var mapKeysOnly, mapValuesOnly bool
+
+// mapLookupRaw is set by --raw on map lookup
+var mapLookupRaw bool
+
+// mapLookupLPM is set by --lpm on map lookup
+var mapLookupLPM string
+
+// mapUpdateStdin is set by --stdin on map update
+var mapUpdateStdin bool
+
+// mapReadCount and mapReadTimeout are set by --count and --timeout on map
+// read and map perf-read
+var (
+	mapReadCount   int
+	mapReadTimeout time.Duration
+)
+
+// mapPerCPUBuffer is set by --per-cpu-buffer on map perf-read
+var mapPerCPUBuffer string
+
 // mapCmd represents the map command
 var mapCmd = &cobra.Command{
 	Use:   "map",
@@ -25,8 +119,19 @@ var mapCmd = &cobra.Command{
 Available commands:
   show      Show information about loaded maps
   dump      Dump all entries in a map
+  diff      Print the entries of a map that change over time
   lookup    Lookup a key in a map
   getnext   Get next key in a map
+  update    Set the value of a key in a map
+  delete    Delete a key from a map
+  push      Push a value onto a stack or queue map
+  pop       Remove and print the next value of a stack or queue map
+  peek      Print the next value of a stack or queue map
+  read      Stream the records of a ring buffer map
+  perf-read Stream the samples of a perf event array map
+  freeze    Make a map read-only from user space
+  pin       Pin a map to the BPF filesystem
+  unpin     Remove map pins from the BPF filesystem
   help      Display help for map commands`,
 	Run: func(cmd *cobra.Command, args []string) {
 		// If no subcommand is provided, show help
@@ -47,7 +152,15 @@ With arguments, shows specific map(s):
   gobpftool map show                    # List all maps
   gobpftool map show id 123             # Show map with ID 123
   gobpftool map show name my_map        # Show maps with name
-  gobpftool map show pinned /sys/fs/bpf/my_map  # Show pinned map`,
+  gobpftool map show name my_map --index 1  # Second of several, by ID
+  gobpftool map show pinned /sys/fs/bpf/my_map  # Show pinned map
+  gobpftool map list --type hash --name-contains conn  # Filter the list
+  gobpftool map list --sort memlock --reverse  # Biggest consumers first
+  gobpftool map list --type hash --count  # Number of hash maps
+  gobpftool map list --columns id,name,memlock  # Table of selected columns
+  gobpftool map list --memlock-min 10M  # Maps locking at least 10 MiB
+  gobpftool map list --id-range 100-  # Maps with ID 100 or above
+  gobpftool map show id 123 --entries-count  # Count the populated entries`,
 	RunE: runMapShow,
 }
 
@@ -59,7 +172,24 @@ var mapDumpCmd = &cobra.Command{
 
   gobpftool map dump id 123             # Dump map with ID 123
   gobpftool map dump name my_map        # Dump maps with name
-  gobpftool map dump pinned /sys/fs/bpf/my_map  # Dump pinned map`,
+  gobpftool map dump pinned /sys/fs/bpf/my_map  # Dump pinned map
+  gobpftool map dump id 123 --decode -p  # Decode entries using BTF
+  gobpftool map dump id 123 --cpu 2      # Only CPU 2 of a per-CPU map
+  gobpftool map dump id 123 --keys-only  # Only print the keys
+  gobpftool map dump id 123 --value-as u64  # Print counters in decimal
+  gobpftool map dump id 123 --key-as ip  # Print 4- and 16-byte keys as IP addresses
+  gobpftool map dump id 123 --csv        # Output entries as CSV
+  gobpftool map dump id 123 --watch 1s   # Dump again every second
+  gobpftool map dump id 123 --jsonl      # Stream one JSON object per entry
+  gobpftool map dump id 123 --offset 100 --limit 50  # Entries 100 to 149
+  gobpftool map dump id 123 --key-prefix 0a000001  # Keys starting with 0a 00 00 01
+  gobpftool map dump id 123 --sort-keys  # Order entries by key bytes
+
+--offset and --limit count the entries matching --key-prefix.
+
+The kernel hands out entries in an arbitrary order. --sort-keys orders them
+by their key bytes so two dumps can be diffed; it buffers the whole dump,
+so it can't be combined with --jsonl, --offset or --limit.`,
 	RunE: runMapDump,
 }
 
@@ -69,10 +199,24 @@ var mapLookupCmd = &cobra.Command{
 	Short: "Lookup a key in a map",
 	Long: `Lookup a specific key in an eBPF map.
 
-Key data is specified as space-separated hex bytes.
+Key data is specified as space-separated hex bytes, as a decimal integer
+with --key-dec, or as the raw contents of a file with --key-file. Keys of
+LPM trie maps can be given as an IPv4 or IPv6 CIDR with --lpm, which is
+encoded as the prefix length followed by the address.
 
   gobpftool map lookup id 123 key 0a 0b 0c 0d
-  gobpftool map lookup pinned /sys/fs/bpf/my_map key 01 02 03 04`,
+  gobpftool map lookup id 123 --key-dec 42
+  gobpftool map lookup id 123 --key-dec 42 --key-endian be
+  gobpftool map lookup id 123 --key-file key.bin
+  gobpftool map lookup id 123 --lpm 10.0.0.0/8
+  gobpftool map lookup id 123 key 0a 00 00 01 --key-as ip
+  gobpftool map lookup pinned /sys/fs/bpf/my_map key 01 02 03 04
+  gobpftool map lookup id 123 key 0a 0b 0c 0d --decode
+  gobpftool map lookup id 123 key 0a 0b 0c 0d --cpu 0
+  gobpftool map lookup id 123 key 0a 0b 0c 0d --raw | xxd
+
+--raw writes just the value's bytes, without formatting or a newline. Per-CPU
+maps need --cpu to pick the value.`,
 	RunE: runMapLookup,
 }
 
@@ -90,6 +234,204 @@ With a key, returns the next key after the specified key.
 	RunE: runMapGetNext,
 }
 
+// mapClearCmd represents the map clear command
+var mapClearCmd = &cobra.Command{
+	Use:     "clear MAP",
+	Aliases: []string{"delete-all"},
+	Short:   "Delete all entries in a map",
+	Long: `Delete every entry in an eBPF map without recreating it.
+
+Array maps can't be cleared since their elements can't be deleted.
+
+  gobpftool map clear id 123
+  gobpftool map clear pinned /sys/fs/bpf/my_map`,
+	Annotations: requiresBPFCapability,
+	RunE:        runMapClear,
+}
+
+// mapUpdateCmd represents the map update command
+var mapUpdateCmd = &cobra.Command{
+	Use:   "update MAP key KEY_DATA value VALUE_DATA",
+	Short: "Set the value of a key in a map",
+	Long: `Set the value of a key in an eBPF map, creating the entry if needed.
+
+Key and value data are specified as space-separated hex bytes and must be
+exactly the map's key and value sizes. Like with map lookup, the key can
+also be given with --key-dec or --key-file, and the value can be read from
+a file with --value-file. Per-CPU maps get the value on every CPU.
+
+With --stdin, entries are read from standard input, one per line: the key
+bytes followed by the value bytes. Empty lines and lines starting with #
+are skipped. Lines that fail are reported and the rest still applied, then
+the number of updated and failed entries is printed.
+
+  gobpftool map update id 123 key 01 00 00 00 value 2a 00 00 00 00 00 00 00
+  gobpftool map update id 123 --key-dec 1 --value-file value.bin
+  cat pairs.txt | gobpftool map update id 123 --stdin`,
+	Annotations: requiresBPFCapability,
+	RunE:        runMapUpdate,
+}
+
+// mapDeleteCmd represents the map delete command
+var mapDeleteCmd = &cobra.Command{
+	Use:   "delete MAP key KEY_DATA",
+	Short: "Delete a key from a map",
+	Long: `Delete the entry for a key from an eBPF map.
+
+Key data is specified as space-separated hex bytes and must be exactly the
+map's key size. Array maps don't support deleting since their elements
+always exist; use map update to reset a value instead.
+
+  gobpftool map delete id 123 key 01 00 00 00
+  gobpftool map delete pinned /sys/fs/bpf/my_map key 0a 00 00 01`,
+	Annotations: requiresBPFCapability,
+	RunE:        runMapDelete,
+}
+
+// mapPushCmd represents the map push command
+var mapPushCmd = &cobra.Command{
+	Use:   "push MAP value VALUE_DATA",
+	Short: "Push a value onto a stack or queue map",
+	Long: `Push a value onto an eBPF stack or queue map.
+
+Value data is specified as space-separated hex bytes and must be exactly
+the map's value size.
+
+  gobpftool map push id 123 value 01 00 00 00
+  gobpftool map push pinned /sys/fs/bpf/my_queue value 2a 00 00 00`,
+	Annotations: requiresBPFCapability,
+	RunE:        runMapPush,
+}
+
+// mapPopCmd represents the map pop command
+var mapPopCmd = &cobra.Command{
+	Use:   "pop MAP",
+	Short: "Remove and print the next value of a stack or queue map",
+	Long: `Remove the next value from an eBPF stack or queue map and print it.
+
+Stacks return the most recently pushed value, queues the oldest.
+
+  gobpftool map pop id 123
+  gobpftool map pop pinned /sys/fs/bpf/my_queue`,
+	Annotations: requiresBPFCapability,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		return runMapTake(cmd, args, "popping from", mapService.PopRef)
+	},
+}
+
+// mapPeekCmd represents the map peek command
+var mapPeekCmd = &cobra.Command{
+	Use:   "peek MAP",
+	Short: "Print the next value of a stack or queue map",
+	Long: `Print the value a pop would return without removing it.
+
+  gobpftool map peek id 123
+  gobpftool map peek pinned /sys/fs/bpf/my_queue`,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		return runMapTake(cmd, args, "peeking at", mapService.PeekRef)
+	},
+}
+
+// mapReadCmd represents the map read command
+var mapReadCmd = &cobra.Command{
+	Use:   "read MAP",
+	Short: "Stream the records of a ring buffer map",
+	Long: `Print the records BPF programs submit to a ring buffer map as they
+arrive, until interrupted, --count records were read or --timeout passed.
+
+Records are printed as hex bytes, or one JSON object per record with -j
+and the data base64-encoded. Reading consumes the records, so they no
+longer reach other readers of the ring buffer.
+
+  gobpftool map read id 123
+  gobpftool map read id 123 --count 10 --timeout 5s
+  gobpftool -j map read pinned /sys/fs/bpf/events`,
+	RunE: runMapRead,
+}
+
+// mapPerfReadCmd represents the map perf-read command
+var mapPerfReadCmd = &cobra.Command{
+	Use:   "perf-read MAP",
+	Short: "Stream the samples of a perf event array map",
+	Long: `Print the samples BPF programs output to a perf event array map as they
+arrive, until interrupted, --count samples were read or --timeout passed.
+
+Every sample is printed with the CPU it was written on and its bytes in
+hex, or as one JSON object per sample with -j and the data
+base64-encoded. When a CPU's buffer fills up the kernel drops samples;
+a warning with the number lost is printed to stderr. A larger
+--per-cpu-buffer makes drops less likely.
+
+  gobpftool map perf-read id 123
+  gobpftool map perf-read id 123 --count 10 --timeout 5s
+  gobpftool map perf-read id 123 --per-cpu-buffer 1M
+  gobpftool -j map perf-read pinned /sys/fs/bpf/events`,
+	RunE: runMapPerfRead,
+}
+
+// mapDiffCmd represents the map diff command
+var mapDiffCmd = &cobra.Command{
+	Use:   "diff MAP",
+	Short: "Print the entries of a map that change over time",
+	Long: `Dump a map at an interval and print only the entries that were added (+),
+removed (-) or changed (~) since the previous dump, until interrupted.
+
+  gobpftool map diff id 123                          # Compare every second
+  gobpftool map diff id 123 --interval 5s --value-as u64  # Watch counters
+  gobpftool -j map diff pinned /sys/fs/bpf/my_map    # added/removed/changed per line`,
+	RunE: runMapDiff,
+}
+
+// mapFreezeCmd represents the map freeze command
+var mapFreezeCmd = &cobra.Command{
+	Use:   "freeze MAP",
+	Short: "Make a map read-only from user space",
+	Long: `Freeze an eBPF map so it can no longer be changed from user space.
+
+BPF programs can still update it. Freezing can't be undone; later map
+update, push and clear fail with "map is frozen (read-only)".
+
+  gobpftool map freeze id 123
+  gobpftool map freeze pinned /sys/fs/bpf/my_config`,
+	Annotations: requiresBPFCapability,
+	RunE:        runMapFreeze,
+}
+
+// mapPinCmd represents the map pin command
+var mapPinCmd = &cobra.Command{
+	Use:   "pin MAP FILE",
+	Short: "Pin a map to the BPF filesystem",
+	Long: `Pin a loaded map to a path under the BPF filesystem.
+
+Missing parent directories are created. The path must be under the bpffs root.
+
+  gobpftool map pin id 123 /sys/fs/bpf/my_map
+  gobpftool map pin id 123 /sys/fs/bpf/app/maps/my_map`,
+	Annotations: requiresBPFCapability,
+	RunE:        runMapPin,
+}
+
+// mapUnpinCmd represents the map unpin command
+var mapUnpinCmd = &cobra.Command{
+	Use:   "unpin [FILE]",
+	Short: "Remove map pins from the BPF filesystem",
+	Long: `Remove a map pin, or with --by-id every pin of a map.
+
+Unpinning a path with nothing pinned succeeds with a warning.
+
+  gobpftool map unpin /sys/fs/bpf/my_map
+  gobpftool map unpin --by-id 123`,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		return runUnpin(cmd, args, "map", func(id uint32) ([]string, error) {
+			mapInfo, err := mapService.GetByID(id)
+			if err != nil {
+				return nil, err
+			}
+			return mapInfo.PinnedPaths, nil
+		}, mapService.Unpin)
+	},
+}
+
 // mapHelpCmd represents the map help command
 var mapHelpCmd = &cobra.Command{
 	Use:   "help",
@@ -99,8 +441,19 @@ var mapHelpCmd = &cobra.Command{
 Available map commands:
   show      Show information about loaded maps
   dump      Dump all entries in a map
+  diff      Print the entries of a map that change over time
   lookup    Lookup a key in a map
   getnext   Get next key in a map
+  update    Set the value of a key in a map
+  delete    Delete a key from a map
+  push      Push a value onto a stack or queue map
+  pop       Remove and print the next value of a stack or queue map
+  peek      Print the next value of a stack or queue map
+  read      Stream the records of a ring buffer map
+  perf-read Stream the samples of a perf event array map
+  freeze    Make a map read-only from user space
+  pin       Pin a map to the BPF filesystem
+  unpin     Remove map pins from the BPF filesystem
   help      Display this help message
 
 Examples:
@@ -109,13 +462,21 @@ Examples:
   gobpftool map show name my_map                  # Show maps with name
   gobpftool map show pinned /sys/fs/bpf/map       # Show pinned map
   gobpftool map dump id 123                       # Dump all entries
+  gobpftool map diff id 123 --interval 5s         # Print changed entries
   gobpftool map lookup id 123 key 0a 0b 0c 0d     # Lookup key
   gobpftool map getnext id 123                    # Get first key
   gobpftool map getnext id 123 key 0a 0b 0c 0d    # Get next key
-
-Global flags:
-  -j, --json     Output in JSON format
-  -p, --pretty   Output in pretty-printed JSON format`,
+  gobpftool map clear id 123                      # Delete all entries
+  gobpftool map update id 123 key 01 00 00 00 value 2a 00 00 00  # Set a value
+  gobpftool map delete id 123 key 01 00 00 00     # Delete a key
+  gobpftool map push id 123 value 01 00 00 00     # Push onto a queue
+  gobpftool map pop id 123                        # Pop from a queue
+  gobpftool map read id 123 --count 10            # Read ring buffer records
+  gobpftool map perf-read id 123 --timeout 5s     # Read perf event samples
+  gobpftool map freeze id 123                     # Make a map read-only
+  gobpftool map pin id 123 /sys/fs/bpf/my_map     # Pin map
+  gobpftool map unpin /sys/fs/bpf/my_map          # Remove a pin
+  gobpftool map unpin --by-id 123                 # Remove all pins of a map`,
 	Run: func(cmd *cobra.Command, args []string) {
 		mapCmd.Help()
 	},
@@ -123,19 +484,70 @@ Global flags:
 
 // runMapShow handles the map show command
 func runMapShow(cmd *cobra.Command, args []string) error {
-	format := getOutputFormat()
-	formatter := output.NewFormatter(format)
+	formatter, err := listFormatter(mapShowFlags.Columns, output.MapColumnNames())
+	if err != nil {
+		printErrorf("Error: %v", err)
+		return err
+	}
 
 	var mapInfos []maps.MapInfo
-	var err error
+
+	// Reject unknown sort fields before querying the kernel
+	if err := maps.Sort(nil, mapShowFlags.Sort, false); err != nil {
+		printErrorf("Error: %v", err)
+		return err
+	}
+	if cmd.Flags().Changed("index") && (len(args) < 2 || args[0] != "name") {
+		err := fmt.Errorf("--index only applies to 'name' lookups")
+		printErrorf("Error: %v", err)
+		return err
+	}
+	if err := checkIndex(cmd, mapShowFlags.Index); err != nil {
+		printErrorf("Error: %v", err)
+		return err
+	}
+	if mapShowFlags.MaxScan < 0 {
+		err := fmt.Errorf("--max-scan can't be negative")
+		printErrorf("Error: %v", err)
+		return err
+	}
+	filter := mapShowFlags.Filter
+	if filter.Type != "" {
+		// Reject unknown types and match the canonical name, so e.g.
+		// lru_hash finds lruhash maps
+		typ, ok := maps.MapTypeFromString(filter.Type)
+		if !ok {
+			err := fmt.Errorf("unknown map type %q, valid types: %s", filter.Type, strings.Join(maps.MapTypeNames(), ", "))
+			printErrorf("Error: %v", err)
+			return err
+		}
+		filter.Type = maps.MapTypeName(typ)
+	}
+	if mapShowFlags.Memlock != "" {
+		if filter.MemlockMin, err = utils.ParseSize(mapShowFlags.Memlock); err != nil {
+			printErrorf("Error: %v", err)
+			return err
+		}
+	}
+	if mapShowFlags.IDRange != "" {
+		idRange, err := utils.ParseIDRange(mapShowFlags.IDRange)
+		if err != nil {
+			printErrorf("Error: %v", err)
+			return err
+		}
+		filter.IDRange = &idRange
+	}
+
+	// Maps the listing couldn't read, reported after the results
+	var skipped int
 
 	if len(args) == 0 {
 		// List all maps
-		mapInfos, err = mapService.List()
+		result, err := mapService.ListWithSkipped(cmd.Context())
 		if err != nil {
-			handleError(err, "listing maps")
-			return err
+			return handleError(err, "listing maps")
 		}
+		mapInfos, skipped = result.Maps, result.Skipped
 	} else if len(args) >= 2 {
 		// Parse map identifier
 		identifier := args[0]
@@ -143,260 +555,498 @@ func runMapShow(cmd *cobra.Command, args []string) error {
 
 		switch identifier {
 		case "id":
-			id, parseErr := strconv.ParseUint(value, 10, 32)
+			id, parseErr := parseID(value)
 			if parseErr != nil {
-				fmt.Fprintf(os.Stderr, "Error: invalid map ID: %s\n", value)
-				return bpferrors.ErrInvalidID
+				printErrorf("Error: %v", parseErr)
+				return parseErr
 			}
 
-			mapInfo, getErr := mapService.GetByID(uint32(id))
+			mapInfo, getErr := mapService.GetByID(id)
 			if getErr != nil {
-				handleError(getErr, fmt.Sprintf("getting map with ID %d", id))
-				return getErr
+				return handleError(getErr, fmt.Sprintf("getting map with ID %d", id))
 			}
 			mapInfos = []maps.MapInfo{*mapInfo}
 
 		case "name":
-			mapInfos, err = mapService.GetByName(value)
+			mapInfos, err = mapService.GetByNameContext(cmd.Context(), value)
+			if err != nil {
+				return handleError(err, fmt.Sprintf("getting maps with name %s", value))
+			}
+			if len(mapInfos) == 0 {
+				return handleError(bpferrors.ErrNotFound, fmt.Sprintf("getting maps with name %s", value))
+			}
+			mapInfos, err = selectMatch("map", "name "+value, mapInfos, func(m maps.MapInfo) uint32 { return m.ID }, mapShowFlags.Index)
 			if err != nil {
-				handleError(err, fmt.Sprintf("getting maps with name %s", value))
+				printErrorf("Error: %v", err)
 				return err
 			}
 
 		case "pinned":
-			mapInfo, getErr := mapService.GetByPinnedPath(value)
+			mapInfo, getErr := getPinnedMap(value)
 			if getErr != nil {
-				handleError(getErr, fmt.Sprintf("getting pinned map at %s", value))
 				return getErr
 			}
 			mapInfos = []maps.MapInfo{*mapInfo}
 
 		default:
-			fmt.Fprintf(os.Stderr, "Error: invalid map identifier: %s. Use 'id', 'name', or 'pinned'\n", identifier)
+			printErrorf("Error: invalid map identifier: %s. Use 'id', 'name', or 'pinned'", identifier)
 			return fmt.Errorf("invalid identifier: %s", identifier)
 		}
 	} else {
-		fmt.Fprintf(os.Stderr, "Error: invalid arguments. Use 'gobpftool map show' or 'gobpftool map show <identifier> <value>'\n")
+		printError("Error: invalid arguments. Use 'gobpftool map show' or 'gobpftool map show <identifier> <value>'")
 		return fmt.Errorf("invalid arguments")
 	}
 
+	// Apply the type, name, memlock and ID range filters
+	mapInfos = maps.FilterMaps(mapInfos, filter)
+
+	defer warnPinScan()
+	defer warnSkipped(skipped, "map")
+	if skipped > 0 {
+		// The columns were validated above
+		formatter, _ = listFormatter(mapShowFlags.Columns, output.MapColumnNames(), output.WithPartial(skippedReason(skipped, "map")))
+	}
+
+	if mapShowFlags.Count {
+		fmt.Fprint(resultWriter(cmd), formatter.FormatCount(len(mapInfos), "map"))
+		return nil
+	}
+	_ = maps.Sort(mapInfos, mapShowFlags.Sort, mapShowFlags.Reverse)
+
 	// Convert maps.MapInfo to output.MapInfo
 	outputMaps := make([]output.MapInfo, len(mapInfos))
 	for i, m := range mapInfos {
-		outputMaps[i] = output.MapInfo{
-			ID:         m.ID,
-			Type:       m.Type,
-			Name:       m.Name,
-			KeySize:    m.KeySize,
-			ValueSize:  m.ValueSize,
-			MaxEntries: m.MaxEntries,
-			Flags:      m.Flags,
-			MemLock:    m.MemLock,
-		}
+		outputMaps[i] = toOutputMapInfo(m)
+	}
+	if mapShowFlags.Entries {
+		countMapEntries(outputMaps)
 	}
 
-	result := formatter.FormatMaps(outputMaps)
-	fmt.Print(result)
+	// A map looked up by ID or pinned path is printed as a single object,
+	// like bpftool does
+	var result string
+	if len(args) >= 2 && (args[0] == "id" || args[0] == "pinned") && len(outputMaps) == 1 {
+		result = formatter.FormatMap(outputMaps[0])
+	} else {
+		result = formatter.FormatMaps(outputMaps)
+	}
+	fmt.Fprint(resultWriter(cmd), result)
 
 	return nil
 }
 
+// countMapEntries fills in the number of populated entries of each map,
+// counting at most --max-scan. Maps whose entries can't be counted are
+// left without a count
+func countMapEntries(mapInfos []output.MapInfo) {
+	for i := range mapInfos {
+		count, capped, err := mapService.CountEntries(mapInfos[i].ID, mapShowFlags.MaxScan)
+		if err != nil {
+			continue
+		}
+		mapInfos[i].Entries = count
+		mapInfos[i].EntriesCounted = true
+		mapInfos[i].EntriesCapped = capped
+	}
+}
+
 // runMapDump handles the map dump command
 func runMapDump(cmd *cobra.Command, args []string) error {
-	format := getOutputFormat()
-	formatter := output.NewFormatter(format)
+	fields := output.EntryKeysAndValues
+	if mapKeysOnly {
+		fields = output.EntryKeysOnly
+	} else if mapValuesOnly {
+		fields = output.EntryValuesOnly
+	}
+	if mapDumpOffset < 0 || mapDumpLimit < 0 {
+		err := fmt.Errorf("--offset and --limit can't be negative")
+		printErrorf("Error: %v", err)
+		return err
+	}
+	opts := []output.Option{output.WithEntryFields(fields), output.WithValueAs(mapValueAs), output.WithKeyAs(mapKeyAs)}
+	if paged() {
+		opts = append(opts, output.WithPage(mapDumpOffset, mapDumpLimit))
+	}
+
+	format := resolveFormat(GetGlobalFlags())
+	if mapDumpCSV || mapDumpJSONL {
+		if format != output.FormatPlain {
+			err := fmt.Errorf("--csv and --jsonl can't be combined with --json, --pretty, --yaml or --table")
+			printErrorf("Error: %v", err)
+			return err
+		}
+		format = output.FormatCSV
+		if mapDumpJSONL {
+			// Entries are printed one at a time as compact JSON
+			format = output.FormatJSON
+		}
+	}
+	formatter := newFormatter(format, opts...)
 
 	if len(args) < 2 {
-		fmt.Fprintf(os.Stderr, "Error: map identifier required. Use 'gobpftool map dump <identifier> <value>'\n")
+		printError("Error: map identifier required. Use 'gobpftool map dump <identifier> <value>'")
 		return fmt.Errorf("map identifier required")
 	}
 
-	identifier := args[0]
-	value := args[1]
-
 	// Get map info first to get key/value sizes
-	var mapInfo *maps.MapInfo
-	var mapID uint32
-	var err error
+	ref, mapInfo, err := lookupMap(cmd, args[0], args[1])
+	if err != nil {
+		return err
+	}
 
-	switch identifier {
-	case "id":
-		id, parseErr := strconv.ParseUint(value, 10, 32)
-		if parseErr != nil {
-			fmt.Fprintf(os.Stderr, "Error: invalid map ID: %s\n", value)
-			return bpferrors.ErrInvalidID
-		}
-		mapID = uint32(id)
-		mapInfo, err = mapService.GetByID(mapID)
-		if err != nil {
-			handleError(err, fmt.Sprintf("getting map with ID %d", mapID))
+	if err := checkValueAs(mapInfo); err != nil {
+		printErrorf("Error: %v", err)
+		return err
+	}
+	if err := checkKeyAs(mapInfo); err != nil {
+		printErrorf("Error: %v", err)
+		return err
+	}
+	if len(mapDumpKeyPrefix) > int(mapInfo.KeySize) {
+		err := fmt.Errorf("--key-prefix is %d bytes, longer than the %d-byte keys of %s", len(mapDumpKeyPrefix), mapInfo.KeySize, ref)
+		printErrorf("Error: %v", err)
+		return err
+	}
+
+	if mapDumpJSONL {
+		_, err := streamPage(cmd.Context(), ref, func(e maps.MapEntry) error {
+			entry := toOutputMapEntry(maps.DecodedEntry{MapEntry: e})
+			_, err := fmt.Fprintln(resultWriter(cmd), formatter.FormatMapEntry(entry, mapInfo.KeySize, mapInfo.ValueSize))
 			return err
+		})
+		if err != nil {
+			return handleError(err, fmt.Sprintf("dumping %s", ref))
 		}
+		return nil
+	}
 
-	case "name":
-		mapInfos, getErr := mapService.GetByName(value)
-		if getErr != nil {
-			handleError(getErr, fmt.Sprintf("getting maps with name %s", value))
-			return getErr
+	render := func() error {
+		entries, partial, err := dumpMapEntries(cmd, ref, mapInfo)
+		if entries == nil && err != nil {
+			return err
 		}
-		if len(mapInfos) == 0 {
-			fmt.Fprintf(os.Stderr, "Error: no maps found with name: %s\n", value)
-			return bpferrors.ErrNotFound
+		formatter := formatter
+		if partial != "" {
+			formatter = newFormatter(format, append(slices.Clone(opts), output.WithPartial(partial))...)
 		}
-		mapInfo = &mapInfos[0]
-		mapID = mapInfo.ID
+		fmt.Fprint(resultWriter(cmd), formatter.FormatMapEntries(entries, mapInfo.KeySize, mapInfo.ValueSize))
+		return err
+	}
 
-	case "pinned":
-		mapInfo, err = mapService.GetByPinnedPath(value)
-		if err != nil {
-			handleError(err, fmt.Sprintf("getting pinned map at %s", value))
-			return err
-		}
-		mapID = mapInfo.ID
+	if mapWatch > 0 {
+		ticker := time.NewTicker(mapWatch)
+		defer ticker.Stop()
 
-	default:
-		fmt.Fprintf(os.Stderr, "Error: invalid map identifier: %s. Use 'id', 'name', or 'pinned'\n", identifier)
-		return fmt.Errorf("invalid identifier: %s", identifier)
+		return watchLoop(cmd.Context(), ticker.C, func() error {
+			// Only a terminal can be cleared, not an --output file
+			if resultFile == nil && (format == output.FormatPlain || format == output.FormatTable) {
+				fmt.Fprint(resultWriter(cmd), clearScreen)
+			}
+			if format == output.FormatYAML {
+				// Each dump is a separate YAML document
+				fmt.Fprint(resultWriter(cmd), "---\n")
+			}
+			err := render()
+			if err == nil && format != output.FormatYAML {
+				// Newline-terminate each dump so JSON emits one document per line
+				fmt.Fprintln(resultWriter(cmd))
+			}
+			return err
+		})
 	}
 
-	// Dump all entries
-	entries, err := mapService.Dump(mapID)
+	return render()
+}
+
+// runMapDiff handles the map diff command
+func runMapDiff(cmd *cobra.Command, args []string) error {
+	format := resolveFormat(GetGlobalFlags())
+	formatter := newFormatter(format, output.WithValueAs(mapValueAs))
+
+	ref, err := resolveMapRef(args)
 	if err != nil {
-		handleError(err, fmt.Sprintf("dumping map %d", mapID))
+		return err
+	}
+	if mapDiffInterval <= 0 {
+		err := fmt.Errorf("--interval must be positive, got %s", mapDiffInterval)
+		printErrorf("Error: %v", err)
 		return err
 	}
 
-	// Convert to output.MapEntry
-	outputEntries := make([]output.MapEntry, len(entries))
-	for i, e := range entries {
-		outputEntries[i] = output.MapEntry{
-			Key:   e.Key,
-			Value: e.Value,
-		}
+	mapInfo, err := mapService.GetByRef(ref)
+	if err != nil {
+		return handleError(err, fmt.Sprintf("getting %s", ref))
+	}
+	if err := checkValueAs(mapInfo); err != nil {
+		printErrorf("Error: %v", err)
+		return err
 	}
 
-	result := formatter.FormatMapEntries(outputEntries, mapInfo.KeySize, mapInfo.ValueSize)
-	fmt.Print(result)
+	ticker := time.NewTicker(mapDiffInterval)
+	defer ticker.Stop()
 
-	return nil
+	return watchLoop(cmd.Context(), ticker.C, newMapDiffRenderer(cmd.Context(), resultWriter(cmd), ref, format, formatter))
 }
 
-// runMapLookup handles the map lookup command
-func runMapLookup(cmd *cobra.Command, args []string) error {
-	format := getOutputFormat()
-	formatter := output.NewFormatter(format)
+// newMapDiffRenderer returns a watchLoop render function that dumps the map
+// and writes the entries that changed since its previous successful dump
+// to w, each diff on its own line or as its own YAML document. The first
+// dump only records the baseline.
+func newMapDiffRenderer(ctx context.Context, w io.Writer, ref maps.Ref, format output.Format, formatter output.Formatter) func() error {
+	var prev []maps.MapEntry
+	haveBaseline := false
 
-	if len(args) < 2 {
-		fmt.Fprintf(os.Stderr, "Error: map identifier required. Use 'gobpftool map lookup <identifier> <value> key <key_data>'\n")
-		return fmt.Errorf("map identifier required")
-	}
+	return func() error {
+		cur, err := mapService.DumpRef(ctx, ref, maps.DefaultBatchSize)
+		if err != nil {
+			return handleError(err, fmt.Sprintf("dumping %s", ref))
+		}
 
-	identifier := args[0]
-	value := args[1]
+		if haveBaseline {
+			if diff := maps.Diff(prev, cur); !diff.Empty() {
+				if err := printRecord(w, format, formatter.FormatMapDiff(toOutputMapDiff(diff))); err != nil {
+					return handleError(err, "writing diff")
+				}
+			}
+		}
+		prev, haveBaseline = cur, true
+		return nil
+	}
+}
 
-	// Find the "key" keyword and parse key data
-	keyIndex := -1
-	for i, arg := range args {
-		if arg == "key" {
-			keyIndex = i
-			break
+// dumpMapEntries dumps all entries of a map, applying --decode, --cpu,
+// --key-prefix and --sort-keys. Errors are reported to stderr before
+// they're returned. An interrupted dump returns the entries read so far
+// along with its error. When the entries aren't all of the map's, or all
+// of the page, the reason is returned too
+func dumpMapEntries(cmd *cobra.Command, ref maps.Ref, mapInfo *maps.MapInfo) ([]output.MapEntry, string, error) {
+	var entries []maps.DecodedEntry
+	var partial string
+	var dumpErr error
+	if mapDecode {
+		entries, dumpErr = mapService.DumpDecodedRef(cmd.Context(), ref)
+		if paged() || len(mapDumpKeyPrefix) > 0 {
+			// Decoded dumps aren't streamed, so the page is cut afterwards
+			var page pager
+			var more bool
+			entries, more = page.cut(entries)
+			if more {
+				partial = "more entries past --limit"
+			}
+		}
+	} else if paged() || len(mapDumpKeyPrefix) > 0 {
+		var more bool
+		more, dumpErr = streamPage(cmd.Context(), ref, func(e maps.MapEntry) error {
+			entries = append(entries, maps.DecodedEntry{MapEntry: e})
+			return nil
+		})
+		if more {
+			partial = "more entries past --limit"
+		}
+	} else {
+		var rawEntries []maps.MapEntry
+		rawEntries, dumpErr = mapService.DumpRef(cmd.Context(), ref, maps.DefaultBatchSize)
+		for _, e := range rawEntries {
+			entries = append(entries, maps.DecodedEntry{MapEntry: e})
 		}
 	}
+	if dumpErr != nil {
+		if !interrupted(dumpErr) {
+			return nil, "", handleError(dumpErr, fmt.Sprintf("dumping %s", ref))
+		}
+		partial = "interrupted"
+	}
+	if mapDumpSortKeys {
+		maps.SortDecodedEntries(entries)
+	}
 
-	if keyIndex == -1 || keyIndex >= len(args)-1 {
-		fmt.Fprintf(os.Stderr, "Error: key data required. Use 'gobpftool map lookup <identifier> <value> key <hex_bytes>'\n")
-		return bpferrors.ErrInvalidKey
+	if cmd.Flags().Changed("cpu") {
+		var err error
+		if entries, err = selectCPU(entries, mapInfo); err != nil {
+			printErrorf("Error: %v", err)
+			return nil, "", err
+		}
 	}
 
-	// Parse key data (space-separated hex bytes after "key")
-	keyDataStr := strings.Join(args[keyIndex+1:], " ")
-	keyData, err := utils.ParseHexBytes(keyDataStr)
-	if err != nil {
-		fmt.Fprintf(os.Stderr, "Error: invalid key format: %v\n", err)
-		return bpferrors.ErrInvalidKey
+	// Convert to output.MapEntry
+	outputEntries := make([]output.MapEntry, len(entries))
+	for i, e := range entries {
+		outputEntries[i] = toOutputMapEntry(e)
 	}
+	return outputEntries, partial, handleError(dumpErr, fmt.Sprintf("dumping %s", ref))
+}
 
-	// Get map info and lookup
-	var mapInfo *maps.MapInfo
-	var mapID uint32
+// errPageFull stops the dump of a page once its last entry was read
+var errPageFull = errors.New("page full")
 
-	switch identifier {
-	case "id":
-		id, parseErr := strconv.ParseUint(value, 10, 32)
-		if parseErr != nil {
-			fmt.Fprintf(os.Stderr, "Error: invalid map ID: %s\n", value)
-			return bpferrors.ErrInvalidID
+// paged reports whether map dump was asked for a page of the entries
+func paged() bool {
+	return mapDumpOffset > 0 || mapDumpLimit > 0
+}
+
+// streamPage calls fn for the entries of a map that fall in the page set
+// by --offset and --limit, in iteration order, and reports whether the map
+// has more entries past the page. Entries whose key doesn't start with
+// --key-prefix are skipped as they're read and don't count toward the
+// page. The dump stops at the first entry past the page instead of reading
+// the rest of the map
+func streamPage(ctx context.Context, ref maps.Ref, fn func(maps.MapEntry) error) (bool, error) {
+	var page pager
+	err := mapService.DumpStreamRef(ctx, ref, func(e maps.MapEntry) error {
+		in, err := page.take(e.Key)
+		if !in {
+			return err
 		}
-		mapID = uint32(id)
-		mapInfo, err = mapService.GetByID(mapID)
+		return fn(e)
+	})
+	if errors.Is(err, errPageFull) {
+		return true, nil
+	}
+	return false, err
+}
+
+// pager tracks the entries of a dump against the page set by --offset,
+// --limit and --key-prefix
+type pager struct {
+	// read counts the entries matching --key-prefix so far
+	read int
+}
+
+// take reports whether the entry with key falls in the page. Past the
+// page it returns errPageFull
+func (p *pager) take(key []byte) (bool, error) {
+	if !bytes.HasPrefix(key, mapDumpKeyPrefix) {
+		return false, nil
+	}
+	p.read++
+	if p.read <= mapDumpOffset {
+		return false, nil
+	}
+	if mapDumpLimit > 0 && p.read-mapDumpOffset > mapDumpLimit {
+		return false, errPageFull
+	}
+	return true, nil
+}
+
+// cut returns the entries that fall in the page and whether there are
+// more entries past it
+func (p *pager) cut(entries []maps.DecodedEntry) ([]maps.DecodedEntry, bool) {
+	var page []maps.DecodedEntry
+	for _, e := range entries {
+		in, err := p.take(e.Key)
 		if err != nil {
-			handleError(err, fmt.Sprintf("getting map with ID %d", mapID))
-			return err
+			return page, true
+		}
+		if in {
+			page = append(page, e)
 		}
+	}
+	return page, false
+}
 
-	case "name":
-		mapInfos, getErr := mapService.GetByName(value)
-		if getErr != nil {
-			handleError(getErr, fmt.Sprintf("getting maps with name %s", value))
-			return getErr
+// readMapKey reads the key of a lookup or update from its only source: the
+// hex bytes after "key" (hexGiven), --key-file or --lpm. A --key-dec key
+// is encoded by finishMapKey once the map's key size is known. usage is
+// shown when no key was given
+func readMapKey(cmd *cobra.Command, hexGiven bool, hex []string, usage string) ([]byte, error) {
+	keyFromDec := cmd.Flags().Changed("key-dec")
+	keySources := 0
+	for _, given := range []bool{hexGiven, keyFromDec, mapKeyFile != "", mapLookupLPM != ""} {
+		if given {
+			keySources++
 		}
-		if len(mapInfos) == 0 {
-			fmt.Fprintf(os.Stderr, "Error: no maps found with name: %s\n", value)
-			return bpferrors.ErrNotFound
+	}
+	if keySources > 1 {
+		sources := "hex bytes, with --key-dec or with --key-file"
+		if cmd.Flags().Lookup("lpm") != nil {
+			sources = "hex bytes, with --key-dec, with --key-file or with --lpm"
+		}
+		printErrorf("Error: give the key either as %s", sources)
+		return nil, bpferrors.ErrInvalidKey
+	}
+
+	switch {
+	case keyFromDec:
+		// Only check the byte order here, the size comes with the map
+		if _, err := utils.ParseByteOrder(mapKeyEndian); err != nil {
+			printErrorf("Error: %v", err)
+			return nil, err
 		}
-		mapInfo = &mapInfos[0]
-		mapID = mapInfo.ID
+		return nil, nil
 
-	case "pinned":
-		mapInfo, err = mapService.GetByPinnedPath(value)
+	case mapKeyFile != "":
+		key, err := os.ReadFile(mapKeyFile)
 		if err != nil {
-			handleError(err, fmt.Sprintf("getting pinned map at %s", value))
-			return err
+			printErrorf("Error: reading key file: %v", err)
+			return nil, err
 		}
-		mapID = mapInfo.ID
+		return key, nil
+
+	case mapLookupLPM != "":
+		key, err := utils.EncodeLPMKey(mapLookupLPM)
+		if err != nil {
+			err = fmt.Errorf("%w: %v", bpferrors.ErrInvalidKey, err)
+			printErrorf("Error: %v", err)
+			return nil, err
+		}
+		return key, nil
 
 	default:
-		fmt.Fprintf(os.Stderr, "Error: invalid map identifier: %s. Use 'id', 'name', or 'pinned'\n", identifier)
-		return fmt.Errorf("invalid identifier: %s", identifier)
-	}
+		if !hexGiven || len(hex) == 0 {
+			printErrorf("Error: key data required. Use '%s'", usage)
+			return nil, bpferrors.ErrInvalidKey
+		}
 
-	// Lookup the key
-	valueData, err := mapService.Lookup(mapID, keyData)
-	if err != nil {
-		if bpferrors.IsNotFoundError(err) {
-			fmt.Fprintf(os.Stderr, "Error: key not found in map\n")
-			return bpferrors.ErrKeyNotFound
+		// Parse key data (space-separated hex bytes after "key")
+		key, err := utils.ParseHexBytes(strings.Join(hex, " "))
+		if err != nil {
+			printErrorf("Error: invalid key format: %v", err)
+			return nil, bpferrors.ErrInvalidKey
 		}
-		handleError(err, "looking up key")
-		return err
+		return key, nil
 	}
+}
 
-	entry := output.MapEntry{
-		Key:   keyData,
-		Value: valueData,
+// finishMapKey encodes a --key-dec key in the map's key size and checks
+// that the key read by readMapKey has that size
+func finishMapKey(cmd *cobra.Command, key []byte, mapInfo *maps.MapInfo) ([]byte, error) {
+	if cmd.Flags().Changed("key-dec") {
+		// The byte order was checked by readMapKey
+		order, _ := utils.ParseByteOrder(mapKeyEndian)
+		var err error
+		if key, err = utils.EncodeUint(mapKeyDec, int(mapInfo.KeySize), order); err != nil {
+			err = fmt.Errorf("%w: %v", bpferrors.ErrInvalidKey, err)
+			printErrorf("Error: %v", err)
+			return nil, err
+		}
 	}
 
-	result := formatter.FormatMapEntry(entry, mapInfo.KeySize, mapInfo.ValueSize)
-	fmt.Print(result)
-
-	return nil
+	// Validate the key length against the map's key size
+	if uint32(len(key)) != mapInfo.KeySize {
+		err := fmt.Errorf("%w: expected %d bytes, got %d", bpferrors.ErrInvalidKey, mapInfo.KeySize, len(key))
+		printErrorf("Error: %v", err)
+		return nil, err
+	}
+	return key, nil
 }
 
-// runMapGetNext handles the map getnext command
-func runMapGetNext(cmd *cobra.Command, args []string) error {
-	format := getOutputFormat()
-	formatter := output.NewFormatter(format)
+// runMapLookup handles the map lookup command
+func runMapLookup(cmd *cobra.Command, args []string) error {
+	format := resolveFormat(GetGlobalFlags())
+	formatter := newFormatter(format, output.WithValueAs(mapValueAs), output.WithKeyAs(mapKeyAs))
+
+	if mapLookupRaw && format != output.FormatPlain {
+		err := fmt.Errorf("--raw can't be combined with --json, --pretty, --yaml, --table or --format")
+		printErrorf("Error: %v", err)
+		return err
+	}
 
 	if len(args) < 2 {
-		fmt.Fprintf(os.Stderr, "Error: map identifier required. Use 'gobpftool map getnext <identifier> <value> [key <key_data>]'\n")
+		printError("Error: map identifier required. Use 'gobpftool map lookup <identifier> <value> key <key_data>'")
 		return fmt.Errorf("map identifier required")
 	}
 
-	identifier := args[0]
-	value := args[1]
-
-	// Find the "key" keyword and parse key data (optional)
-	var keyData []byte
+	// Find the "key" keyword and parse key data
 	keyIndex := -1
 	for i, arg := range args {
 		if arg == "key" {
@@ -405,91 +1055,857 @@ func runMapGetNext(cmd *cobra.Command, args []string) error {
 		}
 	}
 
-	if keyIndex != -1 && keyIndex < len(args)-1 {
-		// Parse key data (space-separated hex bytes after "key")
-		keyDataStr := strings.Join(args[keyIndex+1:], " ")
-		var err error
-		keyData, err = utils.ParseHexBytes(keyDataStr)
+	var keyHex []string
+	if keyIndex != -1 {
+		keyHex = args[keyIndex+1:]
+	}
+	keyData, err := readMapKey(cmd, keyIndex != -1, keyHex, "gobpftool map lookup <identifier> <value> key <hex_bytes>")
+	if err != nil {
+		return err
+	}
+
+	// Get map info and lookup
+	ref, mapInfo, err := lookupMap(cmd, args[0], args[1])
+	if err != nil {
+		return err
+	}
+
+	if mapLookupLPM != "" && !maps.IsLPMTrieType(mapInfo.Type) {
+		err = fmt.Errorf("--lpm only applies to LPM trie maps, map %d is a %s map", mapInfo.ID, mapInfo.Type)
+		printErrorf("Error: %v", err)
+		return err
+	}
+
+	if keyData, err = finishMapKey(cmd, keyData, mapInfo); err != nil {
+		return err
+	}
+
+	if err := checkValueAs(mapInfo); err != nil {
+		printErrorf("Error: %v", err)
+		return err
+	}
+	if err := checkKeyAs(mapInfo); err != nil {
+		printErrorf("Error: %v", err)
+		return err
+	}
+
+	// Lookup the key
+	var mapEntry *maps.DecodedEntry
+	if mapDecode {
+		mapEntry, err = mapService.LookupDecodedRef(ref, keyData)
+	} else {
+		var rawEntry *maps.MapEntry
+		if rawEntry, err = mapService.LookupRef(ref, keyData); err == nil {
+			mapEntry = &maps.DecodedEntry{MapEntry: *rawEntry}
+		}
+	}
+	if err != nil {
+		if bpferrors.IsNotFoundError(err) {
+			err = bpferrors.ErrKeyNotFound
+		}
+		return handleError(err, "looking up key")
+	}
+
+	if cmd.Flags().Changed("cpu") {
+		selected, err := selectCPU([]maps.DecodedEntry{*mapEntry}, mapInfo)
 		if err != nil {
-			fmt.Fprintf(os.Stderr, "Error: invalid key format: %v\n", err)
-			return bpferrors.ErrInvalidKey
+			printErrorf("Error: %v", err)
+			return err
 		}
+		mapEntry = &selected[0]
 	}
 
-	// Get map info
-	var mapID uint32
-	var err error
+	if mapLookupRaw {
+		// Bypass the formatter so the bytes can be piped as they are
+		if mapEntry.PerCPUValues != nil {
+			err := fmt.Errorf("--raw needs --cpu to pick one value of a per-CPU map")
+			printErrorf("Error: %v", err)
+			return err
+		}
+		if _, err := resultWriter(cmd).Write(mapEntry.Value); err != nil {
+			return handleError(err, "writing value")
+		}
+		return nil
+	}
 
-	switch identifier {
-	case "id":
-		id, parseErr := strconv.ParseUint(value, 10, 32)
-		if parseErr != nil {
-			fmt.Fprintf(os.Stderr, "Error: invalid map ID: %s\n", value)
-			return bpferrors.ErrInvalidID
+	result := formatter.FormatMapEntry(toOutputMapEntry(*mapEntry), mapInfo.KeySize, mapInfo.ValueSize)
+	fmt.Fprint(resultWriter(cmd), result)
+
+	return nil
+}
+
+// runMapClear handles the map clear command
+func runMapClear(cmd *cobra.Command, args []string) error {
+	ref, err := resolveMapRef(args)
+	if err != nil {
+		return err
+	}
+
+	count, err := mapService.ClearRef(cmd.Context(), ref)
+	if err != nil {
+		return handleError(err, fmt.Sprintf("clearing %s", ref))
+	}
+
+	formatter := newFormatter(resolveFormat(GetGlobalFlags()))
+	fmt.Fprint(resultWriter(cmd), formatter.FormatDeleted(count))
+	return nil
+}
+
+// runMapUpdate handles the map update command
+func runMapUpdate(cmd *cobra.Command, args []string) error {
+	if mapUpdateStdin {
+		return runMapUpdateStdin(cmd, args)
+	}
+
+	const usage = "gobpftool map update <identifier> <value> key <hex_bytes> value <hex_bytes>"
+	if len(args) < 2 {
+		printErrorf("Error: map identifier required. Use '%s'", usage)
+		return fmt.Errorf("invalid arguments")
+	}
+
+	// The key bytes follow "key" and the value bytes follow "value", unless
+	// they come from flags
+	rest := args[2:]
+	valueAt := slices.Index(rest, "value")
+	keyPart, valuePart := rest, []string(nil)
+	if valueAt != -1 {
+		keyPart, valuePart = rest[:valueAt], rest[valueAt+1:]
+	}
+	keyGiven := len(keyPart) > 0
+	if keyGiven && keyPart[0] != "key" {
+		printErrorf("Error: key and value data required. Use '%s'", usage)
+		return fmt.Errorf("invalid arguments")
+	}
+	var keyHex []string
+	if keyGiven {
+		keyHex = keyPart[1:]
+	}
+
+	key, err := readMapKey(cmd, keyGiven, keyHex, usage)
+	if err != nil {
+		return err
+	}
+	value, err := readMapValue(valueAt != -1, valuePart, usage)
+	if err != nil {
+		return err
+	}
+
+	ref, err := resolveMapRef(args[:2])
+	if err != nil {
+		return err
+	}
+	mapInfo, err := mapService.GetByRef(ref)
+	if err != nil {
+		return handleError(err, fmt.Sprintf("getting %s", ref))
+	}
+	if key, err = finishMapKey(cmd, key, mapInfo); err != nil {
+		return err
+	}
+
+	if err := mapService.UpdateRef(ref, key, value); err != nil {
+		return handleError(err, fmt.Sprintf("updating %s", ref))
+	}
+	return nil
+}
+
+// readMapValue reads the value of an update from the hex bytes after
+// "value" (hexGiven) or from --value-file, whichever was given
+func readMapValue(hexGiven bool, hex []string, usage string) ([]byte, error) {
+	switch {
+	case hexGiven && mapValueFile != "":
+		printError("Error: give the value either as hex bytes or with --value-file")
+		return nil, bpferrors.ErrInvalidValue
+
+	case mapValueFile != "":
+		value, err := os.ReadFile(mapValueFile)
+		if err != nil {
+			printErrorf("Error: reading value file: %v", err)
+			return nil, err
 		}
-		mapID = uint32(id)
-		_, err = mapService.GetByID(mapID)
+		return value, nil
+
+	case !hexGiven || len(hex) == 0:
+		printErrorf("Error: value data required. Use '%s'", usage)
+		return nil, bpferrors.ErrInvalidValue
+	}
+
+	value, err := utils.ParseHexBytes(strings.Join(hex, " "))
+	if err != nil {
+		printErrorf("Error: invalid value format: %v", err)
+		return nil, bpferrors.ErrInvalidValue
+	}
+	return value, nil
+}
+
+// runMapDelete handles the map delete command
+func runMapDelete(cmd *cobra.Command, args []string) error {
+	if len(args) < 4 || args[2] != "key" {
+		printError("Error: key data required. Use 'gobpftool map delete <identifier> <value> key <hex_bytes>'")
+		return fmt.Errorf("invalid arguments")
+	}
+
+	key, err := utils.ParseHexBytes(strings.Join(args[3:], " "))
+	if err != nil {
+		printErrorf("Error: invalid key format: %v", err)
+		return bpferrors.ErrInvalidKey
+	}
+
+	ref, err := resolveMapRef(args[:2])
+	if err != nil {
+		return err
+	}
+
+	if err := mapService.DeleteRef(ref, key); err != nil {
+		return handleError(err, fmt.Sprintf("deleting from %s", ref))
+	}
+	return nil
+}
+
+// runMapUpdateStdin handles map update --stdin, applying the entries read
+// from the command's input and printing a summary
+func runMapUpdateStdin(cmd *cobra.Command, args []string) error {
+	if len(args) != 2 {
+		printError("Error: --stdin takes only the map. Use 'gobpftool map update <identifier> <value> --stdin'")
+		return fmt.Errorf("invalid arguments")
+	}
+
+	ref, err := resolveMapRef(args)
+	if err != nil {
+		return err
+	}
+	mapInfo, err := mapService.GetByRef(ref)
+	if err != nil {
+		return handleError(err, fmt.Sprintf("getting %s", ref))
+	}
+
+	result := applyMapUpdates(cmd.InOrStdin(), ref, mapInfo)
+
+	format := resolveFormat(GetGlobalFlags())
+	if format == output.FormatPlain || format == output.FormatTable {
+		// Machine-readable formats carry the errors in the summary
+		for _, e := range result.Errors {
+			printErrorf("Error: %s", e)
+		}
+	}
+	fmt.Fprint(resultWriter(cmd), newFormatter(format).FormatUpdateResult(result))
+
+	if result.Failed > 0 {
+		return fmt.Errorf("%d of %d updates failed", result.Failed, result.Updated+result.Failed)
+	}
+	return nil
+}
+
+// applyMapUpdates updates the map with the entries read from r, one per
+// line as the key bytes followed by the value bytes. Lines that can't be
+// parsed or applied are counted and described in the result, and don't
+// stop the remaining updates.
+func applyMapUpdates(r io.Reader, ref maps.Ref, mapInfo *maps.MapInfo) output.UpdateResult {
+	var result output.UpdateResult
+	fail := func(format string, a ...interface{}) {
+		result.Failed++
+		result.Errors = append(result.Errors, fmt.Sprintf(format, a...))
+	}
+
+	scanner := bufio.NewScanner(r)
+	for lineNo := 1; scanner.Scan(); lineNo++ {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+
+		data, err := utils.ParseHexBytes(line)
 		if err != nil {
-			handleError(err, fmt.Sprintf("getting map with ID %d", mapID))
-			return err
+			fail("line %d: %v", lineNo, err)
+			continue
+		}
+		if uint32(len(data)) != mapInfo.KeySize+mapInfo.ValueSize {
+			fail("line %d: expected %d key and %d value bytes, got %d bytes",
+				lineNo, mapInfo.KeySize, mapInfo.ValueSize, len(data))
+			continue
+		}
+
+		key, value := data[:mapInfo.KeySize], data[mapInfo.KeySize:]
+		if err := mapService.UpdateRef(ref, key, value); err != nil {
+			fail("line %d: %v", lineNo, err)
+			continue
+		}
+		result.Updated++
+	}
+	if err := scanner.Err(); err != nil {
+		fail("reading input: %v", err)
+	}
+
+	return result
+}
+
+// runMapPush handles the map push command
+func runMapPush(cmd *cobra.Command, args []string) error {
+	if len(args) < 4 || args[2] != "value" {
+		printError("Error: value data required. Use 'gobpftool map push <identifier> <value> value <hex_bytes>'")
+		return bpferrors.ErrInvalidValue
+	}
+
+	value, err := utils.ParseHexBytes(strings.Join(args[3:], " "))
+	if err != nil {
+		printErrorf("Error: invalid value format: %v", err)
+		return bpferrors.ErrInvalidValue
+	}
+
+	ref, mapInfo, err := getStackOrQueue(args[:2])
+	if err != nil {
+		return err
+	}
+
+	// Validate the value length against the map's value size
+	if uint32(len(value)) != mapInfo.ValueSize {
+		err = fmt.Errorf("%w: expected %d bytes, got %d", bpferrors.ErrInvalidValue, mapInfo.ValueSize, len(value))
+		printErrorf("Error: %v", err)
+		return err
+	}
+
+	if err := mapService.PushRef(ref, value); err != nil {
+		return handleError(err, fmt.Sprintf("pushing to %s", ref))
+	}
+	return nil
+}
+
+// runMapTake handles the map pop and peek commands, printing the value
+// returned by take
+func runMapTake(cmd *cobra.Command, args []string, action string, take func(ref maps.Ref) ([]byte, error)) error {
+	ref, mapInfo, err := getStackOrQueue(args)
+	if err != nil {
+		return err
+	}
+
+	value, err := take(ref)
+	if err != nil {
+		return handleError(err, fmt.Sprintf("%s %s", action, ref))
+	}
+
+	formatter := newFormatter(resolveFormat(GetGlobalFlags()), output.WithEntryFields(output.EntryValuesOnly))
+	fmt.Fprint(resultWriter(cmd), formatter.FormatMapEntry(output.MapEntry{Value: value}, mapInfo.KeySize, mapInfo.ValueSize))
+	return nil
+}
+
+// runMapRead handles the map read command
+func runMapRead(cmd *cobra.Command, args []string) error {
+	if mapReadCount < 0 {
+		err := fmt.Errorf("--count can't be negative")
+		printErrorf("Error: %v", err)
+		return err
+	}
+
+	ref, err := resolveMapRef(args)
+	if err != nil {
+		return err
+	}
+	mapInfo, err := mapService.GetByRef(ref)
+	if err != nil {
+		return handleError(err, fmt.Sprintf("getting %s", ref))
+	}
+	if !maps.IsRingBufferType(mapInfo.Type) {
+		err := fmt.Errorf("%s is a %s map: %w", ref, mapInfo.Type, maps.ErrNotRingBuffer)
+		printErrorf("Error: %v", err)
+		return err
+	}
+
+	reader, err := mapService.OpenRingBufferRef(ref)
+	if err != nil {
+		return handleError(err, fmt.Sprintf("opening ring buffer of %s", ref))
+	}
+
+	format := resolveFormat(GetGlobalFlags())
+	formatter := newFormatter(format)
+	err = readRecords(cmd.Context(), reader, mapReadCount, mapReadTimeout, func(r maps.Record) error {
+		return printRecord(resultWriter(cmd), format, formatter.FormatRecord(output.Record{Data: r.Data}))
+	})
+	if err != nil {
+		return handleError(err, fmt.Sprintf("reading ring buffer of %s", ref))
+	}
+	return nil
+}
+
+// runMapPerfRead handles the map perf-read command
+func runMapPerfRead(cmd *cobra.Command, args []string) error {
+	if mapReadCount < 0 {
+		err := fmt.Errorf("--count can't be negative")
+		printErrorf("Error: %v", err)
+		return err
+	}
+	perCPUBuffer, err := utils.ParseSize(mapPerCPUBuffer)
+	if err == nil && (perCPUBuffer == 0 || perCPUBuffer > math.MaxInt32) {
+		err = fmt.Errorf("must be between 1 and %d bytes", math.MaxInt32)
+	}
+	if err != nil {
+		err = fmt.Errorf("invalid --per-cpu-buffer %q: %w", mapPerCPUBuffer, err)
+		printErrorf("Error: %v", err)
+		return err
+	}
+
+	ref, err := resolveMapRef(args)
+	if err != nil {
+		return err
+	}
+	mapInfo, err := mapService.GetByRef(ref)
+	if err != nil {
+		return handleError(err, fmt.Sprintf("getting %s", ref))
+	}
+	if !maps.IsPerfEventArrayType(mapInfo.Type) {
+		err := fmt.Errorf("%s is a %s map: %w", ref, mapInfo.Type, maps.ErrNotPerfEventArray)
+		printErrorf("Error: %v", err)
+		return err
+	}
+
+	reader, err := mapService.OpenPerfEventArrayRef(ref, int(perCPUBuffer))
+	if err != nil {
+		return handleError(err, fmt.Sprintf("opening perf event array of %s", ref))
+	}
+
+	format := resolveFormat(GetGlobalFlags())
+	formatter := newFormatter(format)
+	err = readRecords(cmd.Context(), reader, mapReadCount, mapReadTimeout, func(r maps.Record) error {
+		if r.Lost > 0 {
+			printWarning(fmt.Sprintf("Warning: lost %d samples on CPU %d", r.Lost, r.CPU), nil)
+			return nil
+		}
+		return printRecord(resultWriter(cmd), format, formatter.FormatRecord(output.Record{CPU: r.CPU, CPUKnown: true, Data: r.Data}))
+	})
+	if err != nil {
+		return handleError(err, fmt.Sprintf("reading perf event array of %s", ref))
+	}
+	return nil
+}
+
+// printRecord writes a record streamed from a map to w on its own line.
+// YAML records are written as separate documents
+func printRecord(w io.Writer, format output.Format, record string) error {
+	if format == output.FormatYAML {
+		_, err := fmt.Fprint(w, "---\n"+record)
+		return err
+	}
+	_, err := fmt.Fprintln(w, record)
+	return err
+}
+
+// getStackOrQueue resolves a map identifier and returns the map's ref and
+// info, rejecting maps that aren't stacks or queues
+func getStackOrQueue(args []string) (maps.Ref, *maps.MapInfo, error) {
+	ref, err := resolveMapRef(args)
+	if err != nil {
+		return maps.Ref{}, nil, err
+	}
+
+	mapInfo, err := mapService.GetByRef(ref)
+	if err != nil {
+		return maps.Ref{}, nil, handleError(err, fmt.Sprintf("getting %s", ref))
+	}
+	if !maps.IsStackOrQueueType(mapInfo.Type) {
+		err := fmt.Errorf("%s is a %s map: %w", ref, mapInfo.Type, maps.ErrNotStackOrQueue)
+		printErrorf("Error: %v", err)
+		return maps.Ref{}, nil, err
+	}
+	return ref, mapInfo, nil
+}
+
+// runMapPin handles the map pin command
+func runMapPin(cmd *cobra.Command, args []string) error {
+	if len(args) != 3 {
+		printError("Error: invalid arguments. Use 'gobpftool map pin id <ID> <path>'")
+		return fmt.Errorf("invalid arguments")
+	}
+
+	ref, err := resolveMapRef(args[:2])
+	if err != nil {
+		return err
+	}
+
+	path, err := validatePinTarget(args[2])
+	if err != nil {
+		return err
+	}
+
+	if err := mapService.PinRef(ref, path); err != nil {
+		return handleError(err, fmt.Sprintf("pinning %s", ref))
+	}
+
+	return nil
+}
+
+// runMapFreeze handles the map freeze command
+func runMapFreeze(cmd *cobra.Command, args []string) error {
+	ref, err := resolveMapRef(args)
+	if err != nil {
+		return err
+	}
+
+	if err := mapService.FreezeRef(ref); err != nil {
+		return handleError(err, fmt.Sprintf("freezing %s", ref))
+	}
+	return nil
+}
+
+// resolveMapRef parses an "id <ID>" or "pinned <PATH>" map argument pair.
+// The map is left to be opened through the returned Ref, so a pinned map
+// is opened from its pin rather than by an ID another map could reuse.
+// A missing pin and an unmounted bpffs get clear errors here
+func resolveMapRef(args []string) (maps.Ref, error) {
+	ref, err := parseMapRef(args)
+	if err != nil {
+		return maps.Ref{}, err
+	}
+	if ref.IsPinned() {
+		if err := checkPinnedPath("map", ref.Path); err != nil {
+			return maps.Ref{}, err
+		}
+	}
+	return ref, nil
+}
+
+// lookupMap resolves an "id", "name" or "pinned" map identifier and its
+// value to a Ref and the map's info. A name selects the first map with
+// that name by its ID, while a pinned map stays referenced by its pin
+func lookupMap(cmd *cobra.Command, identifier, value string) (maps.Ref, *maps.MapInfo, error) {
+	var ref maps.Ref
+	switch identifier {
+	case "id", "pinned":
+		var err error
+		if ref, err = resolveMapRef([]string{identifier, value}); err != nil {
+			return maps.Ref{}, nil, err
 		}
 
 	case "name":
-		mapInfos, getErr := mapService.GetByName(value)
-		if getErr != nil {
-			handleError(getErr, fmt.Sprintf("getting maps with name %s", value))
-			return getErr
+		mapInfos, err := mapService.GetByNameContext(cmd.Context(), value)
+		if err != nil {
+			return maps.Ref{}, nil, handleError(err, fmt.Sprintf("getting maps with name %s", value))
 		}
 		if len(mapInfos) == 0 {
-			fmt.Fprintf(os.Stderr, "Error: no maps found with name: %s\n", value)
-			return bpferrors.ErrNotFound
+			printErrorf("Error: no maps found with name: %s", value)
+			return maps.Ref{}, nil, bpferrors.ErrNotFound
+		}
+		return maps.ByID(mapInfos[0].ID), &mapInfos[0], nil
+
+	default:
+		printErrorf("Error: invalid map identifier: %s. Use 'id', 'name', or 'pinned'", identifier)
+		return maps.Ref{}, nil, fmt.Errorf("invalid identifier: %s", identifier)
+	}
+
+	mapInfo, err := mapService.GetByRef(ref)
+	if err != nil {
+		return maps.Ref{}, nil, handleError(err, fmt.Sprintf("getting %s", ref))
+	}
+	return ref, mapInfo, nil
+}
+
+// parseMapRef parses an "id <ID>" or "pinned <PATH>" map argument into the
+// map it selects
+func parseMapRef(args []string) (maps.Ref, error) {
+	if len(args) != 2 {
+		printError("Error: map identifier required. Use 'id <ID>' or 'pinned <PATH>'")
+		return maps.Ref{}, fmt.Errorf("map identifier required")
+	}
+
+	identifier, value := args[0], args[1]
+	switch identifier {
+	case "id":
+		id, err := parseID(value)
+		if err != nil {
+			printErrorf("Error: %v", err)
+			return maps.Ref{}, err
 		}
-		mapID = mapInfos[0].ID
+		return maps.ByID(id), nil
 
 	case "pinned":
-		mapInfo, getErr := mapService.GetByPinnedPath(value)
-		if getErr != nil {
-			handleError(getErr, fmt.Sprintf("getting pinned map at %s", value))
-			return getErr
+		if value == "" {
+			printError("Error: pinned path is empty")
+			return maps.Ref{}, fmt.Errorf("pinned path is empty")
 		}
-		mapID = mapInfo.ID
+		return maps.ByPinnedPath(value), nil
 
 	default:
-		fmt.Fprintf(os.Stderr, "Error: invalid map identifier: %s. Use 'id', 'name', or 'pinned'\n", identifier)
-		return fmt.Errorf("invalid identifier: %s", identifier)
+		printErrorf("Error: invalid map identifier: %s. Use 'id' or 'pinned'", identifier)
+		return maps.Ref{}, fmt.Errorf("invalid identifier: %s", identifier)
+	}
+}
+
+// getPinnedMap returns the map pinned at path, checking first that the
+// path exists so a missing pin and an unmounted bpffs get clear errors
+func getPinnedMap(path string) (*maps.MapInfo, error) {
+	if err := checkPinnedPath("map", path); err != nil {
+		return nil, err
+	}
+	mapInfo, err := mapService.GetByPinnedPath(path)
+	if err != nil {
+		return nil, handleError(err, fmt.Sprintf("getting pinned map at %s", path))
+	}
+	return mapInfo, nil
+}
+
+// selectCPU keeps only the --cpu value of per-CPU entries, including the
+// decoded form when present
+func selectCPU(entries []maps.DecodedEntry, mapInfo *maps.MapInfo) ([]maps.DecodedEntry, error) {
+	if !maps.IsPerCPUType(mapInfo.Type) {
+		return nil, fmt.Errorf("%w, map %d is a %s map", maps.ErrNotPerCPU, mapInfo.ID, mapInfo.Type)
+	}
+
+	online, err := maps.OnlineCPUs()
+	if err != nil {
+		return nil, err
+	}
+
+	raw := make([]maps.MapEntry, len(entries))
+	for i, e := range entries {
+		raw[i] = e.MapEntry
+	}
+	raw, err = maps.SelectCPU(raw, mapCPU, online)
+	if err != nil {
+		return nil, err
+	}
+
+	selected := make([]maps.DecodedEntry, len(entries))
+	for i, e := range entries {
+		selected[i] = maps.DecodedEntry{MapEntry: raw[i], FormattedKey: e.FormattedKey}
+		if values, ok := e.FormattedValue.([]interface{}); ok && mapCPU < len(values) {
+			selected[i].FormattedValue = values[mapCPU]
+		}
+	}
+	return selected, nil
+}
+
+// checkValueAs verifies that the --value-as integer kind, if set, matches the
+// map's value size
+func checkValueAs(mapInfo *maps.MapInfo) error {
+	if mapValueAs == "" {
+		return nil
+	}
+	size, err := utils.IntKindSize(mapValueAs)
+	if err != nil {
+		return err
+	}
+	if uint32(size) != mapInfo.ValueSize {
+		return fmt.Errorf("cannot show values of map %d as %s: value size is %d bytes, not %d",
+			mapInfo.ID, mapValueAs, mapInfo.ValueSize, size)
+	}
+	return nil
+}
+
+// checkKeyAs verifies that the --key-as kind, if set, is known and fits the
+// map's key size
+func checkKeyAs(mapInfo *maps.MapInfo) error {
+	if mapKeyAs == "" {
+		return nil
+	}
+	if mapKeyAs != output.KeyAsIP {
+		return fmt.Errorf("invalid --key-as %q, must be %s", mapKeyAs, output.KeyAsIP)
+	}
+	if mapInfo.KeySize != 4 && mapInfo.KeySize != 16 {
+		return fmt.Errorf("cannot show keys of map %d as %s: key size is %d bytes, not 4 or 16",
+			mapInfo.ID, mapKeyAs, mapInfo.KeySize)
+	}
+	return nil
+}
+
+// toOutputMapInfo converts a maps.MapInfo to an output.MapInfo
+func toOutputMapInfo(m maps.MapInfo) output.MapInfo {
+	return output.MapInfo{
+		ID:          m.ID,
+		Type:        m.Type,
+		Name:        m.Name,
+		KeySize:     m.KeySize,
+		ValueSize:   m.ValueSize,
+		MaxEntries:  m.MaxEntries,
+		Flags:       m.Flags,
+		FlagNames:   maps.FlagNames(m.Flags),
+		MemLock:     m.MemLock,
+		PinnedPaths: m.PinnedPaths,
+	}
+}
+
+// toOutputMapDiff converts a maps.EntryDiff to an output.MapDiff
+func toOutputMapDiff(d maps.EntryDiff) output.MapDiff {
+	toOutput := func(e maps.MapEntry) output.MapEntry {
+		return toOutputMapEntry(maps.DecodedEntry{MapEntry: e})
+	}
+
+	var diff output.MapDiff
+	for _, e := range d.Added {
+		diff.Added = append(diff.Added, toOutput(e))
+	}
+	for _, e := range d.Removed {
+		diff.Removed = append(diff.Removed, toOutput(e))
+	}
+	for _, c := range d.Changed {
+		diff.Changed = append(diff.Changed, output.MapEntryChange{Old: toOutput(c.Old), New: toOutput(c.New)})
+	}
+	return diff
+}
+
+// toOutputMapEntry converts a maps.DecodedEntry to an output.MapEntry
+func toOutputMapEntry(e maps.DecodedEntry) output.MapEntry {
+	return output.MapEntry{
+		Key:            e.Key,
+		Value:          e.Value,
+		PerCPUValues:   e.PerCPUValues,
+		FormattedKey:   e.FormattedKey,
+		FormattedValue: e.FormattedValue,
+	}
+}
+
+// runMapGetNext handles the map getnext command
+func runMapGetNext(cmd *cobra.Command, args []string) error {
+	format := resolveFormat(GetGlobalFlags())
+	formatter := newFormatter(format)
+
+	if len(args) < 2 {
+		printError("Error: map identifier required. Use 'gobpftool map getnext <identifier> <value> [key <key_data>]'")
+		return fmt.Errorf("map identifier required")
+	}
+
+	// Find the "key" keyword and parse key data (optional)
+	var keyData []byte
+	keyIndex := -1
+	for i, arg := range args {
+		if arg == "key" {
+			keyIndex = i
+			break
+		}
+	}
+
+	if keyIndex == len(args)-1 {
+		printError("Error: key data required after 'key'. Use 'gobpftool map getnext <identifier> <value> key <hex_bytes>'")
+		return bpferrors.ErrInvalidKey
+	}
+
+	if keyIndex != -1 {
+		// Parse key data (space-separated hex bytes after "key")
+		keyDataStr := strings.Join(args[keyIndex+1:], " ")
+		var err error
+		keyData, err = utils.ParseHexBytes(keyDataStr)
+		if err != nil {
+			printErrorf("Error: invalid key format: %v", err)
+			return bpferrors.ErrInvalidKey
+		}
+	}
+
+	// Get map info
+	ref, _, err := lookupMap(cmd, args[0], args[1])
+	if err != nil {
+		return err
 	}
 
 	// Get next key
-	nextKey, err := mapService.GetNextKey(mapID, keyData)
+	nextKey, err := mapService.GetNextKeyRef(ref, keyData)
 	if err != nil {
 		// Check if it's a "no more keys" error
 		if bpferrors.IsNoMoreKeysError(err) {
 			if keyData == nil {
-				fmt.Fprintf(os.Stderr, "Error: map is empty\n")
+				printError("Error: map is empty")
 				return bpferrors.ErrMapEmpty
 			}
-			fmt.Fprintf(os.Stderr, "Error: no more keys\n")
+			printError("Error: no more keys")
 			return bpferrors.ErrNoMoreKeys
 		}
-		handleError(err, "getting next key")
-		return err
+		return handleError(err, "getting next key")
 	}
 
 	result := formatter.FormatNextKey(keyData, nextKey)
-	fmt.Print(result)
+	fmt.Fprint(resultWriter(cmd), result)
 
 	return nil
 }
 
 func init() {
 	// Initialize the map service
-	mapService = maps.NewService()
+	mapService = maps.NewService(maps.WithInfoCache(mapInfoTTL))
+
+	// Suggest the loaded map IDs after "id"
+	for _, c := range []*cobra.Command{mapShowCmd, mapDumpCmd, mapLookupCmd, mapGetNextCmd, mapClearCmd,
+		mapUpdateCmd, mapDeleteCmd, mapPushCmd, mapPopCmd, mapPeekCmd, mapDiffCmd, mapReadCmd,
+		mapPerfReadCmd, mapFreezeCmd} {
+		c.ValidArgsFunction = completeMapID
+	}
+
+	mapShowCmd.Flags().StringVar(&mapShowFlags.Type, "type", "", "Only show maps of this type (e.g. hash, array)")
+	mapShowCmd.Flags().StringVar(&mapShowFlags.NameContains, "name-contains", "", "Only show maps whose name contains this substring")
+	mapShowCmd.Flags().StringVar(&mapShowFlags.Sort, "sort", "id", "Sort by field: "+strings.Join(maps.SortFields, ", "))
+	mapShowCmd.Flags().BoolVar(&mapShowFlags.Reverse, "reverse", false, "Reverse the sort order")
+	mapShowCmd.Flags().BoolVar(&mapShowFlags.Count, "count", false, "Print the number of matching maps instead of listing them")
+	mapShowCmd.Flags().StringVar(&mapShowFlags.Columns, "columns", "", "Comma-separated table columns to show, implies --table: "+strings.Join(output.MapColumnNames(), ", "))
+	mapShowCmd.Flags().IntVar(&mapShowFlags.Index, "index", noIndex, "Pick the nth (0-based, by ID) of several maps matching a name")
+	mapShowCmd.Flags().BoolVar(&mapShowFlags.Entries, "entries-count", false, "Count the populated entries of each map by walking its keys")
+	mapShowCmd.Flags().IntVar(&mapShowFlags.MaxScan, "max-scan", 100000, "Stop counting entries after this many keys per map (0 for no limit)")
+	mapShowCmd.Flags().StringVar(&mapShowFlags.Memlock, "memlock-min", "", "Only show maps locking at least this much memory, in bytes or with a K, M or G suffix")
+	mapShowCmd.Flags().StringVar(&mapShowFlags.IDRange, "id-range", "", "Only show maps whose ID is in the inclusive range LO-HI; either end may be left out")
+
+	mapDumpCmd.Flags().BoolVar(&mapDecode, "decode", false, "Decode keys and values using the map's BTF")
+	mapLookupCmd.Flags().BoolVar(&mapDecode, "decode", false, "Decode the key and value using the map's BTF")
+	mapDumpCmd.Flags().IntVar(&mapCPU, "cpu", 0, "Only show the value of this CPU for per-CPU maps")
+	mapLookupCmd.Flags().IntVar(&mapCPU, "cpu", 0, "Only show the value of this CPU for per-CPU maps")
+	mapDumpCmd.Flags().StringVar(&mapValueAs, "value-as", "", "Also print values as integers: "+strings.Join(utils.IntKinds, ", "))
+	mapDumpCmd.Flags().StringVar(&mapKeyAs, "key-as", "", "Also print 4- and 16-byte keys as IP addresses: "+output.KeyAsIP)
+	mapLookupCmd.Flags().StringVar(&mapValueAs, "value-as", "", "Also print the value as an integer: "+strings.Join(utils.IntKinds, ", "))
+	mapLookupCmd.Flags().StringVar(&mapKeyAs, "key-as", "", "Also print the key as an IP address: "+output.KeyAsIP)
+	mapLookupCmd.Flags().Uint64Var(&mapKeyDec, "key-dec", 0, "Key as a decimal integer encoded in the map's key size")
+	mapLookupCmd.Flags().StringVar(&mapKeyEndian, "key-endian", "native", "Byte order of --key-dec: native, le or be")
+	mapLookupCmd.Flags().StringVar(&mapKeyFile, "key-file", "", "Read the raw key bytes from a file")
+	mapUpdateCmd.Flags().Uint64Var(&mapKeyDec, "key-dec", 0, "Key as a decimal integer encoded in the map's key size")
+	mapUpdateCmd.Flags().StringVar(&mapKeyEndian, "key-endian", "native", "Byte order of --key-dec: native, le or be")
+	mapUpdateCmd.Flags().StringVar(&mapKeyFile, "key-file", "", "Read the raw key bytes from a file")
+	mapUpdateCmd.Flags().StringVar(&mapValueFile, "value-file", "", "Read the raw value bytes from a file")
+	mapLookupCmd.Flags().StringVar(&mapLookupLPM, "lpm", "", "Key of an LPM trie map as an IPv4 or IPv6 CIDR, e.g. 10.0.0.0/8")
+	mapLookupCmd.Flags().BoolVar(&mapLookupRaw, "raw", false, "Write only the value's raw bytes, e.g. to pipe into xxd")
+	for _, flag := range []string{"decode", "value-as", "key-as"} {
+		mapLookupCmd.MarkFlagsMutuallyExclusive("raw", flag)
+	}
+	mapDumpCmd.Flags().BoolVar(&mapKeysOnly, "keys-only", false, "Only print the keys of the entries")
+	mapDumpCmd.Flags().BoolVar(&mapValuesOnly, "values-only", false, "Only print the values of the entries")
+	mapDumpCmd.MarkFlagsMutuallyExclusive("keys-only", "values-only")
+	mapDiffCmd.Flags().DurationVar(&mapDiffInterval, "interval", time.Second, "Time between dumps")
+	mapDiffCmd.Flags().StringVar(&mapValueAs, "value-as", "", "Also print values as integers: "+strings.Join(utils.IntKinds, ", "))
+	mapDumpCmd.Flags().DurationVar(&mapWatch, "watch", 0, "Dump the map again at this interval until interrupted")
+	mapDumpCmd.Flags().BoolVar(&mapDumpCSV, "csv", false, "Output entries as CSV rows of hex keys and values")
+	mapDumpCmd.Flags().BoolVar(&mapDumpJSONL, "jsonl", false, "Stream entries as one JSON object per line")
+	mapDumpCmd.MarkFlagsMutuallyExclusive("jsonl", "csv")
+	mapDumpCmd.MarkFlagsMutuallyExclusive("jsonl", "watch")
+	mapDumpCmd.MarkFlagsMutuallyExclusive("jsonl", "decode")
+	mapDumpCmd.MarkFlagsMutuallyExclusive("jsonl", "cpu")
+	mapDumpCmd.Flags().IntVar(&mapDumpOffset, "offset", 0, "Skip this many entries before dumping")
+	mapDumpCmd.Flags().IntVar(&mapDumpLimit, "limit", 0, "Dump at most this many entries (0 for all)")
+	mapDumpCmd.Flags().BytesHexVar(&mapDumpKeyPrefix, "key-prefix", nil, "Only dump entries whose key starts with these hex bytes, e.g. 0a000001")
+	mapDumpCmd.Flags().BoolVar(&mapDumpSortKeys, "sort-keys", false, "Sort entries by their key bytes (buffers the whole dump)")
+	mapDumpCmd.MarkFlagsMutuallyExclusive("sort-keys", "jsonl")
+	mapDumpCmd.MarkFlagsMutuallyExclusive("sort-keys", "offset")
+	mapDumpCmd.MarkFlagsMutuallyExclusive("sort-keys", "limit")
+
+	mapUnpinCmd.Flags().Uint32Var(&unpinByID, "by-id", 0, "Remove every pin of the map with this ID")
+
+	mapReadCmd.Flags().IntVar(&mapReadCount, "count", 0, "Stop after this many records (0 for no limit)")
+	mapReadCmd.Flags().DurationVar(&mapReadTimeout, "timeout", 0, "Stop after this long (0 for no limit)")
+	mapPerfReadCmd.Flags().IntVar(&mapReadCount, "count", 0, "Stop after this many samples (0 for no limit)")
+	mapPerfReadCmd.Flags().DurationVar(&mapReadTimeout, "timeout", 0, "Stop after this long (0 for no limit)")
+	mapPerfReadCmd.Flags().StringVar(&mapPerCPUBuffer, "per-cpu-buffer", "64K", "Size of the buffer mapped for every CPU, in bytes or with a K, M or G suffix")
+
+	mapUpdateCmd.Flags().BoolVar(&mapUpdateStdin, "stdin", false, "Read entries from stdin, one 'KEY_DATA VALUE_DATA' line per entry")
+	for _, flag := range []string{"key-dec", "key-file", "value-file"} {
+		mapUpdateCmd.MarkFlagsMutuallyExclusive("stdin", flag)
+	}
 
 	// Add subcommands to map command
 	mapCmd.AddCommand(mapShowCmd)
 	mapCmd.AddCommand(mapDumpCmd)
 	mapCmd.AddCommand(mapLookupCmd)
 	mapCmd.AddCommand(mapGetNextCmd)
+	mapCmd.AddCommand(mapClearCmd)
+	mapCmd.AddCommand(mapUpdateCmd)
+	mapCmd.AddCommand(mapDeleteCmd)
+	mapCmd.AddCommand(mapPushCmd)
+	mapCmd.AddCommand(mapPopCmd)
+	mapCmd.AddCommand(mapPeekCmd)
+	mapCmd.AddCommand(mapReadCmd)
+	mapCmd.AddCommand(mapPerfReadCmd)
+	mapCmd.AddCommand(mapDiffCmd)
+	mapCmd.AddCommand(mapFreezeCmd)
+	mapCmd.AddCommand(mapPinCmd)
+	mapCmd.AddCommand(mapUnpinCmd)
 	mapCmd.AddCommand(mapHelpCmd)
 
 	// Add map command to root command
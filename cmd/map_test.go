@@ -0,0 +1,2049 @@
+package cmd
+
+import (
+	"bytes"
+	"context"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"syscall"
+	"testing"
+
+	bpferrors "github.com/viveksb007/gobpftool/pkg/errors"
+	"github.com/viveksb007/gobpftool/pkg/maps"
+	"github.com/viveksb007/gobpftool/pkg/output"
+)
+
+// mockMapService is a mock implementation of maps.Service for testing.
+type mockMapService struct {
+	maps     []maps.MapInfo
+	entries  map[uint32][]maps.MapEntry
+	unpinned []string
+	// inaccessible lists the IDs ListWithSkipped fails to open
+	inaccessible map[uint32]bool
+	// streamed is called after each entry a dump handed out, e.g. to
+	// cancel the dump halfway
+	streamed func()
+	// reader is the last reader OpenRingBuffer or OpenPerfEventArray
+	// returned
+	reader *fakeRecordReader
+	// perCPUBuffer is the buffer size OpenPerfEventArray was last given
+	perCPUBuffer int
+	// lost makes OpenPerfEventArray report this many samples lost on
+	// CPU 0 before the first sample
+	lost uint64
+	// frozen lists the IDs of the maps Freeze was called on
+	frozen map[uint32]bool
+	// pinned maps the paths GetByPinnedPath knows to map IDs
+	pinned map[string]uint32
+	// refs lists the refs the Ref methods were called with
+	refs []maps.Ref
+}
+
+func (m *mockMapService) List() ([]maps.MapInfo, error) {
+	return m.maps, nil
+}
+
+func (m *mockMapService) ListContext(ctx context.Context) ([]maps.MapInfo, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	return m.List()
+}
+
+func (m *mockMapService) ListWithSkipped(ctx context.Context) (*maps.ListResult, error) {
+	all, err := m.ListContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+	result := &maps.ListResult{}
+	for _, mi := range all {
+		if m.inaccessible[mi.ID] {
+			result.Skipped++
+			continue
+		}
+		result.Maps = append(result.Maps, mi)
+	}
+	return result, nil
+}
+
+func (m *mockMapService) GetByID(id uint32) (*maps.MapInfo, error) {
+	for _, mi := range m.maps {
+		if mi.ID == id {
+			return &mi, nil
+		}
+	}
+	return nil, bpferrors.ErrNotFound
+}
+
+func (m *mockMapService) GetByName(name string) ([]maps.MapInfo, error) {
+	var result []maps.MapInfo
+	for _, mi := range m.maps {
+		if mi.Name == name {
+			result = append(result, mi)
+		}
+	}
+	return result, nil
+}
+
+func (m *mockMapService) GetByNameContext(ctx context.Context, name string) ([]maps.MapInfo, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	return m.GetByName(name)
+}
+
+func (m *mockMapService) GetByPinnedPath(path string) (*maps.MapInfo, error) {
+	id, ok := m.pinned[path]
+	if !ok {
+		return nil, fmt.Errorf("no map pinned at %s: %w", path, bpferrors.ErrNotFound)
+	}
+	return m.GetByID(id)
+}
+
+func (m *mockMapService) Dump(id uint32) ([]maps.MapEntry, error) {
+	if _, err := m.GetByID(id); err != nil {
+		return nil, err
+	}
+	return m.entries[id], nil
+}
+
+func (m *mockMapService) DumpContext(ctx context.Context, id uint32) ([]maps.MapEntry, error) {
+	var entries []maps.MapEntry
+	err := m.DumpStreamContext(ctx, id, func(e maps.MapEntry) error {
+		entries = append(entries, e)
+		return nil
+	})
+	if err != nil && ctx.Err() == nil {
+		return nil, err
+	}
+	return entries, err
+}
+
+func (m *mockMapService) DumpStream(id uint32, fn func(maps.MapEntry) error) error {
+	return m.DumpStreamContext(context.Background(), id, fn)
+}
+
+func (m *mockMapService) DumpStreamContext(ctx context.Context, id uint32, fn func(maps.MapEntry) error) error {
+	entries, err := m.Dump(id)
+	if err != nil {
+		return err
+	}
+	for _, e := range entries {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		if err := fn(e); err != nil {
+			return err
+		}
+		if m.streamed != nil {
+			m.streamed()
+		}
+	}
+	return nil
+}
+
+func (m *mockMapService) DumpBatch(id uint32, batchSize int) ([]maps.MapEntry, error) {
+	return m.Dump(id)
+}
+
+func (m *mockMapService) DumpBatchContext(ctx context.Context, id uint32, batchSize int) ([]maps.MapEntry, error) {
+	return m.DumpContext(ctx, id)
+}
+
+func (m *mockMapService) Lookup(id uint32, key []byte) (*maps.MapEntry, error) {
+	entries, err := m.Dump(id)
+	if err != nil {
+		return nil, err
+	}
+	for _, e := range entries {
+		if bytes.Equal(e.Key, key) {
+			return &e, nil
+		}
+	}
+	return nil, bpferrors.ErrKeyNotFound
+}
+
+func (m *mockMapService) DumpDecoded(id uint32) ([]maps.DecodedEntry, error) {
+	entries, err := m.Dump(id)
+	if err != nil {
+		return nil, err
+	}
+	decoded := make([]maps.DecodedEntry, len(entries))
+	for i, e := range entries {
+		decoded[i] = mockDecode(e)
+	}
+	return decoded, nil
+}
+
+func (m *mockMapService) DumpDecodedContext(ctx context.Context, id uint32) ([]maps.DecodedEntry, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	return m.DumpDecoded(id)
+}
+
+func (m *mockMapService) LookupDecoded(id uint32, key []byte) (*maps.DecodedEntry, error) {
+	entry, err := m.Lookup(id, key)
+	if err != nil {
+		return nil, err
+	}
+	decoded := mockDecode(*entry)
+	return &decoded, nil
+}
+
+// mockDecode decodes test entries as a u32 key and u64 value.
+func mockDecode(e maps.MapEntry) maps.DecodedEntry {
+	return maps.DecodedEntry{
+		MapEntry:       e,
+		FormattedKey:   uint64(binary.LittleEndian.Uint32(e.Key)),
+		FormattedValue: binary.LittleEndian.Uint64(e.Value),
+	}
+}
+
+func (m *mockMapService) GetNextKey(id uint32, key []byte) ([]byte, error) {
+	entries, err := m.Dump(id)
+	if err != nil {
+		return nil, err
+	}
+	if key == nil {
+		if len(entries) == 0 {
+			return nil, bpferrors.ErrNoMoreKeys
+		}
+		return entries[0].Key, nil
+	}
+	for i, e := range entries {
+		if bytes.Equal(e.Key, key) && i+1 < len(entries) {
+			return entries[i+1].Key, nil
+		}
+	}
+	return nil, bpferrors.ErrNoMoreKeys
+}
+
+func (m *mockMapService) Pin(id uint32, path string) error {
+	_, err := m.GetByID(id)
+	return err
+}
+
+// Freeze marks the map frozen, after which Update fails like the kernel
+// does, with EPERM.
+func (m *mockMapService) Freeze(id uint32) error {
+	if _, err := m.GetByID(id); err != nil {
+		return err
+	}
+	if m.frozen == nil {
+		m.frozen = make(map[uint32]bool)
+	}
+	m.frozen[id] = true
+	return nil
+}
+
+func (m *mockMapService) Clear(id uint32) (int, error) {
+	entries, err := m.Dump(id)
+	if err != nil {
+		return 0, err
+	}
+	delete(m.entries, id)
+	return len(entries), nil
+}
+
+func (m *mockMapService) ClearContext(ctx context.Context, id uint32) (int, error) {
+	if err := ctx.Err(); err != nil {
+		return 0, err
+	}
+	return m.Clear(id)
+}
+
+// Update checks the sizes like the kernel does and replaces or adds the entry.
+func (m *mockMapService) Update(id uint32, key, value []byte) error {
+	mi, err := m.GetByID(id)
+	if err != nil {
+		return err
+	}
+	if uint32(len(key)) != mi.KeySize {
+		return bpferrors.ErrInvalidKey
+	}
+	if uint32(len(value)) != mi.ValueSize {
+		return bpferrors.ErrInvalidValue
+	}
+	if m.frozen[id] {
+		return fmt.Errorf("failed to update key: %w", bpferrors.WrapFrozen(syscall.EPERM, true))
+	}
+	for i, e := range m.entries[id] {
+		if bytes.Equal(e.Key, key) {
+			m.entries[id][i].Value = value
+			return nil
+		}
+	}
+	m.entries[id] = append(m.entries[id], maps.MapEntry{Key: key, Value: value})
+	return nil
+}
+
+// Delete rejects array maps like the service does and removes the entry.
+func (m *mockMapService) Delete(id uint32, key []byte) error {
+	mi, err := m.GetByID(id)
+	if err != nil {
+		return err
+	}
+	if mi.Type == "array" || mi.Type == "percpuarray" {
+		return fmt.Errorf("map %d is a %s map: %w", id, mi.Type, bpferrors.ErrDeleteNotSupported)
+	}
+	for i, e := range m.entries[id] {
+		if bytes.Equal(e.Key, key) {
+			m.entries[id] = slices.Delete(m.entries[id], i, i+1)
+			return nil
+		}
+	}
+	return bpferrors.ErrKeyNotFound
+}
+
+func (m *mockMapService) Push(id uint32, value []byte) error {
+	if _, err := m.GetByID(id); err != nil {
+		return err
+	}
+	m.entries[id] = append(m.entries[id], maps.MapEntry{Value: value})
+	return nil
+}
+
+func (m *mockMapService) Pop(id uint32) ([]byte, error) {
+	value, err := m.Peek(id)
+	if err != nil {
+		return nil, err
+	}
+	if mi, _ := m.GetByID(id); mi.Type == "stack" {
+		m.entries[id] = m.entries[id][:len(m.entries[id])-1]
+	} else {
+		m.entries[id] = m.entries[id][1:]
+	}
+	return value, nil
+}
+
+// Peek returns the last pushed value of a stack and the first of a queue.
+func (m *mockMapService) Peek(id uint32) ([]byte, error) {
+	mi, err := m.GetByID(id)
+	if err != nil {
+		return nil, err
+	}
+	entries := m.entries[id]
+	if len(entries) == 0 {
+		return nil, bpferrors.ErrMapEmpty
+	}
+	if mi.Type == "stack" {
+		return entries[len(entries)-1].Value, nil
+	}
+	return entries[0].Value, nil
+}
+
+// CountEntries counts the entries of the map up to limit.
+func (m *mockMapService) CountEntries(id uint32, limit int) (int, bool, error) {
+	if _, err := m.GetByID(id); err != nil {
+		return 0, false, err
+	}
+	count := len(m.entries[id])
+	if limit > 0 && count >= limit {
+		return limit, true, nil
+	}
+	return count, false, nil
+}
+
+// OpenRingBuffer returns a reader of the values of the map's entries.
+func (m *mockMapService) OpenRingBuffer(id uint32) (maps.RecordReader, error) {
+	mi, err := m.GetByID(id)
+	if err != nil {
+		return nil, err
+	}
+	if !maps.IsRingBufferType(mi.Type) {
+		return nil, maps.ErrNotRingBuffer
+	}
+	var records [][]byte
+	for _, e := range m.entries[id] {
+		records = append(records, e.Value)
+	}
+	m.reader = newFakeRecordReader(records...)
+	return m.reader, nil
+}
+
+// OpenPerfEventArray returns a reader of the values of the map's entries,
+// each written on the CPU of its index.
+func (m *mockMapService) OpenPerfEventArray(id uint32, perCPUBuffer int) (maps.RecordReader, error) {
+	mi, err := m.GetByID(id)
+	if err != nil {
+		return nil, err
+	}
+	if !maps.IsPerfEventArrayType(mi.Type) {
+		return nil, maps.ErrNotPerfEventArray
+	}
+	m.perCPUBuffer = perCPUBuffer
+	m.reader = newFakeRecordReader()
+	if m.lost > 0 {
+		m.reader.records = append(m.reader.records, maps.Record{Lost: m.lost})
+	}
+	for i, e := range m.entries[id] {
+		m.reader.records = append(m.reader.records, maps.Record{CPU: i, Data: e.Value})
+	}
+	return m.reader, nil
+}
+
+func (m *mockMapService) Unpin(path string) error {
+	for _, mi := range m.maps {
+		for _, p := range mi.PinnedPaths {
+			if p == path && !slices.Contains(m.unpinned, path) {
+				m.unpinned = append(m.unpinned, path)
+				return nil
+			}
+		}
+	}
+	return bpferrors.ErrNotFound
+}
+
+// resolve records ref and returns the ID of the map it selects.
+func (m *mockMapService) resolve(ref maps.Ref) (uint32, error) {
+	m.refs = append(m.refs, ref)
+	if !ref.IsPinned() {
+		return ref.ID, nil
+	}
+	id, ok := m.pinned[ref.Path]
+	if !ok {
+		return 0, fmt.Errorf("no map pinned at %s: %w", ref.Path, bpferrors.ErrNotFound)
+	}
+	return id, nil
+}
+
+func (m *mockMapService) GetByRef(ref maps.Ref) (*maps.MapInfo, error) {
+	id, err := m.resolve(ref)
+	if err != nil {
+		return nil, err
+	}
+	return m.GetByID(id)
+}
+
+func (m *mockMapService) DumpRef(ctx context.Context, ref maps.Ref, batchSize int) ([]maps.MapEntry, error) {
+	id, err := m.resolve(ref)
+	if err != nil {
+		return nil, err
+	}
+	return m.DumpBatchContext(ctx, id, batchSize)
+}
+
+func (m *mockMapService) DumpStreamRef(ctx context.Context, ref maps.Ref, fn func(maps.MapEntry) error) error {
+	id, err := m.resolve(ref)
+	if err != nil {
+		return err
+	}
+	return m.DumpStreamContext(ctx, id, fn)
+}
+
+func (m *mockMapService) LookupRef(ref maps.Ref, key []byte) (*maps.MapEntry, error) {
+	id, err := m.resolve(ref)
+	if err != nil {
+		return nil, err
+	}
+	return m.Lookup(id, key)
+}
+
+func (m *mockMapService) DumpDecodedRef(ctx context.Context, ref maps.Ref) ([]maps.DecodedEntry, error) {
+	id, err := m.resolve(ref)
+	if err != nil {
+		return nil, err
+	}
+	return m.DumpDecodedContext(ctx, id)
+}
+
+func (m *mockMapService) LookupDecodedRef(ref maps.Ref, key []byte) (*maps.DecodedEntry, error) {
+	id, err := m.resolve(ref)
+	if err != nil {
+		return nil, err
+	}
+	return m.LookupDecoded(id, key)
+}
+
+func (m *mockMapService) ClearRef(ctx context.Context, ref maps.Ref) (int, error) {
+	id, err := m.resolve(ref)
+	if err != nil {
+		return 0, err
+	}
+	return m.ClearContext(ctx, id)
+}
+
+func (m *mockMapService) UpdateRef(ref maps.Ref, key, value []byte) error {
+	id, err := m.resolve(ref)
+	if err != nil {
+		return err
+	}
+	return m.Update(id, key, value)
+}
+
+func (m *mockMapService) DeleteRef(ref maps.Ref, key []byte) error {
+	id, err := m.resolve(ref)
+	if err != nil {
+		return err
+	}
+	return m.Delete(id, key)
+}
+
+func (m *mockMapService) PushRef(ref maps.Ref, value []byte) error {
+	id, err := m.resolve(ref)
+	if err != nil {
+		return err
+	}
+	return m.Push(id, value)
+}
+
+func (m *mockMapService) PopRef(ref maps.Ref) ([]byte, error) {
+	id, err := m.resolve(ref)
+	if err != nil {
+		return nil, err
+	}
+	return m.Pop(id)
+}
+
+func (m *mockMapService) PeekRef(ref maps.Ref) ([]byte, error) {
+	id, err := m.resolve(ref)
+	if err != nil {
+		return nil, err
+	}
+	return m.Peek(id)
+}
+
+func (m *mockMapService) OpenRingBufferRef(ref maps.Ref) (maps.RecordReader, error) {
+	id, err := m.resolve(ref)
+	if err != nil {
+		return nil, err
+	}
+	return m.OpenRingBuffer(id)
+}
+
+func (m *mockMapService) OpenPerfEventArrayRef(ref maps.Ref, perCPUBuffer int) (maps.RecordReader, error) {
+	id, err := m.resolve(ref)
+	if err != nil {
+		return nil, err
+	}
+	return m.OpenPerfEventArray(id, perCPUBuffer)
+}
+
+func (m *mockMapService) FreezeRef(ref maps.Ref) error {
+	id, err := m.resolve(ref)
+	if err != nil {
+		return err
+	}
+	return m.Freeze(id)
+}
+
+func (m *mockMapService) PinRef(ref maps.Ref, path string) error {
+	id, err := m.resolve(ref)
+	if err != nil {
+		return err
+	}
+	return m.Pin(id, path)
+}
+
+func (m *mockMapService) GetNextKeyRef(ref maps.Ref, key []byte) ([]byte, error) {
+	id, err := m.resolve(ref)
+	if err != nil {
+		return nil, err
+	}
+	return m.GetNextKey(id, key)
+}
+
+// withMockMapService swaps in a mock map service for the duration of a test.
+func withMockMapService(t *testing.T, svc *mockMapService) {
+	t.Helper()
+	orig := mapService
+	mapService = svc
+	t.Cleanup(func() { mapService = orig })
+	withBPFCapability(t, true)
+}
+
+// newTestMapService returns a mock with one populated and one empty hash map.
+func newTestMapService() *mockMapService {
+	return &mockMapService{
+		maps: []maps.MapInfo{
+			{ID: 1, Type: "hash", Name: "counts", KeySize: 4, ValueSize: 8, MaxEntries: 16,
+				PinnedPaths: []string{"/sys/fs/bpf/counts", "/sys/fs/bpf/app/counts"}},
+			{ID: 2, Type: "hash", Name: "empty", KeySize: 4, ValueSize: 8, MaxEntries: 16},
+		},
+		entries: map[uint32][]maps.MapEntry{
+			1: {
+				{Key: []byte{1, 0, 0, 0}, Value: []byte{1, 0, 0, 0, 0, 0, 0, 0}},
+				{Key: []byte{2, 0, 0, 0}, Value: []byte{2, 0, 0, 0, 0, 0, 0, 0}},
+			},
+		},
+	}
+}
+
+func TestMapDump(t *testing.T) {
+	withMockMapService(t, newTestMapService())
+
+	tests := []struct {
+		name    string
+		args    []string
+		wantErr error
+	}{
+		{
+			name: "dump by id",
+			args: []string{"map", "dump", "id", "1"},
+		},
+		{
+			name: "dump empty map",
+			args: []string{"map", "dump", "id", "2"},
+		},
+		{
+			name: "dump by name",
+			args: []string{"map", "dump", "name", "counts"},
+		},
+		{
+			name: "dump decoded",
+			args: []string{"map", "dump", "id", "1", "--decode"},
+		},
+		{
+			name:    "non-numeric id",
+			args:    []string{"map", "dump", "id", "abc"},
+			wantErr: bpferrors.ErrInvalidID,
+		},
+		{
+			name:    "missing map",
+			args:    []string{"map", "dump", "id", "3"},
+			wantErr: bpferrors.ErrNotFound,
+		},
+		{
+			name:    "unknown name",
+			args:    []string{"map", "dump", "name", "nope"},
+			wantErr: bpferrors.ErrNotFound,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := executeCommand(tt.args...)
+			if tt.wantErr == nil && err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if tt.wantErr != nil && !errors.Is(err, tt.wantErr) {
+				t.Errorf("error = %v, want %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestMapLookup(t *testing.T) {
+	withMockMapService(t, newTestMapService())
+
+	tests := []struct {
+		name    string
+		args    []string
+		wantErr error
+	}{
+		{
+			name: "existing key",
+			args: []string{"map", "lookup", "id", "1", "key", "01", "00", "00", "00"},
+		},
+		{
+			name: "existing key decoded",
+			args: []string{"map", "lookup", "id", "1", "--decode", "key", "01", "00", "00", "00"},
+		},
+		{
+			name:    "missing key",
+			args:    []string{"map", "lookup", "id", "1", "key", "03", "00", "00", "00"},
+			wantErr: bpferrors.ErrKeyNotFound,
+		},
+		{
+			name:    "short key",
+			args:    []string{"map", "lookup", "id", "1", "key", "01", "00"},
+			wantErr: bpferrors.ErrInvalidKey,
+		},
+		{
+			name:    "no key data",
+			args:    []string{"map", "lookup", "id", "1"},
+			wantErr: bpferrors.ErrInvalidKey,
+		},
+		{
+			name:    "invalid hex",
+			args:    []string{"map", "lookup", "id", "1", "key", "zz", "00", "00", "00"},
+			wantErr: bpferrors.ErrInvalidKey,
+		},
+		{
+			name: "decimal key",
+			args: []string{"map", "lookup", "id", "1", "--key-dec", "2", "--key-endian", "le"},
+		},
+		{
+			name:    "decimal key big endian",
+			args:    []string{"map", "lookup", "id", "1", "--key-dec", "2", "--key-endian", "be"},
+			wantErr: bpferrors.ErrKeyNotFound,
+		},
+		{
+			name:    "decimal key too large",
+			args:    []string{"map", "lookup", "id", "1", "--key-dec", "4294967296"},
+			wantErr: bpferrors.ErrInvalidKey,
+		},
+		{
+			name:    "decimal and hex key",
+			args:    []string{"map", "lookup", "id", "1", "--key-dec", "2", "key", "02", "00", "00", "00"},
+			wantErr: bpferrors.ErrInvalidKey,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := executeCommand(tt.args...)
+			if tt.wantErr == nil && err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if tt.wantErr != nil && !errors.Is(err, tt.wantErr) {
+				t.Errorf("error = %v, want %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestMapGetNext(t *testing.T) {
+	withMockMapService(t, newTestMapService())
+
+	tests := []struct {
+		name    string
+		args    []string
+		wantErr error
+	}{
+		{
+			name: "first key",
+			args: []string{"map", "getnext", "id", "1"},
+		},
+		{
+			name: "next key",
+			args: []string{"map", "getnext", "id", "1", "key", "01", "00", "00", "00"},
+		},
+		{
+			name:    "last key",
+			args:    []string{"map", "getnext", "id", "1", "key", "02", "00", "00", "00"},
+			wantErr: bpferrors.ErrNoMoreKeys,
+		},
+		{
+			name:    "empty map",
+			args:    []string{"map", "getnext", "id", "2"},
+			wantErr: bpferrors.ErrMapEmpty,
+		},
+		{
+			name:    "key without data",
+			args:    []string{"map", "getnext", "id", "1", "key"},
+			wantErr: bpferrors.ErrInvalidKey,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := executeCommand(tt.args...)
+			if tt.wantErr == nil && err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if tt.wantErr != nil && !errors.Is(err, tt.wantErr) {
+				t.Errorf("error = %v, want %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestMapListFilters(t *testing.T) {
+	withMockMapService(t, newTestMapService())
+
+	found, _, err := GetRootCmd().Find([]string{"map", "list"})
+	if err != nil || found != mapShowCmd {
+		t.Fatalf("Find(map list) = %v, %v, want map show", found, err)
+	}
+
+	if err := executeCommand("map", "list", "--type", "HASH", "--name-contains", "count"); err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+}
+
+func TestMapPinRejectsPathOutsideBPFFS(t *testing.T) {
+	withMockMapService(t, newTestMapService())
+
+	err := executeCommand("map", "pin", "id", "1", "/sys/fs/bpf/../counts")
+	if err == nil || errors.Is(err, bpferrors.ErrBpfFSNotMounted) {
+		t.Errorf("error = %v, want a path validation error", err)
+	}
+	if err := executeCommand("map", "pin", "id", "1"); err == nil {
+		t.Error("expected error for missing path, got nil")
+	}
+}
+
+func TestMapUnpin(t *testing.T) {
+	svc := newTestMapService()
+	withMockMapService(t, svc)
+
+	if err := executeCommand("map", "unpin", "/sys/fs/bpf/counts"); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	// Unpinning again only warns
+	if err := executeCommand("map", "unpin", "/sys/fs/bpf/counts"); err != nil {
+		t.Fatalf("unexpected error for repeated unpin: %v", err)
+	}
+	if err := executeCommand("map", "unpin", "--by-id", "1"); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	want := []string{"/sys/fs/bpf/counts", "/sys/fs/bpf/app/counts"}
+	if !slices.Equal(svc.unpinned, want) {
+		t.Errorf("unpinned = %v, want %v", svc.unpinned, want)
+	}
+
+	if err := executeCommand("map", "unpin", "/etc/passwd"); err == nil {
+		t.Error("expected error for a path outside bpffs, got nil")
+	}
+	if err := executeCommand("map", "unpin", "--by-id", "3"); !errors.Is(err, bpferrors.ErrNotFound) {
+		t.Errorf("error = %v, want %v", err, bpferrors.ErrNotFound)
+	}
+	if err := executeCommand("map", "unpin"); err == nil {
+		t.Error("expected error without a path or --by-id, got nil")
+	}
+}
+
+func TestMapClear(t *testing.T) {
+	svc := newTestMapService()
+	withMockMapService(t, svc)
+
+	if err := executeCommand("map", "clear", "id", "1"); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(svc.entries[1]) != 0 {
+		t.Errorf("%d entries left, want 0", len(svc.entries[1]))
+	}
+	if err := executeCommand("map", "delete-all", "id", "2"); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if err := executeCommand("map", "clear", "id", "3"); !errors.Is(err, bpferrors.ErrNotFound) {
+		t.Errorf("error = %v, want %v", err, bpferrors.ErrNotFound)
+	}
+}
+
+func TestMapClear_CancelledContext(t *testing.T) {
+	svc := newTestMapService()
+	withMockMapService(t, svc)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	if err := executeCommandContext(ctx, "map", "clear", "id", "1"); !errors.Is(err, context.Canceled) {
+		t.Errorf("error = %v, want %v", err, context.Canceled)
+	}
+	if len(svc.entries[1]) != 2 {
+		t.Errorf("%d entries left, want 2", len(svc.entries[1]))
+	}
+}
+
+func TestMapPushPopPeek(t *testing.T) {
+	svc := newTestMapService()
+	svc.maps = append(svc.maps,
+		maps.MapInfo{ID: 3, Type: "queue", Name: "queue", ValueSize: 4, MaxEntries: 4},
+		maps.MapInfo{ID: 4, Type: "stack", Name: "stack", ValueSize: 4, MaxEntries: 4},
+	)
+	withMockMapService(t, svc)
+
+	for _, id := range []string{"3", "4"} {
+		for _, v := range []string{"01", "02"} {
+			if err := executeCommand("map", "push", "id", id, "value", v, "00", "00", "00"); err != nil {
+				t.Fatalf("push %s to map %s: %v", v, id, err)
+			}
+		}
+	}
+
+	tests := []struct {
+		args []string
+		want string
+	}{
+		{[]string{"map", "peek", "id", "3"}, "value: 01 00 00 00"},
+		{[]string{"map", "pop", "id", "3"}, "value: 01 00 00 00"},
+		{[]string{"map", "pop", "id", "3"}, "value: 02 00 00 00"},
+		{[]string{"map", "pop", "id", "4"}, "value: 02 00 00 00"},
+		{[]string{"-j", "map", "peek", "id", "4"}, `{"value":"AQAAAA=="}`},
+	}
+	for _, tt := range tests {
+		out, err := executeCommandStdout(t, tt.args...)
+		if err != nil {
+			t.Fatalf("%v: unexpected error: %v", tt.args, err)
+		}
+		if out != tt.want {
+			t.Errorf("%v printed %q, want %q", tt.args, out, tt.want)
+		}
+	}
+
+	if err := executeCommand("map", "pop", "id", "3"); !errors.Is(err, bpferrors.ErrMapEmpty) {
+		t.Errorf("pop of empty queue error = %v, want %v", err, bpferrors.ErrMapEmpty)
+	}
+}
+
+func TestMapPushPopPeek_Errors(t *testing.T) {
+	svc := newTestMapService()
+	svc.maps = append(svc.maps, maps.MapInfo{ID: 3, Type: "queue", Name: "queue", ValueSize: 4, MaxEntries: 4})
+	withMockMapService(t, svc)
+
+	tests := []struct {
+		name string
+		args []string
+		want error
+	}{
+		{"value too short", []string{"map", "push", "id", "3", "value", "01"}, bpferrors.ErrInvalidValue},
+		{"value too long", []string{"map", "push", "id", "3", "value", "01", "00", "00", "00", "00"}, bpferrors.ErrInvalidValue},
+		{"missing value", []string{"map", "push", "id", "3"}, bpferrors.ErrInvalidValue},
+		{"bad hex", []string{"map", "push", "id", "3", "value", "zz"}, bpferrors.ErrInvalidValue},
+		{"push to hash map", []string{"map", "push", "id", "1", "value", "01", "00", "00", "00"}, maps.ErrNotStackOrQueue},
+		{"pop from hash map", []string{"map", "pop", "id", "1"}, maps.ErrNotStackOrQueue},
+		{"peek at hash map", []string{"map", "peek", "id", "1"}, maps.ErrNotStackOrQueue},
+		{"unknown map", []string{"map", "pop", "id", "9"}, bpferrors.ErrNotFound},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := executeCommand(tt.args...); !errors.Is(err, tt.want) {
+				t.Errorf("error = %v, want %v", err, tt.want)
+			}
+		})
+	}
+	if len(svc.entries[3]) != 0 {
+		t.Errorf("%d values pushed, want 0", len(svc.entries[3]))
+	}
+}
+
+func TestMapCPUSelection(t *testing.T) {
+	svc := newTestMapService()
+	svc.maps = append(svc.maps, maps.MapInfo{ID: 3, Type: "percpuhash", Name: "percpu", KeySize: 4, ValueSize: 4})
+	svc.entries[3] = []maps.MapEntry{
+		{Key: []byte{1, 0, 0, 0}, PerCPUValues: [][]byte{{1, 0, 0, 0}}},
+	}
+	withMockMapService(t, svc)
+
+	if err := executeCommand("map", "dump", "id", "3", "--cpu", "0"); err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+	if err := executeCommand("map", "lookup", "id", "3", "--cpu", "0", "key", "01", "00", "00", "00"); err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+	if err := executeCommand("map", "dump", "id", "3", "--cpu", "100000"); err == nil {
+		t.Error("expected error for out of range CPU, got nil")
+	}
+	if err := executeCommand("map", "dump", "id", "1", "--cpu", "0"); !errors.Is(err, maps.ErrNotPerCPU) {
+		t.Errorf("error = %v, want %v", err, maps.ErrNotPerCPU)
+	}
+}
+
+func TestMapLookupKeyFile(t *testing.T) {
+	withMockMapService(t, newTestMapService())
+
+	path := filepath.Join(t.TempDir(), "key.bin")
+	if err := os.WriteFile(path, []byte{2, 0, 0, 0}, 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	out, err := executeCommandStdout(t, "map", "lookup", "id", "1", "--key-file", path)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !strings.Contains(out, "value: 02 00 00 00 00 00 00 00") {
+		t.Errorf("output = %q, want the value of key 2", out)
+	}
+
+	if err := executeCommand("map", "lookup", "id", "1", "--key-file", filepath.Join(t.TempDir(), "missing")); err == nil {
+		t.Error("expected error for missing key file, got nil")
+	}
+}
+
+func TestMapLookupLPM(t *testing.T) {
+	svc := newTestMapService()
+	svc.maps = append(svc.maps, maps.MapInfo{ID: 3, Type: "lpmtrie", Name: "routes", KeySize: 8, ValueSize: 4})
+	key := binary.NativeEndian.AppendUint32(nil, 8)
+	key = append(key, 10, 0, 0, 0)
+	svc.entries[3] = []maps.MapEntry{{Key: key, Value: []byte{7, 0, 0, 0}}}
+	withMockMapService(t, svc)
+
+	out, err := executeCommandStdout(t, "map", "lookup", "id", "3", "--lpm", "10.0.0.0/8")
+	if err != nil {
+		t.Fatalf("map lookup --lpm error = %v", err)
+	}
+	if !strings.Contains(out, "value: 07 00 00 00") {
+		t.Errorf("output = %q, want the value of 10.0.0.0/8", out)
+	}
+
+	for _, args := range [][]string{
+		{"map", "lookup", "id", "3", "--lpm", "10.0.0.0"},
+		{"map", "lookup", "id", "3", "--lpm", "2001:db8::/32"},
+		{"map", "lookup", "id", "1", "--lpm", "10.0.0.0/8"},
+		{"map", "lookup", "id", "3", "--lpm", "10.0.0.0/8", "key", "08", "00", "00", "00", "0a", "00", "00", "00"},
+	} {
+		if err := executeCommand(args...); !errors.Is(err, bpferrors.ErrInvalidKey) && !strings.Contains(fmt.Sprint(err), "--lpm") {
+			t.Errorf("%v: error = %v, want an invalid key or --lpm error", args, err)
+		}
+	}
+}
+
+func TestMapKeyAsIP(t *testing.T) {
+	svc := newTestMapService()
+	svc.maps = append(svc.maps, maps.MapInfo{ID: 3, Type: "hash", Name: "ports", KeySize: 2, ValueSize: 8})
+	withMockMapService(t, svc)
+
+	out, err := executeCommandStdout(t, "map", "dump", "id", "1", "--key-as", "ip")
+	if err != nil {
+		t.Fatalf("map dump --key-as ip error = %v", err)
+	}
+	if !strings.Contains(out, "key: 02 00 00 00 (2.0.0.0)  value:") {
+		t.Errorf("output = %q, want keys as IPv4 addresses", out)
+	}
+
+	// Tabular dumps render the keys too
+	out, err = executeCommandStdout(t, "--table", "map", "dump", "id", "1", "--key-as", "ip")
+	if err != nil || !strings.Contains(out, "(2.0.0.0)") {
+		t.Errorf("--table map dump --key-as ip = %q, %v, want keys as IPv4 addresses", out, err)
+	}
+	out, err = executeCommandStdout(t, "map", "dump", "id", "1", "--csv", "--key-as", "ip")
+	if err != nil || !strings.HasPrefix(out, "key_hex,key_ip,value_hex\n") || !strings.Contains(out, ",2.0.0.0,") {
+		t.Errorf("map dump --csv --key-as ip = %q, %v, want a key_ip column", out, err)
+	}
+
+	out, err = executeCommandStdout(t, "map", "lookup", "id", "1", "key", "01", "00", "00", "00", "--key-as", "ip")
+	if err != nil {
+		t.Fatalf("map lookup --key-as ip error = %v", err)
+	}
+	if !strings.HasPrefix(out, "key: 01 00 00 00 (1.0.0.0) value:") {
+		t.Errorf("output = %q, want the key as an IPv4 address", out)
+	}
+
+	for _, args := range [][]string{
+		{"map", "dump", "id", "1", "--key-as", "mac"},
+		{"map", "dump", "id", "3", "--key-as", "ip"},
+	} {
+		if got, err := executeCommandStdout(t, args...); err == nil || got != "" {
+			t.Errorf("%v = %q, %v, want an error", args, got, err)
+		}
+	}
+}
+
+func TestMapDumpFields(t *testing.T) {
+	withMockMapService(t, newTestMapService())
+
+	out, err := executeCommandStdout(t, "map", "dump", "id", "1", "--keys-only")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if strings.Contains(out, "value") {
+		t.Errorf("--keys-only output = %q, want no values", out)
+	}
+
+	if err := executeCommand("map", "dump", "id", "1", "--keys-only", "--values-only"); err == nil {
+		t.Error("expected error for --keys-only with --values-only, got nil")
+	}
+}
+
+func TestMapDumpValueAs(t *testing.T) {
+	withMockMapService(t, newTestMapService())
+
+	out, err := executeCommandStdout(t, "map", "dump", "id", "1", "--value-as", "u64le")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !strings.Contains(out, "value: 02 00 00 00 00 00 00 00 (2)") {
+		t.Errorf("output = %q, want decimal values", out)
+	}
+
+	for _, kind := range []string{"u32", "i64"} {
+		if err := executeCommand("map", "dump", "id", "1", "--value-as", kind); err == nil {
+			t.Errorf("expected error for --value-as %s, got nil", kind)
+		}
+	}
+}
+
+func TestMapDumpCSV(t *testing.T) {
+	withMockMapService(t, newTestMapService())
+
+	out, err := executeCommandStdout(t, "map", "dump", "id", "1", "--csv")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	want := "key_hex,value_hex\n01000000,0100000000000000\n02000000,0200000000000000\n"
+	if out != want {
+		t.Errorf("output = %q, want %q", out, want)
+	}
+
+	if err := executeCommand("-j", "map", "dump", "id", "1", "--csv"); err == nil {
+		t.Error("expected error for --csv with --json, got nil")
+	}
+}
+
+func TestMapDumpJSONL(t *testing.T) {
+	withMockMapService(t, newTestMapService())
+
+	out, err := executeCommandStdout(t, "map", "dump", "id", "1", "--jsonl", "--keys-only")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	want := `{"key":"AQAAAA=="}` + "\n" + `{"key":"AgAAAA=="}` + "\n"
+	if out != want {
+		t.Errorf("output = %q, want %q", out, want)
+	}
+
+	out, err = executeCommandStdout(t, "map", "dump", "id", "1", "--jsonl")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	lines := strings.Split(strings.TrimSuffix(out, "\n"), "\n")
+	if len(lines) != 2 {
+		t.Fatalf("got %d lines, want 2: %q", len(lines), out)
+	}
+	for _, line := range lines {
+		var entry struct {
+			Key   []byte `json:"key"`
+			Value []byte `json:"value"`
+		}
+		if err := json.Unmarshal([]byte(line), &entry); err != nil {
+			t.Errorf("line %q is not a JSON object: %v", line, err)
+		}
+		if len(entry.Key) != 4 || len(entry.Value) != 8 {
+			t.Errorf("line %q has key %d bytes and value %d bytes, want 4 and 8", line, len(entry.Key), len(entry.Value))
+		}
+	}
+
+	if err := executeCommand("-j", "map", "dump", "id", "1", "--jsonl"); err == nil {
+		t.Error("expected error for --jsonl with --json, got nil")
+	}
+}
+
+func TestMapDumpPage(t *testing.T) {
+	svc := newTestMapService()
+	for k := byte(3); k <= 5; k++ {
+		svc.entries[1] = append(svc.entries[1], maps.MapEntry{Key: []byte{k, 0, 0, 0}, Value: make([]byte, 8)})
+	}
+	withMockMapService(t, svc)
+
+	tests := []struct {
+		offset, limit string
+		wantKeys      []byte
+		wantRead      int // entries read before the dump stopped
+		wantPage      string
+	}{
+		{offset: "0", limit: "2", wantKeys: []byte{1, 2}, wantRead: 2, wantPage: `"offset":0,"limit":2,"partial":true,"partial_reason":"more entries past --limit"}`},
+		{offset: "1", limit: "2", wantKeys: []byte{2, 3}, wantRead: 3, wantPage: `"offset":1,"limit":2,"partial":true`},
+		{offset: "3", limit: "0", wantKeys: []byte{4, 5}, wantRead: 5, wantPage: `"offset":3,"partial":false}`},
+		{offset: "3", limit: "2", wantKeys: []byte{4, 5}, wantRead: 5, wantPage: `"offset":3,"limit":2,"partial":false}`},
+		{offset: "4", limit: "5", wantKeys: []byte{5}, wantRead: 5, wantPage: `"offset":4,"limit":5,"partial":false}`},
+		{offset: "5", limit: "1", wantKeys: nil, wantRead: 5, wantPage: `"offset":5,"limit":1,"partial":false}`},
+		{offset: "9", limit: "3", wantKeys: nil, wantRead: 5, wantPage: `"offset":9,"limit":3,"partial":false}`},
+	}
+
+	for _, tt := range tests {
+		// --jsonl streams the page, -j collects it into one document
+		for _, args := range [][]string{
+			{"map", "dump", "id", "1", "--jsonl", "--keys-only"},
+			{"-j", "map", "dump", "id", "1", "--keys-only"},
+		} {
+			args = append(args, "--offset", tt.offset, "--limit", tt.limit)
+			read := 0
+			svc.streamed = func() { read++ }
+
+			out, err := executeCommandStdout(t, args...)
+			if err != nil {
+				t.Fatalf("%v: unexpected error: %v", args, err)
+			}
+
+			var keys []byte
+			for _, e := range decodeDumpedEntries(t, args[0] == "-j", out) {
+				keys = append(keys, e.Key[0])
+			}
+			if !bytes.Equal(keys, tt.wantKeys) {
+				t.Errorf("%v: keys = %v, want %v", args, keys, tt.wantKeys)
+			}
+			// The stream isn't drained once the page is complete
+			if read != tt.wantRead {
+				t.Errorf("%v: read %d entries, want %d", args, read, tt.wantRead)
+			}
+			if args[0] == "-j" {
+				if want := fmt.Sprintf(`"count":%d,%s`, len(tt.wantKeys), tt.wantPage); !strings.Contains(out, want) {
+					t.Errorf("%v: output = %s, want it to contain %s", args, out, want)
+				}
+			}
+		}
+	}
+
+	if err := executeCommand("map", "dump", "id", "1", "--limit", "-1"); err == nil {
+		t.Error("expected error for a negative --limit, got nil")
+	}
+
+	// Decoded dumps are paged too
+	out, err := executeCommandStdout(t, "-j", "map", "dump", "id", "1", "--decode", "--offset", "1", "--limit", "2")
+	if err != nil {
+		t.Fatalf("--decode with a page error = %v", err)
+	}
+	var keys []byte
+	for _, e := range decodeDumpedEntries(t, true, out) {
+		keys = append(keys, e.Key[0])
+	}
+	if !bytes.Equal(keys, []byte{2, 3}) || !strings.Contains(out, `"partial":true`) {
+		t.Errorf("--decode --offset 1 --limit 2 = %s, want keys 2 and 3 with more past the page", out)
+	}
+}
+
+func TestMapDumpKeyPrefix(t *testing.T) {
+	svc := newTestMapService()
+	svc.entries[1] = []maps.MapEntry{
+		{Key: []byte{0x0a, 0, 0, 1}, Value: make([]byte, 8)},
+		{Key: []byte{0x0b, 0, 0, 1}, Value: make([]byte, 8)},
+		{Key: []byte{0x0a, 0, 0, 2}, Value: make([]byte, 8)},
+		{Key: []byte{0x0a, 1, 0, 0}, Value: make([]byte, 8)},
+		{Key: []byte{0x0a, 0, 0, 3}, Value: make([]byte, 8)},
+	}
+	withMockMapService(t, svc)
+
+	tests := []struct {
+		prefix   string
+		page     []string
+		wantKeys [][]byte
+		wantRead int // entries read before the dump stopped
+	}{
+		{prefix: "0a00", wantKeys: [][]byte{{0x0a, 0, 0, 1}, {0x0a, 0, 0, 2}, {0x0a, 0, 0, 3}}, wantRead: 5},
+		{prefix: "0A000002", wantKeys: [][]byte{{0x0a, 0, 0, 2}}, wantRead: 5},
+		{prefix: "0c", wantKeys: nil, wantRead: 5},
+		// The page counts matching entries only, and the dump stops at the
+		// first match past it
+		{prefix: "0a00", page: []string{"--limit", "1"}, wantKeys: [][]byte{{0x0a, 0, 0, 1}}, wantRead: 2},
+		{prefix: "0a00", page: []string{"--offset", "1", "--limit", "1"}, wantKeys: [][]byte{{0x0a, 0, 0, 2}}, wantRead: 4},
+	}
+
+	for _, tt := range tests {
+		for _, args := range [][]string{
+			{"map", "dump", "id", "1", "--jsonl"},
+			{"-j", "map", "dump", "id", "1"},
+		} {
+			args = append(append(args, "--key-prefix", tt.prefix), tt.page...)
+			read := 0
+			svc.streamed = func() { read++ }
+
+			out, err := executeCommandStdout(t, args...)
+			if err != nil {
+				t.Fatalf("%v: unexpected error: %v", args, err)
+			}
+
+			var keys [][]byte
+			for _, e := range decodeDumpedEntries(t, args[0] == "-j", out) {
+				keys = append(keys, e.Key)
+			}
+			if !slices.EqualFunc(keys, tt.wantKeys, bytes.Equal) {
+				t.Errorf("%v: keys = %v, want %v", args, keys, tt.wantKeys)
+			}
+			// Non-matching entries are skipped while streaming
+			if read != tt.wantRead {
+				t.Errorf("%v: read %d entries, want %d", args, read, tt.wantRead)
+			}
+		}
+
+		// Decoded dumps get the same entries
+		out, err := executeCommandStdout(t, append([]string{"-j", "map", "dump", "id", "1", "--decode", "--key-prefix", tt.prefix}, tt.page...)...)
+		if err != nil {
+			t.Fatalf("--decode --key-prefix %s: unexpected error: %v", tt.prefix, err)
+		}
+		var keys [][]byte
+		for _, e := range decodeDumpedEntries(t, true, out) {
+			keys = append(keys, e.Key)
+		}
+		if !slices.EqualFunc(keys, tt.wantKeys, bytes.Equal) {
+			t.Errorf("--decode --key-prefix %s %v: keys = %v, want %v", tt.prefix, tt.page, keys, tt.wantKeys)
+		}
+	}
+
+	for _, args := range [][]string{
+		{"map", "dump", "id", "1", "--key-prefix", "0a0000000a"},
+		{"map", "dump", "id", "1", "--key-prefix", "zz"},
+	} {
+		if err := executeCommand(args...); err == nil {
+			t.Errorf("%v: expected error, got nil", args)
+		}
+	}
+}
+
+func TestMapDumpSortKeys(t *testing.T) {
+	svc := newTestMapService()
+	svc.entries[1] = []maps.MapEntry{
+		{Key: []byte{3, 0, 0, 0}, Value: make([]byte, 8)},
+		{Key: []byte{1, 0, 0, 1}, Value: make([]byte, 8)},
+		{Key: []byte{2, 0, 0, 0}, Value: make([]byte, 8)},
+		{Key: []byte{1, 0, 0, 0}, Value: make([]byte, 8)},
+	}
+	withMockMapService(t, svc)
+
+	tests := []struct {
+		args     []string
+		wantKeys [][]byte
+	}{
+		{
+			args:     []string{"-j", "map", "dump", "id", "1", "--sort-keys"},
+			wantKeys: [][]byte{{1, 0, 0, 0}, {1, 0, 0, 1}, {2, 0, 0, 0}, {3, 0, 0, 0}},
+		},
+		{
+			args:     []string{"-j", "map", "dump", "id", "1", "--sort-keys", "--key-prefix", "0100"},
+			wantKeys: [][]byte{{1, 0, 0, 0}, {1, 0, 0, 1}},
+		},
+	}
+
+	for _, tt := range tests {
+		out, err := executeCommandStdout(t, tt.args...)
+		if err != nil {
+			t.Fatalf("%v: unexpected error: %v", tt.args, err)
+		}
+		var keys [][]byte
+		for _, e := range decodeDumpedEntries(t, true, out) {
+			keys = append(keys, e.Key)
+		}
+		if !slices.EqualFunc(keys, tt.wantKeys, bytes.Equal) {
+			t.Errorf("%v: keys = %v, want %v", tt.args, keys, tt.wantKeys)
+		}
+	}
+
+	// Sorting buffers the dump, which streaming and paging avoid
+	for _, flag := range []string{"--jsonl", "--offset=1", "--limit=1"} {
+		if err := executeCommand("map", "dump", "id", "1", "--sort-keys", flag); err == nil {
+			t.Errorf("expected error for --sort-keys with %s, got nil", flag)
+		}
+	}
+}
+
+func TestMapFreeze(t *testing.T) {
+	svc := newTestMapService()
+	withMockMapService(t, svc)
+
+	if err := executeCommand("map", "freeze", "id", "1"); err != nil {
+		t.Fatalf("map freeze error = %v", err)
+	}
+	if !svc.frozen[1] {
+		t.Error("map freeze didn't freeze map 1")
+	}
+
+	update := []string{"map", "update", "id", "1", "key", "01", "00", "00", "00",
+		"value", "00", "00", "00", "00", "00", "00", "00", "00"}
+	stderr, err := executeCommandStderr(t, update...)
+	if !errors.Is(err, bpferrors.ErrMapFrozen) {
+		t.Fatalf("map update error = %v, want %v", err, bpferrors.ErrMapFrozen)
+	}
+	if want := "Error updating map 1: map is frozen (read-only)\n"; stderr != want {
+		t.Errorf("stderr = %q, want %q", stderr, want)
+	}
+
+	stderr, _ = executeCommandStderr(t, append([]string{"-j"}, update...)...)
+	if !strings.Contains(stderr, "map is frozen (read-only)") {
+		t.Errorf("-j stderr = %q, want the frozen error", stderr)
+	}
+
+	if err := executeCommand("map", "freeze", "id", "99"); err == nil {
+		t.Error("expected error freezing a missing map, got nil")
+	}
+}
+
+func TestMapDelete(t *testing.T) {
+	svc := newTestMapService()
+	svc.maps = append(svc.maps, maps.MapInfo{ID: 3, Type: "array", Name: "slots", KeySize: 4, ValueSize: 8, MaxEntries: 4})
+	withMockMapService(t, svc)
+
+	out, err := executeCommandStdout(t, "map", "delete", "id", "1", "key", "01", "00", "00", "00")
+	if err != nil {
+		t.Fatalf("map delete error = %v", err)
+	}
+	if out != "" {
+		t.Errorf("map delete output = %q, want none", out)
+	}
+	if len(svc.entries[1]) != 1 || !bytes.Equal(svc.entries[1][0].Key, []byte{2, 0, 0, 0}) {
+		t.Errorf("entries after delete = %v, want only key 02000000", svc.entries[1])
+	}
+
+	stderr, err := executeCommandStderr(t, "map", "delete", "id", "1", "key", "01", "00", "00", "00")
+	if !errors.Is(err, bpferrors.ErrKeyNotFound) {
+		t.Errorf("map delete of a missing key error = %v, want %v", err, bpferrors.ErrKeyNotFound)
+	}
+	if want := "Error: key not found in map\n"; stderr != want {
+		t.Errorf("stderr = %q, want %q", stderr, want)
+	}
+
+	stderr, err = executeCommandStderr(t, "map", "delete", "id", "3", "key", "00", "00", "00", "00")
+	if !errors.Is(err, bpferrors.ErrDeleteNotSupported) {
+		t.Fatalf("map delete from an array error = %v, want %v", err, bpferrors.ErrDeleteNotSupported)
+	}
+	if want := "Error deleting from map 3: delete is not supported for array maps\n"; stderr != want {
+		t.Errorf("stderr = %q, want %q", stderr, want)
+	}
+
+	if err := executeCommand("map", "delete", "id", "1"); err == nil {
+		t.Error("expected error without key data, got nil")
+	}
+}
+
+func TestMapShowPinned(t *testing.T) {
+	svc := newTestMapService()
+	pin := filepath.Join(t.TempDir(), "counts")
+	if err := os.WriteFile(pin, nil, 0o600); err != nil {
+		t.Fatal(err)
+	}
+	svc.pinned = map[string]uint32{pin: 1}
+	withMockMapService(t, svc)
+	t.Cleanup(ResetFlags)
+
+	// A pinned map is a single object, like one looked up by ID
+	got, err := executeCommandStdout(t, "-j", "map", "show", "pinned", pin)
+	if err != nil {
+		t.Fatalf("map show pinned error = %v", err)
+	}
+	if !strings.HasPrefix(got, `{"id":1,`) {
+		t.Errorf("map show pinned = %s, want a bare map object", got)
+	}
+
+	// The path exists, but nothing is pinned there
+	other := filepath.Join(filepath.Dir(pin), "other")
+	if err := os.WriteFile(other, nil, 0o600); err != nil {
+		t.Fatal(err)
+	}
+	if err := executeCommand("map", "show", "pinned", other); !errors.Is(err, bpferrors.ErrNotFound) {
+		t.Errorf("map show pinned of an unpinned path error = %v, want %v", err, bpferrors.ErrNotFound)
+	}
+
+	// A missing path under a root that isn't bpffs points at mounting it
+	root := t.TempDir()
+	stderr, err := executeCommandStderr(t, "--bpffs", root, "map", "dump", "pinned", filepath.Join(root, "missing"))
+	if !errors.Is(err, bpferrors.ErrBpfFSNotMounted) {
+		t.Errorf("map dump pinned without bpffs error = %v, want %v", err, bpferrors.ErrBpfFSNotMounted)
+	}
+	if !strings.Contains(stderr, "sudo mount -t bpf bpf /sys/fs/bpf") {
+		t.Errorf("stderr = %q, want the mount guidance", stderr)
+	}
+}
+
+func TestParseMapRef(t *testing.T) {
+	tests := []struct {
+		args    []string
+		want    maps.Ref
+		wantErr bool
+	}{
+		{args: []string{"id", "7"}, want: maps.ByID(7)},
+		{args: []string{"pinned", "/sys/fs/bpf/counts"}, want: maps.ByPinnedPath("/sys/fs/bpf/counts")},
+		{args: []string{"id", "seven"}, wantErr: true},
+		{args: []string{"pinned", ""}, wantErr: true},
+		{args: []string{"name", "counts"}, wantErr: true},
+		{args: []string{"id"}, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		got, err := parseMapRef(tt.args)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("parseMapRef(%q) error = %v, wantErr %v", tt.args, err, tt.wantErr)
+			continue
+		}
+		if got != tt.want {
+			t.Errorf("parseMapRef(%q) = %+v, want %+v", tt.args, got, tt.want)
+		}
+	}
+}
+
+func TestResolveMapRefPinned(t *testing.T) {
+	svc := newTestMapService()
+	pin := filepath.Join(t.TempDir(), "counts")
+	if err := os.WriteFile(pin, nil, 0o600); err != nil {
+		t.Fatal(err)
+	}
+	svc.pinned = map[string]uint32{pin: 1}
+	withMockMapService(t, svc)
+
+	// Commands taking a map accept its pinned path in place of the ID
+	update := []string{"map", "update", "pinned", pin, "key", "03", "00", "00", "00",
+		"value", "01", "00", "00", "00", "00", "00", "00", "00"}
+	if err := executeCommand(update...); err != nil {
+		t.Fatalf("map update pinned error = %v", err)
+	}
+	if err := executeCommand("map", "delete", "pinned", pin, "key", "03", "00", "00", "00"); err != nil {
+		t.Fatalf("map delete pinned error = %v", err)
+	}
+	if n := len(svc.entries[1]); n != 2 {
+		t.Errorf("map 1 has %d entries after update and delete by path, want 2", n)
+	}
+
+	// The map is opened from its pin, not by the ID the pin resolves to
+	for _, ref := range svc.refs {
+		if ref != maps.ByPinnedPath(pin) {
+			t.Errorf("service called with %+v, want the pinned path", ref)
+		}
+	}
+	if len(svc.refs) == 0 {
+		t.Error("service wasn't called with a Ref")
+	}
+
+	// The path exists, but nothing is pinned there
+	other := filepath.Join(filepath.Dir(pin), "other")
+	if err := os.WriteFile(other, nil, 0o600); err != nil {
+		t.Fatal(err)
+	}
+	if err := executeCommand("map", "freeze", "pinned", other); !errors.Is(err, bpferrors.ErrNotFound) {
+		t.Errorf("map freeze of an unpinned path error = %v, want %v", err, bpferrors.ErrNotFound)
+	}
+}
+
+func TestMapReadPinned(t *testing.T) {
+	svc := newTestMapService()
+	pin := filepath.Join(t.TempDir(), "counts")
+	if err := os.WriteFile(pin, nil, 0o600); err != nil {
+		t.Fatal(err)
+	}
+	svc.pinned = map[string]uint32{pin: 1}
+	withMockMapService(t, svc)
+
+	// Reading commands open a pinned map from its pin too, decoded or not
+	for _, args := range [][]string{
+		{"map", "dump", "pinned", pin},
+		{"map", "dump", "pinned", pin, "--decode"},
+		{"map", "dump", "pinned", pin, "--limit", "1"},
+		{"map", "lookup", "pinned", pin, "key", "01", "00", "00", "00"},
+		{"map", "lookup", "pinned", pin, "key", "01", "00", "00", "00", "--decode"},
+		{"map", "getnext", "pinned", pin},
+	} {
+		svc.refs = nil
+		if _, err := executeCommandStdout(t, args...); err != nil {
+			t.Fatalf("%v error = %v", args, err)
+		}
+		if len(svc.refs) < 2 {
+			t.Errorf("%v: service called with %+v, want the info and the read by Ref", args, svc.refs)
+		}
+		for _, ref := range svc.refs {
+			if ref != maps.ByPinnedPath(pin) {
+				t.Errorf("%v: service called with %+v, want the pinned path", args, ref)
+			}
+		}
+	}
+}
+
+// decodeDumpedEntries parses the output of map dump, either one JSON
+// document with -j or one JSON object per line with --jsonl
+func decodeDumpedEntries(t *testing.T, document bool, out string) []maps.MapEntry {
+	t.Helper()
+	var entries []maps.MapEntry
+	if document {
+		var doc struct {
+			Entries []maps.MapEntry `json:"entries"`
+		}
+		if err := json.Unmarshal([]byte(out), &doc); err != nil {
+			t.Fatalf("output %q is not a JSON document: %v", out, err)
+		}
+		return doc.Entries
+	}
+	for _, line := range strings.Split(strings.TrimSuffix(out, "\n"), "\n") {
+		if line == "" {
+			continue
+		}
+		var e maps.MapEntry
+		if err := json.Unmarshal([]byte(line), &e); err != nil {
+			t.Fatalf("line %q is not a JSON object: %v", line, err)
+		}
+		entries = append(entries, e)
+	}
+	return entries
+}
+
+func TestMapDiffRenderer(t *testing.T) {
+	svc := newTestMapService()
+	withMockMapService(t, svc)
+
+	var out bytes.Buffer
+	render := newMapDiffRenderer(context.Background(), &out, maps.ByID(1), output.FormatPlain, output.NewFormatter(output.FormatPlain))
+
+	// The first dump is the baseline and prints nothing
+	_ = render()
+	if out.String() != "" {
+		t.Errorf("baseline output = %q, want empty", out.String())
+	}
+
+	svc.entries[1] = []maps.MapEntry{
+		{Key: []byte{1, 0, 0, 0}, Value: []byte{5, 0, 0, 0, 0, 0, 0, 0}},
+		{Key: []byte{3, 0, 0, 0}, Value: []byte{3, 0, 0, 0, 0, 0, 0, 0}},
+	}
+	_ = render()
+	want := "+ key: 03 00 00 00  value: 03 00 00 00 00 00 00 00\n" +
+		"- key: 02 00 00 00  value: 02 00 00 00 00 00 00 00\n" +
+		"~ key: 01 00 00 00  value: 01 00 00 00 00 00 00 00 -> 05 00 00 00 00 00 00 00\n"
+	if out.String() != want {
+		t.Errorf("diff output =\n%q\nwant:\n%q", out.String(), want)
+	}
+
+	// Unchanged dumps print nothing
+	out.Reset()
+	_ = render()
+	if out.String() != "" {
+		t.Errorf("unchanged output = %q, want empty", out.String())
+	}
+
+	// Each YAML diff is its own document
+	render = newMapDiffRenderer(context.Background(), &out, maps.ByID(1), output.FormatYAML, output.NewFormatter(output.FormatYAML))
+	_ = render()
+	svc.entries[1] = svc.entries[1][:1]
+	_ = render()
+	if got := out.String(); !strings.HasPrefix(got, "---\n") || strings.Count(got, "---") != 1 {
+		t.Errorf("YAML diff output = %q, want one document", got)
+	}
+}
+
+func TestMapListColumns(t *testing.T) {
+	withMockMapService(t, newTestMapService())
+
+	for _, args := range [][]string{
+		{"map", "list", "--columns", "id,name"},
+		{"--table", "map", "list", "--columns", "ID,Name"},
+	} {
+		got, err := executeCommandStdout(t, args...)
+		if err != nil {
+			t.Fatalf("%v: unexpected error: %v", args, err)
+		}
+		if want := "ID  NAME\n1   counts\n2   empty"; got != want {
+			t.Errorf("%v: output = %q, want %q", args, got, want)
+		}
+	}
+
+	if _, err := executeCommandStdout(t, "map", "list", "--columns", "id,tag"); err == nil {
+		t.Error("expected an error for an unknown column")
+	}
+}
+
+func TestMapListSkipped(t *testing.T) {
+	svc := newTestMapService()
+	svc.inaccessible = map[uint32]bool{2: true}
+	withMockMapService(t, svc)
+
+	stdout, err := executeCommandStdout(t, "map", "list")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if strings.Contains(stdout, "empty") || !strings.Contains(stdout, "counts") {
+		t.Errorf("output = %q, want only the accessible map", stdout)
+	}
+
+	stderr, err := executeCommandStderr(t, "-j", "map", "list", "--count")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if want := `"error":"1 map not accessible; try sudo"`; !strings.Contains(stderr, want) {
+		t.Errorf("stderr = %q, want %q", stderr, want)
+	}
+
+	// JSON consumers are told the listing is incomplete
+	stdout, err = executeCommandStdout(t, "-j", "map", "list")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if want := `"partial":true,"partial_reason":"1 map not accessible"}`; !strings.HasSuffix(stdout, want) {
+		t.Errorf("output = %s, want it to end with %s", stdout, want)
+	}
+	svc.inaccessible = nil
+	if stdout, _ = executeCommandStdout(t, "-j", "map", "list"); !strings.HasSuffix(stdout, `"partial":false}`) {
+		t.Errorf("output = %s, want a complete listing", stdout)
+	}
+
+	// Looking up by ID doesn't enumerate, so nothing is skipped
+	if stderr, _ := executeCommandStderr(t, "map", "show", "id", "1"); strings.Contains(stderr, "not accessible") {
+		t.Errorf("stderr = %q, want no note", stderr)
+	}
+}
+
+func TestMapShowEntriesCount(t *testing.T) {
+	withMockMapService(t, newTestMapService())
+
+	got, err := executeCommandStdout(t, "map", "show", "--entries-count")
+	if err != nil {
+		t.Fatalf("map show --entries-count error = %v", err)
+	}
+	for _, want := range []string{"max_entries 16  memlock 0B  entries 2/16", "entries 0/"} {
+		if !strings.Contains(got, want) {
+			t.Errorf("map show --entries-count = %q, want it to contain %q", got, want)
+		}
+	}
+
+	got, err = executeCommandStdout(t, "-j", "map", "show", "id", "1", "--entries-count", "--max-scan", "1")
+	if err != nil {
+		t.Fatalf("map show --max-scan error = %v", err)
+	}
+	if want := `"entries":1,"entries_capped":true`; !strings.Contains(got, want) {
+		t.Errorf("map show --max-scan = %s, want it to contain %s", got, want)
+	}
+
+	// Counting is opt-in
+	if got, _ := executeCommandStdout(t, "map", "show"); strings.Contains(got, "  entries ") {
+		t.Errorf("map show = %q, want no entry counts", got)
+	}
+	if err := executeCommand("map", "show", "--entries-count", "--max-scan", "-1"); err == nil {
+		t.Error("expected an error for a negative --max-scan")
+	}
+}
+
+func TestMapListCount(t *testing.T) {
+	withMockMapService(t, newTestMapService())
+
+	tests := []struct {
+		args []string
+		want string
+	}{
+		{args: []string{"map", "list", "--count"}, want: "2 maps"},
+		{args: []string{"map", "list", "--count", "--type", "array"}, want: "0 maps"},
+		{args: []string{"map", "list", "--count", "--name-contains", "count"}, want: "1 map"},
+		{args: []string{"-j", "map", "list", "--count", "--type", "hash"}, want: `{"count":2}`},
+	}
+
+	for _, tt := range tests {
+		got, err := executeCommandStdout(t, tt.args...)
+		if err != nil {
+			t.Fatalf("%v: unexpected error: %v", tt.args, err)
+		}
+		if got != tt.want {
+			t.Errorf("%v: output = %q, want %q", tt.args, got, tt.want)
+		}
+	}
+}
+
+func TestMapListMemlockMin(t *testing.T) {
+	svc := newTestMapService()
+	svc.maps[0].MemLock = 8192
+	svc.maps[1].MemLock = 4096
+	withMockMapService(t, svc)
+
+	got, err := executeCommandStdout(t, "map", "list", "--memlock-min", "8k", "--columns", "id,name")
+	if err != nil {
+		t.Fatalf("map list --memlock-min error = %v", err)
+	}
+	if want := "ID  NAME\n1   counts"; got != want {
+		t.Errorf("map list --memlock-min = %q, want %q", got, want)
+	}
+
+	if got, err := executeCommandStdout(t, "map", "list", "--memlock-min", "ten"); err == nil || got != "" {
+		t.Errorf("map list --memlock-min ten = %q, %v, want an error", got, err)
+	}
+}
+
+func TestMapListUnknownType(t *testing.T) {
+	withMockMapService(t, newTestMapService())
+
+	got, err := executeCommandStdout(t, "map", "list", "--type", "hsah")
+	if err == nil || got != "" {
+		t.Errorf("map list --type hsah = %q, %v, want an error", got, err)
+	}
+	if err != nil && !strings.Contains(err.Error(), "valid types: hash, array") {
+		t.Errorf("error = %v, want the valid types listed", err)
+	}
+}
+
+func TestMapListIDRange(t *testing.T) {
+	withMockMapService(t, newTestMapService())
+
+	tests := []struct {
+		args []string
+		want string
+	}{
+		{[]string{"--id-range", "2-"}, "ID  NAME\n2   empty"},
+		{[]string{"--id-range", "-1"}, "ID  NAME\n1   counts"},
+		{[]string{"--id-range", "1-2", "--name-contains", "count"}, "ID  NAME\n1   counts"},
+	}
+	for _, tt := range tests {
+		args := append([]string{"map", "list", "--columns", "id,name"}, tt.args...)
+		got, err := executeCommandStdout(t, args...)
+		if err != nil {
+			t.Fatalf("%v: unexpected error: %v", tt.args, err)
+		}
+		if got != tt.want {
+			t.Errorf("%v: output = %q, want %q", tt.args, got, tt.want)
+		}
+	}
+
+	if got, err := executeCommandStdout(t, "map", "list", "--id-range", "5-1"); err == nil || got != "" {
+		t.Errorf("map list --id-range 5-1 = %q, %v, want an error", got, err)
+	}
+}
+
+func TestMapDumpInterrupted(t *testing.T) {
+	for _, args := range [][]string{
+		{"map", "dump", "id", "1", "--jsonl", "--keys-only"},
+		{"-j", "map", "dump", "id", "1"},
+	} {
+		svc := newTestMapService()
+		ctx, cancel := context.WithCancel(context.Background())
+		// SIGINT arrives once the first entry was read
+		svc.streamed = cancel
+		withMockMapService(t, svc)
+
+		var err error
+		var out bytes.Buffer
+		stderr := captureFile(t, &os.Stderr, func() { err = runCommand(ctx, &out, args...) })
+		cancel()
+		stdout := out.String()
+
+		if !errors.Is(err, bpferrors.ErrInterrupted) || bpferrors.ExitCode(err) == 0 {
+			t.Errorf("%v: error = %v, want %v", args, err, bpferrors.ErrInterrupted)
+		}
+		if stderr != "" {
+			t.Errorf("%v: stderr = %q, want nothing", args, stderr)
+		}
+		// Only the first entry, as a complete document
+		if !strings.Contains(stdout, `"AQAAAA=="`) || strings.Contains(stdout, `"AgAAAA=="`) {
+			t.Errorf("%v: output = %q, want only the first entry", args, stdout)
+		}
+		if !json.Valid([]byte(stdout)) {
+			t.Errorf("%v: output = %q, want valid JSON", args, stdout)
+		}
+	}
+}
+
+func TestApplyMapUpdates(t *testing.T) {
+	svc := newTestMapService()
+	withMockMapService(t, svc)
+	mapInfo, _ := svc.GetByID(1)
+
+	input := strings.NewReader(`# key      value
+01 00 00 00 0a 00 00 00 00 00 00 00
+
+03 00 00 00 0b 00 00 00 00 00 00 00
+04 00 00 00 0c
+zz 00 00 00 0d 00 00 00 00 00 00 00
+`)
+	result := applyMapUpdates(input, maps.ByID(mapInfo.ID), mapInfo)
+
+	if result.Updated != 2 || result.Failed != 2 {
+		t.Errorf("updated %d, failed %d, want 2 updated, 2 failed", result.Updated, result.Failed)
+	}
+	wantErrors := []string{"line 5: expected 4 key and 8 value bytes, got 5 bytes", "line 6: invalid hex byte 'zz'"}
+	if len(result.Errors) != len(wantErrors) {
+		t.Fatalf("errors = %q, want %d", result.Errors, len(wantErrors))
+	}
+	for i, want := range wantErrors {
+		if !strings.HasPrefix(result.Errors[i], want) {
+			t.Errorf("error %d = %q, want prefix %q", i, result.Errors[i], want)
+		}
+	}
+
+	entries := svc.entries[1]
+	if len(entries) != 3 || entries[0].Value[0] != 0x0a || entries[2].Value[0] != 0x0b {
+		t.Errorf("entries = %v, want key 1 updated and key 3 added", entries)
+	}
+}
+
+func TestMapUpdate(t *testing.T) {
+	svc := newTestMapService()
+	withMockMapService(t, svc)
+
+	if err := executeCommand("map", "update", "id", "2", "key", "05", "00", "00", "00", "value", "01", "00", "00", "00", "00", "00", "00", "00"); err != nil {
+		t.Fatalf("map update error = %v", err)
+	}
+	if len(svc.entries[2]) != 1 || svc.entries[2][0].Key[0] != 5 {
+		t.Errorf("entries = %v, want key 5", svc.entries[2])
+	}
+
+	if err := executeCommand("map", "update", "id", "2", "key", "05", "value", "01"); !errors.Is(err, bpferrors.ErrInvalidKey) {
+		t.Errorf("map update with a short key error = %v, want %v", err, bpferrors.ErrInvalidKey)
+	}
+	if err := executeCommand("map", "update", "id", "2", "key", "05", "00", "00", "00"); err == nil {
+		t.Error("map update without a value succeeded")
+	}
+
+	root := GetRootCmd()
+	t.Cleanup(func() { root.SetIn(nil) })
+	root.SetIn(strings.NewReader("06 00 00 00 01 00 00 00 00 00 00 00\n07 00\n"))
+	got, err := executeCommandStdout(t, "-j", "map", "update", "id", "2", "--stdin")
+	if err == nil {
+		t.Error("map update --stdin with a bad line succeeded")
+	}
+	want := `{"updated":1,"failed":1,"errors":["line 2: expected 4 key and 8 value bytes, got 2 bytes"]}`
+	if got != want {
+		t.Errorf("map update --stdin = %q, want %q", got, want)
+	}
+}
+
+func TestMapUpdateKeyValueSources(t *testing.T) {
+	svc := newTestMapService()
+	withMockMapService(t, svc)
+
+	dir := t.TempDir()
+	keyFile := filepath.Join(dir, "key.bin")
+	valueFile := filepath.Join(dir, "value.bin")
+	if err := os.WriteFile(keyFile, []byte{9, 0, 0, 0}, 0o600); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(valueFile, []byte{0xaa, 0, 0, 0, 0, 0, 0, 0xbb}, 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		args      []string
+		wantKey   []byte
+		wantValue []byte
+	}{
+		{
+			args:      []string{"--key-dec", "5", "--key-endian", "be", "value", "01", "00", "00", "00", "00", "00", "00", "00"},
+			wantKey:   []byte{0, 0, 0, 5},
+			wantValue: []byte{1, 0, 0, 0, 0, 0, 0, 0},
+		},
+		{
+			args:      []string{"--key-file", keyFile, "--value-file", valueFile},
+			wantKey:   []byte{9, 0, 0, 0},
+			wantValue: []byte{0xaa, 0, 0, 0, 0, 0, 0, 0xbb},
+		},
+		{
+			args:      []string{"key", "06", "00", "00", "00", "--value-file", valueFile},
+			wantKey:   []byte{6, 0, 0, 0},
+			wantValue: []byte{0xaa, 0, 0, 0, 0, 0, 0, 0xbb},
+		},
+	}
+	for _, tt := range tests {
+		args := append([]string{"map", "update", "id", "2"}, tt.args...)
+		if err := executeCommand(args...); err != nil {
+			t.Fatalf("%v: unexpected error: %v", tt.args, err)
+		}
+		entry, err := svc.Lookup(2, tt.wantKey)
+		if err != nil {
+			t.Fatalf("%v: key % x not updated: %v", tt.args, tt.wantKey, err)
+		}
+		if !bytes.Equal(entry.Value, tt.wantValue) {
+			t.Errorf("%v: value = % x, want % x", tt.args, entry.Value, tt.wantValue)
+		}
+	}
+
+	for _, tt := range []struct {
+		args    []string
+		wantErr error
+	}{
+		{[]string{"key", "05", "00", "00", "00", "--key-dec", "5", "--value-file", valueFile}, bpferrors.ErrInvalidKey},
+		{[]string{"--key-dec", "5", "value", "01", "--value-file", valueFile}, bpferrors.ErrInvalidValue},
+		{[]string{"--key-dec", "5"}, bpferrors.ErrInvalidValue},
+		{[]string{"--key-dec", "4294967296", "--value-file", valueFile}, bpferrors.ErrInvalidKey},
+		{[]string{"--key-file", keyFile, "--value-file", filepath.Join(dir, "missing")}, os.ErrNotExist},
+	} {
+		args := append([]string{"map", "update", "id", "2"}, tt.args...)
+		if err := executeCommand(args...); !errors.Is(err, tt.wantErr) {
+			t.Errorf("%v: error = %v, want %v", tt.args, err, tt.wantErr)
+		}
+	}
+}
+
+func TestMapShowIDJSON(t *testing.T) {
+	withMockMapService(t, newTestMapService())
+
+	// A map looked up by ID is a bare object, like bpftool prints it
+	got, err := executeCommandStdout(t, "-j", "map", "show", "id", "1")
+	if err != nil {
+		t.Fatalf("map show id error = %v", err)
+	}
+	if !strings.HasPrefix(got, `{"id":1,`) {
+		t.Errorf("map show id = %s, want a bare map object", got)
+	}
+
+	got, err = executeCommandStdout(t, "-j", "map", "show", "name", "counts")
+	if err != nil {
+		t.Fatalf("map show name error = %v", err)
+	}
+	if !strings.HasPrefix(got, `{"maps":[`) {
+		t.Errorf("map show name = %s, want a list of maps", got)
+	}
+}
+
+func TestMapShowName(t *testing.T) {
+	svc := newTestMapService()
+	svc.maps = append(svc.maps,
+		maps.MapInfo{ID: 5, Type: "array", Name: "counts"},
+		maps.MapInfo{ID: 3, Type: "hash", Name: "counts"})
+	withMockMapService(t, svc)
+
+	// Names aren't unique, so every match is printed, ordered by ID
+	got, err := executeCommandStdout(t, "map", "show", "name", "counts", "--columns", "id,type")
+	if err != nil {
+		t.Fatalf("map show name error = %v", err)
+	}
+	if want := "ID  TYPE\n1   hash\n3   hash\n5   array"; got != want {
+		t.Errorf("map show name = %q, want %q", got, want)
+	}
+
+	stderr, err := executeCommandStderr(t, "map", "show", "name", "missing")
+	if !errors.Is(err, bpferrors.ErrNotFound) {
+		t.Errorf("map show name of a missing map error = %v, want %v", err, bpferrors.ErrNotFound)
+	}
+	if want := "Error getting maps with name missing: not found\n"; stderr != want {
+		t.Errorf("stderr = %q, want %q", stderr, want)
+	}
+}
+
+func TestMapLookupRaw(t *testing.T) {
+	svc := newTestMapService()
+	svc.entries[1][0].Value = []byte{0xde, 0xad, 0xbe, 0xef, 0x0a, 0x00, 0xff, 0x25}
+	withMockMapService(t, svc)
+
+	got, err := executeCommandStdout(t, "map", "lookup", "id", "1", "key", "01", "00", "00", "00", "--raw")
+	if err != nil {
+		t.Fatalf("map lookup --raw error = %v", err)
+	}
+	if want := []byte{0xde, 0xad, 0xbe, 0xef, 0x0a, 0x00, 0xff, 0x25}; !bytes.Equal([]byte(got), want) {
+		t.Errorf("map lookup --raw = % x, want % x", got, want)
+	}
+
+	got, err = executeCommandStdout(t, "-j", "map", "lookup", "id", "1", "key", "01", "00", "00", "00", "--raw")
+	if err == nil || got != "" {
+		t.Errorf("map lookup -j --raw = %q, %v, want an error", got, err)
+	}
+
+	// --raw writes the bytes alone, so it can't be combined with renderings
+	for _, flag := range [][]string{{"--decode"}, {"--value-as", "u64"}, {"--key-as", "ip"}} {
+		args := append([]string{"map", "lookup", "id", "1", "key", "01", "00", "00", "00", "--raw"}, flag...)
+		if got, err := executeCommandStdout(t, args...); err == nil || got != "" {
+			t.Errorf("%v = %q, %v, want an error", args, got, err)
+		}
+	}
+}
+
+func TestMapDumpQuiet(t *testing.T) {
+	withMockMapService(t, newTestMapService())
+
+	got, err := executeCommandStdout(t, "map", "dump", "id", "1", "--keys-only")
+	if err != nil {
+		t.Fatalf("map dump error = %v", err)
+	}
+	if !strings.HasSuffix(got, "Found 2 elements") {
+		t.Errorf("map dump = %q, want the trailer", got)
+	}
+
+	got, err = executeCommandStdout(t, "-q", "map", "dump", "id", "1", "--keys-only")
+	if err != nil {
+		t.Fatalf("map dump -q error = %v", err)
+	}
+	if want := "key: 01 00 00 00\nkey: 02 00 00 00"; got != want {
+		t.Errorf("map dump -q = %q, want %q", got, want)
+	}
+
+	stderr, _ := executeCommandStderr(t, "--quiet", "map", "show", "name", "missing")
+	if want := "getting maps with name missing: not found\n"; stderr != want {
+		t.Errorf("stderr = %q, want %q", stderr, want)
+	}
+	stderr, _ = executeCommandStderr(t, "--quiet", "map", "dump", "id", "x")
+	if want := "invalid ID \"x\": not a decimal number\n"; stderr != want {
+		t.Errorf("stderr = %q, want %q", stderr, want)
+	}
+}
+
+func TestMapShowNameIndex(t *testing.T) {
+	svc := newTestMapService()
+	svc.maps = append(svc.maps, maps.MapInfo{ID: 5, Type: "hash", Name: "counts"})
+	withMockMapService(t, svc)
+
+	got, err := executeCommandStdout(t, "map", "show", "name", "counts", "--index", "1", "--columns", "id,name")
+	if err != nil {
+		t.Fatalf("map show --index error = %v", err)
+	}
+	if want := "ID  NAME\n5   counts"; got != want {
+		t.Errorf("map show --index 1 = %q, want %q", got, want)
+	}
+
+	stderr, err := executeCommandStderr(t, "-j", "map", "show", "name", "counts")
+	if err != nil {
+		t.Fatalf("map show error = %v", err)
+	}
+	if !strings.Contains(stderr, "--index (0=id 1, 1=id 5)") {
+		t.Errorf("stderr = %q, want the available indices", stderr)
+	}
+
+	if err := executeCommand("map", "list", "--index", "0"); err == nil {
+		t.Error("map list --index succeeded, want an error")
+	}
+	if err := executeCommand("map", "show", "name", "counts", "--index", "-1"); err == nil {
+		t.Error("map show --index -1 succeeded, want an error")
+	}
+}
@@ -0,0 +1,93 @@
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"os"
+
+	"github.com/spf13/cobra"
+
+	"github.com/viveksb007/gobpftool/internal/bpffs"
+	bpferrors "github.com/viveksb007/gobpftool/pkg/errors"
+)
+
+// validatePinTarget checks that path is inside the configured bpffs root and
+// that bpffs is mounted there, returning the cleaned absolute path
+func validatePinTarget(path string) (string, error) {
+	root := bpffs.GetScanner().Root()
+
+	target, err := bpffs.ValidatePinPath(root, path)
+	if err != nil {
+		printErrorf("Error: %v", err)
+		return "", err
+	}
+
+	if err := bpffs.CheckMounted(root); err != nil {
+		printError(bpferrors.FormatBpfFSError())
+		return "", err
+	}
+
+	return target, nil
+}
+
+// checkPinnedPath reports a path with nothing pinned as ErrNotFound, or as
+// ErrBpfFSNotMounted when bpffs isn't mounted at the configured root, so
+// neither surfaces as a raw ENOENT from the kernel. Other stat errors,
+// e.g. permissions, are left to the load to report
+func checkPinnedPath(kind, path string) error {
+	if _, err := os.Stat(path); !errors.Is(err, os.ErrNotExist) {
+		return nil
+	}
+
+	context := fmt.Sprintf("getting pinned %s at %s", kind, path)
+	if err := bpffs.CheckMounted(bpffs.GetScanner().Root()); err != nil {
+		return handleError(err, context)
+	}
+	return handleError(bpferrors.ErrNotFound, context)
+}
+
+// unpinByID is set by --by-id on prog unpin and map unpin
+var unpinByID uint32
+
+// runUnpin removes either the single pin given in args or, with --by-id,
+// every pin of the object returned by pinnedPaths. Paths with nothing
+// pinned are skipped with a warning so repeated runs succeed.
+func runUnpin(cmd *cobra.Command, args []string, kind string,
+	pinnedPaths func(id uint32) ([]string, error), unpin func(path string) error) error {
+	var paths []string
+	switch {
+	case cmd.Flags().Changed("by-id") && len(args) == 0:
+		var err error
+		if paths, err = pinnedPaths(unpinByID); err != nil {
+			return handleError(err, fmt.Sprintf("getting pinned paths of %s %d", kind, unpinByID))
+		}
+	case !cmd.Flags().Changed("by-id") && len(args) == 1:
+		paths = args
+	default:
+		printErrorf("Error: invalid arguments. Use 'gobpftool %s unpin <path>' or 'gobpftool %s unpin --by-id <ID>'", kind, kind)
+		return fmt.Errorf("invalid arguments")
+	}
+
+	root := bpffs.GetScanner().Root()
+	var removed []string
+	for _, path := range paths {
+		target, err := bpffs.ValidatePinPath(root, path)
+		if err != nil {
+			printErrorf("Error: %v", err)
+			return err
+		}
+
+		if err := unpin(target); err != nil {
+			if errors.Is(err, bpferrors.ErrNotFound) {
+				printWarning(fmt.Sprintf("Warning: nothing pinned at %s", target), err)
+				continue
+			}
+			return handleError(err, fmt.Sprintf("unpinning %s", target))
+		}
+		removed = append(removed, target)
+	}
+
+	formatter := newFormatter(resolveFormat(GetGlobalFlags()))
+	fmt.Fprint(resultWriter(cmd), formatter.FormatUnpinned(removed))
+	return nil
+}
@@ -3,17 +3,39 @@ package cmd
 import (
 	"fmt"
 	"os"
-	"strconv"
+	"strings"
+	"time"
 
 	"github.com/spf13/cobra"
 
+	"github.com/viveksb007/gobpftool/internal/bpffs"
+	"github.com/viveksb007/gobpftool/internal/utils"
 	bpferrors "github.com/viveksb007/gobpftool/pkg/errors"
 	"github.com/viveksb007/gobpftool/pkg/output"
 	"github.com/viveksb007/gobpftool/pkg/prog"
 )
 
+// progService is the program service every prog subcommand goes through.
+// init sets it to the kernel-backed prog.NewService; tests swap in a mock
+// with withMockProgService to run whole commands without BPF access
 var progService prog.Service
 
+// progShowFlags holds the flags for the prog show command
+var progShowFlags struct {
+	Type            string // --type
+	TagPrefix       string // --tag-prefix
+	Sort            string // --sort
+	Reverse         bool   // --reverse
+	Count           bool   // --count
+	WithMaps        bool   // --with-maps
+	Columns         string // --columns
+	Memlock         string // --memlock-min
+	IDRange         string // --id-range
+	Metadata        bool   // --metadata
+	Index           int    // --index
+	ResolveMapNames bool   // --resolve-map-names
+}
+
 // progCmd represents the prog command
 var progCmd = &cobra.Command{
 	Use:   "prog",
@@ -22,6 +44,12 @@ var progCmd = &cobra.Command{
 
 Available commands:
   show    Show information about loaded programs
+  dump    Dump the instructions of a program
+  stats   Show the run time statistics of a program
+  pin     Pin a program to the BPF filesystem
+  unpin   Remove program pins from the BPF filesystem
+  load    Load programs from an ELF object and pin them
+  run     Test-run a program against input data
   help    Display help for prog commands`,
 	Run: func(cmd *cobra.Command, args []string) {
 		// If no subcommand is provided, show help
@@ -43,25 +71,92 @@ With arguments, shows specific program(s):
   gobpftool prog show id 123             # Show program with ID 123
   gobpftool prog show tag f0055c08993fea1e  # Show programs with tag
   gobpftool prog show name my_prog       # Show programs with name
-  gobpftool prog show pinned /sys/fs/bpf/my_prog  # Show pinned program`,
+  gobpftool prog show name my_prog --index 1  # Second of several, by ID
+  gobpftool prog show pinned /sys/fs/bpf/my_prog  # Show pinned program
+  gobpftool prog list --type xdp         # List only XDP programs
+  gobpftool prog list --sort memlock --reverse  # Biggest consumers first
+  gobpftool prog list --type xdp --count  # Number of XDP programs
+  gobpftool prog show id 123 --with-maps  # Include details of its maps
+  gobpftool prog list --columns id,name,memlock  # Table of selected columns
+  gobpftool prog list --memlock-min 10M  # Programs locking at least 10 MiB
+  gobpftool prog list --id-range 10-20 --type xdp  # XDP programs with IDs 10 to 20
+  gobpftool prog list --tag-prefix f005  # Programs whose tag starts with f005
+  gobpftool prog show id 123 --metadata  # Include its bpf_metadata_ variables
+  gobpftool -j prog show id 123 --resolve-map-names  # map_ids with map names`,
 	RunE: runProgShow,
 }
 
 func runProgShow(cmd *cobra.Command, args []string) error {
-	// Determine output format
-	format := getOutputFormat()
-	formatter := output.NewFormatter(format)
+	formatter, err := listFormatter(progShowFlags.Columns, output.ProgramColumnNames())
+	if err != nil {
+		printErrorf("Error: %v", err)
+		return err
+	}
 
 	var programs []prog.ProgramInfo
-	var err error
+
+	// Reject unknown types before querying the kernel
+	if progShowFlags.Type != "" {
+		if err := prog.ValidateType(progShowFlags.Type); err != nil {
+			printErrorf("Error: %v", err)
+			return err
+		}
+	}
+	if progShowFlags.TagPrefix != "" {
+		if err := prog.ValidateTagPrefix(progShowFlags.TagPrefix); err != nil {
+			printErrorf("Error: %v", err)
+			return err
+		}
+	}
+	if err := prog.Sort(nil, progShowFlags.Sort, false); err != nil {
+		printErrorf("Error: %v", err)
+		return err
+	}
+	if cmd.Flags().Changed("index") && (len(args) < 2 || (args[0] != "name" && args[0] != "tag")) {
+		err := fmt.Errorf("--index only applies to 'name' and 'tag' lookups")
+		printErrorf("Error: %v", err)
+		return err
+	}
+	if err := checkIndex(cmd, progShowFlags.Index); err != nil {
+		printErrorf("Error: %v", err)
+		return err
+	}
+	if progShowFlags.ResolveMapNames {
+		switch resolveFormat(GetGlobalFlags()) {
+		case output.FormatJSON, output.FormatJSONPretty, output.FormatYAML:
+		default:
+			err := fmt.Errorf("--resolve-map-names only applies to --json, --pretty and --yaml output")
+			printErrorf("Error: %v", err)
+			return err
+		}
+	}
+	var memlockMin uint64
+	if progShowFlags.Memlock != "" {
+		if memlockMin, err = utils.ParseSize(progShowFlags.Memlock); err != nil {
+			printErrorf("Error: %v", err)
+			return err
+		}
+	}
+	var idRange *utils.IDRange
+	if progShowFlags.IDRange != "" {
+		r, err := utils.ParseIDRange(progShowFlags.IDRange)
+		if err != nil {
+			printErrorf("Error: %v", err)
+			return err
+		}
+		idRange = &r
+	}
+
+	// Programs the listing couldn't read, reported after the results
+	var skipped int
 
 	if len(args) == 0 {
 		// List all programs
-		programs, err = progService.List()
+		result, err := progService.ListWithSkipped(cmd.Context())
 		if err != nil {
-			handleError(err, "listing programs")
-			return err
+			return handleError(err, "listing programs")
 		}
+		programs, skipped = result.Programs, result.Skipped
 	} else if len(args) >= 2 {
 		// Parse program identifier
 		identifier := args[0]
@@ -69,75 +164,637 @@ func runProgShow(cmd *cobra.Command, args []string) error {
 
 		switch identifier {
 		case "id":
-			id, parseErr := strconv.ParseUint(value, 10, 32)
+			id, parseErr := parseID(value)
 			if parseErr != nil {
-				fmt.Fprintf(os.Stderr, "Error: invalid program ID: %s\n", value)
-				return bpferrors.ErrInvalidID
+				printErrorf("Error: %v", parseErr)
+				return parseErr
 			}
 
-			program, getErr := progService.GetByID(uint32(id))
+			program, getErr := progService.GetByID(id)
 			if getErr != nil {
-				handleError(getErr, fmt.Sprintf("getting program with ID %d", id))
-				return getErr
+				return handleError(getErr, fmt.Sprintf("getting program with ID %d", id))
 			}
 			programs = []prog.ProgramInfo{*program}
 
 		case "tag":
-			programs, err = progService.GetByTag(value)
+			if _, parseErr := utils.ParseHexString(value); parseErr != nil {
+				printErrorf("Error: invalid program tag: %s", value)
+				return fmt.Errorf("invalid tag %s: %w", value, parseErr)
+			}
+
+			programs, err = progService.GetByTagContext(cmd.Context(), value)
 			if err != nil {
-				handleError(err, fmt.Sprintf("getting programs with tag %s", value))
+				return handleError(err, fmt.Sprintf("getting programs with tag %s", value))
+			}
+			if len(programs) == 0 {
+				printErrorf("Error: no programs found with tag: %s", value)
+				return bpferrors.ErrNotFound
+			}
+			programs, err = selectMatch("program", "tag "+value, programs, programID, progShowFlags.Index)
+			if err != nil {
+				printErrorf("Error: %v", err)
 				return err
 			}
 
 		case "name":
-			programs, err = progService.GetByName(value)
+			programs, err = progService.GetByNameContext(cmd.Context(), value)
+			if err != nil {
+				return handleError(err, fmt.Sprintf("getting programs with name %s", value))
+			}
+			if len(programs) == 0 {
+				printErrorf("Error: no programs found with name: %s", value)
+				return bpferrors.ErrNotFound
+			}
+			programs, err = selectMatch("program", "name "+value, programs, programID, progShowFlags.Index)
 			if err != nil {
-				handleError(err, fmt.Sprintf("getting programs with name %s", value))
+				printErrorf("Error: %v", err)
 				return err
 			}
 
 		case "pinned":
-			program, getErr := progService.GetByPinnedPath(value)
+			program, getErr := getPinnedProgram(value)
 			if getErr != nil {
-				handleError(getErr, fmt.Sprintf("getting pinned program at %s", value))
 				return getErr
 			}
 			programs = []prog.ProgramInfo{*program}
 
 		default:
-			fmt.Fprintf(os.Stderr, "Error: invalid program identifier: %s. Use 'id', 'tag', 'name', or 'pinned'\n", identifier)
+			printErrorf("Error: invalid program identifier: %s. Use 'id', 'tag', 'name', or 'pinned'", identifier)
 			return fmt.Errorf("invalid identifier: %s", identifier)
 		}
 	} else {
-		fmt.Fprintf(os.Stderr, "Error: invalid arguments. Use 'gobpftool prog show' or 'gobpftool prog show <identifier> <value>'\n")
+		printError("Error: invalid arguments. Use 'gobpftool prog show' or 'gobpftool prog show <identifier> <value>'")
 		return fmt.Errorf("invalid arguments")
 	}
 
+	// Apply the type, tag prefix, memlock and ID range filters
+	if progShowFlags.Type != "" {
+		programs, _ = prog.FilterByType(programs, progShowFlags.Type)
+	}
+	if progShowFlags.TagPrefix != "" {
+		programs, _ = prog.FilterByTagPrefix(programs, progShowFlags.TagPrefix)
+	}
+	if memlockMin > 0 {
+		programs = prog.FilterByMemlock(programs, memlockMin)
+	}
+	if idRange != nil {
+		programs = prog.FilterByIDRange(programs, *idRange)
+	}
+
+	defer warnPinScan()
+	defer warnSkipped(skipped, "program")
+	var opts []output.Option
+	if skipped > 0 {
+		// The columns were validated above
+		opts = append(opts, output.WithPartial(skippedReason(skipped, "program")))
+		formatter, _ = listFormatter(progShowFlags.Columns, output.ProgramColumnNames(), opts...)
+	}
+
+	if progShowFlags.Count {
+		fmt.Fprint(resultWriter(cmd), formatter.FormatCount(len(programs), "program"))
+		return nil
+	}
+
+	_ = prog.Sort(programs, progShowFlags.Sort, progShowFlags.Reverse)
+
 	// Convert prog.ProgramInfo to output.ProgramInfo
 	outputPrograms := make([]output.ProgramInfo, len(programs))
 	for i, p := range programs {
-		outputPrograms[i] = output.ProgramInfo{
-			ID:        p.ID,
-			Type:      p.Type,
-			Name:      p.Name,
-			Tag:       p.Tag,
-			GPL:       p.GPL,
-			LoadedAt:  p.LoadedAt,
-			UID:       p.UID,
-			BytesXlat: p.BytesXlated,
-			BytesJIT:  p.BytesJIT,
-			MemLock:   p.MemLock,
-			MapIDs:    p.MapIDs,
+		outputPrograms[i] = toOutputProgramInfo(p)
+	}
+
+	if progShowFlags.WithMaps {
+		expandProgramMaps(outputPrograms)
+	}
+	if progShowFlags.Metadata {
+		addProgramMetadata(outputPrograms)
+	}
+	if progShowFlags.ResolveMapNames {
+		opts = append(opts, output.WithMapNames(resolveMapNames(outputPrograms)))
+		formatter, _ = listFormatter(progShowFlags.Columns, output.ProgramColumnNames(), opts...)
+	}
+
+	// Format and output the results. A program looked up by ID or pinned
+	// path is printed as a single object, like bpftool does
+	var result string
+	if len(args) >= 2 && (args[0] == "id" || args[0] == "pinned") && len(outputPrograms) == 1 {
+		result = formatter.FormatProgram(outputPrograms[0])
+	} else {
+		result = formatter.FormatPrograms(outputPrograms)
+	}
+	fmt.Fprint(resultWriter(cmd), result)
+
+	return nil
+}
+
+// programID returns the ID of p, for selectMatch
+func programID(p prog.ProgramInfo) uint32 { return p.ID }
+
+// toOutputProgramInfo converts a prog.ProgramInfo to an output.ProgramInfo
+func toOutputProgramInfo(p prog.ProgramInfo) output.ProgramInfo {
+	var attach *output.AttachInfo
+	if a := p.Attach; a != nil {
+		attach = &output.AttachInfo{
+			Type:     a.Type,
+			Ifindex:  a.Ifindex,
+			BTFObjID: a.BTFObjID,
+			BTFID:    a.BTFID,
+			Target:   a.Target,
+		}
+	}
+
+	return output.ProgramInfo{
+		ID:            p.ID,
+		Type:          p.Type,
+		Name:          p.Name,
+		Tag:           p.Tag,
+		GPL:           p.GPL,
+		LoadedAt:      p.LoadedAt,
+		UID:           p.UID,
+		UIDKnown:      p.UIDKnown,
+		BytesXlat:     p.BytesXlated,
+		BytesJIT:      p.BytesJIT,
+		MemLock:       p.MemLock,
+		MapIDs:        p.MapIDs,
+		BTFID:         p.BTFID,
+		PinnedPaths:   p.PinnedPaths,
+		RunTimeNS:     p.RunTimeNS,
+		RunCount:      p.RunCount,
+		VerifiedInsns: p.VerifiedInsns,
+		Attach:        attach,
+	}
+}
+
+// expandProgramMaps fills in the details of each program's maps. Maps that
+// can't be read get a placeholder noting the error so one unreadable map
+// doesn't hide the rest of the listing.
+func expandProgramMaps(programs []output.ProgramInfo) {
+	// Programs often share maps; look each one up once
+	seen := make(map[uint32]output.ProgramMap)
+	for i := range programs {
+		for _, id := range programs[i].MapIDs {
+			m, ok := seen[id]
+			if !ok {
+				m = output.ProgramMap{ID: id}
+				if info, err := mapService.GetByID(id); err != nil {
+					m.Error = err.Error()
+				} else {
+					outputInfo := toOutputMapInfo(*info)
+					m.Info = &outputInfo
+				}
+				seen[id] = m
+			}
+			programs[i].Maps = append(programs[i].Maps, m)
+		}
+	}
+}
+
+// resolveMapNames looks up the names of the maps the programs use, once per
+// map. Maps that can't be read are left out, so they're listed by ID only.
+func resolveMapNames(programs []output.ProgramInfo) map[uint32]string {
+	names := make(map[uint32]string)
+	tried := make(map[uint32]bool)
+	for _, p := range programs {
+		for _, id := range p.MapIDs {
+			if tried[id] {
+				continue
+			}
+			tried[id] = true
+			if info, err := mapService.GetByID(id); err == nil {
+				names[id] = info.Name
+			}
 		}
 	}
+	return names
+}
 
-	// Format and output the results
-	result := formatter.FormatPrograms(outputPrograms)
-	fmt.Print(result)
+// addProgramMetadata fills in the metadata each program declares. Programs
+// whose metadata can't be read are shown without it rather than failing the
+// listing.
+func addProgramMetadata(programs []output.ProgramInfo) {
+	for i := range programs {
+		metadata, err := progService.Metadata(programs[i].ID)
+		if err != nil {
+			continue
+		}
+		for _, m := range metadata {
+			programs[i].Metadata = append(programs[i].Metadata, output.MetadataEntry{Name: m.Name, Value: m.Value})
+		}
+	}
+}
 
+// progDumpCmd represents the prog dump command
+var progDumpCmd = &cobra.Command{
+	Use:   "dump",
+	Short: "Dump the instructions of a program",
+	Long: `Dump the instructions of a loaded eBPF program.
+
+Available commands:
+  xlated  Dump the translated (verifier-processed) bytecode
+  jited   Dump the JIT-compiled machine code`,
+	Run: func(cmd *cobra.Command, args []string) {
+		cmd.Help()
+	},
+}
+
+// progDumpXlatedCmd represents the prog dump xlated command
+var progDumpXlatedCmd = &cobra.Command{
+	Use:   "xlated PROG",
+	Short: "Dump the translated bytecode of a program",
+	Long: `Dump the translated bytecode of a program after the verifier rewrote it.
+
+  gobpftool prog dump xlated id 123                  # One instruction per line
+  gobpftool prog dump xlated pinned /sys/fs/bpf/prog # Dump pinned program
+  gobpftool -j prog dump xlated id 123               # Offsets and raw bytes`,
+	RunE: runProgDumpXlated,
+}
+
+// progDumpJitedCmd represents the prog dump jited command
+var progDumpJitedCmd = &cobra.Command{
+	Use:   "jited PROG",
+	Short: "Dump the JIT-compiled machine code of a program",
+	Long: `Dump the JIT-compiled machine code of a program as a hexdump.
+
+  gobpftool prog dump jited id 123                  # 16 bytes per row
+  gobpftool prog dump jited pinned /sys/fs/bpf/prog # Dump pinned program
+  gobpftool -j prog dump jited id 123               # Raw bytes under "jited"`,
+	RunE: runProgDumpJited,
+}
+
+// runProgDumpXlated handles the prog dump xlated command
+func runProgDumpXlated(cmd *cobra.Command, args []string) error {
+	formatter := newFormatter(resolveFormat(GetGlobalFlags()))
+
+	id, err := resolveProgID(args)
+	if err != nil {
+		return err
+	}
+
+	insns, err := progService.DumpXlated(id)
+	if err != nil {
+		return handleError(err, fmt.Sprintf("dumping program %d", id))
+	}
+
+	outputInsns := make([]output.Instruction, len(insns))
+	for i, ins := range insns {
+		outputInsns[i] = output.Instruction{Offset: ins.Offset, Raw: ins.Raw, Text: ins.Text}
+	}
+
+	fmt.Fprint(resultWriter(cmd), formatter.FormatInstructions(outputInsns))
 	return nil
 }
 
+// runProgDumpJited handles the prog dump jited command
+func runProgDumpJited(cmd *cobra.Command, args []string) error {
+	formatter := newFormatter(resolveFormat(GetGlobalFlags()))
+
+	id, err := resolveProgID(args)
+	if err != nil {
+		return err
+	}
+
+	code, err := progService.DumpJited(id)
+	if err != nil {
+		return handleError(err, fmt.Sprintf("dumping program %d", id))
+	}
+
+	fmt.Fprint(resultWriter(cmd), formatter.FormatJited(code))
+	return nil
+}
+
+// progStatsSample is set by --sample on prog stats
+var progStatsSample time.Duration
+
+// statsEnabled reports whether the kernel collects BPF run statistics;
+// replaced in tests
+var statsEnabled = prog.StatsEnabled
+
+// enableStats turns on BPF run statistics until the handle is closed;
+// replaced in tests
+var enableStats = prog.EnableStats
+
+// progStatsCmd represents the prog stats command
+var progStatsCmd = &cobra.Command{
+	Use:   "stats PROG",
+	Short: "Show the run time statistics of a program",
+	Long: `Show how often a program ran and how long it took on average.
+
+The kernel only collects statistics while they're enabled, either with
+'sysctl -w kernel.bpf_stats_enabled=1' or for the --sample duration.
+
+  gobpftool prog stats id 123                  # Totals and average run time
+  gobpftool prog stats id 123 --sample 5s      # Collect for 5s, then report
+  gobpftool -j prog stats pinned /sys/fs/bpf/prog  # run_time_ns, run_cnt, avg_ns`,
+	RunE: runProgStats,
+}
+
+// runProgStats handles the prog stats command
+func runProgStats(cmd *cobra.Command, args []string) error {
+	formatter := newFormatter(resolveFormat(GetGlobalFlags()))
+
+	id, err := resolveProgID(args)
+	if err != nil {
+		return err
+	}
+
+	sampled := progStatsSample > 0
+	if sampled {
+		stats, err := enableStats()
+		if err != nil {
+			return handleError(err, "enabling BPF stats")
+		}
+		// Ctrl-C stops the sample early without reporting it
+		select {
+		case <-cmd.Context().Done():
+			stats.Close()
+			return handleError(cmd.Context().Err(), "sampling BPF stats")
+		case <-time.After(progStatsSample):
+		}
+		stats.Close()
+	}
+
+	program, err := progService.GetByID(id)
+	if err != nil {
+		return handleError(err, fmt.Sprintf("getting program with ID %d", id))
+	}
+
+	// Zero counters usually just mean nobody turned stats on
+	if program.RunTimeNS == 0 && program.RunCount == 0 && !sampled && !statsEnabled() {
+		err := fmt.Errorf("run statistics of program %d %w: BPF stats are disabled", id, bpferrors.ErrNotAvailable)
+		printErrorf("Error: %v", err)
+		// The hint would break the error document of the other formats
+		if resolveFormat(GetGlobalFlags()) == output.FormatPlain {
+			fmt.Fprintln(os.Stderr, "Enable them with 'sysctl -w kernel.bpf_stats_enabled=1' or collect for a while with --sample 5s")
+		}
+		return err
+	}
+
+	fmt.Fprint(resultWriter(cmd), formatter.FormatStats(toOutputProgramInfo(*program)))
+	return nil
+}
+
+// progPinCmd represents the prog pin command
+var progPinCmd = &cobra.Command{
+	Use:   "pin PROG FILE",
+	Short: "Pin a program to the BPF filesystem",
+	Long: `Pin a loaded program to a path under the BPF filesystem.
+
+Missing parent directories are created. The path must be under the bpffs root.
+
+  gobpftool prog pin id 123 /sys/fs/bpf/my_prog
+  gobpftool prog pin id 123 /sys/fs/bpf/app/progs/my_prog`,
+	Annotations: requiresBPFCapability,
+	RunE:        runProgPin,
+}
+
+// runProgPin handles the prog pin command
+func runProgPin(cmd *cobra.Command, args []string) error {
+	if len(args) != 3 {
+		printError("Error: invalid arguments. Use 'gobpftool prog pin id <ID> <path>'")
+		return fmt.Errorf("invalid arguments")
+	}
+
+	id, err := resolveProgID(args[:2])
+	if err != nil {
+		return err
+	}
+
+	path, err := validatePinTarget(args[2])
+	if err != nil {
+		return err
+	}
+
+	if err := progService.Pin(id, path); err != nil {
+		return handleError(err, fmt.Sprintf("pinning program %d", id))
+	}
+
+	return nil
+}
+
+// progLoadFlags holds the flags for the prog load command
+var progLoadFlags struct {
+	Section string // --section
+}
+
+// progLoadCmd represents the prog load command
+var progLoadCmd = &cobra.Command{
+	Use:   "load OBJ PATH [type TYPE]",
+	Short: "Load programs from an ELF object and pin them",
+	Long: `Load the programs of a BPF ELF object file and pin them under the BPF filesystem.
+
+A single program is pinned at PATH. When the object holds several programs,
+each one is pinned at PATH/<name>. The program type is inferred from the ELF
+section name unless it is given with 'type TYPE'. If the verifier rejects a
+program, its full log is printed.
+
+  gobpftool prog load xdp_pass.o /sys/fs/bpf/xdp_pass
+  gobpftool prog load prog.o /sys/fs/bpf/app --section xdp
+  gobpftool prog load prog.o /sys/fs/bpf/my_prog type xdp`,
+	Annotations: requiresBPFCapability,
+	RunE:        runProgLoad,
+}
+
+// runProgLoad handles the prog load command
+func runProgLoad(cmd *cobra.Command, args []string) error {
+	opts := prog.LoadOptions{Section: progLoadFlags.Section}
+	switch {
+	case len(args) == 2:
+	case len(args) == 4 && args[2] == "type":
+		if err := prog.ValidateType(args[3]); err != nil {
+			printErrorf("Error: %v", err)
+			return err
+		}
+		opts.Type = args[3]
+	default:
+		printError("Error: invalid arguments. Use 'gobpftool prog load <OBJ> <path> [type <TYPE>]'")
+		return fmt.Errorf("invalid arguments")
+	}
+
+	file := args[0]
+	path, err := validatePinTarget(args[1])
+	if err != nil {
+		return err
+	}
+
+	programs, err := progService.Load(file, path, opts)
+	if err != nil {
+		if log, ok := prog.VerifierLog(err); ok {
+			printErrorf("Error: program rejected by the verifier:\n%s", log)
+			return bpferrors.WrapError(err, fmt.Sprintf("loading %s", file))
+		}
+		return handleError(err, fmt.Sprintf("loading %s", file))
+	}
+
+	outputPrograms := make([]output.ProgramInfo, len(programs))
+	for i, p := range programs {
+		outputPrograms[i] = toOutputProgramInfo(p)
+	}
+
+	formatter := newFormatter(resolveFormat(GetGlobalFlags()))
+	fmt.Fprint(resultWriter(cmd), formatter.FormatPrograms(outputPrograms))
+	return nil
+}
+
+// progRunFlags holds the flags for the prog run command
+var progRunFlags struct {
+	Repeat uint32 // --repeat
+	CtxIn  string // --ctx-in
+}
+
+// progRunCmd represents the prog run command
+var progRunCmd = &cobra.Command{
+	Use:   "run PROG [data_in DATA]",
+	Short: "Test-run a program against input data",
+	Long: `Run a loaded program in the kernel against the given input without attaching it,
+and print its return value and the output data.
+
+DATA and the --ctx-in context are hex bytes such as "0a0b0c" or "0a 0b 0c".
+XDP and skb programs need at least an Ethernet header (14 bytes) of data.
+Program types the kernel can't test-run, such as kprobes, are rejected.
+
+  gobpftool prog run id 123 data_in 000000000000000000000000080045000000
+  gobpftool prog run id 123 data_in 00000000000000000000000008004500 --repeat 1000
+  gobpftool prog run pinned /sys/fs/bpf/my_prog --ctx-in 0100000000000000`,
+	Annotations: requiresBPFCapability,
+	RunE:        runProgRun,
+}
+
+// runProgRun handles the prog run command
+func runProgRun(cmd *cobra.Command, args []string) error {
+	progArgs, data, err := parseProgRunArgs(args)
+	if err != nil {
+		printErrorf("Error: %v", err)
+		return err
+	}
+
+	var ctxIn []byte
+	if progRunFlags.CtxIn != "" {
+		if ctxIn, err = parseRunInput("ctx_in", progRunFlags.CtxIn); err != nil {
+			printErrorf("Error: %v", err)
+			return err
+		}
+	}
+
+	id, err := resolveProgID(progArgs)
+	if err != nil {
+		return err
+	}
+
+	result, err := progService.Run(id, prog.RunOptions{
+		Data:    data,
+		Context: ctxIn,
+		Repeat:  progRunFlags.Repeat,
+	})
+	if err != nil {
+		return handleError(err, fmt.Sprintf("running program %d", id))
+	}
+
+	formatter := newFormatter(resolveFormat(GetGlobalFlags()))
+	fmt.Fprint(resultWriter(cmd), formatter.FormatRunResult(output.RunResult{
+		ReturnValue: result.ReturnValue,
+		DataOut:     result.DataOut,
+		ContextOut:  result.ContextOut,
+		Repeat:      result.Repeat,
+		Duration:    result.Duration,
+	}))
+	return nil
+}
+
+// parseProgRunArgs splits the prog run arguments into the program
+// identifier pair and the decoded data_in bytes, nil when omitted
+func parseProgRunArgs(args []string) ([]string, []byte, error) {
+	switch {
+	case len(args) == 2:
+		return args, nil, nil
+	case len(args) == 4 && args[2] == "data_in":
+		data, err := parseRunInput("data_in", args[3])
+		if err != nil {
+			return nil, nil, err
+		}
+		return args[:2], data, nil
+	default:
+		return nil, nil, fmt.Errorf("invalid arguments. Use 'gobpftool prog run id <ID> [data_in <hex>]'")
+	}
+}
+
+// parseRunInput decodes hex test run input, with or without spaces between
+// the bytes and an optional 0x prefix
+func parseRunInput(name, s string) ([]byte, error) {
+	data, err := utils.ParseHexString(strings.TrimPrefix(strings.TrimSpace(s), "0x"))
+	if err != nil {
+		return nil, fmt.Errorf("%w: %s: %v", bpferrors.ErrInvalidValue, name, err)
+	}
+	if len(data) == 0 {
+		return nil, fmt.Errorf("%w: %s is empty", bpferrors.ErrInvalidValue, name)
+	}
+	return data, nil
+}
+
+// progUnpinCmd represents the prog unpin command
+var progUnpinCmd = &cobra.Command{
+	Use:   "unpin [FILE]",
+	Short: "Remove program pins from the BPF filesystem",
+	Long: `Remove a program pin, or with --by-id every pin of a program.
+
+Unpinning a path with nothing pinned succeeds with a warning.
+
+  gobpftool prog unpin /sys/fs/bpf/my_prog
+  gobpftool prog unpin --by-id 123`,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		return runUnpin(cmd, args, "prog", func(id uint32) ([]string, error) {
+			program, err := progService.GetByID(id)
+			if err != nil {
+				return nil, err
+			}
+			return program.PinnedPaths, nil
+		}, progService.Unpin)
+	},
+}
+
+// getPinnedProgram returns the program pinned at path, checking first that
+// the path exists so a missing pin and an unmounted bpffs get clear errors
+func getPinnedProgram(path string) (*prog.ProgramInfo, error) {
+	if err := checkPinnedPath("program", path); err != nil {
+		return nil, err
+	}
+	program, err := progService.GetByPinnedPath(path)
+	if err != nil {
+		return nil, handleError(err, fmt.Sprintf("getting pinned program at %s", path))
+	}
+	return program, nil
+}
+
+// resolveProgID resolves an "id <ID>" or "pinned <PATH>" program argument
+// pair to a program ID
+func resolveProgID(args []string) (uint32, error) {
+	if len(args) != 2 {
+		printError("Error: program identifier required. Use 'id <ID>' or 'pinned <PATH>'")
+		return 0, fmt.Errorf("program identifier required")
+	}
+
+	identifier, value := args[0], args[1]
+	switch identifier {
+	case "id":
+		id, err := parseID(value)
+		if err != nil {
+			printErrorf("Error: %v", err)
+			return 0, err
+		}
+		return id, nil
+
+	case "pinned":
+		program, err := getPinnedProgram(value)
+		if err != nil {
+			return 0, err
+		}
+		return program.ID, nil
+
+	default:
+		printErrorf("Error: invalid program identifier: %s. Use 'id' or 'pinned'", identifier)
+		return 0, fmt.Errorf("invalid identifier: %s", identifier)
+	}
+}
+
 // progHelpCmd represents the prog help command
 var progHelpCmd = &cobra.Command{
 	Use:   "help",
@@ -146,6 +803,12 @@ var progHelpCmd = &cobra.Command{
 
 Available prog commands:
   show    Show information about loaded programs
+  dump    Dump the instructions of a program
+  stats   Show the run time statistics of a program
+  pin     Pin a program to the BPF filesystem
+  unpin   Remove program pins from the BPF filesystem
+  load    Load programs from an ELF object and pin them
+  run     Test-run a program against input data
   help    Display this help message
 
 Examples:
@@ -154,33 +817,133 @@ Examples:
   gobpftool prog show tag f0055c08993fea1e      # Show programs with tag
   gobpftool prog show name my_prog              # Show programs with name
   gobpftool prog show pinned /sys/fs/bpf/prog   # Show pinned program
-
-Global flags:
-  -j, --json     Output in JSON format
-  -p, --pretty   Output in pretty-printed JSON format`,
+  gobpftool prog dump xlated id 123             # Dump translated bytecode
+  gobpftool prog dump jited id 123              # Dump JIT-compiled code
+  gobpftool prog stats id 123                   # Show run time statistics
+  gobpftool prog pin id 123 /sys/fs/bpf/prog    # Pin program
+  gobpftool prog unpin /sys/fs/bpf/prog         # Remove a pin
+  gobpftool prog unpin --by-id 123              # Remove all pins of a program
+  gobpftool prog load prog.o /sys/fs/bpf/prog   # Load and pin a program
+  gobpftool prog run id 123 data_in 0a0b0c...   # Test-run a program`,
 	Run: func(cmd *cobra.Command, args []string) {
 		// Show the help for the prog command
 		progCmd.Help()
 	},
 }
 
-// getOutputFormat determines the output format based on global flags
-func getOutputFormat() output.Format {
-	flags := GetGlobalFlags()
-	if flags.Pretty {
-		return output.FormatJSONPretty
-	} else if flags.JSON {
-		return output.FormatJSON
+// warnSkipped warns on stderr that count objects of kind couldn't be read,
+// so an empty or short listing isn't mistaken for the full picture
+func warnSkipped(count int, kind string) {
+	if count == 0 {
+		return
+	}
+	printWarning(fmt.Sprintf("Warning: %s; try sudo", skippedReason(count, kind)), bpferrors.ErrPermission)
+}
+
+// warnPinScan notes on stderr that pinned paths are missing from a listing
+// because the bpffs root couldn't be scanned for lack of permissions
+func warnPinScan() {
+	err := bpffs.GetScanner().LastScanError()
+	if err == nil || !bpferrors.IsPermissionError(err) {
+		return
+	}
+	printWarning(fmt.Sprintf("Warning: pinned paths not shown: %v", err), err)
+}
+
+// skippedReason says that count objects of kind couldn't be read, for the
+// stderr note and the partial_reason of JSON and YAML listings
+func skippedReason(count int, kind string) string {
+	if count != 1 {
+		kind += "s"
+	}
+	return fmt.Sprintf("%d %s not accessible", count, kind)
+}
+
+// listFormatter returns the formatter for a program or map listing, with
+// opts applied. A --columns spec selects the table columns from known and
+// implies --table
+func listFormatter(columns string, known []string, opts ...output.Option) (output.Formatter, error) {
+	format := resolveFormat(GetGlobalFlags())
+	if columns == "" {
+		return newFormatter(format, opts...), nil
+	}
+
+	switch format {
+	case output.FormatPlain:
+		format = output.FormatTable
+	case output.FormatTable:
+	default:
+		return nil, fmt.Errorf("--columns only applies to table output")
+	}
+
+	selected, err := output.ParseColumns(columns, known)
+	if err != nil {
+		return nil, err
+	}
+	return newFormatter(format, append(opts, output.WithColumns(selected))...), nil
+}
+
+// newFormatter creates a formatter for format honoring the global
+// formatting flags such as --timestamp, --human and --no-color, followed by
+// any command-specific opts
+func newFormatter(format output.Format, opts ...output.Option) output.Formatter {
+	// The flag was validated before the command ran
+	tf, _ := output.ParseTimestampFormat(GetGlobalFlags().Timestamp)
+	global := []output.Option{output.WithTimestampFormat(tf)}
+	if GetGlobalFlags().Human {
+		global = append(global, output.WithHumanSizes())
+	}
+	if colorEnabled() {
+		global = append(global, output.WithColor())
+	}
+	if GetGlobalFlags().Quiet {
+		global = append(global, output.WithQuiet())
 	}
-	return output.FormatPlain
+	opts = append(global, opts...)
+	return output.NewFormatter(format, opts...)
 }
 
 func init() {
 	// Initialize the program service
 	progService = prog.NewService()
 
+	// Suggest the loaded program IDs after "id"
+	for _, c := range []*cobra.Command{progShowCmd, progDumpXlatedCmd, progDumpJitedCmd, progStatsCmd, progRunCmd} {
+		c.ValidArgsFunction = completeProgID
+	}
+
+	progShowCmd.Flags().StringVar(&progShowFlags.Type, "type", "", "Only show programs of this type (e.g. xdp, kprobe, sched_cls)")
+	progShowCmd.Flags().StringVar(&progShowFlags.TagPrefix, "tag-prefix", "", "Only show programs whose tag starts with these hex digits")
+	progShowCmd.Flags().StringVar(&progShowFlags.Sort, "sort", "id", "Sort by field: "+strings.Join(prog.SortFields, ", "))
+	progShowCmd.Flags().BoolVar(&progShowFlags.Reverse, "reverse", false, "Reverse the sort order")
+	progShowCmd.Flags().BoolVar(&progShowFlags.Count, "count", false, "Print the number of matching programs instead of listing them")
+	progShowCmd.Flags().BoolVar(&progShowFlags.WithMaps, "with-maps", false, "Show the details of the maps each program uses")
+	progShowCmd.Flags().StringVar(&progShowFlags.Columns, "columns", "", "Comma-separated table columns to show, implies --table: "+strings.Join(output.ProgramColumnNames(), ", "))
+	progShowCmd.Flags().IntVar(&progShowFlags.Index, "index", noIndex, "Pick the nth (0-based, by ID) of several programs matching a name or tag")
+	progShowCmd.Flags().BoolVar(&progShowFlags.Metadata, "metadata", false, "Show the bpf_metadata_ variables each program declares")
+	progShowCmd.Flags().BoolVar(&progShowFlags.ResolveMapNames, "resolve-map-names", false, "List map_ids as objects with the map names in JSON and YAML output")
+	progShowCmd.Flags().StringVar(&progShowFlags.Memlock, "memlock-min", "", "Only show programs locking at least this much memory, in bytes or with a K, M or G suffix")
+	progShowCmd.Flags().StringVar(&progShowFlags.IDRange, "id-range", "", "Only show programs whose ID is in the inclusive range LO-HI; either end may be left out")
+
+	progStatsCmd.Flags().DurationVar(&progStatsSample, "sample", 0, "Enable BPF stats for this long before reading them")
+
+	progUnpinCmd.Flags().Uint32Var(&unpinByID, "by-id", 0, "Remove every pin of the program with this ID")
+
+	progLoadCmd.Flags().StringVar(&progLoadFlags.Section, "section", "", "Only load the programs in this ELF section")
+
+	progRunCmd.Flags().Uint32Var(&progRunFlags.Repeat, "repeat", 1, "Run the program this many times and report the average duration")
+	progRunCmd.Flags().StringVar(&progRunFlags.CtxIn, "ctx-in", "", "Context to run the program with, as hex bytes")
+
 	// Add subcommands to prog command
 	progCmd.AddCommand(progShowCmd)
+	progDumpCmd.AddCommand(progDumpXlatedCmd)
+	progDumpCmd.AddCommand(progDumpJitedCmd)
+	progCmd.AddCommand(progDumpCmd)
+	progCmd.AddCommand(progStatsCmd)
+	progCmd.AddCommand(progPinCmd)
+	progCmd.AddCommand(progUnpinCmd)
+	progCmd.AddCommand(progLoadCmd)
+	progCmd.AddCommand(progRunCmd)
 	progCmd.AddCommand(progHelpCmd)
 
 	// Add prog command to root command
@@ -0,0 +1,987 @@
+package cmd
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"reflect"
+	"slices"
+	"strings"
+	"syscall"
+	"testing"
+
+	"github.com/viveksb007/gobpftool/internal/bpffs"
+	bpferrors "github.com/viveksb007/gobpftool/pkg/errors"
+	"github.com/viveksb007/gobpftool/pkg/prog"
+)
+
+// mockProgService is a mock implementation of prog.Service for testing.
+type mockProgService struct {
+	programs []prog.ProgramInfo
+	err      error // returned by List when set
+	// inaccessible lists the IDs ListWithSkipped fails to open
+	inaccessible map[uint32]bool
+	// metadata is what Metadata returns per program ID
+	metadata map[uint32][]prog.MetadataEntry
+	// pinned maps the paths GetByPinnedPath knows to program IDs
+	pinned map[string]uint32
+	// unpinned records the paths Unpin removed, in order
+	unpinned []string
+}
+
+func (m *mockProgService) List() ([]prog.ProgramInfo, error) {
+	if m.err != nil {
+		return nil, m.err
+	}
+	return m.programs, nil
+}
+
+func (m *mockProgService) ListContext(ctx context.Context) ([]prog.ProgramInfo, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	return m.List()
+}
+
+func (m *mockProgService) ListWithSkipped(ctx context.Context) (*prog.ListResult, error) {
+	all, err := m.ListContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+	result := &prog.ListResult{}
+	for _, p := range all {
+		if m.inaccessible[p.ID] {
+			result.Skipped++
+			continue
+		}
+		result.Programs = append(result.Programs, p)
+	}
+	return result, nil
+}
+
+func (m *mockProgService) GetByID(id uint32) (*prog.ProgramInfo, error) {
+	for _, p := range m.programs {
+		if p.ID == id {
+			return &p, nil
+		}
+	}
+	return nil, bpferrors.ErrNotFound
+}
+
+func (m *mockProgService) GetByTag(tag string) ([]prog.ProgramInfo, error) {
+	var result []prog.ProgramInfo
+	for _, p := range m.programs {
+		if p.Tag == tag {
+			result = append(result, p)
+		}
+	}
+	return result, nil
+}
+
+func (m *mockProgService) GetByTagContext(ctx context.Context, tag string) ([]prog.ProgramInfo, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	return m.GetByTag(tag)
+}
+
+func (m *mockProgService) GetByName(name string) ([]prog.ProgramInfo, error) {
+	var result []prog.ProgramInfo
+	for _, p := range m.programs {
+		if p.Name == name {
+			result = append(result, p)
+		}
+	}
+	return result, nil
+}
+
+func (m *mockProgService) GetByNameContext(ctx context.Context, name string) ([]prog.ProgramInfo, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	return m.GetByName(name)
+}
+
+func (m *mockProgService) GetByPinnedPath(path string) (*prog.ProgramInfo, error) {
+	id, ok := m.pinned[path]
+	if !ok {
+		return nil, fmt.Errorf("no program pinned at %s: %w", path, bpferrors.ErrNotFound)
+	}
+	return m.GetByID(id)
+}
+
+func (m *mockProgService) DumpXlated(id uint32) ([]prog.Instruction, error) {
+	if _, err := m.GetByID(id); err != nil {
+		return nil, err
+	}
+	return []prog.Instruction{
+		{Offset: 0, Raw: []byte{0xb7, 0, 0, 0, 0, 0, 0, 0}, Text: "MovImm dst: r0 imm: 0"},
+		{Offset: 1, Raw: []byte{0x95, 0, 0, 0, 0, 0, 0, 0}, Text: "Exit"},
+	}, nil
+}
+
+func (m *mockProgService) DumpJited(id uint32) ([]byte, error) {
+	if _, err := m.GetByID(id); err != nil {
+		return nil, err
+	}
+	return []byte{0x0f, 0x1f, 0x44, 0x00, 0x00, 0x31, 0xc0, 0xc3}, nil
+}
+
+// Pin records path as a pin of program id.
+func (m *mockProgService) Pin(id uint32, path string) error {
+	if _, err := m.GetByID(id); err != nil {
+		return err
+	}
+	if m.pinned == nil {
+		m.pinned = make(map[string]uint32)
+	}
+	m.pinned[path] = id
+	for i := range m.programs {
+		if m.programs[i].ID == id {
+			m.programs[i].PinnedPaths = append(m.programs[i].PinnedPaths, path)
+		}
+	}
+	return nil
+}
+
+// Unpin removes path from the pins of the program pinned there.
+func (m *mockProgService) Unpin(path string) error {
+	for i := range m.programs {
+		paths := m.programs[i].PinnedPaths
+		if at := slices.Index(paths, path); at >= 0 {
+			m.programs[i].PinnedPaths = slices.Delete(slices.Clone(paths), at, at+1)
+			delete(m.pinned, path)
+			m.unpinned = append(m.unpinned, path)
+			return nil
+		}
+	}
+	return bpferrors.ErrNotFound
+}
+
+func (m *mockProgService) Load(file, pinPath string, opts prog.LoadOptions) ([]prog.ProgramInfo, error) {
+	return m.programs, nil
+}
+
+// Run echoes the input back as output and returns XDP_PASS. Kprobes are
+// rejected like the kernel does.
+func (m *mockProgService) Run(id uint32, opts prog.RunOptions) (*prog.RunResult, error) {
+	p, err := m.GetByID(id)
+	if err != nil {
+		return nil, err
+	}
+	if p.Type == "kprobe" {
+		return nil, prog.ErrNotTestRunnable
+	}
+	return &prog.RunResult{ReturnValue: 2, DataOut: opts.Data, ContextOut: opts.Context, Repeat: opts.Repeat}, nil
+}
+
+func (m *mockProgService) Metadata(id uint32) ([]prog.MetadataEntry, error) {
+	if _, err := m.GetByID(id); err != nil {
+		return nil, err
+	}
+	return m.metadata[id], nil
+}
+
+// withMockProgService swaps in a mock program service for the duration of a test.
+func withMockProgService(t *testing.T, programs []prog.ProgramInfo) {
+	t.Helper()
+	orig := progService
+	progService = &mockProgService{programs: programs}
+	t.Cleanup(func() { progService = orig })
+	withBPFCapability(t, true)
+}
+
+func TestProgListShowMocked(t *testing.T) {
+	withMockProgService(t, []prog.ProgramInfo{
+		{ID: 3, Type: "xdp", Name: "first", Tag: "aa", MapIDs: []uint32{5}},
+		{ID: 4, Type: "kprobe", Name: "second", Tag: "bb"},
+	})
+
+	// The whole command runs against the mock: arguments, lookup and formatting
+	got, err := executeCommandStdout(t, "prog", "list")
+	if err != nil {
+		t.Fatalf("prog list error = %v", err)
+	}
+	for _, want := range []string{"3: xdp  name first  tag aa", "map_ids 5", "4: kprobe  name second  tag bb"} {
+		if !strings.Contains(got, want) {
+			t.Errorf("prog list = %q, want it to contain %q", got, want)
+		}
+	}
+
+	got, err = executeCommandStdout(t, "-j", "prog", "show", "id", "4")
+	if err != nil {
+		t.Fatalf("prog show error = %v", err)
+	}
+	if !strings.HasPrefix(got, `{"id":4,"type":"kprobe","name":"second"`) {
+		t.Errorf("prog show id 4 = %s, want program 4", got)
+	}
+
+	stderr, err := executeCommandStderr(t, "-j", "prog", "show", "id", "5")
+	if !errors.Is(err, bpferrors.ErrNotFound) {
+		t.Errorf("prog show of a missing program error = %v, want %v", err, bpferrors.ErrNotFound)
+	}
+	if !strings.HasPrefix(stderr, `{"error":`) {
+		t.Errorf("stderr = %q, want a JSON error", stderr)
+	}
+}
+
+func TestProgListAlias(t *testing.T) {
+	for _, args := range [][]string{{"prog", "show"}, {"prog", "list"}} {
+		found, _, err := GetRootCmd().Find(args)
+		if err != nil {
+			t.Fatalf("Find(%v) error = %v", args, err)
+		}
+		if found != progShowCmd {
+			t.Errorf("Find(%v) = %s, want prog show", args, found.Name())
+		}
+	}
+}
+
+func TestProgShowID(t *testing.T) {
+	withMockProgService(t, []prog.ProgramInfo{
+		{ID: 7, Type: "xdp", Name: "prog7", Tag: "f0055c08993fea1e"},
+	})
+
+	tests := []struct {
+		name    string
+		args    []string
+		wantErr error
+	}{
+		{
+			name:    "existing program",
+			args:    []string{"prog", "show", "id", "7"},
+			wantErr: nil,
+		},
+		{
+			name:    "non-numeric id",
+			args:    []string{"prog", "show", "id", "abc"},
+			wantErr: bpferrors.ErrInvalidID,
+		},
+		{
+			name:    "missing program",
+			args:    []string{"prog", "show", "id", "8"},
+			wantErr: bpferrors.ErrNotFound,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := executeCommand(tt.args...)
+			if tt.wantErr == nil && err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if tt.wantErr != nil && !errors.Is(err, tt.wantErr) {
+				t.Errorf("error = %v, want %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestProgShowIDJSON(t *testing.T) {
+	withMockProgService(t, []prog.ProgramInfo{
+		{ID: 7, Type: "xdp", Name: "prog7", Tag: "f0055c08993fea1e"},
+		{ID: 8, Type: "xdp", Name: "prog8", Tag: "f0055c08993fea1e"},
+	})
+
+	// A program looked up by ID is a bare object, like bpftool prints it
+	got, err := executeCommandStdout(t, "-j", "prog", "show", "id", "7")
+	if err != nil {
+		t.Fatalf("prog show id error = %v", err)
+	}
+	if !strings.HasPrefix(got, `{"id":7,`) {
+		t.Errorf("prog show id = %s, want a bare program object", got)
+	}
+
+	got, err = executeCommandStdout(t, "-j", "prog", "show", "tag", "f0055c08993fea1e")
+	if err != nil {
+		t.Fatalf("prog show tag error = %v", err)
+	}
+	if !strings.HasPrefix(got, `{"programs":[`) {
+		t.Errorf("prog show tag = %s, want a list of programs", got)
+	}
+}
+
+func TestProgShowTagAndName(t *testing.T) {
+	withMockProgService(t, []prog.ProgramInfo{
+		{ID: 1, Type: "xdp", Name: "my_prog", Tag: "f0055c08993fea1e"},
+		{ID: 2, Type: "xdp", Name: "my_prog", Tag: "f0055c08993fea1e"},
+	})
+
+	tests := []struct {
+		name    string
+		args    []string
+		wantErr error
+	}{
+		{
+			name:    "matching tag",
+			args:    []string{"prog", "show", "tag", "f0055c08993fea1e"},
+			wantErr: nil,
+		},
+		{
+			name:    "unmatched tag",
+			args:    []string{"prog", "show", "tag", "0000000000000000"},
+			wantErr: bpferrors.ErrNotFound,
+		},
+		{
+			name:    "matching name",
+			args:    []string{"prog", "show", "name", "my_prog"},
+			wantErr: nil,
+		},
+		{
+			name:    "unmatched name",
+			args:    []string{"prog", "show", "name", "other"},
+			wantErr: bpferrors.ErrNotFound,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := executeCommand(tt.args...)
+			if tt.wantErr == nil && err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if tt.wantErr != nil && !errors.Is(err, tt.wantErr) {
+				t.Errorf("error = %v, want %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestProgShowPinned(t *testing.T) {
+	withMockProgService(t, []prog.ProgramInfo{{ID: 3, Type: "xdp", Name: "pinned_prog", Tag: "aa"}})
+	pin := filepath.Join(t.TempDir(), "prog")
+	if err := os.WriteFile(pin, nil, 0o600); err != nil {
+		t.Fatal(err)
+	}
+	progService.(*mockProgService).pinned = map[string]uint32{pin: 3}
+	t.Cleanup(ResetFlags)
+
+	// A pinned program is a single object, like one looked up by ID
+	got, err := executeCommandStdout(t, "-j", "prog", "show", "pinned", pin)
+	if err != nil {
+		t.Fatalf("prog show pinned error = %v", err)
+	}
+	if !strings.HasPrefix(got, `{"id":3,`) {
+		t.Errorf("prog show pinned = %s, want a bare program object", got)
+	}
+
+	// A missing path under a root that isn't bpffs points at mounting it
+	root := t.TempDir()
+	missing := filepath.Join(root, "missing")
+	err = executeCommand("--bpffs", root, "prog", "show", "pinned", missing)
+	if !errors.Is(err, bpferrors.ErrBpfFSNotMounted) {
+		t.Errorf("prog show pinned without bpffs error = %v, want %v", err, bpferrors.ErrBpfFSNotMounted)
+	}
+	ResetFlags()
+
+	// With bpffs mounted, a missing path is simply not found
+	if bpffs.CheckMounted(bpffs.GetScanner().Root()) == nil {
+		if err := executeCommand("prog", "show", "pinned", missing); !errors.Is(err, bpferrors.ErrNotFound) {
+			t.Errorf("prog show pinned of a missing path error = %v, want %v", err, bpferrors.ErrNotFound)
+		}
+	}
+}
+
+func TestProgShowInvalidTag(t *testing.T) {
+	withMockProgService(t, nil)
+
+	if err := executeCommand("prog", "show", "tag", "xyz"); err == nil {
+		t.Error("expected error for non-hex tag, got nil")
+	}
+}
+
+func TestProgListTypeFilter(t *testing.T) {
+	withMockProgService(t, []prog.ProgramInfo{
+		{ID: 1, Type: "XDP", Name: "prog1"},
+		{ID: 2, Type: "Kprobe", Name: "prog2"},
+	})
+
+	if err := executeCommand("prog", "list", "--type", "xdp"); err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+	if err := executeCommand("prog", "list", "--type", "bogus"); err == nil {
+		t.Error("expected error for unknown type, got nil")
+	}
+}
+
+func TestProgDump(t *testing.T) {
+	withMockProgService(t, []prog.ProgramInfo{{ID: 7, Type: "xdp", Name: "prog7"}})
+
+	tests := []struct {
+		name    string
+		args    []string
+		wantErr error
+	}{
+		{
+			name: "existing program",
+			args: []string{"prog", "dump", "xlated", "id", "7"},
+		},
+		{
+			name: "json output",
+			args: []string{"-j", "prog", "dump", "xlated", "id", "7"},
+		},
+		{
+			name: "jited",
+			args: []string{"prog", "dump", "jited", "id", "7"},
+		},
+		{
+			name:    "jited missing program",
+			args:    []string{"prog", "dump", "jited", "id", "8"},
+			wantErr: bpferrors.ErrNotFound,
+		},
+		{
+			name:    "non-numeric id",
+			args:    []string{"prog", "dump", "xlated", "id", "abc"},
+			wantErr: bpferrors.ErrInvalidID,
+		},
+		{
+			name:    "missing program",
+			args:    []string{"prog", "dump", "xlated", "id", "8"},
+			wantErr: bpferrors.ErrNotFound,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := executeCommand(tt.args...)
+			if tt.wantErr == nil && err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if tt.wantErr != nil && !errors.Is(err, tt.wantErr) {
+				t.Errorf("error = %v, want %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestProgPinRejectsPathOutsideBPFFS(t *testing.T) {
+	withMockProgService(t, []prog.ProgramInfo{{ID: 7, Type: "xdp", Name: "prog7"}})
+
+	err := executeCommand("prog", "pin", "id", "7", "/tmp/prog7")
+	if err == nil || errors.Is(err, bpferrors.ErrBpfFSNotMounted) {
+		t.Errorf("error = %v, want a path validation error", err)
+	}
+	if err := executeCommand("prog", "pin", "id", "abc", "/sys/fs/bpf/prog7"); !errors.Is(err, bpferrors.ErrInvalidID) {
+		t.Errorf("error = %v, want %v", err, bpferrors.ErrInvalidID)
+	}
+}
+
+func TestProgUnpin(t *testing.T) {
+	withMockProgService(t, []prog.ProgramInfo{
+		{ID: 7, Type: "xdp", Name: "prog7", PinnedPaths: []string{"/sys/fs/bpf/prog7", "/sys/fs/bpf/app/prog7"}},
+	})
+	svc := progService.(*mockProgService)
+
+	got, err := executeCommandStdout(t, "-j", "prog", "unpin", "/sys/fs/bpf/prog7")
+	if err != nil {
+		t.Fatalf("prog unpin error = %v", err)
+	}
+	if !strings.Contains(got, "/sys/fs/bpf/prog7") {
+		t.Errorf("prog unpin = %s, want the removed path", got)
+	}
+
+	// Unpinning again only warns
+	stderr, err := executeCommandStderr(t, "prog", "unpin", "/sys/fs/bpf/prog7")
+	if err != nil {
+		t.Fatalf("repeated prog unpin error = %v", err)
+	}
+	if !strings.Contains(stderr, "nothing pinned at /sys/fs/bpf/prog7") {
+		t.Errorf("stderr = %q, want a warning", stderr)
+	}
+
+	if err := executeCommand("prog", "unpin", "--by-id", "7"); err != nil {
+		t.Fatalf("prog unpin --by-id error = %v", err)
+	}
+	want := []string{"/sys/fs/bpf/prog7", "/sys/fs/bpf/app/prog7"}
+	if !slices.Equal(svc.unpinned, want) {
+		t.Errorf("unpinned = %v, want %v", svc.unpinned, want)
+	}
+
+	if err := executeCommand("prog", "unpin", "--by-id", "8"); !errors.Is(err, bpferrors.ErrNotFound) {
+		t.Errorf("prog unpin --by-id of a missing program error = %v, want %v", err, bpferrors.ErrNotFound)
+	}
+}
+
+func TestProgLoadArgs(t *testing.T) {
+	withMockProgService(t, []prog.ProgramInfo{{ID: 7, Type: "xdp", Name: "xdp_pass"}})
+
+	tests := []struct {
+		name string
+		args []string
+	}{
+		{"missing path", []string{"prog", "load", "xdp_pass.o"}},
+		{"dangling type keyword", []string{"prog", "load", "xdp_pass.o", "/sys/fs/bpf/xdp", "type"}},
+		{"wrong keyword", []string{"prog", "load", "xdp_pass.o", "/sys/fs/bpf/xdp", "kind", "xdp"}},
+		{"unknown type", []string{"prog", "load", "xdp_pass.o", "/sys/fs/bpf/xdp", "type", "bogus"}},
+		{"path outside bpffs", []string{"prog", "load", "xdp_pass.o", "/tmp/xdp"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := executeCommandStdout(t, tt.args...)
+			if err == nil {
+				t.Fatal("expected an error")
+			}
+			if got != "" {
+				t.Errorf("stdout = %q, want nothing loaded", got)
+			}
+		})
+	}
+}
+
+func TestParseProgRunArgs(t *testing.T) {
+	tests := []struct {
+		name     string
+		args     []string
+		wantProg []string
+		wantData []byte
+		wantErr  bool
+	}{
+		{"no data", []string{"id", "7"}, []string{"id", "7"}, nil, false},
+		{"continuous hex", []string{"id", "7", "data_in", "0a0b0c"}, []string{"id", "7"}, []byte{0x0a, 0x0b, 0x0c}, false},
+		{"spaced hex", []string{"id", "7", "data_in", "0a 0B 0c"}, []string{"id", "7"}, []byte{0x0a, 0x0b, 0x0c}, false},
+		{"0x prefix", []string{"pinned", "/sys/fs/bpf/p", "data_in", "0xff00"}, []string{"pinned", "/sys/fs/bpf/p"}, []byte{0xff, 0x00}, false},
+		{"odd length", []string{"id", "7", "data_in", "abc"}, nil, nil, true},
+		{"not hex", []string{"id", "7", "data_in", "zz"}, nil, nil, true},
+		{"empty data", []string{"id", "7", "data_in", ""}, nil, nil, true},
+		{"wrong keyword", []string{"id", "7", "data", "00"}, nil, nil, true},
+		{"missing data", []string{"id", "7", "data_in"}, nil, nil, true},
+		{"missing program", nil, nil, nil, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			gotProg, gotData, err := parseProgRunArgs(tt.args)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("parseProgRunArgs(%q) error = %v, wantErr %v", tt.args, err, tt.wantErr)
+			}
+			if !reflect.DeepEqual(gotProg, tt.wantProg) || !bytes.Equal(gotData, tt.wantData) {
+				t.Errorf("parseProgRunArgs(%q) = %q, %x, want %q, %x", tt.args, gotProg, gotData, tt.wantProg, tt.wantData)
+			}
+		})
+	}
+}
+
+func TestParseRunInput_InvalidValue(t *testing.T) {
+	if _, err := parseRunInput("ctx_in", "0g"); !errors.Is(err, bpferrors.ErrInvalidValue) {
+		t.Errorf("parseRunInput() error = %v, want %v", err, bpferrors.ErrInvalidValue)
+	}
+}
+
+func TestProgRun(t *testing.T) {
+	withMockProgService(t, []prog.ProgramInfo{
+		{ID: 7, Type: "xdp", Name: "xdp_pass"},
+		{ID: 8, Type: "kprobe", Name: "kp"},
+	})
+
+	got, err := executeCommandStdout(t, "prog", "run", "id", "7", "data_in", "aabb", "--ctx-in", "01 02", "--repeat", "5")
+	if err != nil {
+		t.Fatalf("prog run error = %v", err)
+	}
+	want := "retval 2  repeat 5  duration 0s\ndata_out:\n00000000: aa bb\nctx_out:\n00000000: 01 02"
+	if got != want {
+		t.Errorf("prog run = %q, want %q", got, want)
+	}
+
+	if _, err := executeCommandStdout(t, "prog", "run", "id", "8", "data_in", "aabb"); !errors.Is(err, prog.ErrNotTestRunnable) {
+		t.Errorf("prog run on a kprobe error = %v, want %v", err, prog.ErrNotTestRunnable)
+	}
+	if _, err := executeCommandStdout(t, "prog", "run", "id", "7", "--ctx-in", "xyz"); !errors.Is(err, bpferrors.ErrInvalidValue) {
+		t.Errorf("prog run with a bad context error = %v, want %v", err, bpferrors.ErrInvalidValue)
+	}
+}
+
+func TestProgListColumns(t *testing.T) {
+	withMockProgService(t, []prog.ProgramInfo{
+		{ID: 1, Type: "XDP", Name: "prog1", MemLock: 4096},
+		{ID: 2, Type: "Kprobe", Name: "prog2", MemLock: 8192},
+	})
+
+	got, err := executeCommandStdout(t, "prog", "list", "--columns", "name,memlock")
+	if err != nil {
+		t.Fatalf("prog list --columns error = %v", err)
+	}
+	if want := "NAME   MEMLOCK\nprog1  4096B\nprog2  8192B"; got != want {
+		t.Errorf("prog list --columns = %q, want %q", got, want)
+	}
+
+	for _, args := range [][]string{
+		{"prog", "list", "--columns", "name,size"},
+		{"-j", "prog", "list", "--columns", "name"},
+	} {
+		if got, err := executeCommandStdout(t, args...); err == nil || got != "" {
+			t.Errorf("%v = %q, %v, want an error", args, got, err)
+		}
+	}
+}
+
+func TestProgListSkipped(t *testing.T) {
+	withMockProgService(t, []prog.ProgramInfo{
+		{ID: 1, Type: "XDP", Name: "prog1"},
+		{ID: 2, Type: "Kprobe", Name: "prog2"},
+		{ID: 3, Type: "XDP", Name: "prog3"},
+	})
+	progService.(*mockProgService).inaccessible = map[uint32]bool{1: true, 3: true}
+
+	got, err := executeCommandStdout(t, "prog", "list", "--count")
+	if err != nil {
+		t.Fatalf("prog list error = %v", err)
+	}
+	if got != "1 program" {
+		t.Errorf("prog list --count = %q, want %q", got, "1 program")
+	}
+
+	stderr, err := executeCommandStderr(t, "prog", "list")
+	if err != nil {
+		t.Fatalf("prog list error = %v", err)
+	}
+	if want := "Warning: 2 programs not accessible; try sudo"; !strings.Contains(stderr, want) {
+		t.Errorf("stderr = %q, want %q", stderr, want)
+	}
+
+	got, err = executeCommandStdout(t, "-y", "prog", "list")
+	if err != nil {
+		t.Fatalf("prog list -y error = %v", err)
+	}
+	if want := "partial: true\npartial_reason: 2 programs not accessible\n"; !strings.Contains(got, want) {
+		t.Errorf("prog list -y = %q, want it to contain %q", got, want)
+	}
+}
+
+func TestProgListCount(t *testing.T) {
+	withMockProgService(t, []prog.ProgramInfo{
+		{ID: 1, Type: "XDP", Name: "prog1"},
+		{ID: 2, Type: "Kprobe", Name: "prog2"},
+		{ID: 3, Type: "XDP", Name: "prog3"},
+	})
+
+	tests := []struct {
+		args []string
+		want string
+	}{
+		{args: []string{"prog", "list", "--count"}, want: "3 programs"},
+		{args: []string{"prog", "list", "--count", "--type", "kprobe"}, want: "1 program"},
+		{args: []string{"prog", "list", "--count", "--type", "sk_skb"}, want: "0 programs"},
+		{args: []string{"-j", "prog", "list", "--count", "--type", "xdp"}, want: `{"count":2}`},
+	}
+
+	for _, tt := range tests {
+		got, err := executeCommandStdout(t, tt.args...)
+		if err != nil {
+			t.Fatalf("%v: unexpected error: %v", tt.args, err)
+		}
+		if got != tt.want {
+			t.Errorf("%v: output = %q, want %q", tt.args, got, tt.want)
+		}
+	}
+}
+
+func TestProgListTagPrefix(t *testing.T) {
+	withMockProgService(t, []prog.ProgramInfo{
+		{ID: 1, Type: "XDP", Name: "prog1", Tag: "f0055c08993fea1e"},
+		{ID: 2, Type: "XDP", Name: "prog2", Tag: "f00a1b2c3d4e5f60"},
+		{ID: 3, Type: "XDP", Name: "prog3", Tag: "0123456789abcdef"},
+	})
+
+	got, err := executeCommandStdout(t, "prog", "list", "--count", "--tag-prefix", "F00")
+	if err != nil {
+		t.Fatalf("prog list --tag-prefix error = %v", err)
+	}
+	if got != "2 programs" {
+		t.Errorf("prog list --tag-prefix = %q, want %q", got, "2 programs")
+	}
+
+	if err := executeCommand("prog", "list", "--tag-prefix", "xyz"); err == nil {
+		t.Error("expected an error for a non-hex --tag-prefix")
+	}
+}
+
+func TestProgShowResolveMapNames(t *testing.T) {
+	withMockProgService(t, []prog.ProgramInfo{
+		{ID: 1, Type: "XDP", Name: "prog1", MapIDs: []uint32{1, 99}},
+		{ID: 2, Type: "XDP", Name: "prog2", MapIDs: []uint32{2}},
+	})
+	withMockMapService(t, newTestMapService())
+
+	// Map 99 doesn't exist and is listed by ID only
+	got, err := executeCommandStdout(t, "-j", "prog", "list", "--resolve-map-names")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	for _, want := range []string{`"map_ids":[{"id":1,"name":"counts"},{"id":99}]`, `"map_ids":[{"id":2,"name":"empty"}]`} {
+		if !strings.Contains(got, want) {
+			t.Errorf("prog list --resolve-map-names = %s, want it to contain %s", got, want)
+		}
+	}
+
+	got, err = executeCommandStdout(t, "-j", "prog", "show", "id", "1")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !strings.Contains(got, `"map_ids":[1,99]`) {
+		t.Errorf("prog show = %s, want bare map_ids by default", got)
+	}
+
+	if err := executeCommand("prog", "list", "--resolve-map-names"); err == nil {
+		t.Error("expected an error for --resolve-map-names with plain output")
+	}
+}
+
+func TestProgListWithMaps(t *testing.T) {
+	withMockProgService(t, []prog.ProgramInfo{
+		{ID: 1, Type: "XDP", Name: "prog1", MapIDs: []uint32{1, 99}},
+	})
+	withMockMapService(t, newTestMapService())
+
+	got, err := executeCommandStdout(t, "-j", "prog", "list", "--with-maps")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	var parsed struct {
+		Programs []struct {
+			Maps []struct {
+				ID    uint32 `json:"id"`
+				Name  string `json:"name"`
+				Error string `json:"error"`
+			} `json:"maps"`
+		} `json:"programs"`
+	}
+	if err := json.Unmarshal([]byte(got), &parsed); err != nil {
+		t.Fatalf("invalid JSON %q: %v", got, err)
+	}
+	maps := parsed.Programs[0].Maps
+	if len(maps) != 2 {
+		t.Fatalf("got %d maps, want 2", len(maps))
+	}
+	if maps[0].Name != "counts" || maps[0].Error != "" {
+		t.Errorf("maps[0] = %+v, want the counts map", maps[0])
+	}
+	if maps[1].ID != 99 || maps[1].Error == "" {
+		t.Errorf("maps[1] = %+v, want an error placeholder for map 99", maps[1])
+	}
+}
+
+func TestProgStats(t *testing.T) {
+	withMockProgService(t, []prog.ProgramInfo{
+		{ID: 1, Type: "xdp", Name: "busy", RunTimeNS: 5000, RunCount: 10},
+		{ID: 2, Type: "xdp", Name: "idle"},
+	})
+	origEnabled := statsEnabled
+	statsEnabled = func() bool { return false }
+	t.Cleanup(func() { statsEnabled = origEnabled })
+
+	got, err := executeCommandStdout(t, "-j", "prog", "stats", "id", "1")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	want := `{"id":1,"type":"xdp","name":"busy","run_time_ns":5000,"run_cnt":10,"avg_ns":500}`
+	if got != want {
+		t.Errorf("output = %s, want %s", got, want)
+	}
+
+	// Without stats enabled, zero counters are reported as unavailable
+	stderr, err := executeCommandStderr(t, "prog", "stats", "id", "2")
+	if !errors.Is(err, bpferrors.ErrNotAvailable) {
+		t.Errorf("error = %v, want %v", err, bpferrors.ErrNotAvailable)
+	}
+	if !strings.Contains(stderr, "kernel.bpf_stats_enabled=1") {
+		t.Errorf("stderr = %q, want the sysctl hint", stderr)
+	}
+
+	// JSON gets the error document alone
+	stderr, _ = executeCommandStderr(t, "-j", "prog", "stats", "id", "2")
+	var doc map[string]any
+	if err := json.Unmarshal([]byte(stderr), &doc); err != nil {
+		t.Errorf("-j stderr = %q, want a single JSON document: %v", stderr, err)
+	}
+
+	statsEnabled = func() bool { return true }
+	got, err = executeCommandStdout(t, "prog", "stats", "id", "2")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if want := "2: xdp  name idle  run_time_ns 0  run_cnt 0  avg_ns 0"; got != want {
+		t.Errorf("output = %q, want %q", got, want)
+	}
+}
+
+// closeRecorder is an io.Closer that records whether it was closed.
+type closeRecorder struct{ closed bool }
+
+func (c *closeRecorder) Close() error {
+	c.closed = true
+	return nil
+}
+
+func TestProgStatsSampleInterrupted(t *testing.T) {
+	withMockProgService(t, []prog.ProgramInfo{{ID: 1, Type: "xdp", Name: "busy"}})
+	stats := &closeRecorder{}
+	origEnable := enableStats
+	enableStats = func() (io.Closer, error) { return stats, nil }
+	t.Cleanup(func() { enableStats = origEnable })
+
+	// A cancelled context ends the sample right away
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	err := executeCommandContext(ctx, "prog", "stats", "id", "1", "--sample", "5m")
+	if !errors.Is(err, bpferrors.ErrInterrupted) {
+		t.Errorf("prog stats --sample error = %v, want %v", err, bpferrors.ErrInterrupted)
+	}
+	if !stats.closed {
+		t.Error("stats handle left open after the interrupted sample")
+	}
+}
+
+func TestExecute_ReturnsServiceError(t *testing.T) {
+	orig := progService
+	progService = &mockProgService{err: syscall.EPERM}
+	t.Cleanup(func() { progService = orig })
+
+	ResetFlags()
+	rootCmd.SetArgs([]string{"prog", "list"})
+	var err error
+	captureFile(t, &os.Stderr, func() { err = Execute() })
+
+	if err == nil {
+		t.Fatal("Execute() = nil, want the service error")
+	}
+	if !errors.Is(err, syscall.EPERM) || !errors.Is(err, bpferrors.ErrPermission) {
+		t.Errorf("Execute() = %v, want it to wrap EPERM and ErrPermission", err)
+	}
+	if code := bpferrors.ExitCode(err); code == 0 {
+		t.Errorf("ExitCode() = 0, want non-zero")
+	}
+}
+
+func TestExecute_ReportsUsageErrors(t *testing.T) {
+	withMockProgService(t, nil)
+
+	ResetFlags()
+	rootCmd.SetArgs([]string{"prog", "list", "--no-such-flag"})
+	var err error
+	stderr := captureFile(t, &os.Stderr, func() { err = Execute() })
+
+	if err == nil {
+		t.Fatal("Execute() = nil, want a flag error")
+	}
+	if !strings.Contains(stderr, "unknown flag") {
+		t.Errorf("stderr = %q, want the flag error", stderr)
+	}
+}
+
+func TestProgListMemlockMin(t *testing.T) {
+	withMockProgService(t, []prog.ProgramInfo{
+		{ID: 1, Type: "XDP", Name: "prog1", MemLock: 4096},
+		{ID: 2, Type: "Kprobe", Name: "prog2", MemLock: 2 << 20},
+	})
+
+	got, err := executeCommandStdout(t, "prog", "list", "--memlock-min", "1M", "--columns", "id,name")
+	if err != nil {
+		t.Fatalf("prog list --memlock-min error = %v", err)
+	}
+	if want := "ID  NAME\n2   prog2"; got != want {
+		t.Errorf("prog list --memlock-min = %q, want %q", got, want)
+	}
+
+	if got, err := executeCommandStdout(t, "prog", "list", "--memlock-min", "10X"); err == nil || got != "" {
+		t.Errorf("prog list --memlock-min 10X = %q, %v, want an error", got, err)
+	}
+}
+
+func TestProgListIDRange(t *testing.T) {
+	withMockProgService(t, []prog.ProgramInfo{
+		{ID: 1, Type: "XDP", Name: "prog1"},
+		{ID: 2, Type: "Kprobe", Name: "prog2"},
+		{ID: 3, Type: "XDP", Name: "prog3"},
+	})
+
+	got, err := executeCommandStdout(t, "prog", "list", "--id-range", "2-", "--type", "xdp", "--columns", "id,name")
+	if err != nil {
+		t.Fatalf("prog list --id-range error = %v", err)
+	}
+	if want := "ID  NAME\n3   prog3"; got != want {
+		t.Errorf("prog list --id-range = %q, want %q", got, want)
+	}
+
+	if got, err := executeCommandStdout(t, "prog", "list", "--id-range", "x-"); err == nil || got != "" {
+		t.Errorf("prog list --id-range x- = %q, %v, want an error", got, err)
+	}
+}
+
+func TestProgShowMetadata(t *testing.T) {
+	withMockProgService(t, []prog.ProgramInfo{
+		{ID: 1, Type: "xdp", Name: "xdp_fw", Tag: "00"},
+		{ID: 2, Type: "xdp", Name: "plain", Tag: "00"},
+	})
+	progService.(*mockProgService).metadata = map[uint32][]prog.MetadataEntry{
+		1: {{Name: "version", Value: "1.2"}, {Name: "build", Value: uint64(42)}},
+	}
+
+	got, err := executeCommandStdout(t, "-j", "prog", "show", "--metadata")
+	if err != nil {
+		t.Fatalf("prog show --metadata error = %v", err)
+	}
+	var doc struct {
+		Programs []map[string]json.RawMessage `json:"programs"`
+	}
+	if err := json.Unmarshal([]byte(got), &doc); err != nil {
+		t.Fatalf("invalid JSON %q: %v", got, err)
+	}
+	if len(doc.Programs) != 2 {
+		t.Fatalf("got %d programs, want 2", len(doc.Programs))
+	}
+	if metadata := string(doc.Programs[0]["metadata"]); metadata != `{"version":"1.2","build":42}` {
+		t.Errorf("metadata = %s, want the declared variables in order", metadata)
+	}
+	if _, ok := doc.Programs[1]["metadata"]; ok {
+		t.Error("program without metadata has a metadata field")
+	}
+
+	got, err = executeCommandStdout(t, "prog", "show", "id", "1", "--metadata")
+	if err != nil {
+		t.Fatalf("prog show --metadata error = %v", err)
+	}
+	if want := "\n\tmetadata:\n\t\tversion = \"1.2\"\n\t\tbuild = 42"; !strings.HasSuffix(got, want) {
+		t.Errorf("prog show --metadata = %q, want suffix %q", got, want)
+	}
+}
+
+func TestProgShowNameIndex(t *testing.T) {
+	withMockProgService(t, []prog.ProgramInfo{
+		{ID: 9, Type: "xdp", Name: "dup", Tag: "00"},
+		{ID: 4, Type: "xdp", Name: "dup", Tag: "00"},
+		{ID: 6, Type: "xdp", Name: "other", Tag: "00"},
+	})
+
+	got, err := executeCommandStdout(t, "prog", "show", "name", "dup", "--index", "1", "--columns", "id,name")
+	if err != nil {
+		t.Fatalf("prog show --index error = %v", err)
+	}
+	if want := "ID  NAME\n9   dup"; got != want {
+		t.Errorf("prog show --index 1 = %q, want %q", got, want)
+	}
+
+	stderr, err := executeCommandStderr(t, "-j", "prog", "show", "name", "dup")
+	if err != nil {
+		t.Fatalf("prog show error = %v", err)
+	}
+	if want := "Note: 2 programs match name dup; pick one with --index (0=id 4, 1=id 9)\n"; stderr != want {
+		t.Errorf("stderr = %q, want %q", stderr, want)
+	}
+
+	for _, args := range [][]string{
+		{"prog", "show", "name", "dup", "--index", "2"},
+		{"prog", "show", "id", "4", "--index", "0"},
+		{"prog", "show", "name", "dup", "--index", "-1"},
+	} {
+		if err := executeCommand(args...); err == nil {
+			t.Errorf("%v succeeded, want an error", args)
+		}
+	}
+}
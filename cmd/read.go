@@ -0,0 +1,48 @@
+package cmd
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/viveksb007/gobpftool/pkg/maps"
+)
+
+// readRecords calls emit for every record r reads until count records were
+// read (0 for no limit), timeout passed (0 for none) or ctx is cancelled.
+// Lost-sample notifications are passed to emit too but don't count toward
+// count. Reaching count or the timeout is a normal end; cancellation
+// returns ctx's error. r is closed on return, which also unblocks a
+// pending Read once ctx is cancelled
+func readRecords(ctx context.Context, r maps.RecordReader, count int, timeout time.Duration, emit func(maps.Record) error) error {
+	defer r.Close()
+
+	readCtx := ctx
+	if timeout > 0 {
+		var cancel context.CancelFunc
+		readCtx, cancel = context.WithTimeout(ctx, timeout)
+		defer cancel()
+	}
+	stop := context.AfterFunc(readCtx, func() { r.Close() })
+	defer stop()
+
+	for n := 0; count == 0 || n < count; {
+		rec, err := r.Read()
+		if err != nil {
+			if ctx.Err() != nil {
+				return ctx.Err()
+			}
+			if errors.Is(readCtx.Err(), context.DeadlineExceeded) {
+				return nil
+			}
+			return err
+		}
+		if err := emit(rec); err != nil {
+			return err
+		}
+		if rec.Lost == 0 {
+			n++
+		}
+	}
+	return nil
+}
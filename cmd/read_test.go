@@ -0,0 +1,210 @@
+package cmd
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"slices"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/viveksb007/gobpftool/pkg/maps"
+)
+
+// fakeRecordReader hands out its records and then blocks like an idle ring
+// buffer until it's closed
+type fakeRecordReader struct {
+	records []maps.Record
+	err     error // returned once the records ran out, instead of blocking
+	closed  chan struct{}
+	once    sync.Once
+}
+
+func newFakeRecordReader(data ...[]byte) *fakeRecordReader {
+	r := &fakeRecordReader{closed: make(chan struct{})}
+	for _, d := range data {
+		r.records = append(r.records, maps.Record{Data: d})
+	}
+	return r
+}
+
+func (r *fakeRecordReader) Read() (maps.Record, error) {
+	select {
+	case <-r.closed:
+		return maps.Record{}, fmt.Errorf("read: %w", os.ErrClosed)
+	default:
+	}
+	if len(r.records) > 0 {
+		rec := r.records[0]
+		r.records = r.records[1:]
+		return rec, nil
+	}
+	if r.err != nil {
+		return maps.Record{}, r.err
+	}
+	<-r.closed
+	return maps.Record{}, fmt.Errorf("read: %w", os.ErrClosed)
+}
+
+func (r *fakeRecordReader) Close() error {
+	r.once.Do(func() { close(r.closed) })
+	return nil
+}
+
+func (r *fakeRecordReader) isClosed() bool {
+	select {
+	case <-r.closed:
+		return true
+	default:
+		return false
+	}
+}
+
+func TestReadRecords(t *testing.T) {
+	records := [][]byte{{1}, {2}, {3}}
+	readErr := errors.New("ring buffer gone")
+	failing := newFakeRecordReader(records...)
+	failing.err = readErr
+	// Lost-sample notifications are emitted but don't count as records
+	withLost := newFakeRecordReader(records...)
+	withLost.records = slices.Insert(withLost.records, 1, maps.Record{CPU: 1, Lost: 7})
+
+	tests := []struct {
+		name    string
+		reader  *fakeRecordReader
+		count   int
+		timeout time.Duration
+		cancel  bool // cancel the context once the records ran out
+		want    int
+		wantErr error
+	}{
+		{name: "count", reader: newFakeRecordReader(records...), count: 2, want: 2},
+		{name: "timeout", reader: newFakeRecordReader(records...), timeout: 20 * time.Millisecond, want: 3},
+		{name: "count not reached before timeout", reader: newFakeRecordReader(records...), count: 5, timeout: 20 * time.Millisecond, want: 3},
+		{name: "interrupted", reader: newFakeRecordReader(records...), cancel: true, want: 3, wantErr: context.Canceled},
+		{name: "read error", reader: failing, want: 3, wantErr: readErr},
+		{name: "lost samples", reader: withLost, count: 3, want: 4},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx, cancel := context.WithCancel(context.Background())
+			defer cancel()
+
+			var got int
+			err := readRecords(ctx, tt.reader, tt.count, tt.timeout, func(maps.Record) error {
+				got++
+				if tt.cancel && got == len(records) {
+					// Read blocks next; cancelling must unblock it
+					cancel()
+				}
+				return nil
+			})
+			if !errors.Is(err, tt.wantErr) || (tt.wantErr == nil && err != nil) {
+				t.Errorf("readRecords() error = %v, want %v", err, tt.wantErr)
+			}
+			if got != tt.want {
+				t.Errorf("readRecords() emitted %d records, want %d", got, tt.want)
+			}
+			if !tt.reader.isClosed() {
+				t.Error("reader wasn't closed")
+			}
+		})
+	}
+}
+
+func TestMapRead(t *testing.T) {
+	svc := newTestMapService()
+	svc.maps = append(svc.maps, maps.MapInfo{ID: 3, Type: "ringbuf", Name: "events", MaxEntries: 4096})
+	svc.entries[3] = []maps.MapEntry{{Value: []byte{0xde, 0xad}}, {Value: []byte{0xbe, 0xef}}, {Value: []byte{1}}}
+	withMockMapService(t, svc)
+
+	got, err := executeCommandStdout(t, "map", "read", "id", "3", "--count", "2")
+	if err != nil {
+		t.Fatalf("map read error = %v", err)
+	}
+	if want := "de ad\nbe ef\n"; got != want {
+		t.Errorf("map read = %q, want %q", got, want)
+	}
+	if !svc.reader.isClosed() {
+		t.Error("ring buffer reader wasn't closed")
+	}
+
+	got, err = executeCommandStdout(t, "-j", "map", "read", "id", "3", "--timeout", "20ms")
+	if err != nil {
+		t.Fatalf("map read -j error = %v", err)
+	}
+	if want := `{"data":"3q0="}` + "\n" + `{"data":"vu8="}` + "\n" + `{"data":"AQ=="}` + "\n"; got != want {
+		t.Errorf("map read -j = %q, want %q", got, want)
+	}
+
+	got, err = executeCommandStdout(t, "-y", "map", "read", "id", "3", "--count", "2")
+	if err != nil {
+		t.Fatalf("map read -y error = %v", err)
+	}
+	if strings.Count(got, "---\n") != 2 {
+		t.Errorf("map read -y = %q, want one YAML document per record", got)
+	}
+
+	if err := executeCommand("map", "read", "id", "1", "--count", "1"); !errors.Is(err, maps.ErrNotRingBuffer) {
+		t.Errorf("map read of a hash map error = %v, want %v", err, maps.ErrNotRingBuffer)
+	}
+	if err := executeCommand("map", "read", "id", "3", "--count", "-1"); err == nil {
+		t.Error("expected an error for a negative --count")
+	}
+}
+
+func TestMapPerfRead(t *testing.T) {
+	svc := newTestMapService()
+	svc.maps = append(svc.maps, maps.MapInfo{ID: 3, Type: "perfeventarray", Name: "events", KeySize: 4, ValueSize: 4, MaxEntries: 2})
+	svc.entries[3] = []maps.MapEntry{{Value: []byte{0xde, 0xad}}, {Value: []byte{0xbe, 0xef}}, {Value: []byte{1}}}
+	withMockMapService(t, svc)
+
+	got, err := executeCommandStdout(t, "map", "perf-read", "id", "3", "--count", "2")
+	if err != nil {
+		t.Fatalf("map perf-read error = %v", err)
+	}
+	if want := "cpu 0: de ad\ncpu 1: be ef\n"; got != want {
+		t.Errorf("map perf-read = %q, want %q", got, want)
+	}
+	if !svc.reader.isClosed() {
+		t.Error("perf event array reader wasn't closed")
+	}
+	if svc.perCPUBuffer != 64<<10 {
+		t.Errorf("per-CPU buffer = %d, want the 64K default", svc.perCPUBuffer)
+	}
+
+	got, err = executeCommandStdout(t, "-j", "map", "perf-read", "id", "3", "--count", "1", "--per-cpu-buffer", "1M")
+	if err != nil {
+		t.Fatalf("map perf-read -j error = %v", err)
+	}
+	if want := `{"cpu":0,"data":"3q0="}` + "\n"; got != want {
+		t.Errorf("map perf-read -j = %q, want %q", got, want)
+	}
+	if svc.perCPUBuffer != 1<<20 {
+		t.Errorf("per-CPU buffer = %d, want %d", svc.perCPUBuffer, 1<<20)
+	}
+
+	// A lost-samples notification warns on stderr and isn't a sample
+	svc.lost = 5
+	stderr, err := executeCommandStderr(t, "map", "perf-read", "id", "3", "--count", "3")
+	if err != nil {
+		t.Fatalf("map perf-read with lost samples error = %v", err)
+	}
+	if !strings.Contains(stderr, "Warning: lost 5 samples on CPU 0") {
+		t.Errorf("map perf-read stderr = %q, want a lost samples warning", stderr)
+	}
+
+	if err := executeCommand("map", "perf-read", "id", "1", "--count", "1"); !errors.Is(err, maps.ErrNotPerfEventArray) {
+		t.Errorf("map perf-read of a hash map error = %v, want %v", err, maps.ErrNotPerfEventArray)
+	}
+	if err := executeCommand("map", "perf-read", "id", "3", "--per-cpu-buffer", "0"); err == nil {
+		t.Error("expected an error for a zero --per-cpu-buffer")
+	}
+	if err := executeCommand("map", "perf-read", "id", "3", "--per-cpu-buffer", "lots"); err == nil {
+		t.Error("expected an error for an invalid --per-cpu-buffer")
+	}
+}
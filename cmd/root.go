@@ -1,12 +1,19 @@
 package cmd
 
 import (
+	"context"
+	"errors"
 	"fmt"
 	"os"
+	"os/signal"
+	"strings"
 
 	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
 
+	"github.com/viveksb007/gobpftool/internal/bpffs"
 	bpferrors "github.com/viveksb007/gobpftool/pkg/errors"
+	"github.com/viveksb007/gobpftool/pkg/output"
 )
 
 // Version information - can be set at build time using ldflags
@@ -18,8 +25,18 @@ var (
 
 // GlobalFlags holds the global CLI flags
 type GlobalFlags struct {
-	JSON   bool // -j, --json
-	Pretty bool // -p, --pretty
+	JSON      bool   // -j, --json
+	Pretty    bool   // -p, --pretty
+	YAML      bool   // -y, --yaml
+	Table     bool   // --table
+	Format    string // --format
+	BPFFS     string // --bpffs
+	Timestamp string // --timestamp
+	Output    string // -o, --output
+	Gzip      bool   // --gzip
+	Human     bool   // -H, --human
+	NoColor   bool   // --no-color
+	Quiet     bool   // -q, --quiet
 }
 
 var globalFlags GlobalFlags
@@ -32,27 +49,144 @@ var rootCmd = &cobra.Command{
 the Linux bpftool utility for inspecting eBPF programs and maps.
 
 It uses the cilium/ebpf library to interact with the kernel's eBPF subsystem.`,
+	PersistentPreRunE: func(cmd *cobra.Command, args []string) error {
+		if _, err := output.ParseTimestampFormat(globalFlags.Timestamp); err != nil {
+			return err
+		}
+		if globalFlags.Format != "" {
+			if _, err := output.ParseFormat(globalFlags.Format); err != nil {
+				return err
+			}
+		}
+		if globalFlags.Gzip && globalFlags.Output == "" {
+			return fmt.Errorf("--gzip requires --output: compressed results can't be written to the terminal")
+		}
+		if globalFlags.Output != "" {
+			if err := openResultFile(globalFlags.Output, globalFlags.Gzip); err != nil {
+				return fmt.Errorf("opening output file: %w", err)
+			}
+		}
+		commandStarted = true
+		installSignalHandler(cmd)
+		if globalFlags.BPFFS != "" {
+			bpffs.SetBPFFSRoot(globalFlags.BPFFS)
+		}
+		// Fail fast instead of letting a deep syscall fail with EPERM
+		if requiresBPF(cmd) && !hasBPFCapability() {
+			return handleError(bpferrors.ErrPermission, "checking capabilities")
+		}
+		return nil
+	},
+	PersistentPostRunE: func(cmd *cobra.Command, args []string) error {
+		if err := closeResultFile(); err != nil {
+			printErrorf("Error: closing output file: %v", err)
+			return err
+		}
+		return nil
+	},
 	Run: func(cmd *cobra.Command, args []string) {
 		if showVersion {
-			printVersionInfo()
+			printVersionInfo(resultWriter(cmd))
 			return
 		}
 		// If no subcommand is provided, show help
 		cmd.Help()
 	},
 	SilenceUsage: true,
+	// Commands report their own errors; Execute prints the rest
+	SilenceErrors: true,
+}
+
+// bpfCapAnnotation marks commands that change BPF state and need CAP_BPF or
+// CAP_SYS_ADMIN. Read-only commands still attempt the operation since some
+// work unprivileged
+const bpfCapAnnotation = "gobpftool/requires-bpf-capability"
+
+// requiresBPFCapability is the Annotations of commands checked for
+// CAP_BPF or CAP_SYS_ADMIN before they run
+var requiresBPFCapability = map[string]string{bpfCapAnnotation: "true"}
+
+// hasBPFCapability reports whether the process may run privileged
+// commands; tests swap it to simulate missing privileges
+var hasBPFCapability = bpferrors.HasBPFCapability
+
+// requiresBPF reports whether cmd is checked for CAP_BPF or CAP_SYS_ADMIN
+func requiresBPF(cmd *cobra.Command) bool {
+	_, ok := cmd.Annotations[bpfCapAnnotation]
+	return ok
+}
+
+// commandStarted records whether flag and argument validation passed and a
+// command began running. Errors after that point were already reported.
+var commandStarted bool
+
+// stopSignals tears down the SIGINT handler of the running command, nil
+// while no command runs
+var stopSignals context.CancelFunc
+
+// installSignalHandler cancels cmd's context on SIGINT so long listings,
+// scans and dumps stop early, print what they have and exit non-zero. The
+// handler stays until the command finishes, see removeSignalHandler
+func installSignalHandler(cmd *cobra.Command) {
+	ctx, stop := signal.NotifyContext(cmd.Context(), os.Interrupt)
+	cmd.SetContext(ctx)
+	stopSignals = stop
 }
 
-// Execute runs the root command
+// removeSignalHandler restores the default SIGINT behavior once a command
+// finished
+func removeSignalHandler() {
+	if stopSignals != nil {
+		stopSignals()
+		stopSignals = nil
+	}
+}
+
+// interrupted reports whether err comes from a command whose context was
+// cancelled, usually by SIGINT
+func interrupted(err error) bool {
+	return errors.Is(err, context.Canceled)
+}
+
+// Execute runs the root command and returns the error of a failed command
+// so the caller can pick an exit code
 func Execute() error {
-	return rootCmd.Execute()
+	commandStarted = false
+	err := rootCmd.ExecuteContext(context.Background())
+	if err != nil && !commandStarted {
+		// Usage errors detected by cobra before any command ran
+		printErrorf("Error: %v", err)
+	}
+	return err
 }
 
 func init() {
 	rootCmd.PersistentFlags().BoolVarP(&globalFlags.JSON, "json", "j", false, "Output in JSON format")
-	rootCmd.PersistentFlags().BoolVarP(&globalFlags.Pretty, "pretty", "p", false, "Output in pretty-printed JSON format")
+	rootCmd.PersistentFlags().BoolVarP(&globalFlags.Pretty, "pretty", "p", false, "Output in pretty-printed JSON format (implies --json)")
+	rootCmd.PersistentFlags().BoolVarP(&globalFlags.YAML, "yaml", "y", false, "Output in YAML format")
+	rootCmd.PersistentFlags().BoolVar(&globalFlags.Table, "table", false, "Output program and map lists as a table")
+	rootCmd.PersistentFlags().StringVar(&globalFlags.BPFFS, "bpffs", "", "BPF filesystem mount point (default "+bpffs.DefaultRoot+")")
+	rootCmd.PersistentFlags().StringVar(&globalFlags.Timestamp, "timestamp", "iso", "Timestamp format for loaded_at: iso, rfc3339nano or epoch")
+	rootCmd.PersistentFlags().BoolVarP(&globalFlags.Human, "human", "H", false, "Print program and map sizes with K, M and G suffixes in plain output")
+	rootCmd.PersistentFlags().BoolVar(&globalFlags.NoColor, "no-color", false, "Don't color plain output, which is colored on a terminal unless NO_COLOR is set")
+	rootCmd.PersistentFlags().BoolVarP(&globalFlags.Quiet, "quiet", "q", false, "Print only data: no \"Found N elements\" trailer after plain map dumps and no \"Error\" label on errors")
+	rootCmd.PersistentFlags().StringVarP(&globalFlags.Output, "output", "o", "", "Write results to this file instead of stdout")
+	rootCmd.PersistentFlags().BoolVar(&globalFlags.Gzip, "gzip", false, "Compress the --output file with gzip")
+	rootCmd.PersistentFlags().StringVar(&globalFlags.Format, "format", "", "Output format by name: plain, json, json-pretty, yaml, table or a registered format")
+	rootCmd.MarkFlagsMutuallyExclusive("json", "yaml", "table", "format")
+	rootCmd.MarkFlagsMutuallyExclusive("pretty", "yaml", "table", "format")
 	rootCmd.Flags().BoolVar(&showVersion, "version", false, "Display version information")
 
+	// The help commands end with the global flags, listed from the flags
+	// themselves so new ones show up
+	for _, help := range []*cobra.Command{mapHelpCmd, progHelpCmd, btfHelpCmd} {
+		help.Long += "\n\nGlobal flags:\n" + strings.TrimSuffix(rootCmd.PersistentFlags().FlagUsages(), "\n")
+	}
+
+	// Close the --output file and drop the SIGINT handler whether or not the
+	// command succeeded. A failed command already reported its error, and a
+	// successful one closed the file in PersistentPostRunE
+	cobra.OnFinalize(func() { closeResultFile() }, removeSignalHandler)
 }
 
 // GetGlobalFlags returns the global flags
@@ -60,6 +194,29 @@ func GetGlobalFlags() GlobalFlags {
 	return globalFlags
 }
 
+// resolveFormat returns the output format selected by the global flags:
+// the --format name when given, else -j, -p, -y or --table. --pretty
+// implies --json
+func resolveFormat(flags GlobalFlags) output.Format {
+	if flags.Format != "" {
+		// The name was validated before the command ran
+		format, _ := output.ParseFormat(flags.Format)
+		return format
+	}
+	switch {
+	case flags.Pretty:
+		return output.FormatJSONPretty
+	case flags.JSON:
+		return output.FormatJSON
+	case flags.YAML:
+		return output.FormatYAML
+	case flags.Table:
+		return output.FormatTable
+	default:
+		return output.FormatPlain
+	}
+}
+
 // SetVersionInfo allows setting version info programmatically (useful for testing)
 func SetVersionInfo(version, commit, date string) {
 	Version = version
@@ -74,36 +231,141 @@ func GetRootCmd() *cobra.Command {
 
 // ResetFlags resets all flags to their default values (useful for testing)
 func ResetFlags() {
-	globalFlags = GlobalFlags{}
+	globalFlags = GlobalFlags{Timestamp: "iso"}
 	showVersion = false
+	resetCommandFlags(rootCmd)
+	closeResultFile()
+
+	// Undo a --bpffs override from a previous Execute
+	if bpffs.GetScanner().Root() != bpffs.DefaultRoot {
+		bpffs.SetBPFFSRoot(bpffs.DefaultRoot)
+	}
 }
 
-// handleError writes a formatted error message to stderr.
+// resetCommandFlags restores every flag of cmd and its subcommands to its
+// default value and clears the changed state left by a previous Execute.
+func resetCommandFlags(cmd *cobra.Command) {
+	reset := func(f *pflag.Flag) {
+		_ = f.Value.Set(f.DefValue)
+		f.Changed = false
+	}
+	cmd.PersistentFlags().VisitAll(reset)
+	cmd.Flags().VisitAll(reset)
+	for _, sub := range cmd.Commands() {
+		resetCommandFlags(sub)
+	}
+}
+
+// handleError writes a formatted error message to stderr and returns err
+// wrapped with context for the caller to return from RunE.
 // It detects common error types (permission, BPF filesystem) and provides
 // helpful guidance to the user.
-func handleError(err error, context string) {
+func handleError(err error, context string) error {
 	if err == nil {
-		return
+		return nil
+	}
+	// An interrupted command stops quietly, keeping what it already printed
+	if interrupted(err) {
+		return fmt.Errorf("%s: %w: %w", context, bpferrors.ErrInterrupted, err)
 	}
 
-	// Check for permission errors first
-	if bpferrors.IsPermissionError(err) {
-		fmt.Fprintln(os.Stderr, bpferrors.FormatPermissionError())
-		return
+	wrapped := bpferrors.WrapError(err, context)
+
+	// Machine-readable formats get the error as a document, without guidance
+	switch format := resolveFormat(GetGlobalFlags()); format {
+	case output.FormatJSON, output.FormatJSONPretty, output.FormatYAML:
+		formatter := newFormatter(format)
+		fmt.Fprintln(os.Stderr, formatter.FormatError(wrapped))
+		return wrapped
 	}
 
+	switch {
+	// Frozen maps reject writes with EPERM, which isn't about privileges
+	case errors.Is(err, bpferrors.ErrMapFrozen):
+		printErrorf("Error %s: map is frozen (read-only)", context)
+
+	case errors.Is(err, bpferrors.ErrDeleteNotSupported):
+		printErrorf("Error %s: delete is not supported for array maps", context)
+
+	// Check for permission errors first
+	case bpferrors.IsPermissionError(err):
+		printError(bpferrors.FormatPermissionError())
+
 	// Check for BPF filesystem issues
-	if bpferrors.IsBpfFSNotMounted() {
-		fmt.Fprintln(os.Stderr, bpferrors.FormatBpfFSError())
-		return
-	}
+	case errors.Is(err, bpferrors.ErrBpfFSNotMounted) || bpferrors.IsBpfFSNotMounted():
+		printError(bpferrors.FormatBpfFSError())
 
 	// Check for specific error types
-	if bpferrors.IsNoMoreKeysError(err) {
-		fmt.Fprintln(os.Stderr, "Error: no more keys")
+	case errors.Is(err, bpferrors.ErrKeyNotFound):
+		printError("Error: key not found in map")
+
+	case bpferrors.IsNoMoreKeysError(err):
+		printError("Error: no more keys")
+
+	// Default error formatting
+	default:
+		printErrorf("Error %s: %v", context, err)
+	}
+
+	return wrapped
+}
+
+// printError writes msg, an error message starting with "Error", to
+// stderr. --quiet drops the "Error" label so only the message remains.
+// With -j, -p or -y the message is written as an error document instead,
+// like handleError does
+func printError(msg string) {
+	report(msg, "Error", nil)
+}
+
+// printErrorf is printError with a format string. The errors among args
+// classify the error document of machine-readable formats
+func printErrorf(format string, args ...any) {
+	var errs []error
+	for _, arg := range args {
+		if err, ok := arg.(error); ok {
+			errs = append(errs, err)
+		}
+	}
+	report(fmt.Sprintf(format, args...), "Error", errs)
+}
+
+// printWarning writes msg, a warning starting with "Warning", to stderr.
+// With -j, -p or -y it's written as an error document classified by cause,
+// which may be nil, so stderr stays machine-readable
+func printWarning(msg string, cause error) {
+	var errs []error
+	if cause != nil {
+		errs = []error{cause}
+	}
+	report(msg, "Warning", errs)
+}
+
+// reportedError is a message written by report, wrapping the errors it
+// was formatted from so bpferrors.Code can classify it
+type reportedError struct {
+	msg  string
+	errs []error
+}
+
+func (e *reportedError) Error() string   { return e.msg }
+func (e *reportedError) Unwrap() []error { return e.errs }
+
+// report writes msg, starting with label, to stderr: as an error document
+// classified by errs under -j, -p or -y, and otherwise as it is, without
+// the "Error" label under --quiet
+func report(msg, label string, errs []error) {
+	unlabeled := strings.TrimPrefix(strings.TrimPrefix(msg, label+": "), label+" ")
+
+	switch format := resolveFormat(GetGlobalFlags()); format {
+	case output.FormatJSON, output.FormatJSONPretty, output.FormatYAML:
+		err := &reportedError{msg: unlabeled, errs: errs}
+		fmt.Fprintln(os.Stderr, newFormatter(format).FormatError(err))
 		return
 	}
 
-	// Default error formatting
-	fmt.Fprintf(os.Stderr, "Error %s: %v\n", context, err)
+	if label == "Error" && globalFlags.Quiet {
+		msg = unlabeled
+	}
+	fmt.Fprintln(os.Stderr, msg)
 }
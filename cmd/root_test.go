@@ -2,10 +2,109 @@ package cmd
 
 import (
 	"bytes"
+	"compress/gzip"
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
 	"strings"
+	"syscall"
 	"testing"
+	"time"
+
+	"github.com/spf13/cobra"
+
+	"github.com/viveksb007/gobpftool/internal/bpffs"
+	bpferrors "github.com/viveksb007/gobpftool/pkg/errors"
+	"github.com/viveksb007/gobpftool/pkg/output"
+	"github.com/viveksb007/gobpftool/pkg/prog"
 )
 
+// executeCommand runs the root command with the given arguments.
+func executeCommand(args ...string) error {
+	return executeCommandContext(context.Background(), args...)
+}
+
+// executeCommandContext runs the root command with the given arguments and
+// ctx as the context of the command that runs.
+func executeCommandContext(ctx context.Context, args ...string) error {
+	return runCommand(ctx, &bytes.Buffer{}, args...)
+}
+
+// runCommand runs the root command with the given arguments, ctx as the
+// context of the command that runs and out as its output.
+func runCommand(ctx context.Context, out io.Writer, args ...string) error {
+	ResetFlags()
+	cmd := GetRootCmd()
+	// Cobra only hands the root's context to subcommands without one
+	clearContexts(cmd)
+	cmd.SetArgs(args)
+	cmd.SetOut(out)
+	cmd.SetErr(&bytes.Buffer{})
+	return cmd.ExecuteContext(ctx)
+}
+
+// clearContexts removes the context a previous run left on cmd and its
+// subcommands.
+func clearContexts(cmd *cobra.Command) {
+	cmd.SetContext(nil)
+	for _, sub := range cmd.Commands() {
+		clearContexts(sub)
+	}
+}
+
+// executeCommandStdout runs the root command and returns the results it
+// wrote to its output, stdout outside of tests.
+func executeCommandStdout(t *testing.T, args ...string) (string, error) {
+	t.Helper()
+	var out bytes.Buffer
+	err := runCommand(context.Background(), &out, args...)
+	return out.String(), err
+}
+
+// executeCommandStderr runs the root command and returns what it printed to
+// os.Stderr, where errors are written.
+func executeCommandStderr(t *testing.T, args ...string) (string, error) {
+	t.Helper()
+	var err error
+	out := captureFile(t, &os.Stderr, func() { err = executeCommand(args...) })
+	return out, err
+}
+
+// withBPFCapability makes privileged commands see the process as holding
+// CAP_BPF or not.
+func withBPFCapability(t *testing.T, has bool) {
+	t.Helper()
+	orig := hasBPFCapability
+	hasBPFCapability = func() bool { return has }
+	t.Cleanup(func() { hasBPFCapability = orig })
+}
+
+// captureFile redirects *f to a pipe while fn runs and returns the output.
+func captureFile(t *testing.T, f **os.File, fn func()) string {
+	t.Helper()
+
+	r, w, err := os.Pipe()
+	if err != nil {
+		t.Fatal(err)
+	}
+	orig := *f
+	*f = w
+	defer func() { *f = orig }()
+
+	done := make(chan string)
+	go func() {
+		data, _ := io.ReadAll(r)
+		done <- string(data)
+	}()
+
+	fn()
+	w.Close()
+	return <-done
+}
+
 func TestGlobalFlags_JSON(t *testing.T) {
 	tests := []struct {
 		name     string
@@ -136,6 +235,220 @@ func TestGlobalFlags_Combined(t *testing.T) {
 	}
 }
 
+func TestResolveFormat(t *testing.T) {
+	tests := []struct {
+		name  string
+		flags GlobalFlags
+		want  output.Format
+	}{
+		{"no flags", GlobalFlags{}, output.FormatPlain},
+		{"json", GlobalFlags{JSON: true}, output.FormatJSON},
+		{"pretty alone", GlobalFlags{Pretty: true}, output.FormatJSONPretty},
+		{"json and pretty", GlobalFlags{JSON: true, Pretty: true}, output.FormatJSONPretty},
+		{"yaml", GlobalFlags{YAML: true}, output.FormatYAML},
+		{"table", GlobalFlags{Table: true}, output.FormatTable},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := resolveFormat(tt.flags); got != tt.want {
+				t.Errorf("resolveFormat(%+v) = %v, want %v", tt.flags, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestPrettyImpliesJSON(t *testing.T) {
+	withMockProgService(t, []prog.ProgramInfo{{ID: 1, Type: "xdp", Name: "p"}})
+
+	out, err := executeCommandStdout(t, "-p", "prog", "list")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	var parsed map[string]interface{}
+	if err := json.Unmarshal([]byte(out), &parsed); err != nil {
+		t.Fatalf("-p output isn't JSON: %v\n%s", err, out)
+	}
+	if !strings.Contains(out, "\n  ") {
+		t.Errorf("-p output isn't indented:\n%s", out)
+	}
+}
+
+func TestOutputFile(t *testing.T) {
+	withMockProgService(t, []prog.ProgramInfo{{ID: 1, Type: "xdp", Name: "p"}})
+	path := filepath.Join(t.TempDir(), "progs.json")
+
+	// A stale file is truncated
+	if err := os.WriteFile(path, []byte("stale contents that are longer than the result"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	stdout, err := executeCommandStdout(t, "-j", "-o", path, "prog", "list")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if stdout != "" {
+		t.Errorf("stdout = %q, want nothing", stdout)
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("reading output file: %v", err)
+	}
+	var parsed struct {
+		Programs []struct {
+			ID uint32 `json:"id"`
+		} `json:"programs"`
+	}
+	if err := json.Unmarshal(data, &parsed); err != nil {
+		t.Fatalf("output file isn't JSON: %v\n%s", err, data)
+	}
+	if len(parsed.Programs) != 1 || parsed.Programs[0].ID != 1 {
+		t.Errorf("output file = %s, want program 1", data)
+	}
+
+	// Errors still go to stderr
+	stderr, err := executeCommandStderr(t, "-o", path, "prog", "show", "id", "99")
+	if err == nil {
+		t.Fatal("expected an error for an unknown program")
+	}
+	if !strings.Contains(stderr, "Error") {
+		t.Errorf("stderr = %q, want the error", stderr)
+	}
+
+	if err := executeCommand("-o", filepath.Join(path, "sub", "file"), "prog", "list"); err == nil {
+		t.Error("expected an error for an output path that can't be created")
+	}
+}
+
+func TestOutputFileGzip(t *testing.T) {
+	withMockMapService(t, newTestMapService())
+	path := filepath.Join(t.TempDir(), "dump.json.gz")
+
+	if err := executeCommand("-j", "-o", path, "--gzip", "map", "dump", "id", "1"); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	f, err := os.Open(path)
+	if err != nil {
+		t.Fatalf("opening output file: %v", err)
+	}
+	defer f.Close()
+	zr, err := gzip.NewReader(f)
+	if err != nil {
+		t.Fatalf("output file isn't gzip: %v", err)
+	}
+	data, err := io.ReadAll(zr)
+	if err != nil {
+		t.Fatalf("decompressing output file: %v", err)
+	}
+
+	var parsed struct {
+		Count int `json:"count"`
+	}
+	if err := json.Unmarshal(data, &parsed); err != nil {
+		t.Fatalf("decompressed output isn't JSON: %v\n%s", err, data)
+	}
+	if parsed.Count != 2 {
+		t.Errorf("decompressed output = %s, want the 2 entries of map 1", data)
+	}
+
+	if err := executeCommand("--gzip", "map", "dump", "id", "1"); err == nil {
+		t.Error("expected an error for --gzip without --output")
+	}
+
+	// The compressed results are only written when the file is closed
+	if err := executeCommand("-j", "-o", "/dev/full", "--gzip", "map", "dump", "id", "1"); !errors.Is(err, syscall.ENOSPC) {
+		t.Errorf("writing to a full device error = %v, want %v", err, syscall.ENOSPC)
+	}
+}
+
+// nameFormatter is a custom format registered by the tests, printing only
+// program names
+type nameFormatter struct {
+	output.PlainFormatter
+}
+
+func (nameFormatter) FormatPrograms(progs []output.ProgramInfo) string {
+	names := make([]string, len(progs))
+	for i, p := range progs {
+		names[i] = p.Name
+	}
+	return strings.Join(names, ",")
+}
+
+func init() {
+	output.RegisterFormatter("test-names", func() output.Formatter { return &nameFormatter{} })
+}
+
+func TestColorEnabled(t *testing.T) {
+	// Results piped or written to a file are never colored
+	ResetFlags()
+	if colorEnabled() {
+		t.Error("colorEnabled() = true with stdout not a terminal")
+	}
+
+	withMockMapService(t, newTestMapService())
+	got, err := executeCommandStdout(t, "--no-color", "map", "show", "id", "1")
+	if err != nil {
+		t.Fatalf("--no-color map show error = %v", err)
+	}
+	if strings.Contains(got, "\x1b[") {
+		t.Errorf("--no-color map show = %q, want no escapes", got)
+	}
+}
+
+func TestHumanFlag(t *testing.T) {
+	svc := newTestMapService()
+	svc.maps[0].MemLock = 167936
+	withMockMapService(t, svc)
+
+	got, err := executeCommandStdout(t, "-H", "map", "show", "id", "1")
+	if err != nil {
+		t.Fatalf("-H map show error = %v", err)
+	}
+	if !strings.Contains(got, "memlock 164K") {
+		t.Errorf("-H map show = %q, want memlock 164K", got)
+	}
+
+	got, err = executeCommandStdout(t, "-j", "--human", "map", "show", "id", "1")
+	if err != nil {
+		t.Fatalf("-j --human map show error = %v", err)
+	}
+	if !strings.Contains(got, `"bytes_memlock":167936`) {
+		t.Errorf("-j --human map show = %s, want exact bytes", got)
+	}
+}
+
+func TestFormatFlag(t *testing.T) {
+	withMockProgService(t, []prog.ProgramInfo{{ID: 1, Type: "xdp", Name: "a"}, {ID: 2, Type: "xdp", Name: "b"}})
+
+	got, err := executeCommandStdout(t, "--format", "test-names", "prog", "list")
+	if err != nil {
+		t.Fatalf("--format test-names error = %v", err)
+	}
+	if got != "a,b" {
+		t.Errorf("--format test-names = %q, want %q", got, "a,b")
+	}
+
+	// Built-in formats have names too
+	got, err = executeCommandStdout(t, "--format", "json", "prog", "list")
+	if err != nil {
+		t.Fatalf("--format json error = %v", err)
+	}
+	if !strings.HasPrefix(got, `{"programs":[`) {
+		t.Errorf("--format json = %s, want JSON", got)
+	}
+
+	if err := executeCommand("--format", "xml", "prog", "list"); err == nil {
+		t.Error("expected an error for an unknown format")
+	}
+	if err := executeCommand("--format", "json", "-y", "prog", "list"); err == nil {
+		t.Error("expected an error for --format with --yaml")
+	}
+}
+
 func TestVersionFlag(t *testing.T) {
 	ResetFlags()
 	SetVersionInfo("1.0.0", "abc123", "2025-01-01")
@@ -151,8 +464,44 @@ func TestVersionFlag(t *testing.T) {
 		t.Errorf("Execute() error = %v", err)
 	}
 
-	// Note: printVersion writes to os.Stdout, not cmd's output
-	// The test verifies the flag is parsed correctly
+	want := "gobpftool version 1.0.0\n  git commit: abc123\n  build date: 2025-01-01\n"
+	if got := buf.String(); got != want {
+		t.Errorf("--version output = %q, want %q", got, want)
+	}
+}
+
+func TestVersionJSON(t *testing.T) {
+	SetVersionInfo("1.0.0", "abc123", "unknown")
+
+	for _, args := range [][]string{
+		{"-j", "version"},
+		{"--json", "--version"},
+	} {
+		ResetFlags()
+		cmd := GetRootCmd()
+		buf := &bytes.Buffer{}
+		cmd.SetOut(buf)
+		cmd.SetErr(&bytes.Buffer{})
+		cmd.SetArgs(args)
+
+		if err := cmd.Execute(); err != nil {
+			t.Fatalf("%v error = %v", args, err)
+		}
+
+		want := `{"version":"1.0.0","git_commit":"abc123","build_date":"unknown"}` + "\n"
+		if got := buf.String(); got != want {
+			t.Errorf("%v output = %q, want %q", args, got, want)
+		}
+	}
+
+	// Like other results, the version goes to the --output file
+	path := filepath.Join(t.TempDir(), "version.json")
+	if err := executeCommand("-j", "-o", path, "version"); err != nil {
+		t.Fatalf("version -o error = %v", err)
+	}
+	if data, _ := os.ReadFile(path); !strings.Contains(string(data), `"version":"1.0.0"`) {
+		t.Errorf("output file = %q, want the version", data)
+	}
 }
 
 func TestHelpOutput(t *testing.T) {
@@ -219,3 +568,171 @@ func TestRootCommandNoArgs(t *testing.T) {
 		t.Error("Expected help output when no args provided")
 	}
 }
+
+func TestGlobalFlags_YAML(t *testing.T) {
+	tests := []struct {
+		name     string
+		args     []string
+		wantYAML bool
+		wantErr  bool
+	}{
+		{
+			name:     "short yaml flag",
+			args:     []string{"-y"},
+			wantYAML: true,
+		},
+		{
+			name:     "long yaml flag",
+			args:     []string{"--yaml"},
+			wantYAML: true,
+		},
+		{
+			name:    "yaml with json",
+			args:    []string{"--yaml", "--json"},
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := executeCommand(tt.args...)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Execute() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if tt.wantErr {
+				return
+			}
+			if flags := GetGlobalFlags(); flags.YAML != tt.wantYAML {
+				t.Errorf("YAML flag = %v, want %v", flags.YAML, tt.wantYAML)
+			}
+			if resolveFormat(GetGlobalFlags()) != output.FormatYAML {
+				t.Errorf("resolveFormat(GetGlobalFlags()) = %v, want FormatYAML", resolveFormat(GetGlobalFlags()))
+			}
+		})
+	}
+}
+
+func TestGlobalFlags_BPFFS(t *testing.T) {
+	withMockMapService(t, newTestMapService())
+	t.Cleanup(ResetFlags)
+
+	root := t.TempDir()
+	if err := executeCommand("--bpffs", root, "map", "list"); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if got := GetGlobalFlags().BPFFS; got != root {
+		t.Errorf("BPFFS flag = %q, want %q", got, root)
+	}
+	if got := bpffs.GetScanner().Root(); got != root {
+		t.Errorf("scanner root = %q, want %q", got, root)
+	}
+
+	ResetFlags()
+	if got := GetGlobalFlags().BPFFS; got != "" {
+		t.Errorf("BPFFS flag after ResetFlags = %q, want empty", got)
+	}
+	if got := bpffs.GetScanner().Root(); got != bpffs.DefaultRoot {
+		t.Errorf("scanner root after ResetFlags = %q, want %q", got, bpffs.DefaultRoot)
+	}
+}
+
+func TestHandleError_JSON(t *testing.T) {
+	withMockProgService(t, nil)
+
+	stderr, err := executeCommandStderr(t, "-j", "prog", "show", "id", "8")
+	if err == nil {
+		t.Fatal("expected error for missing program, got nil")
+	}
+
+	var parsed map[string]string
+	if jsonErr := json.Unmarshal([]byte(stderr), &parsed); jsonErr != nil {
+		t.Fatalf("stderr is not a JSON object: %q", stderr)
+	}
+	if !strings.Contains(parsed["error"], "not found") {
+		t.Errorf("error = %q, want it to mention not found", parsed["error"])
+	}
+
+	// Plain mode keeps the human-readable message
+	stderr, _ = executeCommandStderr(t, "prog", "show", "id", "8")
+	if !strings.HasPrefix(stderr, "Error ") {
+		t.Errorf("plain stderr = %q, want an Error prefix", stderr)
+	}
+}
+
+func TestPrintError_JSON(t *testing.T) {
+	withMockMapService(t, newTestMapService())
+
+	// Errors and warnings printed by the commands are documents too,
+	// classified like the ones from handleError
+	tests := []struct {
+		args     []string
+		wantCode string
+	}{
+		{args: []string{"map", "lookup", "id", "1", "key", "zz"}, wantCode: bpferrors.CodeGeneric},
+		{args: []string{"map", "unpin", "/sys/fs/bpf/missing"}, wantCode: bpferrors.CodeNotFound},
+	}
+	for _, tt := range tests {
+		stderr, _ := executeCommandStderr(t, append([]string{"-j"}, tt.args...)...)
+		var parsed map[string]string
+		if err := json.Unmarshal([]byte(stderr), &parsed); err != nil {
+			t.Errorf("%v: stderr is not a JSON object: %q", tt.args, stderr)
+			continue
+		}
+		if parsed["code"] != tt.wantCode {
+			t.Errorf("%v: code = %q, want %q", tt.args, parsed["code"], tt.wantCode)
+		}
+		if strings.HasPrefix(parsed["error"], "Error") || strings.HasPrefix(parsed["error"], "Warning") {
+			t.Errorf("%v: error = %q, want it without the label", tt.args, parsed["error"])
+		}
+	}
+}
+
+func TestCapabilityCheck(t *testing.T) {
+	withMockMapService(t, newTestMapService())
+	withBPFCapability(t, false)
+
+	// Commands changing BPF state fail before touching the map
+	stderr, err := executeCommandStderr(t, "map", "update", "id", "1", "key", "03", "00", "00", "00",
+		"value", "00", "00", "00", "00", "00", "00", "00", "00")
+	if !errors.Is(err, bpferrors.ErrPermission) {
+		t.Fatalf("map update error = %v, want ErrPermission", err)
+	}
+	if !strings.Contains(stderr, "sudo setcap cap_bpf=ep") {
+		t.Errorf("stderr = %q, want the permission hint", stderr)
+	}
+	if len(mapService.(*mockMapService).entries[1]) != 2 {
+		t.Error("map update changed the map without capabilities")
+	}
+
+	// Read-only commands still try
+	if err := executeCommand("map", "list"); err != nil {
+		t.Errorf("map list error = %v, want it to run without capabilities", err)
+	}
+
+	withBPFCapability(t, true)
+	if err := executeCommand("map", "update", "id", "1", "key", "03", "00", "00", "00",
+		"value", "00", "00", "00", "00", "00", "00", "00", "00"); err != nil {
+		t.Errorf("map update with capabilities error = %v", err)
+	}
+	if len(mapService.(*mockMapService).entries[1]) != 3 {
+		t.Error("map update with capabilities didn't add the entry")
+	}
+}
+
+func TestGlobalFlags_Timestamp(t *testing.T) {
+	withMockProgService(t, []prog.ProgramInfo{
+		{ID: 1, Type: "xdp", Name: "p", LoadedAt: time.Unix(1763956246, 0)},
+	})
+
+	out, err := executeCommandStdout(t, "--timestamp", "epoch", "prog", "list")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !strings.Contains(out, "loaded_at 1763956246 ") {
+		t.Errorf("output = %q, want epoch loaded_at", out)
+	}
+
+	if err := executeCommand("--timestamp", "unix", "prog", "list"); err == nil {
+		t.Error("expected error for invalid --timestamp, got nil")
+	}
+}
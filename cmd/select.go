@@ -0,0 +1,66 @@
+package cmd
+
+import (
+	"cmp"
+	"fmt"
+	"os"
+	"slices"
+	"strings"
+
+	"github.com/spf13/cobra"
+	"github.com/viveksb007/gobpftool/pkg/output"
+)
+
+// noIndex is the --index default: keep every match
+const noIndex = -1
+
+// selectMatch sorts the kind objects matched by a name or tag lookup by ID
+// and keeps the index-th, so the same index keeps picking the same object
+// while the matches stay loaded. noIndex keeps every match and notes them
+// to JSON and YAML consumers
+func selectMatch[T any](kind, lookup string, matches []T, id func(T) uint32, index int) ([]T, error) {
+	slices.SortStableFunc(matches, func(a, b T) int { return cmp.Compare(id(a), id(b)) })
+
+	if index == noIndex {
+		ids := make([]uint32, len(matches))
+		for i, m := range matches {
+			ids[i] = id(m)
+		}
+		noteMatches(kind, lookup, ids)
+		return matches, nil
+	}
+	if index < 0 || index >= len(matches) {
+		return nil, fmt.Errorf("--index %d out of range: %d matches, use 0 to %d", index, len(matches), len(matches)-1)
+	}
+	return matches[index : index+1], nil
+}
+
+// checkIndex rejects a negative --index. An explicit -1 would otherwise
+// read as noIndex and keep every match
+func checkIndex(cmd *cobra.Command, index int) error {
+	if cmd.Flags().Changed("index") && index < 0 {
+		return fmt.Errorf("--index can't be negative")
+	}
+	return nil
+}
+
+// noteMatches tells JSON and YAML consumers, which usually expect a single
+// object from a name or tag lookup, that several matched and how to pick
+// one. ids must be sorted like selectMatch sorts them
+func noteMatches(kind, lookup string, ids []uint32) {
+	if len(ids) < 2 {
+		return
+	}
+	switch resolveFormat(GetGlobalFlags()) {
+	case output.FormatJSON, output.FormatJSONPretty, output.FormatYAML:
+	default:
+		return
+	}
+
+	indices := make([]string, len(ids))
+	for i, id := range ids {
+		indices[i] = fmt.Sprintf("%d=id %d", i, id)
+	}
+	fmt.Fprintf(os.Stderr, "Note: %d %ss match %s; pick one with --index (%s)\n",
+		len(ids), kind, lookup, strings.Join(indices, ", "))
+}
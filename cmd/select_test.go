@@ -0,0 +1,35 @@
+package cmd
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestSelectMatch(t *testing.T) {
+	id := func(n uint32) uint32 { return n }
+
+	tests := []struct {
+		name    string
+		index   int
+		want    []uint32
+		wantErr bool
+	}{
+		{name: "every match sorted by ID", index: noIndex, want: []uint32{3, 7, 9}},
+		{name: "first", index: 0, want: []uint32{3}},
+		{name: "last", index: 2, want: []uint32{9}},
+		{name: "past the end", index: 3, wantErr: true},
+		{name: "negative", index: -2, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := selectMatch("map", "name m", []uint32{9, 3, 7}, id, tt.index)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("selectMatch() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if !tt.wantErr && !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("selectMatch() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
@@ -2,17 +2,22 @@ package cmd
 
 import (
 	"fmt"
-	"os"
+	"io"
 
 	"github.com/spf13/cobra"
+
+	"github.com/viveksb007/gobpftool/pkg/output"
 )
 
 var versionCmd = &cobra.Command{
 	Use:   "version",
 	Short: "Display version information",
-	Long:  `Display the version, git commit, and build date of gobpftool.`,
+	Long: `Display the version, git commit, and build date of gobpftool.
+
+  gobpftool version
+  gobpftool -j version       # {"version":...,"git_commit":...,"build_date":...}`,
 	Run: func(cmd *cobra.Command, args []string) {
-		printVersionInfo()
+		printVersionInfo(resultWriter(cmd))
 	},
 }
 
@@ -20,13 +25,13 @@ func init() {
 	rootCmd.AddCommand(versionCmd)
 }
 
-// printVersionInfo prints detailed version information
-func printVersionInfo() {
-	fmt.Fprintf(os.Stdout, "gobpftool version %s\n", Version)
-	if GitCommit != "unknown" {
-		fmt.Fprintf(os.Stdout, "  git commit: %s\n", GitCommit)
-	}
-	if BuildDate != "unknown" {
-		fmt.Fprintf(os.Stdout, "  build date: %s\n", BuildDate)
-	}
+// printVersionInfo writes detailed version information to w in the format
+// selected by the global flags
+func printVersionInfo(w io.Writer) {
+	formatter := newFormatter(resolveFormat(GetGlobalFlags()))
+	fmt.Fprintln(w, formatter.FormatVersion(output.VersionInfo{
+		Version:   Version,
+		GitCommit: GitCommit,
+		BuildDate: BuildDate,
+	}))
 }
@@ -0,0 +1,29 @@
+package cmd
+
+import (
+	"context"
+	"time"
+)
+
+// clearScreen moves the cursor home and clears the terminal
+const clearScreen = "\033[H\033[2J"
+
+// watchLoop calls render once and then on every tick until ctx is cancelled.
+// A failing render doesn't end the loop since the error is usually transient,
+// like a map that's being recreated; render reports its own errors. The
+// error of the last render is returned so a watch that ends on a failure
+// exits non-zero. A render cut short by the cancellation isn't a failure.
+func watchLoop(ctx context.Context, ticks <-chan time.Time, render func() error) error {
+	for {
+		err := render()
+		if interrupted(err) {
+			err = nil
+		}
+
+		select {
+		case <-ctx.Done():
+			return err
+		case <-ticks:
+		}
+	}
+}
@@ -0,0 +1,51 @@
+package cmd
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestWatchLoop(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	// The test drives the loop through ticks, standing in for a clock
+	ticks := make(chan time.Time)
+	renders := make(chan int)
+	calls := 0
+	errGone := errors.New("map disappeared")
+	done := make(chan error)
+	go func() {
+		done <- watchLoop(ctx, ticks, func() error {
+			calls++
+			renders <- calls
+			if calls >= 2 {
+				return errGone
+			}
+			return nil
+		})
+	}()
+
+	// Renders once immediately, then once per tick, even after an error
+	for want := 1; want <= 3; want++ {
+		if got := <-renders; got != want {
+			t.Fatalf("render call = %d, want %d", got, want)
+		}
+		if want < 3 {
+			ticks <- time.Time{}
+		}
+	}
+
+	cancel()
+	select {
+	case err := <-done:
+		// The watch ended on a failed render
+		if !errors.Is(err, errGone) {
+			t.Errorf("watchLoop() error = %v, want %v", err, errGone)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("watchLoop didn't return after cancel")
+	}
+}
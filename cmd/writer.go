@@ -0,0 +1,74 @@
+package cmd
+
+import (
+	"compress/gzip"
+	"io"
+	"os"
+
+	"github.com/spf13/cobra"
+	"golang.org/x/sys/unix"
+)
+
+// resultFile is the file opened for --output, nil while results go to stdout
+var resultFile *os.File
+
+// resultGzip compresses the results into resultFile with --gzip, nil
+// otherwise
+var resultGzip *gzip.Writer
+
+// openResultFile creates or truncates path and sends command results to
+// it, gzip-compressed when compress is set
+func openResultFile(path string, compress bool) error {
+	f, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	resultFile = f
+	if compress {
+		resultGzip = gzip.NewWriter(f)
+	}
+	return nil
+}
+
+// closeResultFile closes the --output file, if any, once a command
+// finished. With --gzip the compressed stream is completed first. It
+// returns the first error, since results buffered until the close may not
+// have been written
+func closeResultFile() error {
+	var err error
+	if resultGzip != nil {
+		err = resultGzip.Close()
+		resultGzip = nil
+	}
+	if resultFile != nil {
+		if closeErr := resultFile.Close(); err == nil {
+			err = closeErr
+		}
+		resultFile = nil
+	}
+	return err
+}
+
+// resultWriter returns where the results of cmd are written: the --output
+// file when one is set, otherwise the command's output, stdout unless a
+// test set another. Errors always go to stderr
+func resultWriter(cmd *cobra.Command) io.Writer {
+	if resultGzip != nil {
+		return resultGzip
+	}
+	if resultFile != nil {
+		return resultFile
+	}
+	return cmd.OutOrStdout()
+}
+
+// colorEnabled reports whether plain output is colored: only when results
+// go to a terminal, and neither --no-color nor the NO_COLOR environment
+// variable (https://no-color.org) turned it off
+func colorEnabled() bool {
+	if GetGlobalFlags().NoColor || os.Getenv("NO_COLOR") != "" || resultFile != nil {
+		return false
+	}
+	_, err := unix.IoctlGetTermios(int(os.Stdout.Fd()), unix.TCGETS)
+	return err == nil
+}
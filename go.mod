@@ -5,10 +5,9 @@ go 1.25
 require (
 	github.com/cilium/ebpf v0.20.0
 	github.com/spf13/cobra v1.8.1
+	github.com/spf13/pflag v1.0.5
+	golang.org/x/sys v0.37.0
+	gopkg.in/yaml.v3 v3.0.1
 )
 
-require (
-	github.com/inconshreveable/mousetrap v1.1.0 // indirect
-	github.com/spf13/pflag v1.0.5 // indirect
-	golang.org/x/sys v0.37.0 // indirect
-)
+require github.com/inconshreveable/mousetrap v1.1.0 // indirect
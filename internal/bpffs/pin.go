@@ -0,0 +1,45 @@
+package bpffs
+
+import (
+	"fmt"
+	"path/filepath"
+	"strings"
+
+	"golang.org/x/sys/unix"
+
+	bpferrors "github.com/viveksb007/gobpftool/pkg/errors"
+)
+
+// ValidatePinPath checks that path lies strictly inside the bpffs root and
+// returns it cleaned and made absolute.
+func ValidatePinPath(root, path string) (string, error) {
+	if path == "" {
+		return "", fmt.Errorf("pin path is empty")
+	}
+
+	absRoot, err := filepath.Abs(root)
+	if err != nil {
+		return "", fmt.Errorf("invalid bpffs root %s: %w", root, err)
+	}
+	absPath, err := filepath.Abs(path)
+	if err != nil {
+		return "", fmt.Errorf("invalid pin path %s: %w", path, err)
+	}
+
+	rel, err := filepath.Rel(absRoot, absPath)
+	if err != nil || rel == "." || rel == ".." || strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+		return "", fmt.Errorf("pin path %s is not under the bpffs root %s", path, root)
+	}
+
+	return absPath, nil
+}
+
+// CheckMounted returns ErrBpfFSNotMounted unless a BPF filesystem is
+// mounted at root.
+func CheckMounted(root string) error {
+	var st unix.Statfs_t
+	if err := unix.Statfs(root, &st); err != nil || st.Type != unix.BPF_FS_MAGIC {
+		return bpferrors.ErrBpfFSNotMounted
+	}
+	return nil
+}
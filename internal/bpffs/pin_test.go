@@ -0,0 +1,91 @@
+package bpffs
+
+import (
+	"errors"
+	"testing"
+
+	bpferrors "github.com/viveksb007/gobpftool/pkg/errors"
+)
+
+func TestValidatePinPath(t *testing.T) {
+	tests := []struct {
+		name    string
+		root    string
+		path    string
+		want    string
+		wantErr bool
+	}{
+		{
+			name: "direct child",
+			root: "/sys/fs/bpf",
+			path: "/sys/fs/bpf/my_prog",
+			want: "/sys/fs/bpf/my_prog",
+		},
+		{
+			name: "nested path",
+			root: "/sys/fs/bpf",
+			path: "/sys/fs/bpf/tc/globals/my_map",
+			want: "/sys/fs/bpf/tc/globals/my_map",
+		},
+		{
+			name: "uncleaned path",
+			root: "/sys/fs/bpf/",
+			path: "/sys/fs/bpf/a/../b",
+			want: "/sys/fs/bpf/b",
+		},
+		{
+			name:    "root itself",
+			root:    "/sys/fs/bpf",
+			path:    "/sys/fs/bpf",
+			wantErr: true,
+		},
+		{
+			name:    "outside root",
+			root:    "/sys/fs/bpf",
+			path:    "/tmp/my_prog",
+			wantErr: true,
+		},
+		{
+			name:    "escapes root",
+			root:    "/sys/fs/bpf",
+			path:    "/sys/fs/bpf/../../etc/passwd",
+			wantErr: true,
+		},
+		{
+			name:    "sibling with common prefix",
+			root:    "/sys/fs/bpf",
+			path:    "/sys/fs/bpfx/my_prog",
+			wantErr: true,
+		},
+		{
+			name:    "empty path",
+			root:    "/sys/fs/bpf",
+			path:    "",
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ValidatePinPath(tt.root, tt.path)
+			if tt.wantErr {
+				if err == nil {
+					t.Errorf("ValidatePinPath() = %q, want error", got)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("ValidatePinPath() error = %v", err)
+			}
+			if got != tt.want {
+				t.Errorf("ValidatePinPath() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestCheckMounted_NotBPFFS(t *testing.T) {
+	if err := CheckMounted(t.TempDir()); !errors.Is(err, bpferrors.ErrBpfFSNotMounted) {
+		t.Errorf("CheckMounted() error = %v, want %v", err, bpferrors.ErrBpfFSNotMounted)
+	}
+}
@@ -2,22 +2,27 @@
 package bpffs
 
 import (
+	"io/fs"
 	"os"
 	"path/filepath"
+	"runtime"
 	"sync"
 
 	"github.com/cilium/ebpf"
 )
 
-const defaultBPFFS = "/sys/fs/bpf"
+// DefaultRoot is where the BPF filesystem is conventionally mounted.
+const DefaultRoot = "/sys/fs/bpf"
 
 // Scanner discovers pinned BPF objects by scanning the BPF filesystem.
 type Scanner struct {
+	scanMu    sync.Mutex // serializes scans so only one walk runs at a time
 	mu        sync.RWMutex
 	progPaths map[uint32][]string // program ID -> pinned paths
 	mapPaths  map[uint32][]string // map ID -> pinned paths
 	bpffsRoot string
 	scanned   bool
+	scanErr   error // why the last scan couldn't walk the root, if it couldn't
 }
 
 // Global scanner instance
@@ -26,18 +31,41 @@ var (
 	scannerOnce   sync.Once
 )
 
+// NewScannerWithRoot creates a scanner that walks the given bpffs root
+// instead of the default /sys/fs/bpf.
+func NewScannerWithRoot(root string) *Scanner {
+	return &Scanner{
+		progPaths: make(map[uint32][]string),
+		mapPaths:  make(map[uint32][]string),
+		bpffsRoot: root,
+	}
+}
+
 // GetScanner returns the global scanner instance, creating it if necessary.
 func GetScanner() *Scanner {
 	scannerOnce.Do(func() {
-		globalScanner = &Scanner{
-			progPaths: make(map[uint32][]string),
-			mapPaths:  make(map[uint32][]string),
-			bpffsRoot: defaultBPFFS,
-		}
+		globalScanner = NewScannerWithRoot(DefaultRoot)
 	})
 	return globalScanner
 }
 
+// SetBPFFSRoot points the global scanner at a different bpffs mount and
+// marks it unscanned so the next lookup walks the new root.
+func SetBPFFSRoot(path string) {
+	s := GetScanner()
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.bpffsRoot = path
+	s.scanned = false
+}
+
+// Root returns the bpffs root the scanner walks.
+func (s *Scanner) Root() string {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.bpffsRoot
+}
+
 // GetProgramPinnedPaths returns all pinned paths for a program ID.
 func (s *Scanner) GetProgramPinnedPaths(id uint32) []string {
 	s.ensureScanned()
@@ -54,6 +82,16 @@ func (s *Scanner) GetMapPinnedPaths(id uint32) []string {
 	return append([]string(nil), s.mapPaths[id]...)
 }
 
+// LastScanError returns why the last scan couldn't walk the bpffs root,
+// e.g. for lack of permissions, or nil when it could. Pinned paths are
+// missing from lookups while it's set. Unreadable entries below the root
+// are skipped without an error.
+func (s *Scanner) LastScanError() error {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.scanErr
+}
+
 // Refresh forces a rescan of the BPF filesystem, updating the cache.
 func (s *Scanner) Refresh() {
 	s.mu.Lock()
@@ -63,58 +101,137 @@ func (s *Scanner) Refresh() {
 }
 
 // ensureScanned performs the scan if not already done.
+// The walk and probing run without holding s.mu; the write lock is only
+// taken to publish the results.
 func (s *Scanner) ensureScanned() {
+	s.scanMu.Lock()
+	defer s.scanMu.Unlock()
+
+	s.mu.RLock()
+	scanned, root := s.scanned, s.bpffsRoot
+	s.mu.RUnlock()
+
+	if scanned {
+		return
+	}
+
+	progPaths, mapPaths, err := scanRoot(root, runtime.NumCPU())
+
 	s.mu.Lock()
 	defer s.mu.Unlock()
 
-	if s.scanned {
+	// Discard the results if the root was changed while scanning
+	if s.bpffsRoot != root {
 		return
 	}
-
-	// Clear existing data
-	s.progPaths = make(map[uint32][]string)
-	s.mapPaths = make(map[uint32][]string)
+	s.progPaths = progPaths
+	s.mapPaths = mapPaths
+	s.scanErr = err
 	s.scanned = true
+}
+
+// probeResult records what a pinned path resolved to.
+type probeResult struct {
+	id     uint32
+	isProg bool
+	ok     bool
+}
+
+// scanRoot walks root collecting candidate files, then probes them with a
+// pool of workers. Results are merged in walk order so paths are stable.
+// It returns an error when root itself can't be read; entries below it
+// that can't be read are skipped.
+func scanRoot(root string, workers int) (map[uint32][]string, map[uint32][]string, error) {
+	progPaths := make(map[uint32][]string)
+	mapPaths := make(map[uint32][]string)
 
 	// Check if bpffs is mounted
-	if _, err := os.Stat(s.bpffsRoot); os.IsNotExist(err) {
-		return // bpffs not mounted, nothing to scan
+	if _, err := os.Stat(root); os.IsNotExist(err) {
+		return progPaths, mapPaths, nil // bpffs not mounted, nothing to scan
+	} else if err != nil {
+		return progPaths, mapPaths, err
 	}
 
-	// Walk the BPF filesystem
-	_ = filepath.Walk(s.bpffsRoot, func(path string, info os.FileInfo, err error) error {
+	// Collect candidate paths from the BPF filesystem
+	var candidates []string
+	walkErr := filepath.WalkDir(root, func(path string, d fs.DirEntry, err error) error {
 		if err != nil {
+			if path == root {
+				return err
+			}
 			return nil // Skip files we can't access
 		}
 
 		// Skip directories
-		if info.IsDir() {
+		if d.IsDir() {
 			return nil
 		}
 
-		// Try to open as a program first
-		if prog, err := ebpf.LoadPinnedProgram(path, nil); err == nil {
-			progInfo, err := prog.Info()
-			prog.Close()
-			if err == nil {
-				if id, ok := progInfo.ID(); ok {
-					s.progPaths[uint32(id)] = append(s.progPaths[uint32(id)], path)
-				}
+		candidates = append(candidates, path)
+		return nil
+	})
+
+	if workers < 1 {
+		workers = 1
+	}
+
+	// Probe candidates concurrently; each worker writes only its own slots
+	results := make([]probeResult, len(candidates))
+	indexes := make(chan int)
+	var wg sync.WaitGroup
+	for w := 0; w < workers; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range indexes {
+				results[i] = probePath(candidates[i])
+			}
+		}()
+	}
+	for i := range candidates {
+		indexes <- i
+	}
+	close(indexes)
+	wg.Wait()
+
+	for i, r := range results {
+		if !r.ok {
+			continue
+		}
+		if r.isProg {
+			progPaths[r.id] = append(progPaths[r.id], candidates[i])
+		} else {
+			mapPaths[r.id] = append(mapPaths[r.id], candidates[i])
+		}
+	}
+
+	return progPaths, mapPaths, walkErr
+}
+
+// probePath opens a pinned path as a program or map and returns its ID.
+func probePath(path string) probeResult {
+	// Try to open as a program first
+	if prog, err := ebpf.LoadPinnedProgram(path, nil); err == nil {
+		progInfo, err := prog.Info()
+		prog.Close()
+		if err == nil {
+			if id, ok := progInfo.ID(); ok {
+				return probeResult{id: uint32(id), isProg: true, ok: true}
 			}
-			return nil
 		}
+		return probeResult{}
+	}
 
-		// Try to open as a map
-		if m, err := ebpf.LoadPinnedMap(path, nil); err == nil {
-			mapInfo, err := m.Info()
-			m.Close()
-			if err == nil {
-				if id, ok := mapInfo.ID(); ok {
-					s.mapPaths[uint32(id)] = append(s.mapPaths[uint32(id)], path)
-				}
+	// Try to open as a map
+	if m, err := ebpf.LoadPinnedMap(path, nil); err == nil {
+		mapInfo, err := m.Info()
+		m.Close()
+		if err == nil {
+			if id, ok := mapInfo.ID(); ok {
+				return probeResult{id: uint32(id), ok: true}
 			}
 		}
+	}
 
-		return nil
-	})
+	return probeResult{}
 }
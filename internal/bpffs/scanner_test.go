@@ -1,6 +1,12 @@
 package bpffs
 
-import "testing"
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"runtime"
+	"testing"
+)
 
 func TestGetScanner(t *testing.T) {
 	s := GetScanner()
@@ -70,3 +76,135 @@ func TestRefresh(t *testing.T) {
 		t.Error("expected progPaths to be cleared after refresh")
 	}
 }
+
+func TestNewScannerWithRoot_SkipsNonBPFFiles(t *testing.T) {
+	root := t.TempDir()
+	if err := os.MkdirAll(filepath.Join(root, "subdir"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	for _, name := range []string{"plain.txt", filepath.Join("subdir", "fake_map")} {
+		if err := os.WriteFile(filepath.Join(root, name), []byte("not a bpf object"), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	s := NewScannerWithRoot(root)
+	if s.Root() != root {
+		t.Errorf("Root() = %q, want %q", s.Root(), root)
+	}
+
+	if paths := s.GetProgramPinnedPaths(1); len(paths) != 0 {
+		t.Errorf("expected 0 program paths, got %d", len(paths))
+	}
+	if len(s.progPaths) != 0 || len(s.mapPaths) != 0 {
+		t.Error("expected non-BPF files to be skipped")
+	}
+	if !s.scanned {
+		t.Error("expected scanner to be marked scanned")
+	}
+}
+
+func TestLastScanError(t *testing.T) {
+	t.Run("unreadable root", func(t *testing.T) {
+		if os.Geteuid() == 0 {
+			t.Skip("root can read any directory")
+		}
+		root := t.TempDir()
+		if err := os.Chmod(root, 0); err != nil {
+			t.Fatal(err)
+		}
+		t.Cleanup(func() { os.Chmod(root, 0o755) })
+
+		s := NewScannerWithRoot(root)
+		s.GetMapPinnedPaths(1)
+		if err := s.LastScanError(); !os.IsPermission(err) {
+			t.Errorf("LastScanError() = %v, want a permission error", err)
+		}
+	})
+
+	t.Run("root isn't a directory", func(t *testing.T) {
+		file := filepath.Join(t.TempDir(), "file")
+		if err := os.WriteFile(file, nil, 0o644); err != nil {
+			t.Fatal(err)
+		}
+
+		s := NewScannerWithRoot(filepath.Join(file, "bpf"))
+		s.GetMapPinnedPaths(1)
+		if s.LastScanError() == nil {
+			t.Error("LastScanError() = nil, want the stat error")
+		}
+
+		// A later scan of a readable root clears the error
+		s.mu.Lock()
+		s.bpffsRoot = t.TempDir()
+		s.mu.Unlock()
+		s.Refresh()
+		if err := s.LastScanError(); err != nil {
+			t.Errorf("LastScanError() after a clean scan = %v, want nil", err)
+		}
+	})
+
+	t.Run("unreadable entries are skipped", func(t *testing.T) {
+		root := t.TempDir()
+		sub := filepath.Join(root, "private")
+		if err := os.Mkdir(sub, 0); err != nil {
+			t.Fatal(err)
+		}
+		t.Cleanup(func() { os.Chmod(sub, 0o755) })
+
+		s := NewScannerWithRoot(root)
+		s.GetMapPinnedPaths(1)
+		if err := s.LastScanError(); err != nil {
+			t.Errorf("LastScanError() = %v, want nil", err)
+		}
+	})
+
+	t.Run("not mounted", func(t *testing.T) {
+		s := NewScannerWithRoot("/nonexistent/path")
+		s.GetMapPinnedPaths(1)
+		if err := s.LastScanError(); err != nil {
+			t.Errorf("LastScanError() = %v, want nil", err)
+		}
+	})
+}
+
+func TestSetBPFFSRoot(t *testing.T) {
+	s := GetScanner()
+	orig := s.Root()
+	t.Cleanup(func() { SetBPFFSRoot(orig) })
+
+	root := t.TempDir()
+	SetBPFFSRoot(root)
+
+	if s.Root() != root {
+		t.Errorf("Root() = %q, want %q", s.Root(), root)
+	}
+	if s.scanned {
+		t.Error("expected scanner to be marked unscanned after SetBPFFSRoot")
+	}
+}
+
+// benchmarkScanRoot scans a directory of fake pinned files with the given
+// number of workers.
+func benchmarkScanRoot(b *testing.B, workers int) {
+	root := b.TempDir()
+	for i := 0; i < 500; i++ {
+		name := filepath.Join(root, fmt.Sprintf("obj_%d", i))
+		if err := os.WriteFile(name, nil, 0o644); err != nil {
+			b.Fatal(err)
+		}
+	}
+
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		scanRoot(root, workers)
+	}
+}
+
+func BenchmarkScanRoot_Serial(b *testing.B) {
+	benchmarkScanRoot(b, 1)
+}
+
+func BenchmarkScanRoot_Concurrent(b *testing.B) {
+	benchmarkScanRoot(b, runtime.NumCPU())
+}
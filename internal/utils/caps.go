@@ -0,0 +1,52 @@
+package utils
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+)
+
+// Bits of the capabilities gobpftool needs in a capability mask.
+const (
+	// CapSysAdmin allows every BPF operation, and is all older kernels check.
+	CapSysAdmin = 21
+	// CapBPF allows loading and inspecting BPF objects since Linux 5.8.
+	CapBPF = 39
+)
+
+// ParseCapEff extracts the effective capability mask from the contents of
+// a /proc/<pid>/status file, whose CapEff line holds it in hex.
+func ParseCapEff(r io.Reader) (uint64, error) {
+	values, err := ParseFdInfo(r, "CapEff")
+	if err != nil {
+		return 0, err
+	}
+
+	value, ok := values["CapEff"]
+	if !ok {
+		return 0, fmt.Errorf("field CapEff not found in status")
+	}
+	mask, err := strconv.ParseUint(value, 16, 64)
+	if err != nil {
+		return 0, fmt.Errorf("invalid CapEff %q: %w", value, err)
+	}
+	return mask, nil
+}
+
+// ReadCapEff reads the effective capability mask of the current process
+// from /proc/self/status.
+func ReadCapEff() (uint64, error) {
+	f, err := os.Open("/proc/self/status")
+	if err != nil {
+		return 0, err
+	}
+	defer f.Close()
+
+	return ParseCapEff(f)
+}
+
+// HasCap reports whether capability bit c is set in mask.
+func HasCap(mask uint64, c uint) bool {
+	return c < 64 && mask&(1<<c) != 0
+}
@@ -0,0 +1,54 @@
+package utils
+
+import (
+	"strings"
+	"testing"
+)
+
+// sampleStatus is an excerpt of /proc/self/status of a process with
+// CAP_SYS_ADMIN but not CAP_BPF.
+const sampleStatus = `Name:	cat
+Umask:	0022
+State:	R (running)
+Uid:	0	0	0	0
+CapInh:	0000000000000000
+CapPrm:	0000000000200000
+CapEff:	0000000000200000
+CapBnd:	000001ffffffffff
+CapAmb:	0000000000000000
+NoNewPrivs:	0
+`
+
+func TestParseCapEff(t *testing.T) {
+	mask, err := ParseCapEff(strings.NewReader(sampleStatus))
+	if err != nil {
+		t.Fatalf("ParseCapEff() error = %v", err)
+	}
+	if mask != 1<<CapSysAdmin {
+		t.Errorf("ParseCapEff() = %#x, want %#x", mask, uint64(1<<CapSysAdmin))
+	}
+	if !HasCap(mask, CapSysAdmin) || HasCap(mask, CapBPF) {
+		t.Errorf("HasCap(%#x) = sys_admin %v, bpf %v, want true, false", mask, HasCap(mask, CapSysAdmin), HasCap(mask, CapBPF))
+	}
+
+	// A full root mask has both
+	full, err := ParseCapEff(strings.NewReader("CapEff:\t000001ffffffffff\n"))
+	if err != nil || !HasCap(full, CapBPF) || !HasCap(full, CapSysAdmin) {
+		t.Errorf("ParseCapEff() of a root mask = %#x, %v, want CAP_BPF and CAP_SYS_ADMIN", full, err)
+	}
+
+	for _, status := range []string{"Name:\tcat\n", "CapEff:\tnothex\n"} {
+		if _, err := ParseCapEff(strings.NewReader(status)); err == nil {
+			t.Errorf("ParseCapEff(%q) error = nil, want an error", status)
+		}
+	}
+}
+
+func TestHasCap(t *testing.T) {
+	if HasCap(0, CapBPF) {
+		t.Error("HasCap(0) = true, want false")
+	}
+	if HasCap(^uint64(0), 64) {
+		t.Error("HasCap() of a bit past the mask = true, want false")
+	}
+}
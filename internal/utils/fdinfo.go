@@ -0,0 +1,60 @@
+package utils
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+)
+
+// ParseFdInfo extracts the named fields from the "key:\tvalue" lines of an
+// fdinfo file. Fields that aren't present are left out of the result.
+func ParseFdInfo(r io.Reader, fields ...string) (map[string]string, error) {
+	wanted := make(map[string]bool, len(fields))
+	for _, field := range fields {
+		wanted[field] = true
+	}
+
+	values := make(map[string]string, len(fields))
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		key, value, ok := strings.Cut(scanner.Text(), ":")
+		if !ok || !wanted[key] {
+			continue
+		}
+		values[key] = strings.TrimSpace(value)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+
+	return values, nil
+}
+
+// ReadFdInfo reads the named fields from /proc/self/fdinfo/<fd>.
+func ReadFdInfo(fd int, fields ...string) (map[string]string, error) {
+	f, err := os.Open(fmt.Sprintf("/proc/self/fdinfo/%d", fd))
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	return ParseFdInfo(f, fields...)
+}
+
+// ReadFdInfoUint reads a numeric field (e.g. "memlock") from
+// /proc/self/fdinfo/<fd>.
+func ReadFdInfoUint(fd int, field string) (uint64, error) {
+	values, err := ReadFdInfo(fd, field)
+	if err != nil {
+		return 0, err
+	}
+
+	value, ok := values[field]
+	if !ok {
+		return 0, fmt.Errorf("field %s not found in fdinfo", field)
+	}
+	return strconv.ParseUint(value, 10, 64)
+}
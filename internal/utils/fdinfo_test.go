@@ -0,0 +1,57 @@
+package utils
+
+import (
+	"os"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// sampleMapFdInfo is /proc/self/fdinfo/<fd> of a hash map fd.
+const sampleMapFdInfo = `pos:	0
+flags:	02000002
+mnt_id:	15
+ino:	1057
+map_type:	1
+key_size:	4
+value_size:	8
+max_entries:	1024
+map_flags:	0x0
+map_extra:	0x0
+memlock:	86368
+map_id:	42
+frozen:	0
+`
+
+func TestParseFdInfo(t *testing.T) {
+	got, err := ParseFdInfo(strings.NewReader(sampleMapFdInfo), "memlock", "map_id", "btf_id")
+	if err != nil {
+		t.Fatalf("ParseFdInfo() error = %v", err)
+	}
+
+	// btf_id isn't in the sample and is left out
+	want := map[string]string{"memlock": "86368", "map_id": "42"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("ParseFdInfo() = %v, want %v", got, want)
+	}
+}
+
+func TestReadFdInfoUint(t *testing.T) {
+	f, err := os.Open(os.DevNull)
+	if err != nil {
+		t.Fatalf("failed to open %s: %v", os.DevNull, err)
+	}
+	defer f.Close()
+
+	pos, err := ReadFdInfoUint(int(f.Fd()), "pos")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if pos != 0 {
+		t.Errorf("expected pos 0, got %d", pos)
+	}
+
+	if _, err := ReadFdInfoUint(int(f.Fd()), "memlock"); err == nil {
+		t.Error("expected error for missing field, got nil")
+	}
+}
@@ -54,6 +54,26 @@ func FormatHexBytes(data []byte) string {
 	return strings.Join(parts, " ")
 }
 
+// FormatHexDump formats a byte slice as rows of rowSize space-separated hex
+// bytes, each prefixed with its offset like a hexdump.
+// Output format: "00000000: 0a 0b 0c 0d\n00000004: 0e 0f"
+func FormatHexDump(data []byte, rowSize int) string {
+	if rowSize <= 0 {
+		rowSize = 16
+	}
+
+	var rows []string
+	for off := 0; off < len(data); off += rowSize {
+		end := off + rowSize
+		if end > len(data) {
+			end = len(data)
+		}
+		rows = append(rows, fmt.Sprintf("%08x: %s", off, FormatHexBytes(data[off:end])))
+	}
+
+	return strings.Join(rows, "\n")
+}
+
 // FormatHexBytesWithPrefix formats a byte slice as space-separated hex bytes with a prefix.
 // Output format: "key: 0a 0b 0c 0d" or "value: 01 02 03 04"
 func FormatHexBytesWithPrefix(prefix string, data []byte) string {
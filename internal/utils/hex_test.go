@@ -169,6 +169,54 @@ func TestFormatHexBytes(t *testing.T) {
 	}
 }
 
+func TestFormatHexDump(t *testing.T) {
+	data := make([]byte, 20)
+	for i := range data {
+		data[i] = byte(i)
+	}
+
+	tests := []struct {
+		name     string
+		input    []byte
+		rowSize  int
+		expected string
+	}{
+		{
+			name:     "empty slice",
+			input:    nil,
+			rowSize:  16,
+			expected: "",
+		},
+		{
+			name:     "partial last row",
+			input:    data,
+			rowSize:  16,
+			expected: "00000000: 00 01 02 03 04 05 06 07 08 09 0a 0b 0c 0d 0e 0f\n00000010: 10 11 12 13",
+		},
+		{
+			name:     "exact rows",
+			input:    data[:8],
+			rowSize:  4,
+			expected: "00000000: 00 01 02 03\n00000004: 04 05 06 07",
+		},
+		{
+			name:     "default row size",
+			input:    data[:2],
+			rowSize:  0,
+			expected: "00000000: 00 01",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result := FormatHexDump(tt.input, tt.rowSize)
+			if result != tt.expected {
+				t.Errorf("FormatHexDump() = %q, expected %q", result, tt.expected)
+			}
+		})
+	}
+}
+
 func TestFormatHexBytesWithPrefix(t *testing.T) {
 	tests := []struct {
 		name     string
@@ -0,0 +1,63 @@
+package utils
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+)
+
+// IDRange is an inclusive range of program or map IDs.
+type IDRange struct {
+	Min uint32
+	Max uint32
+}
+
+// ParseIDRange parses an ID range of the form "LO-HI". Either end may be
+// left out for an open range, e.g. "100-" or "-50", and a single ID
+// selects just that ID.
+func ParseIDRange(s string) (IDRange, error) {
+	invalid := fmt.Errorf("invalid ID range '%s': use LO-HI, LO-, -HI or a single ID", s)
+
+	str := strings.TrimSpace(s)
+	lo, hi, isRange := strings.Cut(str, "-")
+	if !isRange {
+		id, err := parseRangeID(str)
+		if err != nil {
+			return IDRange{}, invalid
+		}
+		return IDRange{Min: id, Max: id}, nil
+	}
+
+	r := IDRange{Min: 0, Max: math.MaxUint32}
+	lo, hi = strings.TrimSpace(lo), strings.TrimSpace(hi)
+	if lo == "" && hi == "" {
+		return IDRange{}, invalid
+	}
+	var err error
+	if lo != "" {
+		if r.Min, err = parseRangeID(lo); err != nil {
+			return IDRange{}, invalid
+		}
+	}
+	if hi != "" {
+		if r.Max, err = parseRangeID(hi); err != nil {
+			return IDRange{}, invalid
+		}
+	}
+	if r.Min > r.Max {
+		return IDRange{}, fmt.Errorf("invalid ID range '%s': %d is greater than %d", s, r.Min, r.Max)
+	}
+	return r, nil
+}
+
+// parseRangeID parses one end of an ID range.
+func parseRangeID(s string) (uint32, error) {
+	id, err := strconv.ParseUint(s, 10, 32)
+	return uint32(id), err
+}
+
+// Contains reports whether id lies in the range.
+func (r IDRange) Contains(id uint32) bool {
+	return id >= r.Min && id <= r.Max
+}
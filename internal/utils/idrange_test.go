@@ -0,0 +1,51 @@
+package utils
+
+import (
+	"math"
+	"testing"
+)
+
+func TestParseIDRange(t *testing.T) {
+	tests := []struct {
+		input   string
+		want    IDRange
+		wantErr bool
+	}{
+		{input: "10-20", want: IDRange{Min: 10, Max: 20}},
+		{input: " 10 - 20 ", want: IDRange{Min: 10, Max: 20}},
+		{input: "100-", want: IDRange{Min: 100, Max: math.MaxUint32}},
+		{input: "-50", want: IDRange{Min: 0, Max: 50}},
+		{input: "42", want: IDRange{Min: 42, Max: 42}},
+		{input: "7-7", want: IDRange{Min: 7, Max: 7}},
+		{input: "", wantErr: true},
+		{input: "-", wantErr: true},
+		{input: "20-10", wantErr: true},
+		{input: "a-b", wantErr: true},
+		{input: "1-2-3", wantErr: true},
+		{input: "--5", wantErr: true},
+		{input: "1.5", wantErr: true},
+		{input: "4294967296", wantErr: true},
+		{input: "0x10-", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.input, func(t *testing.T) {
+			got, err := ParseIDRange(tt.input)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("ParseIDRange(%q) error = %v, wantErr %v", tt.input, err, tt.wantErr)
+			}
+			if got != tt.want {
+				t.Errorf("ParseIDRange(%q) = %+v, want %+v", tt.input, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestIDRange_Contains(t *testing.T) {
+	r := IDRange{Min: 10, Max: 20}
+	for id, want := range map[uint32]bool{9: false, 10: true, 15: true, 20: true, 21: false} {
+		if got := r.Contains(id); got != want {
+			t.Errorf("%+v.Contains(%d) = %v, want %v", r, id, got, want)
+		}
+	}
+}
@@ -0,0 +1,88 @@
+package utils
+
+import (
+	"encoding/binary"
+	"fmt"
+)
+
+// IntKinds lists the integer interpretations accepted by DecodeInt.
+// u32 and u64 use the host byte order, like the kernel stores map values.
+var IntKinds = []string{"u32", "u32le", "u32be", "u64", "u64le", "u64be"}
+
+// IntKindSize returns the number of bytes an integer of the given kind occupies.
+func IntKindSize(kind string) (int, error) {
+	switch kind {
+	case "u32", "u32le", "u32be":
+		return 4, nil
+	case "u64", "u64le", "u64be":
+		return 8, nil
+	default:
+		return 0, fmt.Errorf("invalid integer kind '%s': must be one of u32, u32le, u32be, u64, u64le, u64be", kind)
+	}
+}
+
+// DecodeInt interprets data as an unsigned integer of the given kind.
+// Returns an error if the kind is unknown or data isn't exactly its size.
+func DecodeInt(data []byte, kind string) (uint64, error) {
+	size, err := IntKindSize(kind)
+	if err != nil {
+		return 0, err
+	}
+	if len(data) != size {
+		return 0, fmt.Errorf("cannot decode %d bytes as %s: expected %d bytes", len(data), kind, size)
+	}
+
+	var order binary.ByteOrder = binary.NativeEndian
+	switch kind {
+	case "u32le", "u64le":
+		order = binary.LittleEndian
+	case "u32be", "u64be":
+		order = binary.BigEndian
+	}
+
+	if size == 4 {
+		return uint64(order.Uint32(data)), nil
+	}
+	return order.Uint64(data), nil
+}
+
+// ParseByteOrder parses a byte order name: "native" (the host order, used
+// by the kernel for map keys and values), "le" or "be".
+func ParseByteOrder(name string) (binary.ByteOrder, error) {
+	switch name {
+	case "native":
+		return binary.NativeEndian, nil
+	case "le":
+		return binary.LittleEndian, nil
+	case "be":
+		return binary.BigEndian, nil
+	default:
+		return nil, fmt.Errorf("invalid byte order '%s': must be one of native, le, be", name)
+	}
+}
+
+// EncodeUint encodes n as an unsigned integer of size bytes in the given
+// byte order. Returns an error if size isn't 1, 2, 4 or 8 or n doesn't fit.
+func EncodeUint(n uint64, size int, order binary.ByteOrder) ([]byte, error) {
+	switch size {
+	case 1, 2, 4, 8:
+	default:
+		return nil, fmt.Errorf("cannot encode an integer in %d bytes: size must be 1, 2, 4 or 8", size)
+	}
+	if size < 8 && n >= 1<<(8*size) {
+		return nil, fmt.Errorf("%d doesn't fit in %d bytes", n, size)
+	}
+
+	data := make([]byte, size)
+	switch size {
+	case 1:
+		data[0] = byte(n)
+	case 2:
+		order.PutUint16(data, uint16(n))
+	case 4:
+		order.PutUint32(data, uint32(n))
+	case 8:
+		order.PutUint64(data, n)
+	}
+	return data, nil
+}
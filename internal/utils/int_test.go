@@ -0,0 +1,94 @@
+package utils
+
+import (
+	"encoding/binary"
+	"reflect"
+	"testing"
+)
+
+func TestDecodeInt(t *testing.T) {
+	native4 := binary.NativeEndian.AppendUint32(nil, 0x01020304)
+	native8 := binary.NativeEndian.AppendUint64(nil, 0x0102030405060708)
+
+	tests := []struct {
+		name     string
+		data     []byte
+		kind     string
+		expected uint64
+		wantErr  bool
+	}{
+		{name: "u32 native", data: native4, kind: "u32", expected: 0x01020304},
+		{name: "u32 little endian", data: []byte{0x04, 0x03, 0x02, 0x01}, kind: "u32le", expected: 0x01020304},
+		{name: "u32 big endian", data: []byte{0x01, 0x02, 0x03, 0x04}, kind: "u32be", expected: 0x01020304},
+		{name: "u64 native", data: native8, kind: "u64", expected: 0x0102030405060708},
+		{name: "u64 little endian", data: []byte{0x08, 0x07, 0x06, 0x05, 0x04, 0x03, 0x02, 0x01}, kind: "u64le", expected: 0x0102030405060708},
+		{name: "u64 big endian", data: []byte{0x01, 0x02, 0x03, 0x04, 0x05, 0x06, 0x07, 0x08}, kind: "u64be", expected: 0x0102030405060708},
+		{name: "u32 too short", data: []byte{0x01, 0x02}, kind: "u32le", wantErr: true},
+		{name: "u32 too long", data: native8, kind: "u32", wantErr: true},
+		{name: "u64 too short", data: native4, kind: "u64be", wantErr: true},
+		{name: "unknown kind", data: native4, kind: "i32", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result, err := DecodeInt(tt.data, tt.kind)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("DecodeInt() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+			if !tt.wantErr && result != tt.expected {
+				t.Errorf("DecodeInt() = %#x, want %#x", result, tt.expected)
+			}
+		})
+	}
+}
+
+func TestEncodeUint(t *testing.T) {
+	tests := []struct {
+		name     string
+		n        uint64
+		size     int
+		order    binary.ByteOrder
+		expected []byte
+		wantErr  bool
+	}{
+		{name: "4 bytes little endian", n: 258, size: 4, order: binary.LittleEndian, expected: []byte{0x02, 0x01, 0x00, 0x00}},
+		{name: "4 bytes big endian", n: 258, size: 4, order: binary.BigEndian, expected: []byte{0x00, 0x00, 0x01, 0x02}},
+		{name: "4 bytes max", n: 0xffffffff, size: 4, order: binary.LittleEndian, expected: []byte{0xff, 0xff, 0xff, 0xff}},
+		{name: "8 bytes little endian", n: 0x0102030405060708, size: 8, order: binary.LittleEndian, expected: []byte{0x08, 0x07, 0x06, 0x05, 0x04, 0x03, 0x02, 0x01}},
+		{name: "8 bytes big endian", n: 1, size: 8, order: binary.BigEndian, expected: []byte{0, 0, 0, 0, 0, 0, 0, 0x01}},
+		{name: "1 byte", n: 0xff, size: 1, order: binary.BigEndian, expected: []byte{0xff}},
+		{name: "4 bytes overflow", n: 1 << 32, size: 4, order: binary.LittleEndian, wantErr: true},
+		{name: "2 bytes overflow", n: 0x10000, size: 2, order: binary.LittleEndian, wantErr: true},
+		{name: "unsupported size", n: 1, size: 3, order: binary.LittleEndian, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result, err := EncodeUint(tt.n, tt.size, tt.order)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("EncodeUint() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+			if !tt.wantErr && !reflect.DeepEqual(result, tt.expected) {
+				t.Errorf("EncodeUint() = %v, want %v", result, tt.expected)
+			}
+		})
+	}
+}
+
+func TestParseByteOrder(t *testing.T) {
+	for name, want := range map[string]binary.ByteOrder{
+		"native": binary.NativeEndian,
+		"le":     binary.LittleEndian,
+		"be":     binary.BigEndian,
+	} {
+		got, err := ParseByteOrder(name)
+		if err != nil || got != want {
+			t.Errorf("ParseByteOrder(%q) = %v, %v, want %v", name, got, err, want)
+		}
+	}
+	if _, err := ParseByteOrder("middle"); err == nil {
+		t.Error("expected error for unknown byte order, got nil")
+	}
+}
@@ -0,0 +1,17 @@
+package utils
+
+import "net/netip"
+
+// BytesToIP renders a 4-byte key as a dotted-quad IPv4 address and a
+// 16-byte key as an IPv6 address, both in network byte order. Other
+// lengths return false.
+func BytesToIP(b []byte) (string, bool) {
+	switch len(b) {
+	case 4:
+		return netip.AddrFrom4([4]byte(b)).String(), true
+	case 16:
+		return netip.AddrFrom16([16]byte(b)).String(), true
+	default:
+		return "", false
+	}
+}
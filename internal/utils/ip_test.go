@@ -0,0 +1,32 @@
+package utils
+
+import "testing"
+
+func TestBytesToIP(t *testing.T) {
+	tests := []struct {
+		name     string
+		data     []byte
+		expected string
+		ok       bool
+	}{
+		{name: "IPv4", data: []byte{10, 0, 0, 1}, expected: "10.0.0.1", ok: true},
+		{name: "IPv4 zero", data: []byte{0, 0, 0, 0}, expected: "0.0.0.0", ok: true},
+		{name: "IPv4 broadcast", data: []byte{255, 255, 255, 255}, expected: "255.255.255.255", ok: true},
+		{name: "IPv6", data: []byte{0x20, 0x01, 0x0d, 0xb8, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 1}, expected: "2001:db8::1", ok: true},
+		{name: "IPv6 loopback", data: []byte{0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 1}, expected: "::1", ok: true},
+		{name: "IPv4-mapped IPv6", data: []byte{0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0xff, 0xff, 192, 168, 0, 1}, expected: "::ffff:192.168.0.1", ok: true},
+		{name: "empty", data: nil},
+		{name: "too short", data: []byte{10, 0, 0}},
+		{name: "8 bytes", data: []byte{10, 0, 0, 1, 0, 0, 0, 0}},
+		{name: "too long", data: make([]byte, 17)},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result, ok := BytesToIP(tt.data)
+			if ok != tt.ok || result != tt.expected {
+				t.Errorf("BytesToIP(% x) = %q, %v, want %q, %v", tt.data, result, ok, tt.expected, tt.ok)
+			}
+		})
+	}
+}
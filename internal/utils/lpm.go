@@ -0,0 +1,29 @@
+package utils
+
+import (
+	"encoding/binary"
+	"fmt"
+	"net/netip"
+	"strings"
+)
+
+// lpmPrefixLenSize is the size of the prefix length that starts every LPM
+// trie key (struct bpf_lpm_trie_key).
+const lpmPrefixLenSize = 4
+
+// EncodeLPMKey encodes an IPv4 or IPv6 CIDR such as "10.0.0.0/8" or
+// "2001:db8::/32" as an LPM trie key: the prefix length as a host-endian
+// 32-bit integer followed by the address in network byte order. Host bits
+// are kept, so "10.1.2.3/32" looks up the longest prefix covering that
+// address. IPv4-mapped IPv6 addresses stay 16 bytes long.
+func EncodeLPMKey(cidr string) ([]byte, error) {
+	prefix, err := netip.ParsePrefix(strings.TrimSpace(cidr))
+	if err != nil {
+		return nil, fmt.Errorf("invalid LPM prefix '%s': use ADDRESS/LENGTH, e.g. 10.0.0.0/8 or 2001:db8::/32", cidr)
+	}
+
+	addr := prefix.Addr().AsSlice()
+	key := make([]byte, 0, lpmPrefixLenSize+len(addr))
+	key = binary.NativeEndian.AppendUint32(key, uint32(prefix.Bits()))
+	return append(key, addr...), nil
+}
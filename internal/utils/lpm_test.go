@@ -0,0 +1,48 @@
+package utils
+
+import (
+	"bytes"
+	"encoding/binary"
+	"testing"
+)
+
+func TestEncodeLPMKey(t *testing.T) {
+	lpmKey := func(bits uint32, addr ...byte) []byte {
+		return append(binary.NativeEndian.AppendUint32(nil, bits), addr...)
+	}
+
+	tests := []struct {
+		cidr     string
+		expected []byte
+		wantErr  bool
+	}{
+		{cidr: "10.0.0.0/8", expected: lpmKey(8, 10, 0, 0, 0)},
+		{cidr: "192.168.1.7/32", expected: lpmKey(32, 192, 168, 1, 7)},
+		{cidr: " 172.16.0.0/12 ", expected: lpmKey(12, 172, 16, 0, 0)},
+		{cidr: "0.0.0.0/0", expected: lpmKey(0, 0, 0, 0, 0)},
+		{cidr: "10.1.2.3/16", expected: lpmKey(16, 10, 1, 2, 3)},
+		{cidr: "2001:db8::/32", expected: lpmKey(32, 0x20, 0x01, 0x0d, 0xb8, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0)},
+		{cidr: "::1/128", expected: lpmKey(128, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 1)},
+		{cidr: "::ffff:10.0.0.1/128", expected: lpmKey(128, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0xff, 0xff, 10, 0, 0, 1)},
+		{cidr: "", wantErr: true},
+		{cidr: "10.0.0.0", wantErr: true},
+		{cidr: "10.0.0.0/33", wantErr: true},
+		{cidr: "2001:db8::/129", wantErr: true},
+		{cidr: "10.0.0/8", wantErr: true},
+		{cidr: "10.0.0.0/-1", wantErr: true},
+		{cidr: "fe80::1%eth0/64", wantErr: true},
+		{cidr: "example.com/24", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.cidr, func(t *testing.T) {
+			result, err := EncodeLPMKey(tt.cidr)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("EncodeLPMKey(%q) error = %v, wantErr %v", tt.cidr, err, tt.wantErr)
+			}
+			if !bytes.Equal(result, tt.expected) {
+				t.Errorf("EncodeLPMKey(%q) = % x, want % x", tt.cidr, result, tt.expected)
+			}
+		})
+	}
+}
@@ -0,0 +1,35 @@
+package utils
+
+import (
+	"fmt"
+	"unsafe"
+
+	"golang.org/x/sys/unix"
+)
+
+// objGetInfoAttr mirrors the info member of union bpf_attr used by
+// BPF_OBJ_GET_INFO_BY_FD.
+type objGetInfoAttr struct {
+	BpfFd   uint32
+	InfoLen uint32
+	Info    uint64
+}
+
+// ObjGetInfo queries BPF_OBJ_GET_INFO_BY_FD for the object fd, filling
+// info, which must mirror the kernel's info struct for the object type
+// (e.g. struct bpf_map_info). cilium/ebpf does not expose every field, so
+// this reads them directly from the kernel.
+func ObjGetInfo[T any](fd int, info *T) error {
+	attr := objGetInfoAttr{
+		BpfFd:   uint32(fd),
+		InfoLen: uint32(unsafe.Sizeof(*info)),
+		Info:    uint64(uintptr(unsafe.Pointer(info))),
+	}
+
+	_, _, errno := unix.Syscall(unix.SYS_BPF, unix.BPF_OBJ_GET_INFO_BY_FD,
+		uintptr(unsafe.Pointer(&attr)), unsafe.Sizeof(attr))
+	if errno != 0 {
+		return fmt.Errorf("BPF_OBJ_GET_INFO_BY_FD: %w", errno)
+	}
+	return nil
+}
@@ -0,0 +1,14 @@
+package utils
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestObjGetInfo_BadFD(t *testing.T) {
+	var info struct{ ID uint32 }
+	err := ObjGetInfo(-1, &info)
+	if err == nil || !strings.HasPrefix(err.Error(), "BPF_OBJ_GET_INFO_BY_FD: ") {
+		t.Errorf("ObjGetInfo(-1) error = %v, want a BPF_OBJ_GET_INFO_BY_FD error", err)
+	}
+}
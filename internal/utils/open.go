@@ -0,0 +1,29 @@
+package utils
+
+import (
+	"errors"
+	"os"
+	"time"
+)
+
+const (
+	// OpenRetries is how many more times a program or map is opened by ID
+	// after it wasn't found, to tell one being replaced from one that's
+	// gone.
+	OpenRetries = 2
+	// OpenRetryDelay is the pause before each retry.
+	OpenRetryDelay = 5 * time.Millisecond
+)
+
+// OpenByID opens the object with the given ID using open, retrying briefly
+// when the kernel reports it doesn't exist. An ID that was just enumerated
+// can fail to open while programs and maps are created and destroyed
+// rapidly.
+func OpenByID[ID, T any](open func(ID) (T, error), id ID) (T, error) {
+	obj, err := open(id)
+	for i := 0; i < OpenRetries && errors.Is(err, os.ErrNotExist); i++ {
+		time.Sleep(OpenRetryDelay)
+		obj, err = open(id)
+	}
+	return obj, err
+}
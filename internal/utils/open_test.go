@@ -0,0 +1,43 @@
+package utils
+
+import (
+	"errors"
+	"os"
+	"testing"
+)
+
+func TestOpenByID(t *testing.T) {
+	errDenied := errors.New("denied")
+
+	tests := []struct {
+		name      string
+		errs      []error
+		wantCalls int
+		wantErr   error
+	}{
+		{name: "opens", wantCalls: 1},
+		{name: "fails once", errs: []error{os.ErrNotExist}, wantCalls: 2},
+		{name: "always fails", errs: []error{os.ErrNotExist, os.ErrNotExist, os.ErrNotExist, os.ErrNotExist},
+			wantCalls: OpenRetries + 1, wantErr: os.ErrNotExist},
+		{name: "other errors aren't retried", errs: []error{errDenied}, wantCalls: 1, wantErr: errDenied},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			calls := 0
+			got, err := OpenByID(func(id uint32) (uint32, error) {
+				calls++
+				if calls <= len(tt.errs) {
+					return 0, tt.errs[calls-1]
+				}
+				return id, nil
+			}, 7)
+			if !errors.Is(err, tt.wantErr) || (tt.wantErr == nil && got != 7) {
+				t.Errorf("OpenByID() = %d, %v, want 7, %v", got, err, tt.wantErr)
+			}
+			if calls != tt.wantCalls {
+				t.Errorf("opened %d times, want %d", calls, tt.wantCalls)
+			}
+		})
+	}
+}
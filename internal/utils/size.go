@@ -0,0 +1,43 @@
+package utils
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+)
+
+// sizeUnits maps the suffixes accepted by ParseSize to their multipliers.
+var sizeUnits = map[string]uint64{
+	"":  1,
+	"K": 1 << 10,
+	"M": 1 << 20,
+	"G": 1 << 30,
+}
+
+// ParseSize parses a size in bytes with an optional K, M or G suffix using
+// base 1024, e.g. "4096", "512K" or "10M". Suffixes are case-insensitive and
+// may be followed by "B" or "iB", so "10MB", "10MiB" and "4096B" work too.
+func ParseSize(s string) (uint64, error) {
+	str := strings.ToUpper(strings.TrimSpace(s))
+	str = strings.TrimSuffix(str, "IB")
+	str = strings.TrimSuffix(str, "B")
+
+	unit := ""
+	if n := len(str); n > 0 {
+		if _, ok := sizeUnits[str[n-1:]]; ok {
+			unit, str = str[n-1:], str[:n-1]
+		}
+	}
+
+	n, err := strconv.ParseUint(str, 10, 64)
+	if err != nil {
+		return 0, fmt.Errorf("invalid size '%s': use bytes or a K, M or G suffix, e.g. 10M", s)
+	}
+
+	mult := sizeUnits[unit]
+	if n > math.MaxUint64/mult {
+		return 0, fmt.Errorf("invalid size '%s': too large", s)
+	}
+	return n * mult, nil
+}
@@ -0,0 +1,41 @@
+package utils
+
+import "testing"
+
+func TestParseSize(t *testing.T) {
+	tests := []struct {
+		input   string
+		want    uint64
+		wantErr bool
+	}{
+		{input: "0", want: 0},
+		{input: "4096", want: 4096},
+		{input: "4096B", want: 4096},
+		{input: "512K", want: 512 << 10},
+		{input: "10M", want: 10485760},
+		{input: "10m", want: 10485760},
+		{input: "10MB", want: 10485760},
+		{input: "10MiB", want: 10485760},
+		{input: "2G", want: 2 << 30},
+		{input: " 1k ", want: 1024},
+		{input: "", wantErr: true},
+		{input: "M", wantErr: true},
+		{input: "-1M", wantErr: true},
+		{input: "1.5M", wantErr: true},
+		{input: "10T", wantErr: true},
+		{input: "ten", wantErr: true},
+		{input: "17179869184G", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.input, func(t *testing.T) {
+			got, err := ParseSize(tt.input)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("ParseSize(%q) error = %v, wantErr %v", tt.input, err, tt.wantErr)
+			}
+			if got != tt.want {
+				t.Errorf("ParseSize(%q) = %d, want %d", tt.input, got, tt.want)
+			}
+		})
+	}
+}
@@ -4,10 +4,9 @@ import (
 	"os"
 
 	"github.com/viveksb007/gobpftool/cmd"
+	bpferrors "github.com/viveksb007/gobpftool/pkg/errors"
 )
 
 func main() {
-	if err := cmd.Execute(); err != nil {
-		os.Exit(1)
-	}
+	os.Exit(bpferrors.ExitCode(cmd.Execute()))
 }
@@ -0,0 +1,217 @@
+package btf
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/cilium/ebpf/btf"
+)
+
+// CDecl renders a BTF type as a C declaration, like a header would declare
+// it. Only types that declare a name are rendered: named structs, unions
+// and enums, typedefs, forward declarations, functions and variables.
+// Base types such as int have no declaration and report false.
+//
+// The rendering is best-effort: it's meant for reading, not compiling.
+func CDecl(typ btf.Type) (string, bool) {
+	switch t := typ.(type) {
+	case *btf.Struct:
+		if t.Name == "" {
+			return "", false
+		}
+		return "struct " + t.Name + " " + memberBlock(t.Members, 0) + ";", true
+
+	case *btf.Union:
+		if t.Name == "" {
+			return "", false
+		}
+		return "union " + t.Name + " " + memberBlock(t.Members, 0) + ";", true
+
+	case *btf.Enum:
+		if t.Name == "" {
+			return "", false
+		}
+		return "enum " + t.Name + " " + enumBlock(t, 0) + ";", true
+
+	case *btf.Typedef:
+		return "typedef " + declarator(t.Type, t.Name, 0) + ";", true
+
+	case *btf.Fwd:
+		return t.Kind.String() + " " + t.Name + ";", true
+
+	case *btf.Func:
+		prefix := ""
+		if t.Linkage == btf.StaticFunc {
+			prefix = "static "
+		}
+		return prefix + declarator(t.Type, t.Name, 0) + ";", true
+
+	case *btf.Var:
+		prefix := ""
+		switch t.Linkage {
+		case btf.StaticVar:
+			prefix = "static "
+		case btf.ExternVar:
+			prefix = "extern "
+		}
+		return prefix + declarator(t.Type, t.Name, 0) + ";", true
+	}
+
+	return "", false
+}
+
+// kindName returns the BTF kind of a type as it's spelled in C, or the
+// BTF kind name for kinds C has no keyword for.
+func kindName(typ btf.Type) string {
+	switch t := typ.(type) {
+	case *btf.Struct:
+		return "struct"
+	case *btf.Union:
+		return "union"
+	case *btf.Enum:
+		return "enum"
+	case *btf.Typedef:
+		return "typedef"
+	case *btf.Fwd:
+		return "fwd"
+	case *btf.Func:
+		return "func"
+	case *btf.Var:
+		return "var"
+	default:
+		return strings.TrimPrefix(fmt.Sprintf("%T", t), "*btf.")
+	}
+}
+
+// declarator returns the C declaration of name with type typ, e.g.
+// "char *name[4]" or "int (*name)(void *)". An empty name renders the
+// abstract type, as used in parameter lists.
+func declarator(typ btf.Type, name string, indent int) string {
+	switch t := typ.(type) {
+	case *btf.Pointer:
+		if proto, ok := t.Target.(*btf.FuncProto); ok {
+			return declarator(proto.Return, "(*"+name+")("+params(proto)+")", indent)
+		}
+		return declarator(t.Target, "*"+name, indent)
+
+	case *btf.Array:
+		if strings.HasPrefix(name, "*") {
+			name = "(" + name + ")"
+		}
+		return declarator(t.Type, fmt.Sprintf("%s[%d]", name, t.Nelems), indent)
+
+	case *btf.FuncProto:
+		return declarator(t.Return, name+"("+params(t)+")", indent)
+
+	case *btf.Const:
+		return "const " + declarator(t.Type, name, indent)
+
+	case *btf.Volatile:
+		return "volatile " + declarator(t.Type, name, indent)
+
+	case *btf.Restrict:
+		return declarator(t.Type, name, indent)
+
+	case *btf.TypeTag:
+		return declarator(t.Type, name, indent)
+
+	case *btf.Func:
+		return declarator(t.Type, name, indent)
+	}
+
+	base := typeName(typ, indent)
+	if name == "" {
+		return base
+	}
+	if strings.HasSuffix(base, "*") {
+		return base + name
+	}
+	return base + " " + name
+}
+
+// typeName returns how a type is referred to in a declaration. Anonymous
+// structs, unions and enums are written out in full.
+func typeName(typ btf.Type, indent int) string {
+	switch t := typ.(type) {
+	case nil, *btf.Void:
+		return "void"
+	case *btf.Struct:
+		if t.Name == "" {
+			return "struct " + memberBlock(t.Members, indent)
+		}
+		return "struct " + t.Name
+	case *btf.Union:
+		if t.Name == "" {
+			return "union " + memberBlock(t.Members, indent)
+		}
+		return "union " + t.Name
+	case *btf.Enum:
+		if t.Name == "" {
+			return "enum " + enumBlock(t, indent)
+		}
+		return "enum " + t.Name
+	case *btf.Fwd:
+		return t.Kind.String() + " " + t.Name
+	}
+
+	if name := typ.TypeName(); name != "" {
+		return name
+	}
+	return kindName(typ)
+}
+
+// params renders the parameter list of a function prototype.
+func params(proto *btf.FuncProto) string {
+	if len(proto.Params) == 0 {
+		return "void"
+	}
+
+	parts := make([]string, len(proto.Params))
+	for i, p := range proto.Params {
+		if _, ok := p.Type.(*btf.Void); ok || p.Type == nil {
+			// A trailing void parameter marks a variadic function
+			parts[i] = "..."
+			continue
+		}
+		parts[i] = declarator(p.Type, p.Name, 0)
+	}
+	return strings.Join(parts, ", ")
+}
+
+// memberBlock renders the members of a struct or union as a C block
+// indented by indent levels.
+func memberBlock(members []btf.Member, indent int) string {
+	pad := strings.Repeat("\t", indent)
+
+	var sb strings.Builder
+	sb.WriteString("{\n")
+	for _, m := range members {
+		sb.WriteString(pad + "\t" + declarator(m.Type, m.Name, indent+1))
+		if m.BitfieldSize > 0 {
+			fmt.Fprintf(&sb, " : %d", m.BitfieldSize)
+		}
+		sb.WriteString(";\n")
+	}
+	sb.WriteString(pad + "}")
+
+	return sb.String()
+}
+
+// enumBlock renders the values of an enum as a C block indented by indent
+// levels.
+func enumBlock(e *btf.Enum, indent int) string {
+	pad := strings.Repeat("\t", indent)
+
+	var sb strings.Builder
+	sb.WriteString("{\n")
+	for _, v := range e.Values {
+		if e.Signed {
+			fmt.Fprintf(&sb, "%s\t%s = %d,\n", pad, v.Name, int64(v.Value))
+		} else {
+			fmt.Fprintf(&sb, "%s\t%s = %d,\n", pad, v.Name, v.Value)
+		}
+	}
+	sb.WriteString(pad + "}")
+
+	return sb.String()
+}
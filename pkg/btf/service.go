@@ -0,0 +1,39 @@
+// Package btf provides services for inspecting BTF objects loaded in the kernel.
+package btf
+
+// BTFInfo contains information about a BTF object loaded in the kernel.
+type BTFInfo struct {
+	// ID is the unique identifier of the BTF object.
+	ID uint32
+	// Name is the name the kernel gave the object, e.g. "vmlinux" or a
+	// module name. BTF loaded by user space has no name.
+	Name string
+	// Size is the size of the raw BTF data in bytes.
+	Size uint32
+	// Kernel indicates whether the BTF describes the kernel or a module.
+	Kernel bool
+}
+
+// TypeDecl is a single BTF type rendered as a C declaration.
+type TypeDecl struct {
+	// ID is the BTF type ID.
+	ID uint32
+	// Kind is the BTF kind, e.g. "struct", "typedef" or "func".
+	Kind string
+	// Name is the type name.
+	Name string
+	// C is the C declaration of the type.
+	C string
+}
+
+// Service defines the interface for inspecting BTF objects.
+type Service interface {
+	// List returns all BTF objects loaded in the kernel.
+	List() ([]BTFInfo, error)
+
+	// GetByID returns the BTF object with the given ID.
+	GetByID(id uint32) (*BTFInfo, error)
+
+	// Dump returns the named types of a BTF object as C declarations.
+	Dump(id uint32) ([]TypeDecl, error)
+}
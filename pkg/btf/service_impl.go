@@ -0,0 +1,168 @@
+package btf
+
+import (
+	"errors"
+	"fmt"
+	"os"
+
+	"github.com/cilium/ebpf"
+	"github.com/cilium/ebpf/btf"
+
+	"github.com/viveksb007/gobpftool/internal/utils"
+	bpferrors "github.com/viveksb007/gobpftool/pkg/errors"
+)
+
+// serviceImpl implements the Service interface using cilium/ebpf.
+type serviceImpl struct{}
+
+// NewService creates a new BTF service.
+func NewService() Service {
+	return &serviceImpl{}
+}
+
+// List returns all BTF objects loaded in the kernel.
+func (s *serviceImpl) List() ([]BTFInfo, error) {
+	var objects []BTFInfo
+
+	it := new(btf.HandleIterator)
+	for it.Next() {
+		info, err := handleInfo(it.Handle)
+		if err != nil {
+			// Skip objects we can't access
+			continue
+		}
+		objects = append(objects, *info)
+	}
+	if err := it.Err(); err != nil {
+		return nil, fmt.Errorf("failed to list BTF objects: %w", btfError(err))
+	}
+
+	return objects, nil
+}
+
+// GetByID returns the BTF object with the given ID.
+func (s *serviceImpl) GetByID(id uint32) (*BTFInfo, error) {
+	handle, err := openHandle(id)
+	if err != nil {
+		return nil, err
+	}
+	defer handle.Close()
+
+	return handleInfo(handle)
+}
+
+// Dump returns the named types of a BTF object as C declarations, in type
+// ID order. Module BTF only holds the types the module adds to vmlinux, so
+// vmlinux types are left out of its dump.
+func (s *serviceImpl) Dump(id uint32) ([]TypeDecl, error) {
+	handle, err := openHandle(id)
+	if err != nil {
+		return nil, err
+	}
+	defer handle.Close()
+
+	info, err := handle.Info()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get info of BTF object %d: %w", id, btfError(err))
+	}
+
+	var base *btf.Spec
+	if info.IsModule() {
+		if base, err = btf.LoadKernelSpec(); err != nil {
+			return nil, fmt.Errorf("module BTF %d needs the kernel BTF: %w", id, btfError(err))
+		}
+	}
+
+	spec, err := handle.Spec(base)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read BTF object %d: %w", id, btfError(err))
+	}
+
+	var decls []TypeDecl
+	for typ, err := range spec.All() {
+		if err != nil {
+			return nil, fmt.Errorf("failed to read types of BTF object %d: %w", id, err)
+		}
+		if base != nil {
+			if _, err := base.TypeID(typ); err == nil {
+				continue
+			}
+		}
+
+		c, ok := CDecl(typ)
+		if !ok {
+			continue
+		}
+		typeID, err := spec.TypeID(typ)
+		if err != nil {
+			continue
+		}
+		decls = append(decls, TypeDecl{
+			ID:   uint32(typeID),
+			Kind: kindName(typ),
+			Name: typ.TypeName(),
+			C:    c,
+		})
+	}
+
+	return decls, nil
+}
+
+// openHandle opens the BTF object with the given ID.
+func openHandle(id uint32) (*btf.Handle, error) {
+	handle, err := btf.NewHandleFromID(btf.ID(id))
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return nil, fmt.Errorf("BTF object with ID %d: %w", id, bpferrors.ErrNotFound)
+		}
+		return nil, fmt.Errorf("failed to get BTF object %d: %w", id, btfError(err))
+	}
+	return handle, nil
+}
+
+// handleInfo converts the info of a BTF handle to a BTFInfo.
+func handleInfo(handle *btf.Handle) (*BTFInfo, error) {
+	info, err := handle.Info()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get BTF info: %w", btfError(err))
+	}
+
+	// cilium/ebpf doesn't expose the size, so it's read directly
+	size, _ := btfSize(handle.FD())
+
+	return &BTFInfo{
+		ID:     uint32(info.ID),
+		Name:   info.Name,
+		Size:   size,
+		Kernel: info.IsKernel,
+	}, nil
+}
+
+// btfError marks errors from kernels without BTF object support as
+// ErrNotAvailable so callers can tell it apart from other failures.
+func btfError(err error) error {
+	if errors.Is(err, ebpf.ErrNotSupported) {
+		return fmt.Errorf("kernel BTF access %w: %v", bpferrors.ErrNotAvailable, err)
+	}
+	return err
+}
+
+// btfObjInfo mirrors the kernel's struct bpf_btf_info.
+type btfObjInfo struct {
+	BTF       uint64
+	BTFSize   uint32
+	ID        uint32
+	Name      uint64
+	NameLen   uint32
+	KernelBTF uint32
+}
+
+// btfSize queries BPF_OBJ_GET_INFO_BY_FD for the size of the raw BTF data.
+// Without a buffer the kernel only reports the size.
+func btfSize(fd int) (uint32, error) {
+	var info btfObjInfo
+	if err := utils.ObjGetInfo(fd, &info); err != nil {
+		return 0, err
+	}
+	return info.BTFSize, nil
+}
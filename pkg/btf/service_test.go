@@ -0,0 +1,139 @@
+package btf
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/cilium/ebpf/btf"
+
+	bpferrors "github.com/viveksb007/gobpftool/pkg/errors"
+)
+
+// TestCDecl tests the C rendering of the BTF kinds that declare a name.
+func TestCDecl(t *testing.T) {
+	u32 := &btf.Int{Name: "unsigned int", Size: 4}
+	char := &btf.Int{Name: "char", Size: 1, Encoding: btf.Char}
+	u32Typedef := &btf.Typedef{Name: "__u32", Type: u32}
+
+	tests := []struct {
+		name   string
+		typ    btf.Type
+		want   string
+		wantOK bool
+	}{
+		{
+			name:   "int",
+			typ:    u32,
+			wantOK: false,
+		},
+		{
+			name:   "typedef",
+			typ:    u32Typedef,
+			want:   "typedef unsigned int __u32;",
+			wantOK: true,
+		},
+		{
+			name: "struct",
+			typ: &btf.Struct{Name: "event", Size: 24, Members: []btf.Member{
+				{Name: "pid", Type: u32Typedef},
+				{Name: "flags", Type: u32, BitfieldSize: 3},
+				{Name: "comm", Type: &btf.Array{Type: char, Nelems: 16}},
+				{Name: "next", Type: &btf.Pointer{Target: &btf.Struct{Name: "event"}}},
+			}},
+			want:   "struct event {\n\t__u32 pid;\n\tunsigned int flags : 3;\n\tchar comm[16];\n\tstruct event *next;\n};",
+			wantOK: true,
+		},
+		{
+			name: "anonymous union member",
+			typ: &btf.Union{Name: "addr", Members: []btf.Member{
+				{Type: &btf.Struct{Members: []btf.Member{{Name: "v4", Type: u32}}}},
+			}},
+			want:   "union addr {\n\tstruct {\n\t\tunsigned int v4;\n\t};\n};",
+			wantOK: true,
+		},
+		{
+			name:   "anonymous struct",
+			typ:    &btf.Struct{},
+			wantOK: false,
+		},
+		{
+			name: "signed enum",
+			typ: &btf.Enum{Name: "state", Signed: true, Values: []btf.EnumValue{
+				{Name: "STATE_ERR", Value: ^uint64(0)},
+				{Name: "STATE_OK", Value: 0},
+			}},
+			want:   "enum state {\n\tSTATE_ERR = -1,\n\tSTATE_OK = 0,\n};",
+			wantOK: true,
+		},
+		{
+			name:   "forward declaration",
+			typ:    &btf.Fwd{Name: "sock", Kind: btf.FwdStruct},
+			want:   "struct sock;",
+			wantOK: true,
+		},
+		{
+			name: "function",
+			typ: &btf.Func{Name: "handle", Linkage: btf.StaticFunc, Type: &btf.FuncProto{
+				Return: u32,
+				Params: []btf.FuncParam{
+					{Name: "ctx", Type: &btf.Pointer{Target: &btf.Void{}}},
+					{Name: "name", Type: &btf.Pointer{Target: &btf.Const{Type: char}}},
+				},
+			}},
+			want:   "static unsigned int handle(void *ctx, const char *name);",
+			wantOK: true,
+		},
+		{
+			name: "function pointer typedef",
+			typ: &btf.Typedef{Name: "callback_t", Type: &btf.Pointer{Target: &btf.FuncProto{
+				Return: &btf.Void{},
+			}}},
+			want:   "typedef void (*callback_t)(void);",
+			wantOK: true,
+		},
+		{
+			name:   "pointer to array",
+			typ:    &btf.Var{Name: "rows", Linkage: btf.GlobalVar, Type: &btf.Pointer{Target: &btf.Array{Type: u32, Nelems: 4}}},
+			want:   "unsigned int (*rows)[4];",
+			wantOK: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, ok := CDecl(tt.typ)
+			if ok != tt.wantOK || got != tt.want {
+				t.Errorf("CDecl() = %q, %v, want %q, %v", got, ok, tt.want, tt.wantOK)
+			}
+		})
+	}
+}
+
+// TestList tests listing the BTF objects loaded in the kernel.
+func TestList(t *testing.T) {
+	objects, err := NewService().List()
+	if err != nil {
+		t.Skipf("can't list BTF objects: %v", err)
+	}
+
+	for _, obj := range objects {
+		if obj.ID == 0 {
+			t.Errorf("BTF object without an ID: %+v", obj)
+		}
+		if obj.Kernel && obj.Size == 0 {
+			t.Errorf("kernel BTF object without a size: %+v", obj)
+		}
+	}
+}
+
+// TestGetByID_NotFound tests that a missing BTF object wraps ErrNotFound.
+func TestGetByID_NotFound(t *testing.T) {
+	if _, err := NewService().List(); err != nil {
+		t.Skipf("can't list BTF objects: %v", err)
+	}
+
+	_, err := NewService().GetByID(1<<31 - 1)
+	if !errors.Is(err, bpferrors.ErrNotFound) {
+		t.Errorf("GetByID() error = %v, want %v", err, bpferrors.ErrNotFound)
+	}
+}
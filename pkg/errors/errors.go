@@ -7,6 +7,8 @@ import (
 	"os"
 	"strings"
 	"syscall"
+
+	"github.com/viveksb007/gobpftool/internal/utils"
 )
 
 // Sentinel errors for common error conditions.
@@ -26,6 +28,9 @@ var (
 	// ErrInvalidKey indicates an invalid key format.
 	ErrInvalidKey = errors.New("invalid key format")
 
+	// ErrInvalidValue indicates an invalid value format.
+	ErrInvalidValue = errors.New("invalid value format")
+
 	// ErrKeyNotFound indicates a key was not found in a map.
 	ErrKeyNotFound = errors.New("key not found in map")
 
@@ -34,6 +39,22 @@ var (
 
 	// ErrMapEmpty indicates the map is empty.
 	ErrMapEmpty = errors.New("map is empty")
+
+	// ErrNotAvailable indicates the kernel didn't expose the requested data.
+	ErrNotAvailable = errors.New("not available")
+
+	// ErrInterrupted indicates the command was stopped by SIGINT.
+	ErrInterrupted = errors.New("interrupted")
+
+	// ErrTooLarge indicates a size reported by the kernel exceeds a sanity limit.
+	ErrTooLarge = errors.New("too large")
+
+	// ErrMapFrozen indicates a write to a map frozen with map freeze.
+	ErrMapFrozen = errors.New("map is frozen (read-only)")
+
+	// ErrDeleteNotSupported indicates a delete from a map type whose
+	// elements can't be deleted, such as arrays.
+	ErrDeleteNotSupported = errors.New("delete is not supported for array maps")
 )
 
 // IsPermissionError checks if the error is a permission-related error.
@@ -63,6 +84,17 @@ func IsPermissionError(err error) bool {
 		strings.Contains(errStr, "operation not permitted")
 }
 
+// WrapFrozen classifies the error of a write to a map: the kernel rejects
+// writes to frozen maps with EPERM, which would otherwise read as missing
+// privileges. When frozen is set, permission errors are returned wrapped
+// in ErrMapFrozen; other errors are returned unchanged.
+func WrapFrozen(err error, frozen bool) error {
+	if err == nil || !frozen || !IsPermissionError(err) {
+		return err
+	}
+	return fmt.Errorf("%w: %w", ErrMapFrozen, err)
+}
+
 // IsNotFoundError checks if the error indicates a resource was not found.
 func IsNotFoundError(err error) bool {
 	if err == nil {
@@ -117,31 +149,57 @@ func IsBpfFSNotMounted() bool {
 }
 
 // WrapError wraps an error with additional context and converts
-// common system errors to our sentinel errors.
+// common system errors to our sentinel errors. The original error stays
+// in the chain so errors.Is still matches it.
 func WrapError(err error, context string) error {
 	if err == nil {
 		return nil
 	}
 
+	// Already classified errors only need the context
+	for _, sentinel := range []error{ErrMapFrozen, ErrDeleteNotSupported, ErrPermission, ErrBpfFSNotMounted, ErrNotFound, ErrKeyNotFound} {
+		if errors.Is(err, sentinel) {
+			return fmt.Errorf("%s: %w", context, err)
+		}
+	}
+
 	// Convert permission errors
 	if IsPermissionError(err) {
-		return fmt.Errorf("%s: %w", context, ErrPermission)
+		return fmt.Errorf("%s: %w: %w", context, ErrPermission, err)
 	}
 
 	// Check for BPF filesystem issues
 	if IsBpfFSNotMounted() && strings.Contains(context, "pinned") {
-		return fmt.Errorf("%s: %w", context, ErrBpfFSNotMounted)
+		return fmt.Errorf("%s: %w: %w", context, ErrBpfFSNotMounted, err)
 	}
 
 	// Convert not found errors
 	if IsNotFoundError(err) {
-		return fmt.Errorf("%s: %w", context, ErrNotFound)
+		return fmt.Errorf("%s: %w: %w", context, ErrNotFound, err)
 	}
 
 	// Default wrapping
 	return fmt.Errorf("%s: %w", context, err)
 }
 
+// HasBPFCapability reports whether the process holds CAP_BPF or
+// CAP_SYS_ADMIN, read from the CapEff line of /proc/self/status. When the
+// capabilities can't be read it reports true and leaves the decision to
+// the kernel.
+func HasBPFCapability() bool {
+	mask, err := utils.ReadCapEff()
+	if err != nil {
+		return true
+	}
+	return allowsBPF(mask)
+}
+
+// allowsBPF reports whether an effective capability mask allows BPF
+// operations.
+func allowsBPF(mask uint64) bool {
+	return utils.HasCap(mask, utils.CapBPF) || utils.HasCap(mask, utils.CapSysAdmin)
+}
+
 // FormatPermissionError returns a user-friendly permission error message.
 func FormatPermissionError() string {
 	return `Error: Permission denied.
@@ -172,6 +230,14 @@ func FormatError(err error) string {
 		return ""
 	}
 
+	if errors.Is(err, ErrMapFrozen) {
+		return "Error: map is frozen (read-only)"
+	}
+
+	if errors.Is(err, ErrDeleteNotSupported) {
+		return "Error: delete is not supported for array maps"
+	}
+
 	if errors.Is(err, ErrPermission) || IsPermissionError(err) {
 		return FormatPermissionError()
 	}
@@ -199,11 +265,51 @@ func FormatError(err error) string {
 	return fmt.Sprintf("Error: %v", err)
 }
 
+// Error codes returned by Code, for consumers of machine-readable output.
+const (
+	CodePermission      = "permission"
+	CodeNotFound        = "not_found"
+	CodeBpfFSNotMounted = "bpffs_not_mounted"
+	CodeInvalidKey      = "invalid_key"
+	CodeMapFrozen       = "map_frozen"
+	CodeNotSupported    = "not_supported"
+	CodeGeneric         = "generic"
+)
+
+// Code classifies an error for programmatic handling, returning one of the
+// Code constants. Unlike FormatError it only looks at the error itself, so
+// the result doesn't depend on the state of the system.
+func Code(err error) string {
+	switch {
+	case err == nil:
+		return ""
+	// Checked before permission errors, since frozen maps reject writes
+	// with EPERM
+	case errors.Is(err, ErrMapFrozen):
+		return CodeMapFrozen
+	case errors.Is(err, ErrDeleteNotSupported):
+		return CodeNotSupported
+	case IsPermissionError(err):
+		return CodePermission
+	case errors.Is(err, ErrBpfFSNotMounted):
+		return CodeBpfFSNotMounted
+	case errors.Is(err, ErrInvalidKey):
+		return CodeInvalidKey
+	case IsNotFoundError(err):
+		return CodeNotFound
+	}
+	return CodeGeneric
+}
+
 // ExitCode returns the appropriate exit code for the given error.
-// Returns 0 for nil (success), 1 for any error (failure).
+// Returns 0 for nil (success), 130 for an interrupted command like shells
+// do for SIGINT, and 1 for any other error (failure).
 func ExitCode(err error) int {
 	if err == nil {
 		return 0
 	}
+	if errors.Is(err, ErrInterrupted) {
+		return 130
+	}
 	return 1
 }
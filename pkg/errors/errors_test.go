@@ -7,6 +7,8 @@ import (
 	"strings"
 	"syscall"
 	"testing"
+
+	"github.com/viveksb007/gobpftool/internal/utils"
 )
 
 func TestIsPermissionError(t *testing.T) {
@@ -217,6 +219,13 @@ func TestWrapError(t *testing.T) {
 			expectContains: "getting program",
 			expectSentinel: ErrNotFound,
 		},
+		{
+			name:           "key not found keeps original",
+			err:            fmt.Errorf("lookup: %w", ErrKeyNotFound),
+			context:        "looking up key",
+			expectContains: "key not found in map",
+			expectSentinel: ErrKeyNotFound,
+		},
 		{
 			name:           "generic error",
 			err:            errors.New("something went wrong"),
@@ -248,10 +257,101 @@ func TestWrapError(t *testing.T) {
 			if tt.expectSentinel != nil && !errors.Is(result, tt.expectSentinel) {
 				t.Errorf("WrapError() should wrap %v", tt.expectSentinel)
 			}
+
+			if !errors.Is(result, tt.err) {
+				t.Errorf("WrapError() should keep the original error %v", tt.err)
+			}
+		})
+	}
+}
+
+func TestAllowsBPF(t *testing.T) {
+	tests := []struct {
+		name   string
+		status string
+		want   bool
+	}{
+		{name: "root", status: "CapEff:\t000001ffffffffff\n", want: true},
+		{name: "CAP_BPF only", status: "CapEff:\t0000008000000000\n", want: true},
+		{name: "CAP_SYS_ADMIN only", status: "CapEff:\t0000000000200000\n", want: true},
+		{name: "CAP_NET_ADMIN only", status: "CapEff:\t0000000000001000\n", want: false},
+		{name: "unprivileged", status: "CapEff:\t0000000000000000\n", want: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			status := "Name:\tgobpftool\nCapInh:\t0000000000000000\nCapPrm:\t0000000000000000\n" + tt.status
+			mask, err := utils.ParseCapEff(strings.NewReader(status))
+			if err != nil {
+				t.Fatalf("ParseCapEff() error = %v", err)
+			}
+			if got := allowsBPF(mask); got != tt.want {
+				t.Errorf("allowsBPF(%#x) = %v, want %v", mask, got, tt.want)
+			}
 		})
 	}
 }
 
+func TestWrapFrozen(t *testing.T) {
+	tests := []struct {
+		name       string
+		err        error
+		frozen     bool
+		wantFrozen bool
+	}{
+		{name: "EPERM on a frozen map", err: fmt.Errorf("update: %w", syscall.EPERM), frozen: true, wantFrozen: true},
+		{name: "EPERM on a map that isn't frozen", err: syscall.EPERM, frozen: false, wantFrozen: false},
+		{name: "other error on a frozen map", err: syscall.E2BIG, frozen: true, wantFrozen: false},
+		{name: "nil error", err: nil, frozen: true, wantFrozen: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := WrapFrozen(tt.err, tt.frozen)
+			if errors.Is(got, ErrMapFrozen) != tt.wantFrozen {
+				t.Errorf("WrapFrozen(%v, %v) = %v, want frozen %v", tt.err, tt.frozen, got, tt.wantFrozen)
+			}
+			// The original error stays in the chain
+			if tt.err != nil && !errors.Is(got, tt.err) {
+				t.Errorf("WrapFrozen(%v, %v) = %v, lost the original error", tt.err, tt.frozen, got)
+			}
+		})
+	}
+
+	// A frozen map is reported as such, not as missing privileges
+	frozen := WrapError(WrapFrozen(syscall.EPERM, true), "updating map 5")
+	if !errors.Is(frozen, ErrMapFrozen) || errors.Is(frozen, ErrPermission) {
+		t.Errorf("WrapError() = %v, want ErrMapFrozen without ErrPermission", frozen)
+	}
+	if got := FormatError(frozen); got != "Error: map is frozen (read-only)" {
+		t.Errorf("FormatError() = %q, want the frozen message", got)
+	}
+}
+
+func TestErrDeleteNotSupported(t *testing.T) {
+	wrapped := fmt.Errorf("map 3 is a Array map: %w", ErrDeleteNotSupported)
+
+	for _, err := range []error{ErrDeleteNotSupported, wrapped} {
+		if IsNotFoundError(err) {
+			t.Errorf("IsNotFoundError(%v) = true, want false", err)
+		}
+		if IsPermissionError(err) {
+			t.Errorf("IsPermissionError(%v) = true, want false", err)
+		}
+	}
+
+	got := WrapError(wrapped, "deleting from map 3")
+	if !errors.Is(got, ErrDeleteNotSupported) {
+		t.Errorf("WrapError() = %v, lost ErrDeleteNotSupported", got)
+	}
+	if errors.Is(got, ErrNotFound) || errors.Is(got, ErrPermission) {
+		t.Errorf("WrapError() = %v, misclassified as not found or permission", got)
+	}
+	if msg := FormatError(got); msg != "Error: delete is not supported for array maps" {
+		t.Errorf("FormatError() = %q, want the delete message", msg)
+	}
+}
+
 func TestFormatPermissionError(t *testing.T) {
 	result := FormatPermissionError()
 
@@ -341,6 +441,30 @@ func TestFormatError(t *testing.T) {
 	}
 }
 
+func TestCode(t *testing.T) {
+	tests := []struct {
+		name string
+		err  error
+		want string
+	}{
+		{name: "nil", err: nil, want: ""},
+		{name: "EPERM", err: fmt.Errorf("loading: %w", syscall.EPERM), want: CodePermission},
+		{name: "ENOENT", err: fmt.Errorf("opening: %w", syscall.ENOENT), want: CodeNotFound},
+		{name: "frozen EPERM", err: WrapFrozen(syscall.EPERM, true), want: CodeMapFrozen},
+		{name: "wrapped invalid key", err: WrapError(ErrInvalidKey, "updating map 5"), want: CodeInvalidKey},
+		{name: "bpffs", err: WrapError(ErrBpfFSNotMounted, "pinned path"), want: CodeBpfFSNotMounted},
+		{name: "other", err: syscall.E2BIG, want: CodeGeneric},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := Code(tt.err); got != tt.want {
+				t.Errorf("Code(%v) = %q, want %q", tt.err, got, tt.want)
+			}
+		})
+	}
+}
+
 func TestExitCode(t *testing.T) {
 	tests := []struct {
 		name     string
@@ -367,6 +491,11 @@ func TestExitCode(t *testing.T) {
 			err:      errors.New("something failed"),
 			expected: 1,
 		},
+		{
+			name:     "interrupted",
+			err:      fmt.Errorf("dumping map 1: %w", ErrInterrupted),
+			expected: 130,
+		},
 	}
 
 	for _, tt := range tests {
@@ -392,6 +521,7 @@ func TestSentinelErrors(t *testing.T) {
 		{ErrInvalidKey, "invalid key"},
 		{ErrKeyNotFound, "key not found"},
 		{ErrNoMoreKeys, "no more keys"},
+		{ErrDeleteNotSupported, "array maps"},
 		{ErrMapEmpty, "empty"},
 	}
 
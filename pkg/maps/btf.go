@@ -0,0 +1,308 @@
+package maps
+
+import (
+	"bytes"
+	"encoding/binary"
+	"encoding/json"
+	"fmt"
+	"math"
+
+	"github.com/cilium/ebpf"
+	"github.com/cilium/ebpf/btf"
+	"github.com/viveksb007/gobpftool/internal/utils"
+)
+
+// DecodedEntry is a map entry whose key and value were decoded using the
+// map's BTF. FormattedKey and FormattedValue are nil when the map has no BTF.
+type DecodedEntry struct {
+	MapEntry
+	// FormattedKey is the BTF-decoded key
+	FormattedKey interface{} `json:"-"`
+	// FormattedValue is the BTF-decoded value, or one decoded value per CPU
+	// for per-CPU maps
+	FormattedValue interface{} `json:"-"`
+}
+
+// DecodedField is a single named member of a decoded struct or union
+type DecodedField struct {
+	Name  string
+	Value interface{}
+}
+
+// DecodedStruct is a decoded struct or union. It marshals to a JSON object
+// with the members in declaration order.
+type DecodedStruct []DecodedField
+
+// MarshalJSON encodes the struct as a JSON object preserving member order
+func (d DecodedStruct) MarshalJSON() ([]byte, error) {
+	var buf bytes.Buffer
+	buf.WriteByte('{')
+	for i, field := range d {
+		if i > 0 {
+			buf.WriteByte(',')
+		}
+		name, err := json.Marshal(field.Name)
+		if err != nil {
+			return nil, err
+		}
+		value, err := json.Marshal(field.Value)
+		if err != nil {
+			return nil, err
+		}
+		buf.Write(name)
+		buf.WriteByte(':')
+		buf.Write(value)
+	}
+	buf.WriteByte('}')
+	return buf.Bytes(), nil
+}
+
+// mapObjInfo mirrors the leading fields of the kernel's struct bpf_map_info
+// up to and including btf_value_type_id, which cilium/ebpf doesn't expose
+type mapObjInfo struct {
+	Type                  uint32
+	ID                    uint32
+	KeySize               uint32
+	ValueSize             uint32
+	MaxEntries            uint32
+	MapFlags              uint32
+	Name                  [16]byte
+	Ifindex               uint32
+	BtfVmlinuxValueTypeID uint32
+	NetnsDev              uint64
+	NetnsIno              uint64
+	BtfID                 uint32
+	BtfKeyTypeID          uint32
+	BtfValueTypeID        uint32
+	_                     uint32
+}
+
+// getMapObjInfo queries BPF_OBJ_GET_INFO_BY_FD for the map fd
+func getMapObjInfo(fd int) (*mapObjInfo, error) {
+	var info mapObjInfo
+	if err := utils.ObjGetInfo(fd, &info); err != nil {
+		return nil, err
+	}
+	return &info, nil
+}
+
+// btfDecoder decodes raw keys and values of a map using its BTF types
+type btfDecoder struct {
+	keyType   btf.Type // nil if the map has no key type
+	valueType btf.Type
+}
+
+// newBTFDecoder loads the BTF key and value types of a map. It returns a
+// nil decoder without error when the map carries no BTF.
+func newBTFDecoder(m *ebpf.Map) (*btfDecoder, error) {
+	info, err := getMapObjInfo(m.FD())
+	if err != nil {
+		return nil, fmt.Errorf("failed to get map info: %w", err)
+	}
+	if info.BtfID == 0 || info.BtfValueTypeID == 0 {
+		return nil, nil
+	}
+
+	handle, err := btf.NewHandleFromID(btf.ID(info.BtfID))
+	if err != nil {
+		return nil, fmt.Errorf("failed to get BTF %d: %w", info.BtfID, err)
+	}
+	defer handle.Close()
+
+	spec, err := handle.Spec(nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load BTF %d: %w", info.BtfID, err)
+	}
+
+	d := &btfDecoder{}
+	if info.BtfKeyTypeID != 0 {
+		if d.keyType, err = spec.TypeByID(btf.TypeID(info.BtfKeyTypeID)); err != nil {
+			return nil, fmt.Errorf("failed to resolve key type: %w", err)
+		}
+	}
+	if d.valueType, err = spec.TypeByID(btf.TypeID(info.BtfValueTypeID)); err != nil {
+		return nil, fmt.Errorf("failed to resolve value type: %w", err)
+	}
+
+	return d, nil
+}
+
+// decode converts a raw entry into a DecodedEntry
+func (d *btfDecoder) decode(entry MapEntry) (DecodedEntry, error) {
+	decoded := DecodedEntry{MapEntry: entry}
+	if d == nil {
+		return decoded, nil
+	}
+
+	var err error
+	if d.keyType != nil {
+		if decoded.FormattedKey, err = decodeBTFValue(d.keyType, entry.Key); err != nil {
+			return decoded, fmt.Errorf("failed to decode key: %w", err)
+		}
+	}
+
+	if entry.PerCPUValues != nil {
+		values := make([]interface{}, len(entry.PerCPUValues))
+		for cpu, value := range entry.PerCPUValues {
+			if values[cpu], err = decodeBTFValue(d.valueType, value); err != nil {
+				return decoded, fmt.Errorf("failed to decode value for CPU %d: %w", cpu, err)
+			}
+		}
+		decoded.FormattedValue = values
+		return decoded, nil
+	}
+
+	if decoded.FormattedValue, err = decodeBTFValue(d.valueType, entry.Value); err != nil {
+		return decoded, fmt.Errorf("failed to decode value: %w", err)
+	}
+
+	return decoded, nil
+}
+
+// decodeBTFValue decodes data according to typ. Types without a natural
+// representation are returned as hex strings.
+func decodeBTFValue(typ btf.Type, data []byte) (interface{}, error) {
+	typ = btf.UnderlyingType(typ)
+
+	size, err := btf.Sizeof(typ)
+	if err != nil {
+		return nil, err
+	}
+	if len(data) < size {
+		return nil, fmt.Errorf("%d bytes is too short for %s (%d bytes)", len(data), typ.TypeName(), size)
+	}
+	data = data[:size]
+
+	switch t := typ.(type) {
+	case *btf.Int:
+		return decodeInt(data, t.Encoding)
+
+	case *btf.Enum:
+		raw, err := decodeInt(data, btf.Unsigned)
+		if err != nil {
+			return nil, err
+		}
+		for _, v := range t.Values {
+			if v.Value == raw.(uint64) {
+				return v.Name, nil
+			}
+		}
+		return raw, nil
+
+	case *btf.Float:
+		switch t.Size {
+		case 4:
+			return math.Float32frombits(binary.NativeEndian.Uint32(data)), nil
+		case 8:
+			return math.Float64frombits(binary.NativeEndian.Uint64(data)), nil
+		}
+
+	case *btf.Pointer:
+		if size == 8 {
+			return fmt.Sprintf("%#x", binary.NativeEndian.Uint64(data)), nil
+		}
+
+	case *btf.Array:
+		return decodeArray(t, data)
+
+	case *btf.Struct:
+		return decodeMembers(t.Members, data)
+
+	case *btf.Union:
+		return decodeMembers(t.Members, data)
+	}
+
+	return fmt.Sprintf("%x", data), nil
+}
+
+// decodeInt decodes a 1, 2, 4 or 8 byte integer. Wider integers are
+// returned as hex strings.
+func decodeInt(data []byte, encoding btf.IntEncoding) (interface{}, error) {
+	var u uint64
+	switch len(data) {
+	case 1:
+		u = uint64(data[0])
+	case 2:
+		u = uint64(binary.NativeEndian.Uint16(data))
+	case 4:
+		u = uint64(binary.NativeEndian.Uint32(data))
+	case 8:
+		u = binary.NativeEndian.Uint64(data)
+	default:
+		return fmt.Sprintf("%x", data), nil
+	}
+
+	switch encoding {
+	case btf.Bool:
+		return u != 0, nil
+	case btf.Signed:
+		// Sign-extend from the integer's width
+		shift := 64 - 8*uint(len(data))
+		return int64(u<<shift) >> shift, nil
+	}
+	return u, nil
+}
+
+// decodeArray decodes an array; char arrays become NUL-terminated strings
+func decodeArray(t *btf.Array, data []byte) (interface{}, error) {
+	if elem, ok := btf.UnderlyingType(t.Type).(*btf.Int); ok && elem.Size == 1 && elem.Encoding == btf.Char {
+		if i := bytes.IndexByte(data, 0); i >= 0 {
+			data = data[:i]
+		}
+		return string(data), nil
+	}
+
+	elemSize, err := btf.Sizeof(t.Type)
+	if err != nil {
+		return nil, err
+	}
+
+	values := make([]interface{}, t.Nelems)
+	for i := range values {
+		off := i * elemSize
+		if values[i], err = decodeBTFValue(t.Type, data[off:off+elemSize]); err != nil {
+			return nil, err
+		}
+	}
+	return values, nil
+}
+
+// decodeMembers decodes struct or union members, including bitfields.
+// Members of anonymous nested structs and unions are flattened.
+func decodeMembers(members []btf.Member, data []byte) (interface{}, error) {
+	var result DecodedStruct
+	for _, member := range members {
+		var value interface{}
+		var err error
+
+		if member.BitfieldSize > 0 {
+			value = decodeBitfield(data, member.Offset, member.BitfieldSize)
+		} else {
+			off := int(member.Offset.Bytes())
+			if off > len(data) {
+				return nil, fmt.Errorf("member %s out of bounds", member.Name)
+			}
+			if value, err = decodeBTFValue(member.Type, data[off:]); err != nil {
+				return nil, fmt.Errorf("member %s: %w", member.Name, err)
+			}
+		}
+
+		if nested, ok := value.(DecodedStruct); ok && member.Name == "" {
+			result = append(result, nested...)
+			continue
+		}
+		result = append(result, DecodedField{Name: member.Name, Value: value})
+	}
+	return result, nil
+}
+
+// decodeBitfield extracts an unsigned bitfield, assuming a little-endian host
+func decodeBitfield(data []byte, offset, size btf.Bits) uint64 {
+	var buf [8]byte
+	copy(buf[:], data[offset/8:])
+	v := binary.LittleEndian.Uint64(buf[:]) >> (offset % 8)
+	if size < 64 {
+		v &= (1 << size) - 1
+	}
+	return v
+}
@@ -0,0 +1,85 @@
+package maps
+
+import (
+	"slices"
+	"sync"
+	"time"
+)
+
+// WithInfoCache keeps the MapInfo read for a map for ttl, so listing maps
+// and looking them up again within one command doesn't reopen every map.
+// Cached info can be up to ttl old, e.g. list a map freed since; pinning
+// and unpinning through the service drop the cache. Without this option
+// the service keeps no state between calls
+func WithInfoCache(ttl time.Duration) Option {
+	return func(s *serviceImpl) {
+		s.cache = newInfoCache(ttl)
+	}
+}
+
+// infoCache holds the MapInfo of recently read maps by ID. A nil cache
+// never hits, so callers don't have to check whether caching is enabled
+type infoCache struct {
+	ttl time.Duration
+	// now returns the current time; tests replace it
+	now func() time.Time
+
+	mu      sync.Mutex
+	entries map[uint32]cachedInfo
+}
+
+// cachedInfo is a cached MapInfo and when it stops being used
+type cachedInfo struct {
+	info    MapInfo
+	expires time.Time
+}
+
+// newInfoCache returns an empty cache whose entries live for ttl
+func newInfoCache(ttl time.Duration) *infoCache {
+	return &infoCache{ttl: ttl, now: time.Now, entries: make(map[uint32]cachedInfo)}
+}
+
+// get returns a copy of the cached info of map id, reporting false when
+// it isn't cached or has expired
+func (c *infoCache) get(id uint32) (*MapInfo, bool) {
+	if c == nil {
+		return nil, false
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	cached, ok := c.entries[id]
+	if !ok {
+		return nil, false
+	}
+	if !c.now().Before(cached.expires) {
+		delete(c.entries, id)
+		return nil, false
+	}
+	info := cached.info
+	info.PinnedPaths = slices.Clone(info.PinnedPaths)
+	return &info, true
+}
+
+// put caches a copy of info
+func (c *infoCache) put(info *MapInfo) {
+	if c == nil {
+		return
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	cached := *info
+	cached.PinnedPaths = slices.Clone(info.PinnedPaths)
+	c.entries[info.ID] = cachedInfo{info: cached, expires: c.now().Add(c.ttl)}
+}
+
+// invalidate drops every cached entry
+func (c *infoCache) invalidate() {
+	if c == nil {
+		return
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	clear(c.entries)
+}
@@ -0,0 +1,109 @@
+package maps
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/cilium/ebpf"
+
+	bpferrors "github.com/viveksb007/gobpftool/pkg/errors"
+)
+
+// keyIterator is the subset of map operations needed to walk the keys of
+// a map
+type keyIterator interface {
+	// NextKey returns the key after key, or the first key if key is nil.
+	// It returns an error wrapping ebpf.ErrKeyNotExist when there is none.
+	NextKey(key []byte) ([]byte, error)
+}
+
+// keyDeleter is the subset of map operations needed to clear a map
+type keyDeleter interface {
+	keyIterator
+	// Delete removes key from the map
+	Delete(key []byte) error
+}
+
+// ebpfKeyDeleter adapts an *ebpf.Map to keyDeleter
+type ebpfKeyDeleter struct {
+	m *ebpf.Map
+}
+
+func (d ebpfKeyDeleter) NextKey(key []byte) ([]byte, error) {
+	var next []byte
+	var prev interface{}
+	if key != nil {
+		prev = key
+	}
+	if err := d.m.NextKey(prev, &next); err != nil {
+		return nil, err
+	}
+	return next, nil
+}
+
+func (d ebpfKeyDeleter) Delete(key []byte) error {
+	return d.m.Delete(key)
+}
+
+// Clear deletes every entry in the map and returns the number removed
+func (s *serviceImpl) Clear(id uint32) (int, error) {
+	return s.ClearContext(context.Background(), id)
+}
+
+// ClearContext is Clear, stopping early when ctx is cancelled
+func (s *serviceImpl) ClearContext(ctx context.Context, id uint32) (int, error) {
+	return s.ClearRef(ctx, ByID(id))
+}
+
+// ClearRef is ClearContext for a map selected by ID or pinned path
+func (s *serviceImpl) ClearRef(ctx context.Context, ref Ref) (int, error) {
+	m, info, err := ref.open()
+	if err != nil {
+		return 0, err
+	}
+	defer m.Close()
+
+	if !supportsDelete(info.Type) {
+		return 0, fmt.Errorf("cannot clear %s, a %s map: %w", ref, info.Type, bpferrors.ErrDeleteNotSupported)
+	}
+
+	deleted, err := clearKeys(ctx, ebpfKeyDeleter{m: m})
+	return deleted, bpferrors.WrapFrozen(err, info.Frozen())
+}
+
+// clearKeys walks the map deleting each key. The successor is fetched
+// before a key is deleted; if the walk loses its place because keys were
+// deleted concurrently, it restarts from the first key. It returns ctx's
+// error with the count so far once ctx is cancelled.
+func clearKeys(ctx context.Context, d keyDeleter) (int, error) {
+	deleted := 0
+
+	key, err := d.NextKey(nil)
+	for {
+		if errors.Is(err, ebpf.ErrKeyNotExist) {
+			return deleted, nil
+		}
+		if err != nil {
+			return deleted, fmt.Errorf("failed to get next key: %w", err)
+		}
+		if err := ctx.Err(); err != nil {
+			return deleted, err
+		}
+
+		next, nextErr := d.NextKey(key)
+
+		if err := d.Delete(key); err == nil {
+			deleted++
+		} else if !errors.Is(err, ebpf.ErrKeyNotExist) {
+			return deleted, fmt.Errorf("failed to delete key: %w", err)
+		}
+
+		// Lost our place mid-loop: start over from the first key
+		if errors.Is(nextErr, ebpf.ErrKeyNotExist) {
+			key, err = d.NextKey(nil)
+			continue
+		}
+		key, err = next, nextErr
+	}
+}
@@ -0,0 +1,52 @@
+package maps
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/cilium/ebpf"
+)
+
+// CountEntries counts the populated entries of a map by walking its keys,
+// stopping once limit keys were counted (0 for no limit). It reports
+// whether the count stopped at limit, in which case the map holds at least
+// that many entries. Entries added or deleted during the walk may be
+// missed or counted twice, so the count is an estimate on busy maps
+func (s *serviceImpl) CountEntries(id uint32, limit int) (int, bool, error) {
+	m, err := openMap(ebpf.MapID(id))
+	if err != nil {
+		return 0, false, fmt.Errorf("failed to get map by ID %d: %w", id, err)
+	}
+	defer m.Close()
+
+	info, err := m.Info()
+	if err != nil {
+		return 0, false, fmt.Errorf("failed to get map info: %w", err)
+	}
+	if err := checkBufferSize("key", info.KeySize, s.maxBuffer); err != nil {
+		return 0, false, fmt.Errorf("map %d: %w", id, err)
+	}
+
+	return countKeys(ebpfKeyDeleter{m: m}, limit)
+}
+
+// countKeys counts the keys it walks with it, up to limit (0 for no limit),
+// and reports whether it stopped at limit
+func countKeys(it keyIterator, limit int) (int, bool, error) {
+	count := 0
+	key, err := it.NextKey(nil)
+	for {
+		if errors.Is(err, ebpf.ErrKeyNotExist) {
+			return count, false, nil
+		}
+		if err != nil {
+			return count, false, fmt.Errorf("failed to get next key: %w", err)
+		}
+
+		count++
+		if limit > 0 && count >= limit {
+			return count, true, nil
+		}
+		key, err = it.NextKey(key)
+	}
+}
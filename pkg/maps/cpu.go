@@ -0,0 +1,80 @@
+package maps
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/cilium/ebpf"
+)
+
+// onlineCPUsPath lists the online CPUs as ranges, e.g. "0-3,5"
+const onlineCPUsPath = "/sys/devices/system/cpu/online"
+
+// ErrNotPerCPU is returned when a CPU is selected on a map that doesn't
+// store per-CPU values
+var ErrNotPerCPU = errors.New("--cpu only applies to per-CPU maps")
+
+// IsPerCPUType reports whether a MapInfo.Type stores one value per CPU
+func IsPerCPUType(mapType string) bool {
+	t, ok := MapTypeFromString(mapType)
+	if !ok {
+		return false
+	}
+	switch t {
+	case ebpf.PerCPUHash, ebpf.PerCPUArray, ebpf.LRUCPUHash, ebpf.PerCPUCGroupStorage:
+		return true
+	}
+	return false
+}
+
+// OnlineCPUs returns the number of online CPUs
+func OnlineCPUs() (int, error) {
+	data, err := os.ReadFile(onlineCPUsPath)
+	if err != nil {
+		return 0, fmt.Errorf("failed to read online CPUs: %w", err)
+	}
+	return parseCPUList(strings.TrimSpace(string(data)))
+}
+
+// parseCPUList counts the CPUs in a kernel CPU list such as "0-3,5"
+func parseCPUList(list string) (int, error) {
+	count := 0
+	for _, part := range strings.Split(list, ",") {
+		lo, hi, isRange := strings.Cut(part, "-")
+		start, err := strconv.Atoi(lo)
+		if err != nil {
+			return 0, fmt.Errorf("invalid CPU list %q", list)
+		}
+		end := start
+		if isRange {
+			if end, err = strconv.Atoi(hi); err != nil || end < start {
+				return 0, fmt.Errorf("invalid CPU list %q", list)
+			}
+		}
+		count += end - start + 1
+	}
+	return count, nil
+}
+
+// SelectCPU reduces per-CPU entries to the value of a single CPU. cpu must
+// be below numCPUs, the number of online CPUs.
+func SelectCPU(entries []MapEntry, cpu, numCPUs int) ([]MapEntry, error) {
+	if cpu < 0 || cpu >= numCPUs {
+		return nil, fmt.Errorf("cpu %d out of range, have %d CPUs", cpu, numCPUs)
+	}
+
+	selected := make([]MapEntry, len(entries))
+	for i, e := range entries {
+		if e.PerCPUValues == nil {
+			return nil, ErrNotPerCPU
+		}
+		if cpu >= len(e.PerCPUValues) {
+			return nil, fmt.Errorf("cpu %d out of range, have %d CPUs", cpu, len(e.PerCPUValues))
+		}
+		selected[i] = MapEntry{Key: e.Key, Value: e.PerCPUValues[cpu]}
+	}
+	return selected, nil
+}
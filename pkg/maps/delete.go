@@ -0,0 +1,47 @@
+package maps
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/cilium/ebpf"
+
+	bpferrors "github.com/viveksb007/gobpftool/pkg/errors"
+)
+
+// supportsDelete reports whether entries of maps of type t can be deleted.
+// Array elements always exist, and the kernel rejects deleting them with
+// an EINVAL that reads like a bad key
+func supportsDelete(t ebpf.MapType) bool {
+	return t != ebpf.Array && t != ebpf.PerCPUArray
+}
+
+// Delete removes the entry for a key from the map. Array maps return an
+// error wrapping ErrDeleteNotSupported before any syscall is made
+func (s *serviceImpl) Delete(id uint32, key []byte) error {
+	return s.DeleteRef(ByID(id), key)
+}
+
+// DeleteRef is Delete for a map selected by ID or pinned path
+func (s *serviceImpl) DeleteRef(ref Ref, key []byte) error {
+	m, info, err := ref.open()
+	if err != nil {
+		return err
+	}
+	defer m.Close()
+
+	if !supportsDelete(info.Type) {
+		return fmt.Errorf("%s is a %s map: %w", ref, info.Type, bpferrors.ErrDeleteNotSupported)
+	}
+	if uint32(len(key)) != info.KeySize {
+		return fmt.Errorf("%w: expected %d key bytes, got %d", bpferrors.ErrInvalidKey, info.KeySize, len(key))
+	}
+
+	if err := m.Delete(key); err != nil {
+		if errors.Is(err, ebpf.ErrKeyNotExist) {
+			return fmt.Errorf("failed to delete key: %w", bpferrors.ErrKeyNotFound)
+		}
+		return fmt.Errorf("failed to delete key: %w", bpferrors.WrapFrozen(err, info.Frozen()))
+	}
+	return nil
+}
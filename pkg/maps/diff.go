@@ -0,0 +1,63 @@
+package maps
+
+import (
+	"bytes"
+	"encoding/hex"
+	"slices"
+)
+
+// EntryChange is an entry whose value differs between two dumps
+type EntryChange struct {
+	Old MapEntry
+	New MapEntry
+}
+
+// EntryDiff holds the differences between two dumps of a map
+type EntryDiff struct {
+	Added   []MapEntry
+	Removed []MapEntry
+	Changed []EntryChange
+}
+
+// Empty reports whether the dumps were identical
+func (d EntryDiff) Empty() bool {
+	return len(d.Added) == 0 && len(d.Removed) == 0 && len(d.Changed) == 0
+}
+
+// Diff compares two dumps of a map, matching entries by key. Added and
+// changed entries keep the order of cur, removed entries the order of prev.
+func Diff(prev, cur []MapEntry) EntryDiff {
+	old := make(map[string]MapEntry, len(prev))
+	for _, e := range prev {
+		old[hex.EncodeToString(e.Key)] = e
+	}
+
+	var diff EntryDiff
+	seen := make(map[string]bool, len(cur))
+	for _, e := range cur {
+		key := hex.EncodeToString(e.Key)
+		seen[key] = true
+
+		o, ok := old[key]
+		switch {
+		case !ok:
+			diff.Added = append(diff.Added, e)
+		case !sameValue(o, e):
+			diff.Changed = append(diff.Changed, EntryChange{Old: o, New: e})
+		}
+	}
+
+	for _, e := range prev {
+		if !seen[hex.EncodeToString(e.Key)] {
+			diff.Removed = append(diff.Removed, e)
+		}
+	}
+
+	return diff
+}
+
+// sameValue reports whether two entries hold the same value, comparing
+// every CPU's value for per-CPU maps
+func sameValue(a, b MapEntry) bool {
+	return bytes.Equal(a.Value, b.Value) && slices.EqualFunc(a.PerCPUValues, b.PerCPUValues, bytes.Equal)
+}
@@ -0,0 +1,163 @@
+package maps
+
+import (
+	"errors"
+	"fmt"
+	"sync"
+
+	"github.com/cilium/ebpf"
+	"github.com/cilium/ebpf/perf"
+	"github.com/cilium/ebpf/ringbuf"
+)
+
+// ErrNotRingBuffer is returned when read is used on a map that isn't a
+// ring buffer
+var ErrNotRingBuffer = errors.New("read only applies to ring buffer maps")
+
+// ErrNotPerfEventArray is returned when perf-read is used on a map that
+// isn't a perf event array
+var ErrNotPerfEventArray = errors.New("perf-read only applies to perf event array maps")
+
+// Record is a record read from a ring buffer or perf event array map
+type Record struct {
+	// CPU is the CPU a perf event array sample was written on; ring
+	// buffer records leave it zero
+	CPU int
+	// Data is the raw record as submitted by the BPF program
+	Data []byte
+	// Lost is the number of perf event array samples dropped on CPU
+	// because its buffer was full. Records reporting lost samples carry
+	// no data
+	Lost uint64
+}
+
+// RecordReader streams the records of a map as BPF programs submit them
+type RecordReader interface {
+	// Read blocks until the next record is available. Once the reader is
+	// closed it returns an error wrapping os.ErrClosed
+	Read() (Record, error)
+
+	// Close releases the reader and unblocks a pending Read. It may be
+	// called more than once
+	Close() error
+}
+
+// IsRingBufferType reports whether a MapInfo.Type is a ring buffer, whose
+// records are read with OpenRingBuffer
+func IsRingBufferType(mapType string) bool {
+	t, ok := MapTypeFromString(mapType)
+	return ok && t == ebpf.RingBuf
+}
+
+// OpenRingBuffer returns a reader for the records of a ring buffer map
+func (s *serviceImpl) OpenRingBuffer(id uint32) (RecordReader, error) {
+	return s.OpenRingBufferRef(ByID(id))
+}
+
+// OpenRingBufferRef is OpenRingBuffer for a map selected by ID or pinned
+// path
+func (s *serviceImpl) OpenRingBufferRef(ref Ref) (RecordReader, error) {
+	m, info, err := ref.open()
+	if err != nil {
+		return nil, err
+	}
+	if info.Type != ebpf.RingBuf {
+		m.Close()
+		return nil, fmt.Errorf("%s is a %s map: %w", ref, info.Type, ErrNotRingBuffer)
+	}
+
+	r, err := ringbuf.NewReader(m)
+	if err != nil {
+		m.Close()
+		return nil, fmt.Errorf("failed to open ring buffer of %s: %w", ref, err)
+	}
+	return &ringbufReader{m: m, r: r}, nil
+}
+
+// IsPerfEventArrayType reports whether a MapInfo.Type is a perf event
+// array, whose samples are read with OpenPerfEventArray
+func IsPerfEventArrayType(mapType string) bool {
+	t, ok := MapTypeFromString(mapType)
+	return ok && t == ebpf.PerfEventArray
+}
+
+// OpenPerfEventArray returns a reader for the samples of a perf event
+// array map, mapping a buffer of perCPUBuffer bytes (rounded up to pages)
+// for every CPU
+func (s *serviceImpl) OpenPerfEventArray(id uint32, perCPUBuffer int) (RecordReader, error) {
+	return s.OpenPerfEventArrayRef(ByID(id), perCPUBuffer)
+}
+
+// OpenPerfEventArrayRef is OpenPerfEventArray for a map selected by ID or
+// pinned path
+func (s *serviceImpl) OpenPerfEventArrayRef(ref Ref, perCPUBuffer int) (RecordReader, error) {
+	m, info, err := ref.open()
+	if err != nil {
+		return nil, err
+	}
+	if info.Type != ebpf.PerfEventArray {
+		m.Close()
+		return nil, fmt.Errorf("%s is a %s map: %w", ref, info.Type, ErrNotPerfEventArray)
+	}
+
+	r, err := perf.NewReader(m, perCPUBuffer)
+	if err != nil {
+		m.Close()
+		return nil, fmt.Errorf("failed to open perf event array of %s: %w", ref, err)
+	}
+	return &perfReader{m: m, r: r}, nil
+}
+
+// ringbufReader is a RecordReader over a ring buffer map, which it keeps
+// open until closed
+type ringbufReader struct {
+	m    *ebpf.Map
+	r    *ringbuf.Reader
+	once sync.Once
+}
+
+// Read returns the next record of the ring buffer
+func (r *ringbufReader) Read() (Record, error) {
+	rec, err := r.r.Read()
+	if err != nil {
+		return Record{}, fmt.Errorf("failed to read ring buffer: %w", err)
+	}
+	return Record{Data: rec.RawSample}, nil
+}
+
+// Close closes the reader and the map
+func (r *ringbufReader) Close() error {
+	var err error
+	r.once.Do(func() {
+		err = r.r.Close()
+		r.m.Close()
+	})
+	return err
+}
+
+// perfReader is a RecordReader over a perf event array map, which it keeps
+// open until closed
+type perfReader struct {
+	m    *ebpf.Map
+	r    *perf.Reader
+	once sync.Once
+}
+
+// Read returns the next sample or lost-samples notification
+func (r *perfReader) Read() (Record, error) {
+	rec, err := r.r.Read()
+	if err != nil {
+		return Record{}, fmt.Errorf("failed to read perf event array: %w", err)
+	}
+	return Record{CPU: rec.CPU, Data: rec.RawSample, Lost: rec.LostSamples}, nil
+}
+
+// Close closes the reader and the map
+func (r *perfReader) Close() error {
+	var err error
+	r.once.Do(func() {
+		err = r.r.Close()
+		r.m.Close()
+	})
+	return err
+}
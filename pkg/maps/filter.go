@@ -0,0 +1,41 @@
+package maps
+
+import (
+	"strings"
+
+	"github.com/viveksb007/gobpftool/internal/utils"
+)
+
+// Filter selects maps by type and name. Empty fields match everything and
+// set fields are combined with a logical AND.
+type Filter struct {
+	// Type matches MapInfo.Type exactly, ignoring case
+	Type string
+	// NameContains matches maps whose name contains the substring
+	NameContains string
+	// MemlockMin matches maps locking at least this many bytes
+	MemlockMin uint64
+	// IDRange matches maps whose ID falls in the range; nil matches all
+	IDRange *utils.IDRange
+}
+
+// FilterMaps returns the maps matching all of the filter's criteria
+func FilterMaps(maps []MapInfo, f Filter) []MapInfo {
+	var matched []MapInfo
+	for _, m := range maps {
+		if f.Type != "" && !strings.EqualFold(m.Type, f.Type) {
+			continue
+		}
+		if f.NameContains != "" && !strings.Contains(m.Name, f.NameContains) {
+			continue
+		}
+		if uint64(m.MemLock) < f.MemlockMin {
+			continue
+		}
+		if f.IDRange != nil && !f.IDRange.Contains(m.ID) {
+			continue
+		}
+		matched = append(matched, m)
+	}
+	return matched
+}
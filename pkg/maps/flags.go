@@ -0,0 +1,47 @@
+package maps
+
+import "fmt"
+
+// mapFlagNames maps each BPF_F_* map creation flag bit to its name, in bit
+// order
+var mapFlagNames = []struct {
+	bit  uint32
+	name string
+}{
+	{1 << 0, "BPF_F_NO_PREALLOC"},
+	{1 << 1, "BPF_F_NO_COMMON_LRU"},
+	{1 << 2, "BPF_F_NUMA_NODE"},
+	{1 << 3, "BPF_F_RDONLY"},
+	{1 << 4, "BPF_F_WRONLY"},
+	{1 << 5, "BPF_F_STACK_BUILD_ID"},
+	{1 << 6, "BPF_F_ZERO_SEED"},
+	{1 << 7, "BPF_F_RDONLY_PROG"},
+	{1 << 8, "BPF_F_WRONLY_PROG"},
+	{1 << 9, "BPF_F_CLONE"},
+	{1 << 10, "BPF_F_MMAPABLE"},
+	{1 << 11, "BPF_F_PRESERVE_ELEMS"},
+	{1 << 12, "BPF_F_INNER_MAP"},
+	{1 << 13, "BPF_F_LINK"},
+	{1 << 14, "BPF_F_PATH_FD"},
+	{1 << 15, "BPF_F_VTYPE_BTF_OBJ_FD"},
+	{1 << 16, "BPF_F_TOKEN_FD"},
+	{1 << 17, "BPF_F_SEGV_ON_FAULT"},
+	{1 << 18, "BPF_F_NO_USER_CONV"},
+}
+
+// FlagNames returns the symbolic names of the flags set in MapInfo.Flags,
+// lowest bit first. Bits without a known name are returned together as one
+// hex value. It returns nil for no flags
+func FlagNames(flags uint32) []string {
+	var names []string
+	for _, f := range mapFlagNames {
+		if flags&f.bit != 0 {
+			names = append(names, f.name)
+			flags &^= f.bit
+		}
+	}
+	if flags != 0 {
+		names = append(names, fmt.Sprintf("0x%x", flags))
+	}
+	return names
+}
@@ -0,0 +1,53 @@
+package maps
+
+import (
+	"fmt"
+
+	"github.com/cilium/ebpf"
+
+	bpferrors "github.com/viveksb007/gobpftool/pkg/errors"
+)
+
+// DefaultMaxBufferSize is the default sanity limit (64 MiB) on the key and
+// value sizes the kernel reports for a map, and on the keys of a full
+// dump. Buffers are allocated from these sizes, so a corrupt or hostile
+// size would otherwise cause a huge allocation
+const DefaultMaxBufferSize = 64 << 20
+
+// checkBufferSize returns an error wrapping ErrTooLarge when a key or value
+// buffer of size bytes exceeds limit. A zero limit disables the check
+func checkBufferSize(what string, size uint32, limit uint64) error {
+	if limit == 0 || uint64(size) <= limit {
+		return nil
+	}
+	return fmt.Errorf("%s size %dB exceeds the %dB limit: %w", what, size, limit, bpferrors.ErrTooLarge)
+}
+
+// checkDumpSize checks the sizes of a map against limit before a dump
+// that holds all its entries in memory
+func checkDumpSize(info *ebpf.MapInfo, limit uint64) error {
+	return checkSizes(MapInfo{KeySize: info.KeySize, ValueSize: info.ValueSize, MaxEntries: info.MaxEntries}, limit)
+}
+
+// checkEntrySize checks the key and value sizes of a map against limit.
+// A streamed dump only holds one entry at a time, so unlike checkDumpSize
+// it doesn't limit the size of the whole map
+func checkEntrySize(keySize, valueSize uint32, limit uint64) error {
+	if err := checkBufferSize("key", keySize, limit); err != nil {
+		return err
+	}
+	return checkBufferSize("value", valueSize, limit)
+}
+
+// checkSizes checks the key and value sizes of a map against limit, and
+// the size of its keys when it's full
+func checkSizes(info MapInfo, limit uint64) error {
+	if err := checkEntrySize(info.KeySize, info.ValueSize, limit); err != nil {
+		return err
+	}
+	if keys := uint64(info.KeySize) * uint64(info.MaxEntries); limit != 0 && keys > limit {
+		return fmt.Errorf("keys of %d entries of %dB exceed the %dB limit: %w",
+			info.MaxEntries, info.KeySize, limit, bpferrors.ErrTooLarge)
+	}
+	return nil
+}
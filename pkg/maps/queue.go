@@ -0,0 +1,109 @@
+package maps
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/cilium/ebpf"
+	bpferrors "github.com/viveksb007/gobpftool/pkg/errors"
+)
+
+// ErrNotStackOrQueue is returned when push, pop or peek is used on a map
+// that isn't a stack or queue
+var ErrNotStackOrQueue = errors.New("push, pop and peek only apply to stack and queue maps")
+
+// IsStackOrQueueType reports whether a MapInfo.Type is a stack or queue,
+// which have no keys and are accessed with push, pop and peek
+func IsStackOrQueueType(mapType string) bool {
+	t, ok := MapTypeFromString(mapType)
+	return ok && (t == ebpf.Stack || t == ebpf.Queue)
+}
+
+// Push adds a value to a stack or queue map
+func (s *serviceImpl) Push(id uint32, value []byte) error {
+	return s.PushRef(ByID(id), value)
+}
+
+// PushRef is Push for a map selected by ID or pinned path
+func (s *serviceImpl) PushRef(ref Ref, value []byte) error {
+	m, info, err := openStackOrQueue(ref)
+	if err != nil {
+		return err
+	}
+	defer m.Close()
+
+	if uint32(len(value)) != info.ValueSize {
+		return fmt.Errorf("%w: expected %d bytes, got %d", bpferrors.ErrInvalidValue, info.ValueSize, len(value))
+	}
+
+	// Stacks and queues take no key
+	if err := m.Update(nil, value, ebpf.UpdateAny); err != nil {
+		return fmt.Errorf("failed to push value: %w", bpferrors.WrapFrozen(err, info.Frozen()))
+	}
+	return nil
+}
+
+// Pop removes and returns the next value of a stack or queue map
+func (s *serviceImpl) Pop(id uint32) ([]byte, error) {
+	return s.PopRef(ByID(id))
+}
+
+// PopRef is Pop for a map selected by ID or pinned path
+func (s *serviceImpl) PopRef(ref Ref) ([]byte, error) {
+	m, info, err := openStackOrQueue(ref)
+	if err != nil {
+		return nil, err
+	}
+	defer m.Close()
+
+	value := make([]byte, info.ValueSize)
+	if err := m.LookupAndDelete(nil, &value); err != nil {
+		return nil, stackOrQueueError("pop", err)
+	}
+	return value, nil
+}
+
+// Peek returns the next value of a stack or queue map without removing it
+func (s *serviceImpl) Peek(id uint32) ([]byte, error) {
+	return s.PeekRef(ByID(id))
+}
+
+// PeekRef is Peek for a map selected by ID or pinned path
+func (s *serviceImpl) PeekRef(ref Ref) ([]byte, error) {
+	m, info, err := openStackOrQueue(ref)
+	if err != nil {
+		return nil, err
+	}
+	defer m.Close()
+
+	value := make([]byte, info.ValueSize)
+	if err := m.Lookup(nil, &value); err != nil {
+		return nil, stackOrQueueError("peek", err)
+	}
+	return value, nil
+}
+
+// openStackOrQueue opens the map selected by ref, rejecting maps that
+// aren't stacks or queues
+func openStackOrQueue(ref Ref) (*ebpf.Map, *ebpf.MapInfo, error) {
+	m, info, err := ref.open()
+	if err != nil {
+		return nil, nil, err
+	}
+
+	if info.Type != ebpf.Stack && info.Type != ebpf.Queue {
+		m.Close()
+		return nil, nil, fmt.Errorf("%s is a %s map: %w", ref, info.Type, ErrNotStackOrQueue)
+	}
+
+	return m, info, nil
+}
+
+// stackOrQueueError wraps an error from popping or peeking, reporting an
+// empty stack or queue as bpferrors.ErrMapEmpty
+func stackOrQueueError(op string, err error) error {
+	if errors.Is(err, ebpf.ErrKeyNotExist) {
+		return fmt.Errorf("failed to %s value: %w", op, bpferrors.ErrMapEmpty)
+	}
+	return fmt.Errorf("failed to %s value: %w", op, err)
+}
@@ -0,0 +1,73 @@
+package maps
+
+import (
+	"errors"
+	"fmt"
+	"os"
+
+	"github.com/cilium/ebpf"
+
+	bpferrors "github.com/viveksb007/gobpftool/pkg/errors"
+)
+
+// Ref selects a map either by ID or by a path it's pinned at in bpffs.
+// Operations open the map once through the Ref and work on the live
+// handle, so the ID and pinned path entry points share the same logic
+type Ref struct {
+	// ID selects the map by ID when Path is empty
+	ID uint32
+	// Path selects the map pinned at this path
+	Path string
+}
+
+// ByID returns a Ref to the map with the given ID
+func ByID(id uint32) Ref {
+	return Ref{ID: id}
+}
+
+// ByPinnedPath returns a Ref to the map pinned at path
+func ByPinnedPath(path string) Ref {
+	return Ref{Path: path}
+}
+
+// IsPinned reports whether the Ref selects the map by pinned path
+func (r Ref) IsPinned() bool {
+	return r.Path != ""
+}
+
+// String describes the referenced map for error messages
+func (r Ref) String() string {
+	if r.IsPinned() {
+		return "map pinned at " + r.Path
+	}
+	return fmt.Sprintf("map %d", r.ID)
+}
+
+// open opens the referenced map and reads its info. The caller must close
+// the map. A path with nothing pinned returns an error wrapping
+// ErrNotFound
+func (r Ref) open() (*ebpf.Map, *ebpf.MapInfo, error) {
+	var m *ebpf.Map
+	var err error
+	if r.IsPinned() {
+		m, err = ebpf.LoadPinnedMap(r.Path, nil)
+		if errors.Is(err, os.ErrNotExist) {
+			return nil, nil, fmt.Errorf("no map pinned at %s: %w", r.Path, bpferrors.ErrNotFound)
+		}
+		if err != nil {
+			return nil, nil, fmt.Errorf("failed to load pinned map at %s: %w", r.Path, err)
+		}
+	} else {
+		m, err = openMap(ebpf.MapID(r.ID))
+		if err != nil {
+			return nil, nil, fmt.Errorf("failed to get map by ID %d: %w", r.ID, err)
+		}
+	}
+
+	info, err := m.Info()
+	if err != nil {
+		m.Close()
+		return nil, nil, fmt.Errorf("failed to get map info: %w", err)
+	}
+	return m, info, nil
+}
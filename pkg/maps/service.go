@@ -1,9 +1,13 @@
 package maps
 
 import (
+	"context"
 	"time"
 )
 
+// DefaultBatchSize is the number of entries Dump reads per batch lookup
+const DefaultBatchSize = 256
+
 // MapInfo represents information about an eBPF map
 type MapInfo struct {
 	ID         uint32    `json:"id"`
@@ -23,7 +27,19 @@ type MapInfo struct {
 // MapEntry represents a key-value pair in an eBPF map
 type MapEntry struct {
 	Key   []byte `json:"key"`
-	Value []byte `json:"value"`
+	Value []byte `json:"value,omitempty"`
+	// PerCPUValues holds one value per possible CPU for per-CPU maps.
+	// Value is nil when this is set.
+	PerCPUValues [][]byte `json:"per_cpu_values,omitempty"`
+}
+
+// ListResult is the outcome of enumerating the loaded maps
+type ListResult struct {
+	// Maps are the maps that could be read
+	Maps []MapInfo
+	// Skipped is the number of maps that couldn't be opened or read,
+	// usually because of missing privileges
+	Skipped int
 }
 
 // Service provides operations for inspecting eBPF maps
@@ -31,22 +47,170 @@ type Service interface {
 	// List returns all loaded eBPF maps
 	List() ([]MapInfo, error)
 
+	// ListContext is like List but returns ctx's error as soon as ctx is
+	// cancelled
+	ListContext(ctx context.Context) ([]MapInfo, error)
+
+	// ListWithSkipped is like ListContext but also reports how many maps
+	// exist that couldn't be read, e.g. for lack of privileges
+	ListWithSkipped(ctx context.Context) (*ListResult, error)
+
 	// GetByID returns map info by ID
 	GetByID(id uint32) (*MapInfo, error)
 
 	// GetByName returns maps matching the name
 	GetByName(name string) ([]MapInfo, error)
 
+	// GetByNameContext is like GetByName but stops early when ctx is cancelled
+	GetByNameContext(ctx context.Context, name string) ([]MapInfo, error)
+
 	// GetByPinnedPath returns map at the pinned path
 	GetByPinnedPath(path string) (*MapInfo, error)
 
 	// Dump returns all entries in the map
 	Dump(id uint32) ([]MapEntry, error)
 
-	// Lookup returns the value for a key in the map
-	Lookup(id uint32, key []byte) ([]byte, error)
+	// DumpContext is like Dump but stops early when ctx is cancelled,
+	// returning the entries read so far along with ctx's error
+	DumpContext(ctx context.Context, id uint32) ([]MapEntry, error)
+
+	// DumpStream calls fn for every entry in the map as it's read, without
+	// holding all entries in memory. It stops at the first error from fn
+	// and returns it
+	DumpStream(id uint32, fn func(MapEntry) error) error
+
+	// DumpStreamContext is like DumpStream but stops early when ctx is
+	// cancelled
+	DumpStreamContext(ctx context.Context, id uint32, fn func(MapEntry) error) error
+
+	// DumpBatch returns all entries in the map, reading batchSize entries
+	// per syscall when the kernel supports batch lookups
+	DumpBatch(id uint32, batchSize int) ([]MapEntry, error)
+
+	// DumpBatchContext is like DumpBatch but stops early when ctx is
+	// cancelled, returning the entries read so far along with ctx's error
+	DumpBatchContext(ctx context.Context, id uint32, batchSize int) ([]MapEntry, error)
+
+	// Lookup returns the entry for a key in the map
+	Lookup(id uint32, key []byte) (*MapEntry, error)
+
+	// DumpDecoded returns all entries in the map with keys and values
+	// decoded using the map's BTF, falling back to raw bytes without BTF
+	DumpDecoded(id uint32) ([]DecodedEntry, error)
+
+	// DumpDecodedContext is like DumpDecoded but stops early when ctx is
+	// cancelled, returning the entries read so far along with ctx's error
+	DumpDecodedContext(ctx context.Context, id uint32) ([]DecodedEntry, error)
+
+	// LookupDecoded returns the entry for a key decoded using the map's BTF
+	LookupDecoded(id uint32, key []byte) (*DecodedEntry, error)
+
+	// Clear deletes every entry in the map and returns the number removed
+	Clear(id uint32) (int, error)
+
+	// ClearContext is like Clear but stops early when ctx is cancelled,
+	// returning the number of entries removed so far
+	ClearContext(ctx context.Context, id uint32) (int, error)
+
+	// Update sets the value of a key, creating the entry when it doesn't
+	// exist. Per-CPU maps get the value on every CPU
+	Update(id uint32, key, value []byte) error
+
+	// Delete removes the entry for a key. Array maps, whose elements
+	// can't be deleted, return an error wrapping ErrDeleteNotSupported
+	Delete(id uint32, key []byte) error
+
+	// Push adds a value to a stack or queue map
+	Push(id uint32, value []byte) error
+
+	// Pop removes and returns the next value of a stack or queue map
+	Pop(id uint32) ([]byte, error)
+
+	// Peek returns the next value of a stack or queue map without
+	// removing it
+	Peek(id uint32) ([]byte, error)
+
+	// CountEntries counts the populated entries of a map by walking its
+	// keys, stopping after limit keys (0 for no limit). It reports whether
+	// it stopped at limit, meaning there are at least that many
+	CountEntries(id uint32, limit int) (int, bool, error)
+
+	// OpenRingBuffer returns a reader for the records of a ring buffer
+	// map. The caller must close it
+	OpenRingBuffer(id uint32) (RecordReader, error)
+
+	// OpenPerfEventArray returns a reader for the samples of a perf event
+	// array map with a buffer of perCPUBuffer bytes per CPU. The caller
+	// must close it
+	OpenPerfEventArray(id uint32, perCPUBuffer int) (RecordReader, error)
+
+	// Freeze makes a map read-only from user space. BPF programs can still
+	// write to it, and it can't be unfrozen
+	Freeze(id uint32) error
+
+	// Pin pins a map to path, creating parent directories as needed
+	Pin(id uint32, path string) error
+
+	// Unpin removes the map pinned at path
+	Unpin(path string) error
 
 	// GetNextKey returns the next key after the given key
 	// If key is nil, returns the first key
 	GetNextKey(id uint32, key []byte) ([]byte, error)
+
+	// The Ref methods are like their ID counterparts but take the map by
+	// ID or by a path it's pinned at. A pinned map is opened from its pin,
+	// so it can't be swapped for another map reusing its ID in between
+
+	// GetByRef returns info of the map selected by ref
+	GetByRef(ref Ref) (*MapInfo, error)
+
+	// DumpRef is like DumpBatchContext for the map selected by ref
+	DumpRef(ctx context.Context, ref Ref, batchSize int) ([]MapEntry, error)
+
+	// DumpStreamRef is like DumpStreamContext for the map selected by ref
+	DumpStreamRef(ctx context.Context, ref Ref, fn func(MapEntry) error) error
+
+	// LookupRef is like Lookup for the map selected by ref
+	LookupRef(ref Ref, key []byte) (*MapEntry, error)
+
+	// DumpDecodedRef is like DumpDecodedContext for the map selected by ref
+	DumpDecodedRef(ctx context.Context, ref Ref) ([]DecodedEntry, error)
+
+	// LookupDecodedRef is like LookupDecoded for the map selected by ref
+	LookupDecodedRef(ref Ref, key []byte) (*DecodedEntry, error)
+
+	// ClearRef is like ClearContext for the map selected by ref
+	ClearRef(ctx context.Context, ref Ref) (int, error)
+
+	// UpdateRef is like Update for the map selected by ref
+	UpdateRef(ref Ref, key, value []byte) error
+
+	// DeleteRef is like Delete for the map selected by ref
+	DeleteRef(ref Ref, key []byte) error
+
+	// PushRef is like Push for the map selected by ref
+	PushRef(ref Ref, value []byte) error
+
+	// PopRef is like Pop for the map selected by ref
+	PopRef(ref Ref) ([]byte, error)
+
+	// PeekRef is like Peek for the map selected by ref
+	PeekRef(ref Ref) ([]byte, error)
+
+	// OpenRingBufferRef is like OpenRingBuffer for the map selected by ref
+	OpenRingBufferRef(ref Ref) (RecordReader, error)
+
+	// OpenPerfEventArrayRef is like OpenPerfEventArray for the map
+	// selected by ref
+	OpenPerfEventArrayRef(ref Ref, perCPUBuffer int) (RecordReader, error)
+
+	// FreezeRef is like Freeze for the map selected by ref
+	FreezeRef(ref Ref) error
+
+	// PinRef is like Pin for the map selected by ref
+	PinRef(ref Ref, path string) error
+
+	// GetNextKeyRef is like GetNextKey for the map selected by ref
+	GetNextKeyRef(ref Ref, key []byte) ([]byte, error)
 }
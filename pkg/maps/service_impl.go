@@ -1,32 +1,100 @@
 package maps
 
 import (
+	"context"
+	"errors"
 	"fmt"
-	"strings"
+	"os"
+	"path/filepath"
+	"reflect"
 
 	"github.com/cilium/ebpf"
 	"github.com/viveksb007/gobpftool/internal/bpffs"
+	"github.com/viveksb007/gobpftool/internal/utils"
+	bpferrors "github.com/viveksb007/gobpftool/pkg/errors"
+	"golang.org/x/sys/unix"
 )
 
+// pinnedPathResolver looks up the bpffs paths a map is pinned at
+type pinnedPathResolver interface {
+	GetMapPinnedPaths(id uint32) []string
+}
+
 // serviceImpl implements the Service interface using cilium/ebpf
-type serviceImpl struct{}
+type serviceImpl struct {
+	// pinned resolves pinned paths; nil disables the bpffs scan
+	pinned pinnedPathResolver
+	// maxBuffer caps the key and value buffers allocated from map sizes
+	maxBuffer uint64
+	// cache holds recently read map info; nil keeps the service stateless
+	cache *infoCache
+}
+
+// Option configures the map service
+type Option func(*serviceImpl)
+
+// WithoutPinnedPaths disables the bpffs scan used to populate PinnedPaths
+func WithoutPinnedPaths() Option {
+	return func(s *serviceImpl) {
+		s.pinned = nil
+	}
+}
+
+// WithMaxBufferSize sets the sanity limit on the key and value sizes the
+// kernel reports for a map, see DefaultMaxBufferSize
+func WithMaxBufferSize(n uint64) Option {
+	return func(s *serviceImpl) {
+		s.maxBuffer = n
+	}
+}
 
 // NewService creates a new map service instance
-func NewService() Service {
-	return &serviceImpl{}
+func NewService(opts ...Option) Service {
+	s := &serviceImpl{pinned: bpffs.GetScanner(), maxBuffer: DefaultMaxBufferSize}
+	for _, opt := range opts {
+		opt(s)
+	}
+	return s
 }
 
 // List returns all loaded eBPF maps
 func (s *serviceImpl) List() ([]MapInfo, error) {
-	var maps []MapInfo
+	return s.ListContext(context.Background())
+}
+
+// ListContext returns all loaded eBPF maps, checking ctx between maps
+func (s *serviceImpl) ListContext(ctx context.Context) ([]MapInfo, error) {
+	result, err := s.ListWithSkipped(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return result.Maps, nil
+}
+
+// newMapFromID opens a map by ID. Tests replace it to simulate maps that
+// can't be opened
+var newMapFromID = ebpf.NewMapFromID
+
+// openMap opens a map by ID, retrying briefly when the kernel reports it
+// doesn't exist, see utils.OpenByID
+func openMap(id ebpf.MapID) (*ebpf.Map, error) {
+	return utils.OpenByID(newMapFromID, id)
+}
+
+// ListWithSkipped returns all loaded eBPF maps along with the number of maps
+// that couldn't be read. Maps freed since they were enumerated aren't
+// counted
+func (s *serviceImpl) ListWithSkipped(ctx context.Context) (*ListResult, error) {
+	result := &ListResult{}
 
 	var id ebpf.MapID
 	firstIteration := true
 
-	// Get the scanner for pinned paths
-	scanner := bpffs.GetScanner()
-
 	for {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+
 		nextID, err := ebpf.MapGetNextID(id)
 		if err != nil {
 			// If this is the first iteration and we get an error, it's likely a permission issue
@@ -39,30 +107,44 @@ func (s *serviceImpl) List() ([]MapInfo, error) {
 		firstIteration = false
 		id = nextID
 
-		m, err := ebpf.NewMapFromID(id)
+		if cached, ok := s.cache.get(uint32(id)); ok {
+			result.Maps = append(result.Maps, *cached)
+			continue
+		}
+
+		m, err := openMap(id)
 		if err != nil {
 			// Skip maps we can't access
+			if !errors.Is(err, os.ErrNotExist) {
+				result.Skipped++
+			}
 			continue
 		}
 
 		mapInfo, err := s.mapToMapInfo(m)
 		m.Close()
 		if err != nil {
+			result.Skipped++
 			continue
 		}
 
 		// Add pinned paths
-		mapInfo.PinnedPaths = scanner.GetMapPinnedPaths(mapInfo.ID)
+		mapInfo.PinnedPaths = s.pinnedPaths(mapInfo.ID)
+		s.cache.put(mapInfo)
 
-		maps = append(maps, *mapInfo)
+		result.Maps = append(result.Maps, *mapInfo)
 	}
 
-	return maps, nil
+	return result, nil
 }
 
 // GetByID returns map info by ID
 func (s *serviceImpl) GetByID(id uint32) (*MapInfo, error) {
-	m, err := ebpf.NewMapFromID(ebpf.MapID(id))
+	if cached, ok := s.cache.get(id); ok {
+		return cached, nil
+	}
+
+	m, err := openMap(ebpf.MapID(id))
 	if err != nil {
 		return nil, fmt.Errorf("failed to get map by ID %d: %w", id, err)
 	}
@@ -74,15 +156,21 @@ func (s *serviceImpl) GetByID(id uint32) (*MapInfo, error) {
 	}
 
 	// Add pinned paths
-	scanner := bpffs.GetScanner()
-	mapInfo.PinnedPaths = scanner.GetMapPinnedPaths(mapInfo.ID)
+	mapInfo.PinnedPaths = s.pinnedPaths(mapInfo.ID)
+	s.cache.put(mapInfo)
 
 	return mapInfo, nil
 }
 
 // GetByName returns maps matching the name
 func (s *serviceImpl) GetByName(name string) ([]MapInfo, error) {
-	allMaps, err := s.List()
+	return s.GetByNameContext(context.Background(), name)
+}
+
+// GetByNameContext returns maps matching the name, stopping early when ctx
+// is cancelled
+func (s *serviceImpl) GetByNameContext(ctx context.Context, name string) ([]MapInfo, error) {
+	allMaps, err := s.ListContext(ctx)
 	if err != nil {
 		return nil, err
 	}
@@ -99,113 +187,495 @@ func (s *serviceImpl) GetByName(name string) ([]MapInfo, error) {
 
 // GetByPinnedPath returns map at the pinned path
 func (s *serviceImpl) GetByPinnedPath(path string) (*MapInfo, error) {
-	m, err := ebpf.LoadPinnedMap(path, nil)
+	return s.GetByRef(ByPinnedPath(path))
+}
+
+// GetByRef returns info of the map selected by ID or pinned path
+func (s *serviceImpl) GetByRef(ref Ref) (*MapInfo, error) {
+	if !ref.IsPinned() {
+		return s.GetByID(ref.ID)
+	}
+
+	m, _, err := ref.open()
 	if err != nil {
-		return nil, fmt.Errorf("failed to load pinned map at %s: %w", path, err)
+		return nil, err
 	}
 	defer m.Close()
 
-	return s.mapToMapInfo(m)
+	mapInfo, err := s.mapToMapInfo(m)
+	if err != nil {
+		return nil, err
+	}
+
+	// Add pinned paths
+	mapInfo.PinnedPaths = s.pinnedPaths(mapInfo.ID)
+	s.cache.put(mapInfo)
+
+	return mapInfo, nil
 }
 
 // Dump returns all entries in the map
 func (s *serviceImpl) Dump(id uint32) ([]MapEntry, error) {
-	m, err := ebpf.NewMapFromID(ebpf.MapID(id))
+	return s.DumpContext(context.Background(), id)
+}
+
+// DumpContext returns all entries in the map, stopping early when ctx is
+// cancelled with the entries read so far and ctx's error
+func (s *serviceImpl) DumpContext(ctx context.Context, id uint32) ([]MapEntry, error) {
+	return s.DumpBatchContext(ctx, id, DefaultBatchSize)
+}
+
+// DumpBatch returns all entries in the map, reading up to batchSize
+// entries per syscall when the kernel supports batch lookups. It falls
+// back to iterating one key at a time otherwise.
+func (s *serviceImpl) DumpBatch(id uint32, batchSize int) ([]MapEntry, error) {
+	return s.DumpBatchContext(context.Background(), id, batchSize)
+}
+
+// DumpBatchContext is DumpBatch with ctx checked between batches or keys.
+// A cancelled dump returns the entries read so far with ctx's error
+func (s *serviceImpl) DumpBatchContext(ctx context.Context, id uint32, batchSize int) ([]MapEntry, error) {
+	return s.DumpRef(ctx, ByID(id), batchSize)
+}
+
+// DumpRef is DumpBatchContext for a map selected by ID or pinned path
+func (s *serviceImpl) DumpRef(ctx context.Context, ref Ref, batchSize int) ([]MapEntry, error) {
+	m, info, err := ref.open()
 	if err != nil {
-		return nil, fmt.Errorf("failed to get map by ID %d: %w", id, err)
+		return nil, err
 	}
 	defer m.Close()
 
+	if err := checkDumpSize(info, s.maxBuffer); err != nil {
+		return nil, fmt.Errorf("%s: %w", ref, err)
+	}
+
+	// Per-CPU values aren't batched; they need the iterator's unmarshaling
+	if batchSize > 0 && !isPerCPU(info.Type) {
+		entries, err := dumpBatch(ctx, m, info, batchSize)
+		if err == nil {
+			return entries, nil
+		}
+		if ctx.Err() != nil {
+			return entries, err
+		}
+		if !batchUnsupported(err) {
+			return nil, fmt.Errorf("failed to batch lookup map entries: %w", err)
+		}
+	}
+
+	return dumpIterate(ctx, m, info)
+}
+
+// batchUnsupported reports whether a batch lookup failed because the
+// kernel or map type can't do it, so iterating should be tried instead
+func batchUnsupported(err error) bool {
+	return errors.Is(err, ebpf.ErrNotSupported) ||
+		errors.Is(err, unix.EINVAL) ||
+		errors.Is(err, unix.ENOTSUP) ||
+		// Hash maps return ENOSPC when a bucket doesn't fit in the batch
+		errors.Is(err, unix.ENOSPC)
+}
+
+// dumpBatch reads all entries with BPF_MAP_LOOKUP_BATCH. Once ctx is
+// cancelled it returns the entries read so far with ctx's error
+func dumpBatch(ctx context.Context, m *ebpf.Map, info *ebpf.MapInfo, batchSize int) ([]MapEntry, error) {
+	// Slices of fixed-size arrays let the kernel fill keys and values
+	// in place
+	keys := reflect.MakeSlice(reflect.SliceOf(reflect.ArrayOf(int(info.KeySize), byteType)), batchSize, batchSize)
+	values := reflect.MakeSlice(reflect.SliceOf(reflect.ArrayOf(int(info.ValueSize), byteType)), batchSize, batchSize)
+
 	var entries []MapEntry
+	cursor := new(ebpf.MapBatchCursor)
+	for {
+		if err := ctx.Err(); err != nil {
+			return entries, err
+		}
 
-	// Get map info to determine key and value sizes
-	info, err := m.Info()
+		n, err := m.BatchLookup(cursor, keys.Interface(), values.Interface(), nil)
+
+		// Copy out of the buffers since they're reused by the next batch
+		for i := 0; i < n; i++ {
+			entries = append(entries, MapEntry{
+				Key:   append([]byte(nil), keys.Index(i).Bytes()...),
+				Value: append([]byte(nil), values.Index(i).Bytes()...),
+			})
+		}
+
+		if errors.Is(err, ebpf.ErrKeyNotExist) {
+			return entries, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+	}
+}
+
+// byteType is the reflect type of a single byte
+var byteType = reflect.TypeOf(byte(0))
+
+// DumpStream calls fn for every entry in the map, one key at a time
+func (s *serviceImpl) DumpStream(id uint32, fn func(MapEntry) error) error {
+	return s.DumpStreamContext(context.Background(), id, fn)
+}
+
+// DumpStreamContext is DumpStream with ctx checked before each entry
+func (s *serviceImpl) DumpStreamContext(ctx context.Context, id uint32, fn func(MapEntry) error) error {
+	return s.DumpStreamRef(ctx, ByID(id), fn)
+}
+
+// DumpStreamRef is DumpStreamContext for a map selected by ID or pinned path
+func (s *serviceImpl) DumpStreamRef(ctx context.Context, ref Ref, fn func(MapEntry) error) error {
+	m, info, err := ref.open()
 	if err != nil {
-		return nil, fmt.Errorf("failed to get map info: %w", err)
+		return err
 	}
+	defer m.Close()
+
+	if err := checkEntrySize(info.KeySize, info.ValueSize, s.maxBuffer); err != nil {
+		return fmt.Errorf("%s: %w", ref, err)
+	}
+
+	return iterateEntries(ctx, m, info, fn)
+}
 
-	keySize := info.KeySize
-	valueSize := info.ValueSize
+// dumpIterate reads all entries one key at a time. Once ctx is cancelled
+// it returns the entries read so far with ctx's error
+func dumpIterate(ctx context.Context, m *ebpf.Map, info *ebpf.MapInfo) ([]MapEntry, error) {
+	var entries []MapEntry
+	err := iterateEntries(ctx, m, info, func(e MapEntry) error {
+		entries = append(entries, e)
+		return nil
+	})
+	if err != nil && ctx.Err() == nil {
+		return nil, err
+	}
+	return entries, err
+}
 
+// iterateEntries calls fn for every entry, one key at a time. The entries
+// passed to fn don't share memory with the iteration buffers. It returns
+// ctx's error once ctx is cancelled
+func iterateEntries(ctx context.Context, m *ebpf.Map, info *ebpf.MapInfo, fn func(MapEntry) error) error {
 	// Create buffers for keys and values
-	key := make([]byte, keySize)
-	value := make([]byte, valueSize)
+	key := make([]byte, info.KeySize)
+	value := make([]byte, info.ValueSize)
+
+	// Per-CPU maps return one value per possible CPU
+	perCPU := isPerCPU(info.Type)
+	var perCPUValue [][]byte
+	var valueOut interface{} = &value
+	if perCPU {
+		valueOut = &perCPUValue
+	}
 
 	// Iterate through all entries
 	iter := m.Iterate()
-	for iter.Next(&key, &value) {
+	for ctx.Err() == nil && iter.Next(&key, valueOut) {
 		// Make copies of the key and value since they're reused
 		keyCopy := make([]byte, len(key))
-		valueCopy := make([]byte, len(value))
 		copy(keyCopy, key)
-		copy(valueCopy, value)
 
-		entries = append(entries, MapEntry{
-			Key:   keyCopy,
-			Value: valueCopy,
-		})
+		entry := MapEntry{Key: keyCopy}
+		if perCPU {
+			entry.PerCPUValues = copyPerCPUValues(perCPUValue)
+		} else {
+			entry.Value = make([]byte, len(value))
+			copy(entry.Value, value)
+		}
+
+		if err := fn(entry); err != nil {
+			return err
+		}
 	}
 
+	if err := ctx.Err(); err != nil {
+		return err
+	}
 	if err := iter.Err(); err != nil {
-		return nil, fmt.Errorf("failed to iterate map entries: %w", err)
+		return fmt.Errorf("failed to iterate map entries: %w", err)
 	}
 
-	return entries, nil
+	return nil
+}
+
+// Lookup returns the entry for a key in the map
+func (s *serviceImpl) Lookup(id uint32, key []byte) (*MapEntry, error) {
+	return s.LookupRef(ByID(id), key)
 }
 
-// Lookup returns the value for a key in the map
-func (s *serviceImpl) Lookup(id uint32, key []byte) ([]byte, error) {
-	m, err := ebpf.NewMapFromID(ebpf.MapID(id))
+// LookupRef is Lookup for a map selected by ID or pinned path
+func (s *serviceImpl) LookupRef(ref Ref, key []byte) (*MapEntry, error) {
+	m, info, err := ref.open()
 	if err != nil {
-		return nil, fmt.Errorf("failed to get map by ID %d: %w", id, err)
+		return nil, err
 	}
 	defer m.Close()
 
-	// Get map info to determine value size
-	info, err := m.Info()
-	if err != nil {
-		return nil, fmt.Errorf("failed to get map info: %w", err)
+	if err := checkBufferSize("value", info.ValueSize, s.maxBuffer); err != nil {
+		return nil, fmt.Errorf("%s: %w", ref, err)
+	}
+
+	// Per-CPU maps return one value per possible CPU
+	if isPerCPU(info.Type) {
+		var perCPUValue [][]byte
+		if err := m.Lookup(key, &perCPUValue); err != nil {
+			return nil, fmt.Errorf("failed to lookup key: %w", err)
+		}
+		return &MapEntry{Key: key, PerCPUValues: perCPUValue}, nil
 	}
 
 	// Create buffer for value
 	value := make([]byte, info.ValueSize)
 
 	// Lookup the key
-	err = m.Lookup(key, &value)
-	if err != nil {
+	if err := m.Lookup(key, &value); err != nil {
 		return nil, fmt.Errorf("failed to lookup key: %w", err)
 	}
 
-	return value, nil
+	return &MapEntry{Key: key, Value: value}, nil
+}
+
+// Update sets the value of a key in the map, creating the entry when it
+// doesn't exist. Per-CPU maps get the same value on every CPU
+func (s *serviceImpl) Update(id uint32, key, value []byte) error {
+	return s.UpdateRef(ByID(id), key, value)
+}
+
+// UpdateRef is Update for a map selected by ID or pinned path
+func (s *serviceImpl) UpdateRef(ref Ref, key, value []byte) error {
+	m, info, err := ref.open()
+	if err != nil {
+		return err
+	}
+	defer m.Close()
+
+	if uint32(len(key)) != info.KeySize {
+		return fmt.Errorf("%w: expected %d key bytes, got %d", bpferrors.ErrInvalidKey, info.KeySize, len(key))
+	}
+	if uint32(len(value)) != info.ValueSize {
+		return fmt.Errorf("%w: expected %d value bytes, got %d", bpferrors.ErrInvalidValue, info.ValueSize, len(value))
+	}
+
+	var newValue interface{} = value
+	if isPerCPU(info.Type) {
+		cpus, err := ebpf.PossibleCPU()
+		if err != nil {
+			return fmt.Errorf("failed to get possible CPUs: %w", err)
+		}
+		perCPUValue := make([][]byte, cpus)
+		for i := range perCPUValue {
+			perCPUValue[i] = value
+		}
+		newValue = perCPUValue
+	}
+
+	if err := m.Update(key, newValue, ebpf.UpdateAny); err != nil {
+		return fmt.Errorf("failed to update key: %w", bpferrors.WrapFrozen(err, info.Frozen()))
+	}
+	return nil
+}
+
+// DumpDecoded returns all entries in the map with keys and values decoded
+// using the map's BTF. Entries are left undecoded when the map has no BTF.
+func (s *serviceImpl) DumpDecoded(id uint32) ([]DecodedEntry, error) {
+	return s.DumpDecodedContext(context.Background(), id)
+}
+
+// DumpDecodedContext is DumpDecoded, stopping early when ctx is cancelled
+// with the entries read so far and ctx's error
+func (s *serviceImpl) DumpDecodedContext(ctx context.Context, id uint32) ([]DecodedEntry, error) {
+	return s.DumpDecodedRef(ctx, ByID(id))
+}
+
+// DumpDecodedRef is DumpDecodedContext for a map selected by ID or pinned
+// path
+func (s *serviceImpl) DumpDecodedRef(ctx context.Context, ref Ref) ([]DecodedEntry, error) {
+	decoder, err := s.decoder(ref)
+	if err != nil {
+		return nil, err
+	}
+
+	entries, dumpErr := s.DumpRef(ctx, ref, DefaultBatchSize)
+	if dumpErr != nil && ctx.Err() == nil {
+		return nil, dumpErr
+	}
+
+	decoded := make([]DecodedEntry, len(entries))
+	for i, entry := range entries {
+		if decoded[i], err = decoder.decode(entry); err != nil {
+			return nil, err
+		}
+	}
+
+	return decoded, dumpErr
+}
+
+// LookupDecoded returns the entry for a key with its key and value decoded
+// using the map's BTF
+func (s *serviceImpl) LookupDecoded(id uint32, key []byte) (*DecodedEntry, error) {
+	return s.LookupDecodedRef(ByID(id), key)
+}
+
+// LookupDecodedRef is LookupDecoded for a map selected by ID or pinned path
+func (s *serviceImpl) LookupDecodedRef(ref Ref, key []byte) (*DecodedEntry, error) {
+	decoder, err := s.decoder(ref)
+	if err != nil {
+		return nil, err
+	}
+
+	entry, err := s.LookupRef(ref, key)
+	if err != nil {
+		return nil, err
+	}
+
+	decoded, err := decoder.decode(*entry)
+	if err != nil {
+		return nil, err
+	}
+
+	return &decoded, nil
+}
+
+// Freeze makes a map read-only from user space
+func (s *serviceImpl) Freeze(id uint32) error {
+	return s.FreezeRef(ByID(id))
+}
+
+// FreezeRef is Freeze for a map selected by ID or pinned path
+func (s *serviceImpl) FreezeRef(ref Ref) error {
+	m, _, err := ref.open()
+	if err != nil {
+		return err
+	}
+	defer m.Close()
+
+	if err := m.Freeze(); err != nil {
+		return fmt.Errorf("failed to freeze %s: %w", ref, err)
+	}
+	return nil
+}
+
+// Pin pins a map to path, creating parent directories as needed
+func (s *serviceImpl) Pin(id uint32, path string) error {
+	return s.PinRef(ByID(id), path)
+}
+
+// PinRef is Pin for a map selected by ID or pinned path
+func (s *serviceImpl) PinRef(ref Ref, path string) error {
+	m, _, err := ref.open()
+	if err != nil {
+		return err
+	}
+	defer m.Close()
+
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return fmt.Errorf("failed to create directory for %s: %w", path, err)
+	}
+	if err := m.Pin(path); err != nil {
+		return fmt.Errorf("failed to pin %s at %s: %w", ref, path, err)
+	}
+
+	s.refreshPinnedPaths()
+	return nil
+}
+
+// Unpin removes the map pinned at path. It returns an error wrapping
+// ErrNotFound if nothing is pinned there.
+func (s *serviceImpl) Unpin(path string) error {
+	m, err := ebpf.LoadPinnedMap(path, nil)
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return fmt.Errorf("no map pinned at %s: %w", path, bpferrors.ErrNotFound)
+		}
+		return fmt.Errorf("failed to load pinned map at %s: %w", path, err)
+	}
+	defer m.Close()
+
+	if err := m.Unpin(); err != nil {
+		return fmt.Errorf("failed to unpin map at %s: %w", path, err)
+	}
+
+	s.refreshPinnedPaths()
+	return nil
+}
+
+// refreshPinnedPaths rescans bpffs so later lookups see pin changes
+func (s *serviceImpl) refreshPinnedPaths() {
+	s.cache.invalidate()
+	if r, ok := s.pinned.(interface{ Refresh() }); ok {
+		r.Refresh()
+	}
+}
+
+// decoder returns the BTF decoder for a map, or nil if it has no BTF
+func (s *serviceImpl) decoder(ref Ref) (*btfDecoder, error) {
+	m, _, err := ref.open()
+	if err != nil {
+		return nil, err
+	}
+	defer m.Close()
+
+	return newBTFDecoder(m)
 }
 
 // GetNextKey returns the next key after the given key
 // If key is nil, returns the first key
 func (s *serviceImpl) GetNextKey(id uint32, key []byte) ([]byte, error) {
-	m, err := ebpf.NewMapFromID(ebpf.MapID(id))
+	return s.GetNextKeyRef(ByID(id), key)
+}
+
+// GetNextKeyRef is GetNextKey for a map selected by ID or pinned path
+func (s *serviceImpl) GetNextKeyRef(ref Ref, key []byte) ([]byte, error) {
+	m, info, err := ref.open()
 	if err != nil {
-		return nil, fmt.Errorf("failed to get map by ID %d: %w", id, err)
+		return nil, err
 	}
 	defer m.Close()
 
-	// Get map info to determine key size
-	info, err := m.Info()
-	if err != nil {
-		return nil, fmt.Errorf("failed to get map info: %w", err)
+	if err := checkBufferSize("key", info.KeySize, s.maxBuffer); err != nil {
+		return nil, fmt.Errorf("%s: %w", ref, err)
 	}
 
 	// Create buffer for next key
 	nextKey := make([]byte, info.KeySize)
 
 	// Get next key
-	err = m.NextKey(key, &nextKey)
-	if err != nil {
+	if err := m.NextKey(key, &nextKey); err != nil {
 		return nil, fmt.Errorf("failed to get next key: %w", err)
 	}
 
 	return nextKey, nil
 }
 
+// pinnedPaths returns the pinned paths for a map, or nil when the bpffs scan is disabled
+func (s *serviceImpl) pinnedPaths(id uint32) []string {
+	if s.pinned == nil {
+		return nil
+	}
+	return s.pinned.GetMapPinnedPaths(id)
+}
+
+// isPerCPU reports whether the map type stores a separate value per possible CPU.
+func isPerCPU(t ebpf.MapType) bool {
+	switch t {
+	case ebpf.PerCPUHash, ebpf.PerCPUArray, ebpf.LRUCPUHash, ebpf.PerCPUCGroupStorage:
+		return true
+	default:
+		return false
+	}
+}
+
+// copyPerCPUValues deep-copies per-CPU values so they don't alias reused buffers.
+func copyPerCPUValues(values [][]byte) [][]byte {
+	result := make([][]byte, len(values))
+	for i, v := range values {
+		result[i] = make([]byte, len(v))
+		copy(result[i], v)
+	}
+	return result
+}
+
 // mapToMapInfo converts an ebpf.Map to MapInfo
 func (s *serviceImpl) mapToMapInfo(m *ebpf.Map) (*MapInfo, error) {
 	info, err := m.Info()
@@ -214,11 +684,17 @@ func (s *serviceImpl) mapToMapInfo(m *ebpf.Map) (*MapInfo, error) {
 	}
 
 	// Convert map type to string
-	mapType := strings.ToLower(info.Type.String())
+	mapType := MapTypeName(info.Type)
 
 	// Get the map ID - info.ID() returns (MapID, bool)
 	mapID, _ := info.ID()
 
+	// Memlock comes from fdinfo; read it ourselves if cilium/ebpf couldn't
+	memlock, ok := info.Memlock()
+	if !ok {
+		memlock, _ = utils.ReadFdInfoUint(m.FD(), "memlock")
+	}
+
 	mapInfo := &MapInfo{
 		ID:         uint32(mapID),
 		Type:       mapType,
@@ -227,6 +703,7 @@ func (s *serviceImpl) mapToMapInfo(m *ebpf.Map) (*MapInfo, error) {
 		ValueSize:  info.ValueSize,
 		MaxEntries: info.MaxEntries,
 		Flags:      uint32(info.Flags),
+		MemLock:    uint32(memlock),
 	}
 
 	return mapInfo, nil
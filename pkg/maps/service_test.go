@@ -1,8 +1,23 @@
 package maps
 
 import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
 	"testing"
 	"time"
+
+	"github.com/cilium/ebpf"
+	"github.com/cilium/ebpf/btf"
+	"github.com/viveksb007/gobpftool/internal/utils"
+	bpferrors "github.com/viveksb007/gobpftool/pkg/errors"
+	"golang.org/x/sys/unix"
 )
 
 func TestMapInfo_JSONTags(t *testing.T) {
@@ -111,3 +126,1393 @@ func TestServiceImpl_Interface(t *testing.T) {
 	_ = service.Lookup
 	_ = service.GetNextKey
 }
+
+func TestIsPerCPU(t *testing.T) {
+	tests := []struct {
+		mapType ebpf.MapType
+		want    bool
+	}{
+		{ebpf.Hash, false},
+		{ebpf.Array, false},
+		{ebpf.PerCPUHash, true},
+		{ebpf.PerCPUArray, true},
+		{ebpf.LRUCPUHash, true},
+	}
+
+	for _, tt := range tests {
+		if got := isPerCPU(tt.mapType); got != tt.want {
+			t.Errorf("isPerCPU(%v) = %v, want %v", tt.mapType, got, tt.want)
+		}
+	}
+}
+
+// fakePinnedPaths is an in-memory pinnedPathResolver for testing.
+type fakePinnedPaths map[uint32][]string
+
+func (f fakePinnedPaths) GetMapPinnedPaths(id uint32) []string {
+	return f[id]
+}
+
+func TestPinnedPaths(t *testing.T) {
+	svc := &serviceImpl{pinned: fakePinnedPaths{
+		1: {"/sys/fs/bpf/a", "/sys/fs/bpf/b"},
+	}}
+
+	if paths := svc.pinnedPaths(1); len(paths) != 2 {
+		t.Errorf("Expected 2 pinned paths, got %d", len(paths))
+	}
+	if paths := svc.pinnedPaths(2); len(paths) != 0 {
+		t.Errorf("Expected 0 pinned paths, got %d", len(paths))
+	}
+
+	WithoutPinnedPaths()(svc)
+	if paths := svc.pinnedPaths(1); paths != nil {
+		t.Errorf("Expected nil pinned paths when disabled, got %v", paths)
+	}
+}
+
+func TestFilterMaps(t *testing.T) {
+	allMaps := []MapInfo{
+		{ID: 1, Type: "hash", Name: "conn_track", MemLock: 1 << 20},
+		{ID: 2, Type: "array", Name: "conn_stats", MemLock: 4096},
+		{ID: 3, Type: "hash", Name: "events", MemLock: 10 << 20},
+		{ID: 4, Type: "percpuarray", Name: "counters"},
+	}
+
+	tests := []struct {
+		name    string
+		filter  Filter
+		wantIDs []uint32
+	}{
+		{
+			name:    "no filter",
+			filter:  Filter{},
+			wantIDs: []uint32{1, 2, 3, 4},
+		},
+		{
+			name:    "empty result",
+			filter:  Filter{Type: "lpmtrie"},
+			wantIDs: nil,
+		},
+		{
+			name:    "single match by type",
+			filter:  Filter{Type: "PerCPUArray"},
+			wantIDs: []uint32{4},
+		},
+		{
+			name:    "multi match by type",
+			filter:  Filter{Type: "hash"},
+			wantIDs: []uint32{1, 3},
+		},
+		{
+			name:    "multi match by name",
+			filter:  Filter{NameContains: "conn"},
+			wantIDs: []uint32{1, 2},
+		},
+		{
+			name:    "type and name combined",
+			filter:  Filter{Type: "hash", NameContains: "conn"},
+			wantIDs: []uint32{1},
+		},
+		{
+			name:    "type and name without overlap",
+			filter:  Filter{Type: "array", NameContains: "events"},
+			wantIDs: nil,
+		},
+		{
+			name:    "memlock threshold is inclusive",
+			filter:  Filter{MemlockMin: 1 << 20},
+			wantIDs: []uint32{1, 3},
+		},
+		{
+			name:    "memlock and name combined",
+			filter:  Filter{NameContains: "conn", MemlockMin: 4097},
+			wantIDs: []uint32{1},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := FilterMaps(allMaps, tt.filter)
+			if len(got) != len(tt.wantIDs) {
+				t.Fatalf("Expected %d maps, got %d", len(tt.wantIDs), len(got))
+			}
+			for i, m := range got {
+				if m.ID != tt.wantIDs[i] {
+					t.Errorf("Expected ID %d at %d, got %d", tt.wantIDs[i], i, m.ID)
+				}
+			}
+		})
+	}
+}
+
+func TestSort(t *testing.T) {
+	newMaps := func() []MapInfo {
+		return []MapInfo{
+			{ID: 1, Name: "b", MemLock: 4096},
+			{ID: 2, Name: "a", MemLock: 8192},
+			{ID: 3, Name: "b", MemLock: 4096},
+		}
+	}
+
+	tests := []struct {
+		name    string
+		field   string
+		reverse bool
+		wantIDs []uint32
+		wantErr bool
+	}{
+		{name: "by id", field: "id", wantIDs: []uint32{1, 2, 3}},
+		{name: "by name keeps kernel order for ties", field: "name", wantIDs: []uint32{2, 1, 3}},
+		{name: "by memlock reversed", field: "memlock", reverse: true, wantIDs: []uint32{2, 1, 3}},
+		{name: "unknown field", field: "size", wantErr: true},
+		{name: "load time isn't known for maps", field: "loaded", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			m := newMaps()
+			err := Sort(m, tt.field, tt.reverse)
+			if tt.wantErr {
+				if err == nil {
+					t.Fatal("Expected error, got nil")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			for i, mi := range m {
+				if mi.ID != tt.wantIDs[i] {
+					t.Errorf("Expected ID %d at %d, got %d", tt.wantIDs[i], i, mi.ID)
+				}
+			}
+		})
+	}
+}
+
+func TestSortEntries(t *testing.T) {
+	tests := []struct {
+		name    string
+		entries []MapEntry
+		// want lists the first value byte of each entry, which tells
+		// entries with equal keys apart
+		want []byte
+	}{
+		{
+			name: "equal-length keys",
+			entries: []MapEntry{
+				{Key: []byte{0, 2}, Value: []byte{1}},
+				{Key: []byte{1, 0}, Value: []byte{2}},
+				{Key: []byte{0, 1}, Value: []byte{3}},
+				{Key: []byte{0, 0}, Value: []byte{4}},
+			},
+			want: []byte{4, 3, 1, 2},
+		},
+		{
+			name: "equal keys keep their order",
+			entries: []MapEntry{
+				{Key: []byte{0, 1}, Value: []byte{1}},
+				{Key: []byte{0, 0}, Value: []byte{2}},
+				{Key: []byte{0, 1}, Value: []byte{3}},
+				{Key: []byte{0, 0}, Value: []byte{4}},
+			},
+			want: []byte{2, 4, 1, 3},
+		},
+		{
+			name: "keys of different lengths",
+			entries: []MapEntry{
+				{Key: []byte{1, 0}, Value: []byte{1}},
+				{Key: []byte{2}, Value: []byte{2}},
+				{Key: []byte{1}, Value: []byte{3}},
+				{Key: nil, Value: []byte{4}},
+				{Key: []byte{1, 0, 0}, Value: []byte{5}},
+			},
+			want: []byte{4, 3, 1, 5, 2},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			decoded := make([]DecodedEntry, len(tt.entries))
+			for i, e := range tt.entries {
+				decoded[i] = DecodedEntry{MapEntry: e}
+			}
+
+			SortEntries(tt.entries)
+			SortDecodedEntries(decoded)
+
+			for i := range tt.want {
+				if got := tt.entries[i].Value[0]; got != tt.want[i] {
+					t.Errorf("SortEntries: entry %d has value %d, want %d", i, got, tt.want[i])
+				}
+				if got := decoded[i].Value[0]; got != tt.want[i] {
+					t.Errorf("SortDecodedEntries: entry %d has value %d, want %d", i, got, tt.want[i])
+				}
+			}
+		})
+	}
+}
+
+func TestDecodeBTFValue(t *testing.T) {
+	u32 := &btf.Int{Name: "u32", Size: 4}
+	s16 := &btf.Int{Name: "s16", Size: 2, Encoding: btf.Signed}
+	char := &btf.Int{Name: "char", Size: 1, Encoding: btf.Char}
+	state := &btf.Enum{Name: "state", Size: 4, Values: []btf.EnumValue{
+		{Name: "IDLE", Value: 0},
+		{Name: "BUSY", Value: 1},
+	}}
+	value := &btf.Struct{
+		Name: "value",
+		Size: 16,
+		Members: []btf.Member{
+			{Name: "count", Type: u32, Offset: 0},
+			{Name: "delta", Type: s16, Offset: 32},
+			{Name: "comm", Type: &btf.Array{Type: char, Nelems: 4}, Offset: 48},
+			{Name: "state", Type: &btf.Typedef{Name: "state_t", Type: state}, Offset: 80},
+			{Name: "flag", Type: u32, Offset: 112, BitfieldSize: 3},
+		},
+	}
+
+	data := []byte{
+		0x2a, 0x00, 0x00, 0x00, // count = 42
+		0xff, 0xff, // delta = -1
+		'a', 'b', 0x00, 0x00, // comm = "ab"
+		0x01, 0x00, 0x00, 0x00, // state = BUSY
+		0x05, 0x00, // flag = 5
+	}
+
+	got, err := decodeBTFValue(value, data)
+	if err != nil {
+		t.Fatalf("decodeBTFValue() error = %v", err)
+	}
+
+	encoded, err := json.Marshal(got)
+	if err != nil {
+		t.Fatalf("json.Marshal() error = %v", err)
+	}
+	want := `{"count":42,"delta":-1,"comm":"ab","state":"BUSY","flag":5}`
+	if string(encoded) != want {
+		t.Errorf("decoded = %s, want %s", encoded, want)
+	}
+
+	if _, err := decodeBTFValue(value, data[:8]); err == nil {
+		t.Error("expected an error for short data")
+	}
+}
+
+func TestBTFDecoder_Decode(t *testing.T) {
+	u32 := &btf.Int{Name: "u32", Size: 4}
+	d := &btfDecoder{valueType: u32}
+
+	entry := MapEntry{
+		Key:          []byte{0x01, 0x00, 0x00, 0x00},
+		PerCPUValues: [][]byte{{0x01, 0x00, 0x00, 0x00}, {0x02, 0x00, 0x00, 0x00}},
+	}
+	decoded, err := d.decode(entry)
+	if err != nil {
+		t.Fatalf("decode() error = %v", err)
+	}
+	if decoded.FormattedKey != nil {
+		t.Errorf("FormattedKey = %v, want nil without a key type", decoded.FormattedKey)
+	}
+	values, ok := decoded.FormattedValue.([]interface{})
+	if !ok || len(values) != 2 || values[1] != uint64(2) {
+		t.Errorf("FormattedValue = %v, want [1 2]", decoded.FormattedValue)
+	}
+
+	// A nil decoder leaves entries undecoded
+	var none *btfDecoder
+	decoded, err = none.decode(entry)
+	if err != nil || decoded.FormattedValue != nil {
+		t.Errorf("nil decoder: got %v, %v", decoded.FormattedValue, err)
+	}
+}
+
+// fakeKeyDeleter is an ordered in-memory map. onNext runs before every
+// NextKey call to simulate concurrent modification.
+type fakeKeyDeleter struct {
+	keys    [][]byte
+	onNext  func(f *fakeKeyDeleter)
+	deletes int
+}
+
+func (f *fakeKeyDeleter) index(key []byte) int {
+	for i, k := range f.keys {
+		if bytes.Equal(k, key) {
+			return i
+		}
+	}
+	return -1
+}
+
+func (f *fakeKeyDeleter) NextKey(key []byte) ([]byte, error) {
+	if f.onNext != nil {
+		f.onNext(f)
+	}
+	i := 0
+	if key != nil {
+		// Like most map types, a missing key means the walk lost its place
+		if i = f.index(key); i < 0 {
+			return nil, ebpf.ErrKeyNotExist
+		}
+		i++
+	}
+	if i >= len(f.keys) {
+		return nil, ebpf.ErrKeyNotExist
+	}
+	return f.keys[i], nil
+}
+
+func (f *fakeKeyDeleter) Delete(key []byte) error {
+	f.deletes++
+	i := f.index(key)
+	if i < 0 {
+		return ebpf.ErrKeyNotExist
+	}
+	f.keys = append(f.keys[:i], f.keys[i+1:]...)
+	return nil
+}
+
+func TestClearKeys(t *testing.T) {
+	keys := func() [][]byte {
+		return [][]byte{{1}, {2}, {3}, {4}, {5}}
+	}
+
+	t.Run("empty map", func(t *testing.T) {
+		f := &fakeKeyDeleter{}
+		n, err := clearKeys(context.Background(), f)
+		if err != nil || n != 0 {
+			t.Errorf("clearKeys() = %d, %v, want 0, nil", n, err)
+		}
+	})
+
+	t.Run("deletes every key", func(t *testing.T) {
+		f := &fakeKeyDeleter{keys: keys()}
+		n, err := clearKeys(context.Background(), f)
+		if err != nil {
+			t.Fatalf("clearKeys() error = %v", err)
+		}
+		if n != 5 || len(f.keys) != 0 {
+			t.Errorf("deleted %d, %d left, want 5 deleted, 0 left", n, len(f.keys))
+		}
+	})
+
+	t.Run("keys deleted concurrently", func(t *testing.T) {
+		f := &fakeKeyDeleter{keys: keys()}
+		calls := 0
+		f.onNext = func(f *fakeKeyDeleter) {
+			// Another process removes the current key and its successor
+			calls++
+			if calls == 3 {
+				f.keys = f.keys[2:]
+			}
+		}
+		n, err := clearKeys(context.Background(), f)
+		if err != nil {
+			t.Fatalf("clearKeys() error = %v", err)
+		}
+		if len(f.keys) != 0 {
+			t.Errorf("%d keys left, want 0", len(f.keys))
+		}
+		if n != 3 {
+			t.Errorf("deleted %d, want 3", n)
+		}
+	})
+
+	t.Run("cancelled context", func(t *testing.T) {
+		f := &fakeKeyDeleter{keys: keys()}
+		ctx, cancel := context.WithCancel(context.Background())
+		cancel()
+		n, err := clearKeys(ctx, f)
+		if !errors.Is(err, context.Canceled) || n != 0 {
+			t.Errorf("clearKeys() = %d, %v, want 0, %v", n, err, context.Canceled)
+		}
+		if len(f.keys) != 5 {
+			t.Errorf("%d keys left, want 5", len(f.keys))
+		}
+	})
+}
+
+func TestCountKeys(t *testing.T) {
+	keys := [][]byte{{1}, {2}, {3}, {4}, {5}}
+	nextErr := errors.New("map gone")
+
+	tests := []struct {
+		name       string
+		iterator   keyIterator
+		limit      int
+		want       int
+		wantCapped bool
+		wantErr    error
+	}{
+		{name: "empty map", iterator: &fakeKeyDeleter{}, want: 0},
+		{name: "no limit", iterator: &fakeKeyDeleter{keys: keys}, want: 5},
+		{name: "under the limit", iterator: &fakeKeyDeleter{keys: keys}, limit: 10, want: 5},
+		{name: "at the limit", iterator: &fakeKeyDeleter{keys: keys}, limit: 5, want: 5, wantCapped: true},
+		{name: "over the limit", iterator: &fakeKeyDeleter{keys: keys}, limit: 2, want: 2, wantCapped: true},
+		{name: "next key error", iterator: failingIterator{err: nextErr}, wantErr: nextErr},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, capped, err := countKeys(tt.iterator, tt.limit)
+			if !errors.Is(err, tt.wantErr) || (tt.wantErr == nil && err != nil) {
+				t.Fatalf("countKeys() error = %v, want %v", err, tt.wantErr)
+			}
+			if got != tt.want || capped != tt.wantCapped {
+				t.Errorf("countKeys() = %d, %v, want %d, %v", got, capped, tt.want, tt.wantCapped)
+			}
+		})
+	}
+}
+
+// failingIterator fails every NextKey call with err
+type failingIterator struct {
+	err error
+}
+
+func (f failingIterator) NextKey([]byte) ([]byte, error) {
+	return nil, f.err
+}
+
+func TestCountEntries(t *testing.T) {
+	_, info := newSyntheticHashMap(t, 100)
+	id, ok := info.ID()
+	if !ok {
+		t.Skip("kernel doesn't report map IDs")
+	}
+	svc := NewService(WithoutPinnedPaths())
+
+	count, capped, err := svc.CountEntries(uint32(id), 0)
+	if err != nil || count != 100 || capped {
+		t.Errorf("CountEntries() = %d, %v, %v, want 100, false, nil", count, capped, err)
+	}
+	count, capped, err = svc.CountEntries(uint32(id), 10)
+	if err != nil || count != 10 || !capped {
+		t.Errorf("CountEntries() with a limit = %d, %v, %v, want 10, true, nil", count, capped, err)
+	}
+}
+
+// newSyntheticHashMap creates a hash map with n entries, skipping the test
+// when maps can't be created (no CAP_BPF).
+func newSyntheticHashMap(tb testing.TB, n int) (*ebpf.Map, *ebpf.MapInfo) {
+	tb.Helper()
+
+	m, err := ebpf.NewMap(&ebpf.MapSpec{
+		Type:       ebpf.Hash,
+		KeySize:    4,
+		ValueSize:  8,
+		MaxEntries: uint32(n),
+	})
+	if err != nil {
+		tb.Skipf("can't create map: %v", err)
+	}
+	tb.Cleanup(func() { m.Close() })
+
+	for i := 0; i < n; i++ {
+		if err := m.Put(uint32(i), uint64(i)*10); err != nil {
+			tb.Fatalf("Put(%d) error = %v", i, err)
+		}
+	}
+
+	info, err := m.Info()
+	if err != nil {
+		tb.Fatalf("Info() error = %v", err)
+	}
+	return m, info
+}
+
+func TestDumpBatch_MatchesIterate(t *testing.T) {
+	m, info := newSyntheticHashMap(t, 1000)
+
+	// A batch size that doesn't divide the entry count exercises the
+	// final partial batch
+	batched, err := dumpBatch(context.Background(), m, info, 64)
+	if err != nil {
+		t.Skipf("batch lookup unavailable: %v", err)
+	}
+	iterated, err := dumpIterate(context.Background(), m, info)
+	if err != nil {
+		t.Fatalf("dumpIterate() error = %v", err)
+	}
+
+	if len(batched) != len(iterated) {
+		t.Fatalf("batch returned %d entries, iterate %d", len(batched), len(iterated))
+	}
+
+	// Entries must not alias the reused batch buffers
+	seen := make(map[string]bool)
+	for _, e := range batched {
+		seen[string(e.Key)] = true
+	}
+	if len(seen) != len(batched) {
+		t.Errorf("got %d distinct keys, want %d", len(seen), len(batched))
+	}
+	for _, e := range iterated {
+		if !seen[string(e.Key)] {
+			t.Errorf("key %x missing from batch dump", e.Key)
+		}
+	}
+}
+
+func BenchmarkDump_Batch(b *testing.B) {
+	m, info := newSyntheticHashMap(b, 100000)
+	if _, err := dumpBatch(context.Background(), m, info, DefaultBatchSize); err != nil {
+		b.Skipf("batch lookup unavailable: %v", err)
+	}
+
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		if _, err := dumpBatch(context.Background(), m, info, DefaultBatchSize); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func BenchmarkDump_Iterate(b *testing.B) {
+	m, info := newSyntheticHashMap(b, 100000)
+
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		if _, err := dumpIterate(context.Background(), m, info); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func TestIsPerCPUType(t *testing.T) {
+	for _, typ := range []string{"percpuhash", "PerCPUArray", "lrucpuhash", "percpucgroupstorage", "percpu_array"} {
+		if !IsPerCPUType(typ) {
+			t.Errorf("IsPerCPUType(%q) = false, want true", typ)
+		}
+	}
+	for _, typ := range []string{"hash", "array", "lruhash", "cgrp_storage", ""} {
+		if IsPerCPUType(typ) {
+			t.Errorf("IsPerCPUType(%q) = true, want false", typ)
+		}
+	}
+}
+
+func TestMapTypeHelpers(t *testing.T) {
+	tests := []struct {
+		name  string
+		is    func(string) bool
+		match []string
+		other []string
+	}{
+		{"IsStackOrQueueType", IsStackOrQueueType, []string{"stack", "Queue"}, []string{"stacktrace", "hash", ""}},
+		{"IsRingBufferType", IsRingBufferType, []string{"ringbuf", "RingBuf"}, []string{"user_ringbuf", "perfeventarray", ""}},
+		{"IsPerfEventArrayType", IsPerfEventArrayType, []string{"perfeventarray", "perf_event_array"}, []string{"ringbuf", "array", ""}},
+	}
+	for _, tt := range tests {
+		for _, typ := range tt.match {
+			if !tt.is(typ) {
+				t.Errorf("%s(%q) = false, want true", tt.name, typ)
+			}
+		}
+		for _, typ := range tt.other {
+			if tt.is(typ) {
+				t.Errorf("%s(%q) = true, want false", tt.name, typ)
+			}
+		}
+	}
+}
+
+func TestParseCPUList(t *testing.T) {
+	tests := []struct {
+		list    string
+		want    int
+		wantErr bool
+	}{
+		{list: "0", want: 1},
+		{list: "0-7", want: 8},
+		{list: "0-3,5,7-8", want: 7},
+		{list: "", wantErr: true},
+		{list: "3-1", wantErr: true},
+		{list: "a-b", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		got, err := parseCPUList(tt.list)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("parseCPUList(%q) error = %v, wantErr %v", tt.list, err, tt.wantErr)
+			continue
+		}
+		if got != tt.want {
+			t.Errorf("parseCPUList(%q) = %d, want %d", tt.list, got, tt.want)
+		}
+	}
+}
+
+func TestSelectCPU(t *testing.T) {
+	entries := []MapEntry{{
+		Key:          []byte{1, 0, 0, 0},
+		PerCPUValues: [][]byte{{10}, {11}, {12}, {13}},
+	}}
+
+	got, err := SelectCPU(entries, 2, 4)
+	if err != nil {
+		t.Fatalf("SelectCPU() error = %v", err)
+	}
+	if len(got) != 1 || got[0].PerCPUValues != nil || !bytes.Equal(got[0].Value, []byte{12}) {
+		t.Errorf("SelectCPU() = %+v, want value of CPU 2", got)
+	}
+
+	_, err = SelectCPU(entries, 9, 8)
+	if err == nil || err.Error() != "cpu 9 out of range, have 8 CPUs" {
+		t.Errorf("SelectCPU() error = %v, want out of range error", err)
+	}
+
+	if _, err := SelectCPU(entries, -1, 4); err == nil {
+		t.Error("expected error for negative CPU")
+	}
+
+	plain := []MapEntry{{Key: []byte{1}, Value: []byte{2}}}
+	if _, err := SelectCPU(plain, 0, 4); err != ErrNotPerCPU {
+		t.Errorf("SelectCPU() error = %v, want %v", err, ErrNotPerCPU)
+	}
+}
+
+func TestDiff(t *testing.T) {
+	prev := []MapEntry{
+		{Key: []byte{1}, Value: []byte{10}},
+		{Key: []byte{2}, Value: []byte{20}},
+		{Key: []byte{3}, Value: []byte{30}},
+		{Key: []byte{4}, PerCPUValues: [][]byte{{1}, {2}}},
+	}
+	cur := []MapEntry{
+		{Key: []byte{1}, Value: []byte{10}},
+		{Key: []byte{3}, Value: []byte{31}},
+		{Key: []byte{4}, PerCPUValues: [][]byte{{1}, {3}}},
+		{Key: []byte{5}, Value: []byte{50}},
+	}
+
+	diff := Diff(prev, cur)
+
+	if !reflect.DeepEqual(diff.Added, []MapEntry{{Key: []byte{5}, Value: []byte{50}}}) {
+		t.Errorf("Added = %v, want key 5", diff.Added)
+	}
+	if !reflect.DeepEqual(diff.Removed, []MapEntry{{Key: []byte{2}, Value: []byte{20}}}) {
+		t.Errorf("Removed = %v, want key 2", diff.Removed)
+	}
+	wantChanged := []EntryChange{
+		{Old: prev[2], New: cur[1]},
+		{Old: prev[3], New: cur[2]},
+	}
+	if !reflect.DeepEqual(diff.Changed, wantChanged) {
+		t.Errorf("Changed = %v, want %v", diff.Changed, wantChanged)
+	}
+
+	if !Diff(cur, cur).Empty() {
+		t.Error("Diff of identical dumps is not empty")
+	}
+	if got := Diff(nil, cur); len(got.Added) != len(cur) {
+		t.Errorf("Diff from empty dump added %d entries, want %d", len(got.Added), len(cur))
+	}
+}
+
+func TestIterateEntries(t *testing.T) {
+	m, info := newSyntheticHashMap(t, 100)
+
+	var streamed []MapEntry
+	err := iterateEntries(context.Background(), m, info, func(e MapEntry) error {
+		streamed = append(streamed, e)
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("iterateEntries() error = %v", err)
+	}
+	if len(streamed) != 100 {
+		t.Errorf("streamed %d entries, want 100", len(streamed))
+	}
+
+	// An error from the callback stops the iteration
+	stop := errors.New("stop")
+	calls := 0
+	err = iterateEntries(context.Background(), m, info, func(e MapEntry) error {
+		calls++
+		return stop
+	})
+	if !errors.Is(err, stop) || calls != 1 {
+		t.Errorf("iterateEntries() = %v after %d calls, want %v after 1", err, calls, stop)
+	}
+}
+
+func TestDump_CancelledContext(t *testing.T) {
+	m, info := newSyntheticHashMap(t, 100)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	calls := 0
+	err := iterateEntries(ctx, m, info, func(e MapEntry) error {
+		calls++
+		return nil
+	})
+	if !errors.Is(err, context.Canceled) || calls != 0 {
+		t.Errorf("iterateEntries() = %v after %d calls, want %v after 0", err, calls, context.Canceled)
+	}
+
+	if _, err := dumpBatch(ctx, m, info, DefaultBatchSize); !errors.Is(err, context.Canceled) {
+		t.Errorf("dumpBatch() error = %v, want %v", err, context.Canceled)
+	}
+
+	id, ok := info.ID()
+	if !ok {
+		t.Skip("kernel doesn't report map IDs")
+	}
+	if _, err := NewService().DumpContext(ctx, uint32(id)); !errors.Is(err, context.Canceled) {
+		t.Errorf("DumpContext() error = %v, want %v", err, context.Canceled)
+	}
+}
+
+func TestCheckSizes(t *testing.T) {
+	const limit = 64 << 20
+
+	tests := []struct {
+		name    string
+		info    MapInfo
+		wantErr bool
+	}{
+		{name: "ordinary hash", info: MapInfo{KeySize: 4, ValueSize: 8, MaxEntries: 1 << 20}},
+		{name: "value at the limit", info: MapInfo{KeySize: 4, ValueSize: limit, MaxEntries: 1}},
+		{name: "huge value", info: MapInfo{KeySize: 4, ValueSize: 0xffffffff, MaxEntries: 1}, wantErr: true},
+		{name: "huge key", info: MapInfo{KeySize: limit + 1, ValueSize: 8, MaxEntries: 1}, wantErr: true},
+		{name: "keys of a full map", info: MapInfo{KeySize: 64, ValueSize: 8, MaxEntries: 1<<20 + 1}, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		err := checkSizes(tt.info, limit)
+		if tt.wantErr != errors.Is(err, bpferrors.ErrTooLarge) {
+			t.Errorf("%s: checkSizes() error = %v, want ErrTooLarge %v", tt.name, err, tt.wantErr)
+		}
+		// A zero limit turns the guard off
+		if err := checkSizes(tt.info, 0); err != nil {
+			t.Errorf("%s: checkSizes() without a limit error = %v", tt.name, err)
+		}
+	}
+
+	// A streamed dump holds one entry at a time, so a big map is fine
+	if err := checkEntrySize(64, 8, limit); err != nil {
+		t.Errorf("checkEntrySize() of a map too big to hold error = %v", err)
+	}
+	if err := checkEntrySize(4, 0xffffffff, limit); !errors.Is(err, bpferrors.ErrTooLarge) {
+		t.Errorf("checkEntrySize() of a huge value error = %v, want %v", err, bpferrors.ErrTooLarge)
+	}
+}
+
+func TestMaxBufferSize(t *testing.T) {
+	_, info := newSyntheticHashMap(t, 4)
+	id, ok := info.ID()
+	if !ok {
+		t.Skip("kernel doesn't report map IDs")
+	}
+
+	// The 8-byte values exceed a 6-byte limit, the 4-byte keys don't
+	svc := NewService(WithoutPinnedPaths(), WithMaxBufferSize(6))
+	if _, err := svc.Dump(uint32(id)); !errors.Is(err, bpferrors.ErrTooLarge) {
+		t.Errorf("Dump() error = %v, want %v", err, bpferrors.ErrTooLarge)
+	}
+	if err := svc.DumpStream(uint32(id), func(MapEntry) error { return nil }); !errors.Is(err, bpferrors.ErrTooLarge) {
+		t.Errorf("DumpStream() error = %v, want %v", err, bpferrors.ErrTooLarge)
+	}
+	if _, err := svc.Lookup(uint32(id), []byte{0, 0, 0, 0}); !errors.Is(err, bpferrors.ErrTooLarge) {
+		t.Errorf("Lookup() error = %v, want %v", err, bpferrors.ErrTooLarge)
+	}
+	if _, err := svc.GetNextKey(uint32(id), []byte{0, 0, 0, 0}); err != nil && !errors.Is(err, ebpf.ErrKeyNotExist) {
+		t.Errorf("GetNextKey() error = %v, want the 4-byte keys to pass", err)
+	}
+
+	if _, err := NewService(WithoutPinnedPaths()).Dump(uint32(id)); err != nil {
+		t.Errorf("Dump() with the default limit error = %v", err)
+	}
+}
+
+func TestOpenRingBuffer(t *testing.T) {
+	rb, err := ebpf.NewMap(&ebpf.MapSpec{Type: ebpf.RingBuf, MaxEntries: uint32(os.Getpagesize())})
+	if err != nil {
+		t.Skipf("can't create ring buffer: %v", err)
+	}
+	defer rb.Close()
+	info, err := rb.Info()
+	if err != nil {
+		t.Fatalf("Info() error = %v", err)
+	}
+	id, ok := info.ID()
+	if !ok {
+		t.Skip("kernel doesn't report map IDs")
+	}
+
+	svc := NewService(WithoutPinnedPaths())
+	r, err := svc.OpenRingBuffer(uint32(id))
+	if err != nil {
+		t.Fatalf("OpenRingBuffer() error = %v", err)
+	}
+
+	// Closing unblocks a Read waiting on the empty ring buffer
+	done := make(chan error)
+	go func() {
+		_, err := r.Read()
+		done <- err
+	}()
+	time.Sleep(10 * time.Millisecond)
+	if err := r.Close(); err != nil {
+		t.Errorf("Close() error = %v", err)
+	}
+	if err := <-done; !errors.Is(err, os.ErrClosed) {
+		t.Errorf("Read() after Close error = %v, want %v", err, os.ErrClosed)
+	}
+	if err := r.Close(); err != nil {
+		t.Errorf("second Close() error = %v", err)
+	}
+
+	_, hashInfo := newSyntheticHashMap(t, 1)
+	hashID, _ := hashInfo.ID()
+	if _, err := svc.OpenRingBuffer(uint32(hashID)); !errors.Is(err, ErrNotRingBuffer) {
+		t.Errorf("OpenRingBuffer() of a hash map error = %v, want %v", err, ErrNotRingBuffer)
+	}
+}
+
+func TestOpenPerfEventArray(t *testing.T) {
+	pa, err := ebpf.NewMap(&ebpf.MapSpec{Type: ebpf.PerfEventArray})
+	if err != nil {
+		t.Skipf("can't create perf event array: %v", err)
+	}
+	defer pa.Close()
+	info, err := pa.Info()
+	if err != nil {
+		t.Fatalf("Info() error = %v", err)
+	}
+	id, ok := info.ID()
+	if !ok {
+		t.Skip("kernel doesn't report map IDs")
+	}
+
+	svc := NewService(WithoutPinnedPaths())
+	r, err := svc.OpenPerfEventArray(uint32(id), os.Getpagesize())
+	if err != nil {
+		t.Skipf("can't open perf event array: %v", err)
+	}
+
+	// Closing unblocks a Read waiting for samples
+	done := make(chan error)
+	go func() {
+		_, err := r.Read()
+		done <- err
+	}()
+	time.Sleep(10 * time.Millisecond)
+	if err := r.Close(); err != nil {
+		t.Errorf("Close() error = %v", err)
+	}
+	if err := <-done; !errors.Is(err, os.ErrClosed) {
+		t.Errorf("Read() after Close error = %v, want %v", err, os.ErrClosed)
+	}
+	if err := r.Close(); err != nil {
+		t.Errorf("second Close() error = %v", err)
+	}
+
+	_, hashInfo := newSyntheticHashMap(t, 1)
+	hashID, _ := hashInfo.ID()
+	if _, err := svc.OpenPerfEventArray(uint32(hashID), os.Getpagesize()); !errors.Is(err, ErrNotPerfEventArray) {
+		t.Errorf("OpenPerfEventArray() of a hash map error = %v, want %v", err, ErrNotPerfEventArray)
+	}
+}
+
+func TestListContext_Cancelled(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	if _, err := NewService().ListContext(ctx); !errors.Is(err, context.Canceled) {
+		t.Errorf("ListContext() error = %v, want %v", err, context.Canceled)
+	}
+}
+
+func TestListWithSkipped(t *testing.T) {
+	m, _ := newSyntheticHashMap(t, 16)
+	info, err := m.Info()
+	if err != nil {
+		t.Fatalf("Info() error = %v", err)
+	}
+	denied, _ := info.ID()
+
+	baseline, err := NewService(WithoutPinnedPaths()).ListWithSkipped(context.Background())
+	if err != nil {
+		t.Fatalf("ListWithSkipped() error = %v", err)
+	}
+
+	orig := newMapFromID
+	t.Cleanup(func() { newMapFromID = orig })
+	newMapFromID = func(id ebpf.MapID) (*ebpf.Map, error) {
+		if id == denied {
+			return nil, fmt.Errorf("open map %d: %w", id, unix.EPERM)
+		}
+		return orig(id)
+	}
+
+	result, err := NewService(WithoutPinnedPaths()).ListWithSkipped(context.Background())
+	if err != nil {
+		t.Fatalf("ListWithSkipped() error = %v", err)
+	}
+	if result.Skipped != baseline.Skipped+1 {
+		t.Errorf("Skipped = %d, want %d", result.Skipped, baseline.Skipped+1)
+	}
+	for _, mi := range result.Maps {
+		if ebpf.MapID(mi.ID) == denied {
+			t.Errorf("map %d listed although it couldn't be opened", mi.ID)
+		}
+	}
+}
+
+func TestGetByID_Retry(t *testing.T) {
+	m, _ := newSyntheticHashMap(t, 16)
+	info, err := m.Info()
+	if err != nil {
+		t.Fatalf("Info() error = %v", err)
+	}
+	id, _ := info.ID()
+
+	orig := newMapFromID
+	t.Cleanup(func() { newMapFromID = orig })
+
+	tests := []struct {
+		name      string
+		failures  int
+		wantCalls int
+		wantErr   bool
+	}{
+		{name: "fails once", failures: 1, wantCalls: 2},
+		{name: "always fails", failures: utils.OpenRetries + 1, wantCalls: utils.OpenRetries + 1, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			calls := 0
+			newMapFromID = func(id ebpf.MapID) (*ebpf.Map, error) {
+				calls++
+				if calls <= tt.failures {
+					return nil, fmt.Errorf("open map %d: %w", id, os.ErrNotExist)
+				}
+				return orig(id)
+			}
+
+			_, err := NewService(WithoutPinnedPaths()).GetByID(uint32(id))
+			if tt.wantErr != (err != nil) || (err != nil && !errors.Is(err, os.ErrNotExist)) {
+				t.Errorf("GetByID() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if calls != tt.wantCalls {
+				t.Errorf("opened %d times, want %d", calls, tt.wantCalls)
+			}
+		})
+	}
+}
+
+func TestMapToMapInfo_MemLock(t *testing.T) {
+	m, _ := newSyntheticHashMap(t, 16)
+
+	mapInfo, err := (&serviceImpl{}).mapToMapInfo(m)
+	if err != nil {
+		t.Fatalf("mapToMapInfo() error = %v", err)
+	}
+	if mapInfo.MemLock == 0 {
+		t.Error("MemLock = 0, want the map's memlock from fdinfo")
+	}
+}
+
+func TestFlagNames(t *testing.T) {
+	tests := []struct {
+		flags uint32
+		want  []string
+	}{
+		{0, nil},
+		{0x1, []string{"BPF_F_NO_PREALLOC"}},
+		{0x2, []string{"BPF_F_NO_COMMON_LRU"}},
+		{0x400 | 0x8, []string{"BPF_F_RDONLY", "BPF_F_MMAPABLE"}},
+		{0x1 | 0x80000000, []string{"BPF_F_NO_PREALLOC", "0x80000000"}},
+	}
+
+	for _, tt := range tests {
+		if got := FlagNames(tt.flags); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("FlagNames(0x%x) = %v, want %v", tt.flags, got, tt.want)
+		}
+	}
+}
+
+func TestStackAndQueue(t *testing.T) {
+	newMap := func(t *testing.T, mapType ebpf.MapType) uint32 {
+		t.Helper()
+		m, err := ebpf.NewMap(&ebpf.MapSpec{Type: mapType, ValueSize: 4, MaxEntries: 4})
+		if err != nil {
+			t.Skipf("can't create map: %v", err)
+		}
+		t.Cleanup(func() { m.Close() })
+		info, err := m.Info()
+		if err != nil {
+			t.Fatalf("Info() error = %v", err)
+		}
+		id, ok := info.ID()
+		if !ok {
+			t.Skip("kernel doesn't report map IDs")
+		}
+		return uint32(id)
+	}
+	svc := NewService(WithoutPinnedPaths())
+
+	for _, tt := range []struct {
+		mapType ebpf.MapType
+		next    byte // first value popped after pushing 1 then 2
+	}{
+		{ebpf.Queue, 1},
+		{ebpf.Stack, 2},
+	} {
+		t.Run(tt.mapType.String(), func(t *testing.T) {
+			id := newMap(t, tt.mapType)
+
+			if _, err := svc.Pop(id); !errors.Is(err, bpferrors.ErrMapEmpty) {
+				t.Errorf("Pop() on empty map error = %v, want %v", err, bpferrors.ErrMapEmpty)
+			}
+			for _, v := range []byte{1, 2} {
+				if err := svc.Push(id, []byte{v, 0, 0, 0}); err != nil {
+					t.Fatalf("Push(%d) error = %v", v, err)
+				}
+			}
+			if err := svc.Push(id, []byte{1}); !errors.Is(err, bpferrors.ErrInvalidValue) {
+				t.Errorf("Push() of short value error = %v, want %v", err, bpferrors.ErrInvalidValue)
+			}
+
+			want := []byte{tt.next, 0, 0, 0}
+			if got, err := svc.Peek(id); err != nil || !bytes.Equal(got, want) {
+				t.Errorf("Peek() = %v, %v, want %v", got, err, want)
+			}
+			if got, err := svc.Pop(id); err != nil || !bytes.Equal(got, want) {
+				t.Errorf("Pop() = %v, %v, want %v", got, err, want)
+			}
+			if got, err := svc.Pop(id); err != nil || bytes.Equal(got, want) {
+				t.Errorf("second Pop() = %v, %v, want the other value", got, err)
+			}
+		})
+	}
+
+	t.Run("hash", func(t *testing.T) {
+		_, info := newSyntheticHashMap(t, 1)
+		id, _ := info.ID()
+		if _, err := svc.Peek(uint32(id)); !errors.Is(err, ErrNotStackOrQueue) {
+			t.Errorf("Peek() on hash map error = %v, want %v", err, ErrNotStackOrQueue)
+		}
+	})
+}
+
+func TestUpdate(t *testing.T) {
+	m, info := newSyntheticHashMap(t, 4)
+	id, _ := info.ID()
+	svc := NewService(WithoutPinnedPaths())
+
+	// The synthetic map is full, so overwrite an existing key
+	key := []byte{2, 0, 0, 0}
+	value := []byte{1, 2, 3, 4, 5, 6, 7, 8}
+	if err := svc.Update(uint32(id), key, value); err != nil {
+		t.Fatalf("Update() error = %v", err)
+	}
+	var got []byte
+	if err := m.Lookup(key, &got); err != nil || !bytes.Equal(got, value) {
+		t.Errorf("Lookup() = %x, %v, want %x", got, err, value)
+	}
+
+	if err := svc.Update(uint32(id), []byte{2}, value); !errors.Is(err, bpferrors.ErrInvalidKey) {
+		t.Errorf("Update() with a short key error = %v, want %v", err, bpferrors.ErrInvalidKey)
+	}
+	if err := svc.Update(uint32(id), key, value[:4]); !errors.Is(err, bpferrors.ErrInvalidValue) {
+		t.Errorf("Update() with a short value error = %v, want %v", err, bpferrors.ErrInvalidValue)
+	}
+}
+
+func TestFreeze(t *testing.T) {
+	m, info := newSyntheticHashMap(t, 4)
+	id, _ := info.ID()
+	svc := NewService(WithoutPinnedPaths())
+
+	if err := svc.Freeze(uint32(id)); err != nil {
+		t.Fatalf("Freeze() error = %v", err)
+	}
+
+	// Reads still work, writes fail as frozen rather than unprivileged
+	var value []byte
+	if err := m.Lookup([]byte{1, 0, 0, 0}, &value); err != nil {
+		t.Errorf("Lookup() on a frozen map error = %v", err)
+	}
+	err := svc.Update(uint32(id), []byte{1, 0, 0, 0}, make([]byte, 8))
+	if !errors.Is(err, bpferrors.ErrMapFrozen) {
+		t.Errorf("Update() on a frozen map error = %v, want %v", err, bpferrors.ErrMapFrozen)
+	}
+	if _, err := svc.Clear(uint32(id)); !errors.Is(err, bpferrors.ErrMapFrozen) {
+		t.Errorf("Clear() on a frozen map error = %v, want %v", err, bpferrors.ErrMapFrozen)
+	}
+
+	if err := svc.Freeze(0); err == nil {
+		t.Error("Freeze() of map 0 succeeded, want an error")
+	}
+}
+
+func TestDelete(t *testing.T) {
+	m, info := newSyntheticHashMap(t, 4)
+	id, _ := info.ID()
+	svc := NewService(WithoutPinnedPaths())
+
+	if err := svc.Delete(uint32(id), []byte{2, 0, 0, 0}); err != nil {
+		t.Fatalf("Delete() error = %v", err)
+	}
+	var value uint64
+	if err := m.Lookup(uint32(2), &value); !errors.Is(err, ebpf.ErrKeyNotExist) {
+		t.Errorf("Lookup() of the deleted key error = %v, want %v", err, ebpf.ErrKeyNotExist)
+	}
+
+	if err := svc.Delete(uint32(id), []byte{2, 0, 0, 0}); !errors.Is(err, bpferrors.ErrKeyNotFound) {
+		t.Errorf("Delete() of a missing key error = %v, want %v", err, bpferrors.ErrKeyNotFound)
+	}
+	if err := svc.Delete(uint32(id), []byte{1}); !errors.Is(err, bpferrors.ErrInvalidKey) {
+		t.Errorf("Delete() of a short key error = %v, want %v", err, bpferrors.ErrInvalidKey)
+	}
+
+	array, err := ebpf.NewMap(&ebpf.MapSpec{Type: ebpf.Array, KeySize: 4, ValueSize: 8, MaxEntries: 2})
+	if err != nil {
+		t.Skipf("can't create map: %v", err)
+	}
+	defer array.Close()
+	arrayInfo, err := array.Info()
+	if err != nil {
+		t.Fatalf("Info() error = %v", err)
+	}
+	arrayID, _ := arrayInfo.ID()
+
+	err = svc.Delete(uint32(arrayID), []byte{0, 0, 0, 0})
+	if !errors.Is(err, bpferrors.ErrDeleteNotSupported) {
+		t.Errorf("Delete() from an array error = %v, want %v", err, bpferrors.ErrDeleteNotSupported)
+	}
+	if bpferrors.IsNotFoundError(err) || bpferrors.IsPermissionError(err) {
+		t.Errorf("Delete() from an array error = %v, misclassified", err)
+	}
+	if _, err := svc.Clear(uint32(arrayID)); !errors.Is(err, bpferrors.ErrDeleteNotSupported) {
+		t.Errorf("Clear() of an array error = %v, want %v", err, bpferrors.ErrDeleteNotSupported)
+	}
+}
+
+// mountBPFFS mounts a BPF filesystem on a temporary directory for the test,
+// skipping the test when that isn't permitted
+func mountBPFFS(t *testing.T) string {
+	t.Helper()
+	dir := t.TempDir()
+	if err := unix.Mount("bpf", dir, "bpf", 0, ""); err != nil {
+		t.Skipf("can't mount bpffs: %v", err)
+	}
+	t.Cleanup(func() { unix.Unmount(dir, 0) })
+	return dir
+}
+
+func TestRef(t *testing.T) {
+	if got := ByID(5).String(); got != "map 5" {
+		t.Errorf("ByID(5).String() = %q, want %q", got, "map 5")
+	}
+	if ByID(5).IsPinned() || !ByPinnedPath("/sys/fs/bpf/m").IsPinned() {
+		t.Error("IsPinned() should only hold for ByPinnedPath")
+	}
+
+	m, info := newSyntheticHashMap(t, 4)
+	id, _ := info.ID()
+
+	byID, byIDInfo, err := ByID(uint32(id)).open()
+	if err != nil {
+		t.Fatalf("open() by ID error = %v", err)
+	}
+	byID.Close()
+	if got, _ := byIDInfo.ID(); got != id {
+		t.Errorf("open() by ID opened map %d, want %d", got, id)
+	}
+
+	missing := ByPinnedPath(filepath.Join(t.TempDir(), "missing"))
+	if _, _, err := missing.open(); !errors.Is(err, bpferrors.ErrNotFound) {
+		t.Errorf("open() of a missing pin error = %v, want %v", err, bpferrors.ErrNotFound)
+	}
+
+	pin := filepath.Join(mountBPFFS(t), "counts")
+	if err := m.Pin(pin); err != nil {
+		t.Fatalf("Pin() error = %v", err)
+	}
+
+	// Operations on the pinned path reach the same map
+	svc := NewService(WithoutPinnedPaths()).(*serviceImpl)
+	if err := svc.UpdateRef(ByPinnedPath(pin), []byte{1, 0, 0, 0}, make([]byte, 8)); err != nil {
+		t.Fatalf("UpdateRef() by path error = %v", err)
+	}
+	entry, err := svc.LookupRef(ByID(uint32(id)), []byte{1, 0, 0, 0})
+	if err != nil {
+		t.Fatalf("LookupRef() by ID of the value set by path error = %v", err)
+	}
+	if !bytes.Equal(entry.Value, make([]byte, 8)) {
+		t.Errorf("LookupRef() value = %x, want zeros", entry.Value)
+	}
+	if err := svc.DeleteRef(ByPinnedPath(pin), []byte{1, 0, 0, 0}); err != nil {
+		t.Errorf("DeleteRef() by path error = %v", err)
+	}
+	entries, err := svc.DumpRef(context.Background(), ByPinnedPath(pin), DefaultBatchSize)
+	if err != nil || len(entries) != 3 {
+		t.Errorf("DumpRef() by path = %d entries, %v, want 3 entries", len(entries), err)
+	}
+
+	// Decoded reads of a pinned map don't reopen it by ID either
+	opens := countOpens(t)
+	decoded, err := svc.DumpDecodedRef(context.Background(), ByPinnedPath(pin))
+	if err != nil || len(decoded) != 3 {
+		t.Fatalf("DumpDecodedRef() by path = %d entries, %v, want 3 entries", len(decoded), err)
+	}
+	if _, err := svc.LookupDecodedRef(ByPinnedPath(pin), decoded[0].Key); err != nil {
+		t.Errorf("LookupDecodedRef() by path error = %v", err)
+	}
+	if *opens != 0 {
+		t.Errorf("decoded reads by path opened %d maps by ID, want 0", *opens)
+	}
+}
+
+// countOpens counts the maps opened by ID until the test ends
+func countOpens(tb testing.TB) *int {
+	tb.Helper()
+	orig := newMapFromID
+	tb.Cleanup(func() { newMapFromID = orig })
+
+	opens := new(int)
+	newMapFromID = func(id ebpf.MapID) (*ebpf.Map, error) {
+		*opens++
+		return orig(id)
+	}
+	return opens
+}
+
+func TestMapTypeTable(t *testing.T) {
+	seen := make(map[string]ebpf.MapType)
+	for typ := ebpf.Hash; typ <= ebpf.Arena; typ++ {
+		name := MapTypeName(typ)
+		if other, dup := seen[name]; dup {
+			t.Errorf("MapTypeName(%v) = %q, also the name of %v", typ, name, other)
+		}
+		seen[name] = typ
+
+		inputs := []string{name, strings.ToUpper(name)}
+		// CgroupStorage's cilium name is ambiguous with CGroupStorage's
+		if typ != ebpf.CgroupStorage {
+			if name != strings.ToLower(typ.String()) {
+				t.Errorf("MapTypeName(%v) = %q, want %q", typ, name, strings.ToLower(typ.String()))
+			}
+			inputs = append(inputs, typ.String())
+		}
+		for _, s := range inputs {
+			if got, ok := MapTypeFromString(s); !ok || got != typ {
+				t.Errorf("MapTypeFromString(%q) = %v, %v, want %v", s, got, ok, typ)
+			}
+		}
+	}
+	if len(MapTypeNames()) != len(seen) {
+		t.Errorf("MapTypeNames() has %d names, want %d", len(MapTypeNames()), len(seen))
+	}
+
+	if got := MapTypeName(ebpf.CgroupStorage); got != "cgrp_storage" {
+		t.Errorf("MapTypeName(%v) = %q, want cgrp_storage", ebpf.CgroupStorage, got)
+	}
+	for s, want := range map[string]ebpf.MapType{"lru_hash": ebpf.LRUHash, "cgrpstorage": ebpf.CgroupStorage} {
+		if got, ok := MapTypeFromString(s); !ok || got != want {
+			t.Errorf("MapTypeFromString(%s) = %v, %v, want %v", s, got, ok, want)
+		}
+	}
+	for _, s := range []string{"", "hsah", "unspecifiedmap"} {
+		if got, ok := MapTypeFromString(s); ok {
+			t.Errorf("MapTypeFromString(%q) = %v, want not found", s, got)
+		}
+	}
+}
+
+func TestInfoCache(t *testing.T) {
+	_, info := newSyntheticHashMap(t, 4)
+	id, _ := info.ID()
+	opens := countOpens(t)
+
+	svc := NewService(WithoutPinnedPaths(), WithInfoCache(time.Minute)).(*serviceImpl)
+	now := time.Now()
+	svc.cache.now = func() time.Time { return now }
+
+	first, err := svc.GetByID(uint32(id))
+	if err != nil {
+		t.Fatalf("GetByID() error = %v", err)
+	}
+	second, err := svc.GetByID(uint32(id))
+	if err != nil {
+		t.Fatalf("GetByID() error = %v", err)
+	}
+	if !reflect.DeepEqual(first, second) {
+		t.Errorf("cached GetByID() = %+v, want %+v", second, first)
+	}
+	if first == second {
+		t.Error("cached GetByID() returned the cached value itself, want a copy")
+	}
+	if *opens != 1 {
+		t.Errorf("opened the map %d times, want 1", *opens)
+	}
+
+	// Listing reuses the entry, and fills the cache for the other maps
+	if _, err := svc.List(); err != nil {
+		t.Fatalf("List() error = %v", err)
+	}
+	listed := *opens
+	if _, err := svc.List(); err != nil {
+		t.Fatalf("List() error = %v", err)
+	}
+	if *opens != listed {
+		t.Errorf("second List() opened %d maps, want 0", *opens-listed)
+	}
+
+	// Expired entries are read again
+	now = now.Add(time.Minute)
+	if _, err := svc.GetByID(uint32(id)); err != nil {
+		t.Fatalf("GetByID() error = %v", err)
+	}
+	if *opens != listed+1 {
+		t.Errorf("GetByID() after expiry opened %d maps, want 1", *opens-listed)
+	}
+
+	// Without the option nothing is cached
+	opens2 := *opens
+	plain := NewService(WithoutPinnedPaths())
+	for i := 0; i < 2; i++ {
+		if _, err := plain.GetByID(uint32(id)); err != nil {
+			t.Fatalf("GetByID() error = %v", err)
+		}
+	}
+	if *opens != opens2+2 {
+		t.Errorf("uncached GetByID() opened %d maps, want 2", *opens-opens2)
+	}
+}
+
+// benchmarkGetByName looks up a map by name and then by ID, like commands
+// taking a map name do, reporting the maps opened per lookup
+func benchmarkGetByName(b *testing.B, opts ...Option) {
+	_, info := newSyntheticHashMap(b, 1)
+	id, _ := info.ID()
+	svc := NewService(append([]Option{WithoutPinnedPaths()}, opts...)...)
+	opens := countOpens(b)
+
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		if _, err := svc.GetByName(info.Name); err != nil {
+			b.Fatal(err)
+		}
+		if _, err := svc.GetByID(uint32(id)); err != nil {
+			b.Fatal(err)
+		}
+	}
+	b.ReportMetric(float64(*opens)/float64(b.N), "opens/op")
+}
+
+func BenchmarkGetByName_NoCache(b *testing.B) {
+	benchmarkGetByName(b)
+}
+
+func BenchmarkGetByName_InfoCache(b *testing.B) {
+	benchmarkGetByName(b, WithInfoCache(time.Minute))
+}
@@ -0,0 +1,54 @@
+package maps
+
+import (
+	"bytes"
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// SortFields lists the fields accepted by Sort. The kernel doesn't report
+// when a map was created, so unlike programs maps can't be sorted by it
+var SortFields = []string{"id", "name", "memlock"}
+
+// Sort orders maps in place by the given field. The sort is stable, so maps
+// with equal keys keep their kernel (ID) order, also when reversed
+func Sort(maps []MapInfo, field string, reverse bool) error {
+	var less func(a, b MapInfo) bool
+	switch field {
+	case "id":
+		less = func(a, b MapInfo) bool { return a.ID < b.ID }
+	case "name":
+		less = func(a, b MapInfo) bool { return a.Name < b.Name }
+	case "memlock":
+		less = func(a, b MapInfo) bool { return a.MemLock < b.MemLock }
+	default:
+		return fmt.Errorf("unknown sort field %q, valid fields: %s", field, strings.Join(SortFields, ", "))
+	}
+
+	sort.SliceStable(maps, func(i, j int) bool {
+		if reverse {
+			return less(maps[j], maps[i])
+		}
+		return less(maps[i], maps[j])
+	})
+
+	return nil
+}
+
+// SortEntries orders entries in place by their key bytes, lexicographically
+// like bytes.Compare, so a key sorts before the longer keys it prefixes.
+// The sort is stable, so entries with equal keys keep their dump order
+func SortEntries(entries []MapEntry) {
+	sort.SliceStable(entries, func(i, j int) bool {
+		return bytes.Compare(entries[i].Key, entries[j].Key) < 0
+	})
+}
+
+// SortDecodedEntries orders decoded entries in place by their raw key
+// bytes, like SortEntries
+func SortDecodedEntries(entries []DecodedEntry) {
+	sort.SliceStable(entries, func(i, j int) bool {
+		return bytes.Compare(entries[i].Key, entries[j].Key) < 0
+	})
+}
@@ -0,0 +1,97 @@
+package maps
+
+import (
+	"strings"
+
+	"github.com/cilium/ebpf"
+)
+
+// mapTypes lists the Linux map types known to cilium/ebpf in kernel enum
+// order, each with the name MapInfo.Type uses for it: cilium's name in
+// lowercase. CgroupStorage would lowercase to the same name as the older
+// CGroupStorage, so it takes the kernel's "cgrp_storage" name instead.
+// Names are looked up normalized, so "cgrpstorage" names it too
+var mapTypes = []struct {
+	typ  ebpf.MapType
+	name string
+}{
+	{ebpf.Hash, "hash"},
+	{ebpf.Array, "array"},
+	{ebpf.ProgramArray, "programarray"},
+	{ebpf.PerfEventArray, "perfeventarray"},
+	{ebpf.PerCPUHash, "percpuhash"},
+	{ebpf.PerCPUArray, "percpuarray"},
+	{ebpf.StackTrace, "stacktrace"},
+	{ebpf.CGroupArray, "cgrouparray"},
+	{ebpf.LRUHash, "lruhash"},
+	{ebpf.LRUCPUHash, "lrucpuhash"},
+	{ebpf.LPMTrie, "lpmtrie"},
+	{ebpf.ArrayOfMaps, "arrayofmaps"},
+	{ebpf.HashOfMaps, "hashofmaps"},
+	{ebpf.DevMap, "devmap"},
+	{ebpf.SockMap, "sockmap"},
+	{ebpf.CPUMap, "cpumap"},
+	{ebpf.XSKMap, "xskmap"},
+	{ebpf.SockHash, "sockhash"},
+	{ebpf.CGroupStorage, "cgroupstorage"},
+	{ebpf.ReusePortSockArray, "reuseportsockarray"},
+	{ebpf.PerCPUCGroupStorage, "percpucgroupstorage"},
+	{ebpf.Queue, "queue"},
+	{ebpf.Stack, "stack"},
+	{ebpf.SkStorage, "skstorage"},
+	{ebpf.DevMapHash, "devmaphash"},
+	{ebpf.StructOpsMap, "structopsmap"},
+	{ebpf.RingBuf, "ringbuf"},
+	{ebpf.InodeStorage, "inodestorage"},
+	{ebpf.TaskStorage, "taskstorage"},
+	{ebpf.BloomFilter, "bloomfilter"},
+	{ebpf.UserRingbuf, "userringbuf"},
+	{ebpf.CgroupStorage, "cgrp_storage"},
+	{ebpf.Arena, "arena"},
+}
+
+// normalizeTypeName folds a map type name so "lru_hash", "LRUHash" and
+// "lruhash" all compare equal
+func normalizeTypeName(name string) string {
+	return strings.ToLower(strings.ReplaceAll(name, "_", ""))
+}
+
+// MapTypeFromString returns the map type named s, ignoring case and
+// underscores, reporting false for unknown names
+func MapTypeFromString(s string) (ebpf.MapType, bool) {
+	want := normalizeTypeName(s)
+	for _, t := range mapTypes {
+		if normalizeTypeName(t.name) == want {
+			return t.typ, true
+		}
+	}
+	return ebpf.UnspecifiedMap, false
+}
+
+// MapTypeName returns the name MapInfo.Type uses for t. Types missing from
+// the table fall back to cilium's name in lowercase
+func MapTypeName(t ebpf.MapType) string {
+	for _, known := range mapTypes {
+		if known.typ == t {
+			return known.name
+		}
+	}
+	return strings.ToLower(t.String())
+}
+
+// IsLPMTrieType reports whether a MapInfo.Type is an LPM trie, whose keys
+// start with a prefix length
+func IsLPMTrieType(mapType string) bool {
+	t, ok := MapTypeFromString(mapType)
+	return ok && t == ebpf.LPMTrie
+}
+
+// MapTypeNames returns the names of all known map types in kernel enum
+// order
+func MapTypeNames() []string {
+	names := make([]string, len(mapTypes))
+	for i, t := range mapTypes {
+		names[i] = t.name
+	}
+	return names
+}
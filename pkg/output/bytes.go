@@ -0,0 +1,36 @@
+package output
+
+import "fmt"
+
+// byteUnits are the suffixes of humanizeBytes, each 1024 times the last.
+var byteUnits = []string{"K", "M", "G", "T", "P", "E"}
+
+// humanizeBytes renders a byte count with a base-1024 K, M, G... suffix
+// like ls -h: one decimal below 10 ("8.0K") and none above ("164K").
+// Counts below 1K are printed in bytes ("512B").
+func humanizeBytes(n uint64) string {
+	if n < 1024 {
+		return fmt.Sprintf("%dB", n)
+	}
+
+	v := float64(n)
+	unit := -1
+	// Move up a unit when rounding would print 1024 of the current one
+	for unit < len(byteUnits)-1 && v >= 1023.5 {
+		v /= 1024
+		unit++
+	}
+	if v < 9.95 {
+		return fmt.Sprintf("%.1f%s", v, byteUnits[unit])
+	}
+	return fmt.Sprintf("%.0f%s", v, byteUnits[unit])
+}
+
+// size renders a byte count for plain output: humanized with
+// WithHumanSizes, exact otherwise.
+func (f *PlainFormatter) size(n uint64) string {
+	if f.human {
+		return humanizeBytes(n)
+	}
+	return fmt.Sprintf("%dB", n)
+}
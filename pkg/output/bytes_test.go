@@ -0,0 +1,66 @@
+package output
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestHumanizeBytes(t *testing.T) {
+	tests := []struct {
+		n    uint64
+		want string
+	}{
+		{0, "0B"},
+		{512, "512B"},
+		{1023, "1023B"},
+		{1024, "1.0K"},
+		{1536, "1.5K"},
+		{8192, "8.0K"},
+		{10188, "9.9K"},
+		{10189, "10K"},
+		{167936, "164K"},
+		{1048063, "1023K"},
+		{1048064, "1.0M"}, // would round to 1024K
+		{1 << 20, "1.0M"},
+		{5 << 20, "5.0M"},
+		{100 << 20, "100M"},
+		{1 << 30, "1.0G"},
+		{3 << 30, "3.0G"},
+		{1 << 40, "1.0T"},
+		{1<<64 - 1, "16E"},
+	}
+
+	for _, tt := range tests {
+		if got := humanizeBytes(tt.n); got != tt.want {
+			t.Errorf("humanizeBytes(%d) = %q, want %q", tt.n, got, tt.want)
+		}
+	}
+}
+
+func TestPlainFormatter_HumanSizes(t *testing.T) {
+	human := NewFormatter(FormatPlain, WithHumanSizes())
+	exact := NewFormatter(FormatPlain)
+	p := ProgramInfo{ID: 1, Type: "xdp", Name: "p", Tag: "00", BytesXlat: 5200, BytesJIT: 3263, MemLock: 8192}
+	m := MapInfo{ID: 2, Type: "hash", Name: "m", KeySize: 4, ValueSize: 8, MaxEntries: 1024, MemLock: 167936}
+
+	tests := []struct {
+		name string
+		got  string
+		want string
+	}{
+		{"human program", human.FormatProgram(p), "xlated 5.1K  jited 3.2K  memlock 8.0K"},
+		{"human map", human.FormatMap(m), "key 4B  value 8B  max_entries 1024  memlock 164K"},
+		{"exact program", exact.FormatProgram(p), "xlated 5200B  jited 3263B  memlock 8192B"},
+		{"exact map", exact.FormatMap(m), "memlock 167936B"},
+	}
+	for _, tt := range tests {
+		if !strings.Contains(tt.got, tt.want) {
+			t.Errorf("%s = %q, want it to contain %q", tt.name, tt.got, tt.want)
+		}
+	}
+
+	// JSON keeps exact byte counts
+	if got := NewFormatter(FormatJSON, WithHumanSizes()).FormatMap(m); !strings.Contains(got, `"bytes_memlock":167936`) {
+		t.Errorf("JSON FormatMap() = %s, want exact bytes", got)
+	}
+}
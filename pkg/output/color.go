@@ -0,0 +1,24 @@
+package output
+
+import "fmt"
+
+// ANSI colors used by plain output with WithColor.
+const (
+	colorCyan   = "36"
+	colorGreen  = "32"
+	colorYellow = "33"
+)
+
+// paint wraps s in the ANSI escape codes of color when coloring is on, and
+// returns s unchanged otherwise.
+func (f *PlainFormatter) paint(color, s string) string {
+	if !f.color {
+		return s
+	}
+	return "\x1b[" + color + "m" + s + "\x1b[0m"
+}
+
+// id renders an object ID, in cyan with coloring on.
+func (f *PlainFormatter) id(id uint32) string {
+	return f.paint(colorCyan, fmt.Sprint(id))
+}
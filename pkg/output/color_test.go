@@ -0,0 +1,53 @@
+package output
+
+import (
+	"regexp"
+	"strings"
+	"testing"
+	"time"
+)
+
+// ansiEscape matches the color escapes of WithColor.
+var ansiEscape = regexp.MustCompile("\x1b\\[[0-9;]*m")
+
+func TestPlainFormatter_Color(t *testing.T) {
+	programs := []ProgramInfo{{
+		ID: 42, Type: "xdp", Name: "fw", Tag: "aabbccdd", GPL: true, LoadedAt: time.Unix(1700000000, 0), MapIDs: []uint32{7},
+		Maps: []ProgramMap{{ID: 7, Info: &MapInfo{ID: 7, Type: "hash", Name: "conns", KeySize: 4, ValueSize: 8, MaxEntries: 16}}},
+	}}
+	maps := []MapInfo{{ID: 7, Type: "hash", Name: "conns", KeySize: 4, ValueSize: 8, MaxEntries: 16}}
+
+	// Without coloring the output is exactly what it always was
+	plain := NewFormatter(FormatPlain, WithTimestampFormat(TimestampEpoch))
+	wantProgram := "42: xdp  name fw  tag aabbccdd  gpl\n" +
+		"\tloaded_at 1700000000  uid 0\n" +
+		"\txlated 0B  jited 0B  memlock 0B  map_ids 7\n" +
+		"\tmaps:\n" +
+		"\t\t7: hash  name conns  key 4B  value 8B  max_entries 16"
+	if got := plain.FormatPrograms(programs); got != wantProgram {
+		t.Errorf("FormatPrograms() = %q, want %q", got, wantProgram)
+	}
+	wantMap := "7: hash  name conns  flags 0x0\n\tkey 4B  value 8B  max_entries 16  memlock 0B"
+	if got := plain.FormatMaps(maps); got != wantMap {
+		t.Errorf("FormatMaps() = %q, want %q", got, wantMap)
+	}
+
+	colored := NewFormatter(FormatPlain, WithTimestampFormat(TimestampEpoch), WithColor())
+	got := colored.FormatPrograms(programs)
+	for _, want := range []string{"\x1b[36m42\x1b[0m: \x1b[32mxdp\x1b[0m", "\x1b[33mgpl\x1b[0m", "\x1b[36m7\x1b[0m: \x1b[32mhash\x1b[0m"} {
+		if !strings.Contains(got, want) {
+			t.Errorf("colored FormatPrograms() = %q, want it to contain %q", got, want)
+		}
+	}
+	if stripped := ansiEscape.ReplaceAllString(got, ""); stripped != wantProgram {
+		t.Errorf("colored FormatPrograms() without escapes = %q, want %q", stripped, wantProgram)
+	}
+	if stripped := ansiEscape.ReplaceAllString(colored.FormatMaps(maps), ""); stripped != wantMap {
+		t.Errorf("colored FormatMaps() without escapes = %q, want %q", stripped, wantMap)
+	}
+
+	// Tables align columns by byte width and stay uncolored
+	if got := NewFormatter(FormatTable, WithColor()).FormatPrograms(programs); ansiEscape.MatchString(got) {
+		t.Errorf("table FormatPrograms() = %q, want no colors", got)
+	}
+}
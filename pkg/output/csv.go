@@ -0,0 +1,90 @@
+package output
+
+import (
+	"encoding/csv"
+	"fmt"
+	"strings"
+
+	"github.com/viveksb007/gobpftool/internal/utils"
+)
+
+// CSVFormatter formats map entries as CSV rows of continuous hex strings
+// with a header line. Everything else uses the plain rendering since only
+// map dumps are tabular data.
+type CSVFormatter struct {
+	PlainFormatter
+}
+
+// FormatMapEntries formats map entries as CSV.
+// Format:
+//
+//	key_hex,value_hex
+//	00010203,0001020304050607
+//
+// Per-CPU entries get one value column per CPU:
+//
+//	key_hex,cpu0_hex,cpu1_hex
+//	01000000,0a00,0b00
+//
+// With WithKeyAs(KeyAsIP) a key_ip column follows the key, left empty for
+// keys that aren't 4 or 16 bytes.
+//
+// The header is printed even when there are no entries.
+func (f *CSVFormatter) FormatMapEntries(entries []MapEntry, keySize, valueSize uint32) string {
+	var sb strings.Builder
+	w := csv.NewWriter(&sb)
+
+	// Size the value columns from the widest per-CPU entry
+	numCPUs := 0
+	for _, e := range entries {
+		numCPUs = max(numCPUs, len(e.PerCPUValues))
+	}
+
+	withKeys := f.fields != EntryValuesOnly
+	keyIP := withKeys && f.keyAs == KeyAsIP
+
+	var header []string
+	if withKeys {
+		header = append(header, "key_hex")
+	}
+	if keyIP {
+		header = append(header, "key_ip")
+	}
+	if f.fields != EntryKeysOnly {
+		if numCPUs == 0 {
+			header = append(header, "value_hex")
+		}
+		for cpu := 0; cpu < numCPUs; cpu++ {
+			header = append(header, fmt.Sprintf("cpu%d_hex", cpu))
+		}
+	}
+	_ = w.Write(header)
+
+	for _, e := range entries {
+		var row []string
+		if withKeys {
+			row = append(row, utils.FormatHexString(e.Key))
+		}
+		if keyIP {
+			ip, _ := utils.BytesToIP(e.Key)
+			row = append(row, ip)
+		}
+		if f.fields != EntryKeysOnly {
+			if numCPUs == 0 {
+				row = append(row, utils.FormatHexString(e.Value))
+			}
+			for cpu := 0; cpu < numCPUs; cpu++ {
+				var value []byte
+				if cpu < len(e.PerCPUValues) {
+					value = e.PerCPUValues[cpu]
+				}
+				row = append(row, utils.FormatHexString(value))
+			}
+		}
+		_ = w.Write(row)
+	}
+
+	// Writes to a strings.Builder can't fail
+	w.Flush()
+	return sb.String()
+}
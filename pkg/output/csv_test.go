@@ -0,0 +1,69 @@
+package output
+
+import "testing"
+
+func TestCSVFormatter_FormatMapEntries(t *testing.T) {
+	formatter := &CSVFormatter{}
+
+	tests := []struct {
+		name     string
+		entries  []MapEntry
+		expected string
+	}{
+		{
+			name:     "empty map prints header",
+			entries:  []MapEntry{},
+			expected: "key_hex,value_hex\n",
+		},
+		{
+			name: "entries",
+			entries: []MapEntry{
+				{Key: []byte{0x00, 0x01, 0x02, 0x03}, Value: []byte{0x0a, 0x0b}},
+				{Key: []byte{0x04, 0x05, 0x06, 0x07}, Value: []byte{0x0c, 0x0d}},
+			},
+			expected: "key_hex,value_hex\n" +
+				"00010203,0a0b\n" +
+				"04050607,0c0d\n",
+		},
+		{
+			name: "per-CPU entries",
+			entries: []MapEntry{
+				{Key: []byte{0x01}, PerCPUValues: [][]byte{{0x0a}, {0x0b}}},
+			},
+			expected: "key_hex,cpu0_hex,cpu1_hex\n" +
+				"01,0a,0b\n",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result := formatter.FormatMapEntries(tt.entries, 4, 2)
+			if result != tt.expected {
+				t.Errorf("FormatMapEntries() =\n%q\nwant:\n%q", result, tt.expected)
+			}
+		})
+	}
+}
+
+func TestCSVFormatter_FormatMapEntries_KeysOnly(t *testing.T) {
+	formatter := &CSVFormatter{PlainFormatter{fields: EntryKeysOnly}}
+	entries := []MapEntry{{Key: []byte{0x01}, Value: []byte{0x0a}}}
+
+	expected := "key_hex\n01\n"
+	if result := formatter.FormatMapEntries(entries, 1, 1); result != expected {
+		t.Errorf("FormatMapEntries() = %q, want %q", result, expected)
+	}
+}
+
+func TestCSVFormatter_FormatMapEntries_KeyAsIP(t *testing.T) {
+	formatter := &CSVFormatter{PlainFormatter{keyAs: KeyAsIP}}
+	entries := []MapEntry{
+		{Key: []byte{0x0a, 0x00, 0x00, 0x01}, Value: []byte{0x0a}},
+		{Key: []byte{0x01}, Value: []byte{0x0b}},
+	}
+
+	expected := "key_hex,key_ip,value_hex\n0a000001,10.0.0.1,0a\n01,,0b\n"
+	if result := formatter.FormatMapEntries(entries, 4, 1); result != expected {
+		t.Errorf("FormatMapEntries() = %q, want %q", result, expected)
+	}
+}
@@ -13,21 +13,70 @@ const (
 	FormatJSON
 	// FormatJSONPretty outputs pretty-printed JSON with indentation.
 	FormatJSONPretty
+	// FormatYAML outputs YAML mirroring the JSON structure.
+	FormatYAML
+	// FormatTable outputs program and map lists as aligned columns.
+	FormatTable
+	// FormatCSV outputs map entries as CSV rows.
+	FormatCSV
 )
 
 // ProgramInfo contains information about an eBPF program.
 type ProgramInfo struct {
-	ID        uint32
-	Type      string
-	Name      string
-	Tag       string
-	GPL       bool
-	LoadedAt  time.Time
-	UID       uint32
-	BytesXlat uint32
-	BytesJIT  uint32
-	MemLock   uint32
-	MapIDs    []uint32
+	ID          uint32
+	Type        string
+	Name        string
+	Tag         string
+	GPL         bool
+	LoadedAt    time.Time
+	UID         uint32
+	UIDKnown    bool
+	BytesXlat   uint32
+	BytesJIT    uint32
+	MemLock     uint32
+	MapIDs      []uint32
+	PinnedPaths []string
+	RunTimeNS   uint64
+	RunCount    uint64
+	// VerifiedInsns is the number of instructions the verifier processed,
+	// zero when the kernel doesn't report it.
+	VerifiedInsns uint32
+	// BTFID is the ID of the program's BTF object, zero when it has none.
+	BTFID uint32
+	// Maps holds the details of the maps in MapIDs when they were expanded
+	// (used by prog list --with-maps).
+	Maps []ProgramMap
+	// Attach describes where the program is attached, or nil if unknown.
+	Attach *AttachInfo
+	// Metadata holds the program's declared metadata in declaration order
+	// (used by prog show --metadata).
+	Metadata []MetadataEntry
+}
+
+// MetadataEntry is a metadata variable declared by a program. Value is a
+// string or an integer.
+type MetadataEntry struct {
+	Name  string
+	Value interface{}
+}
+
+// AttachInfo describes where a program is attached. Ifindex is set for
+// device-bound programs, BTFObjID and BTFID for tracing programs.
+type AttachInfo struct {
+	Type     string
+	Ifindex  uint32
+	BTFObjID uint32
+	BTFID    uint32
+	// Target is the interface or function name, empty if unresolved.
+	Target string
+}
+
+// ProgramMap is a map used by a program. Info is nil and Error describes
+// why when the map couldn't be read.
+type ProgramMap struct {
+	ID    uint32
+	Info  *MapInfo
+	Error string
 }
 
 // MapInfo contains information about an eBPF map.
@@ -39,13 +88,113 @@ type MapInfo struct {
 	ValueSize  uint32
 	MaxEntries uint32
 	Flags      uint32
-	MemLock    uint32
+	// FlagNames holds the symbolic names of the bits set in Flags.
+	FlagNames   []string
+	MemLock     uint32
+	PinnedPaths []string
+	// Entries is the number of populated entries, only meaningful when
+	// EntriesCounted is set. EntriesCapped means counting stopped early
+	// and the map holds at least Entries.
+	Entries        int
+	EntriesCounted bool
+	EntriesCapped  bool
 }
 
 // MapEntry represents a key-value pair in an eBPF map.
 type MapEntry struct {
 	Key   []byte
 	Value []byte
+	// PerCPUValues holds one value per possible CPU for per-CPU maps.
+	PerCPUValues [][]byte
+	// FormattedKey and FormattedValue hold the BTF-decoded key and value.
+	// Both are nil when the entry wasn't decoded.
+	FormattedKey   interface{}
+	FormattedValue interface{}
+}
+
+// MapEntryChange is a map entry whose value changed between two dumps.
+type MapEntryChange struct {
+	Old MapEntry
+	New MapEntry
+}
+
+// MapDiff holds the entries added, removed and changed between two dumps
+// of a map.
+type MapDiff struct {
+	Added   []MapEntry
+	Removed []MapEntry
+	Changed []MapEntryChange
+}
+
+// Instruction represents a single translated eBPF instruction.
+type Instruction struct {
+	Offset int
+	Raw    []byte
+	Text   string
+}
+
+// BTFInfo contains information about a BTF object.
+type BTFInfo struct {
+	ID     uint32
+	Name   string
+	Size   uint32
+	Kernel bool
+}
+
+// BTFType is a BTF type rendered as a C declaration.
+type BTFType struct {
+	ID   uint32
+	Kind string
+	Name string
+	C    string
+}
+
+// UpdateResult is the outcome of applying several map updates. Errors
+// describes each update that failed.
+type UpdateResult struct {
+	Updated int
+	Failed  int
+	Errors  []string
+}
+
+// Record is a record read from a ring buffer or perf event array map.
+// CPU is only meaningful when CPUKnown is set, as ring buffers don't
+// record the CPU.
+type Record struct {
+	CPU      int
+	CPUKnown bool
+	Data     []byte
+}
+
+// VersionInfo describes a gobpftool build.
+type VersionInfo struct {
+	Version   string
+	GitCommit string
+	BuildDate string
+}
+
+// SystemInfo is an overview of the BPF environment (used by info).
+// Programs and Maps are only meaningful when CountsKnown is set, as
+// listing them needs privileges.
+type SystemInfo struct {
+	KernelRelease string
+	BPFFSRoot     string
+	BPFFSMounted  bool
+	Programs      int
+	Maps          int
+	CountsKnown   bool
+	CapBPF        bool
+	CapSysAdmin   bool
+}
+
+// RunResult is the outcome of a program test run.
+type RunResult struct {
+	ReturnValue uint32
+	DataOut     []byte
+	ContextOut  []byte
+	Repeat      uint32
+	// Duration is the average time of a single run.
+	Duration time.Duration
 }
 
 // Formatter defines the interface for formatting eBPF program and map output.
@@ -53,30 +202,265 @@ type Formatter interface {
 	// FormatPrograms formats a list of programs for output.
 	FormatPrograms(progs []ProgramInfo) string
 
+	// FormatProgram formats a single program (used by prog show id).
+	FormatProgram(prog ProgramInfo) string
+
 	// FormatMaps formats a list of maps for output.
 	FormatMaps(maps []MapInfo) string
 
+	// FormatMap formats a single map (used by map show id).
+	FormatMap(m MapInfo) string
+
 	// FormatMapEntries formats map entries for output (used by dump).
 	FormatMapEntries(entries []MapEntry, keySize, valueSize uint32) string
 
 	// FormatMapEntry formats a single map entry (used by lookup).
 	FormatMapEntry(entry MapEntry, keySize, valueSize uint32) string
 
+	// FormatMapDiff formats the differences between two map dumps (used by diff).
+	FormatMapDiff(diff MapDiff) string
+
 	// FormatNextKey formats the next key result (used by getnext).
 	FormatNextKey(currentKey, nextKey []byte) string
 
+	// FormatInstructions formats translated instructions (used by prog dump xlated).
+	FormatInstructions(insns []Instruction) string
+
+	// FormatJited formats JIT-compiled machine code (used by prog dump jited).
+	FormatJited(code []byte) string
+
+	// FormatStats formats the run time statistics of a program (used by prog stats).
+	FormatStats(prog ProgramInfo) string
+
+	// FormatBTFs formats a list of BTF objects (used by btf list).
+	FormatBTFs(objects []BTFInfo) string
+
+	// FormatBTFTypes formats the types of a BTF object (used by btf dump).
+	FormatBTFTypes(types []BTFType) string
+
+	// FormatRunResult formats the outcome of a test run (used by prog run).
+	FormatRunResult(result RunResult) string
+
+	// FormatCount formats the number of objects of a kind such as "program"
+	// or "map" (used by list --count).
+	FormatCount(count int, kind string) string
+
+	// FormatDeleted formats the number of entries removed from a map (used by clear).
+	FormatDeleted(count int) string
+
+	// FormatUpdateResult formats the outcome of several map updates (used
+	// by update --stdin).
+	FormatUpdateResult(result UpdateResult) string
+
+	// FormatRecord formats a record streamed from a map (used by map read).
+	FormatRecord(r Record) string
+
+	// FormatUnpinned formats the paths removed by an unpin operation.
+	FormatUnpinned(paths []string) string
+
+	// FormatVersion formats the build information (used by version).
+	FormatVersion(info VersionInfo) string
+
+	// FormatSystemInfo formats an overview of the BPF environment (used
+	// by info).
+	FormatSystemInfo(info SystemInfo) string
+
 	// FormatError formats an error message.
 	FormatError(err error) string
 }
 
+// averageRunTime returns the average run time of a program in nanoseconds,
+// or zero when it never ran.
+func averageRunTime(p ProgramInfo) uint64 {
+	if p.RunCount == 0 {
+		return 0
+	}
+	return p.RunTimeNS / p.RunCount
+}
+
+// EntryFields selects which parts of map entries FormatMapEntries emits.
+type EntryFields int
+
+const (
+	// EntryKeysAndValues emits both keys and values.
+	EntryKeysAndValues EntryFields = iota
+	// EntryKeysOnly emits only keys.
+	EntryKeysOnly
+	// EntryValuesOnly emits only values.
+	EntryValuesOnly
+)
+
+// apply clears the parts of e that ef leaves out.
+func (ef EntryFields) apply(e MapEntry) MapEntry {
+	switch ef {
+	case EntryKeysOnly:
+		e.Value, e.PerCPUValues, e.FormattedValue = nil, nil, nil
+	case EntryValuesOnly:
+		e.Key, e.FormattedKey = nil, nil
+	}
+	return e
+}
+
+// options holds the settings shared by all formatters.
+type options struct {
+	timestamp TimestampFormat
+	fields    EntryFields
+	valueAs   string
+	columns   []string
+	page      *Page
+	partial   *Partial
+	human     bool
+	color     bool
+	mapNames  map[uint32]string
+	quiet     bool
+	keyAs     string
+}
+
+// Page is the window of entries a paged map dump returned: Offset entries
+// were skipped and at most Limit were kept. A zero Limit means no limit.
+type Page struct {
+	Offset int
+	Limit  int
+}
+
+// Partial marks program and map lists or map dumps as incomplete, e.g.
+// because some objects weren't accessible or the dump was cut short.
+// Reason says why and may be empty.
+type Partial struct {
+	Reason string
+}
+
+// Option configures a Formatter created by NewFormatter.
+type Option func(*options)
+
+// WithTimestampFormat sets how timestamps such as loaded_at are rendered.
+// The default is TimestampISO.
+func WithTimestampFormat(tf TimestampFormat) Option {
+	return func(o *options) {
+		o.timestamp = tf
+	}
+}
+
+// WithEntryFields limits FormatMapEntries to keys or values (used by
+// dump --keys-only and --values-only). The default is EntryKeysAndValues.
+func WithEntryFields(ef EntryFields) Option {
+	return func(o *options) {
+		o.fields = ef
+	}
+}
+
+// WithValueAs interprets map values as integers of the given kind, one of
+// utils.IntKinds, and prints the decimal alongside the hex bytes.
+func WithValueAs(kind string) Option {
+	return func(o *options) {
+		o.valueAs = kind
+	}
+}
+
+// KeyAsIP is the WithKeyAs kind that renders 4-byte keys as IPv4 and
+// 16-byte keys as IPv6 addresses.
+const KeyAsIP = "ip"
+
+// WithKeyAs interprets map keys as the given kind, currently only KeyAsIP,
+// and prints the result alongside the hex bytes of dumped and looked up
+// entries; JSON and YAML add key_ip. Keys of other sizes are left as hex.
+func WithKeyAs(kind string) Option {
+	return func(o *options) {
+		o.keyAs = kind
+	}
+}
+
+// WithColumns selects the columns of program and map tables by name, in
+// order, as returned by ParseColumns. It only affects FormatTable.
+func WithColumns(columns []string) Option {
+	return func(o *options) {
+		o.columns = columns
+	}
+}
+
+// WithPage records the window of a paged map dump (dump --offset and
+// --limit) so JSON and YAML include it next to the entry count.
+func WithPage(offset, limit int) Option {
+	return func(o *options) {
+		o.page = &Page{Offset: offset, Limit: limit}
+	}
+}
+
+// WithPartial marks the results as incomplete, for the reason given, so
+// JSON and YAML set partial and partial_reason in their top-level objects.
+// Without it, partial is false.
+func WithPartial(reason string) Option {
+	return func(o *options) {
+		o.partial = &Partial{Reason: reason}
+	}
+}
+
+// WithHumanSizes renders the byte sizes of programs and maps in plain
+// output with K, M and G suffixes (e.g. 8.0K) instead of exact bytes.
+// JSON and YAML always keep exact byte counts.
+func WithHumanSizes() Option {
+	return func(o *options) {
+		o.human = true
+	}
+}
+
+// WithColor highlights program and map IDs, types and the gpl marker in
+// plain output with ANSI colors, for terminals. Other formats, including
+// tables, ignore it.
+func WithColor() Option {
+	return func(o *options) {
+		o.color = true
+	}
+}
+
+// WithMapNames makes JSON and YAML list a program's map_ids as objects
+// with the map's ID and name, e.g. {"id":85,"name":"some_map"}, instead of
+// bare IDs. names maps each resolved map ID to its name; maps missing from
+// it are listed with their ID only. Plain output ignores it.
+func WithMapNames(names map[uint32]string) Option {
+	return func(o *options) {
+		o.mapNames = names
+	}
+}
+
+// WithQuiet leaves the "Found <n> elements" trailer out of plain map
+// dumps, so scripts only see entries. JSON and YAML ignore it.
+func WithQuiet() Option {
+	return func(o *options) {
+		o.quiet = true
+	}
+}
+
 // NewFormatter creates a new Formatter based on the specified format.
-func NewFormatter(format Format) Formatter {
+// Formats added with RegisterFormatter come from their factory, which
+// ignores opts.
+func NewFormatter(format Format, opts ...Option) Formatter {
+	if f, ok := registeredFormatter(format); ok {
+		return f
+	}
+
+	var o options
+	for _, opt := range opts {
+		opt(&o)
+	}
+
+	plain := PlainFormatter{timestamp: o.timestamp, fields: o.fields, valueAs: o.valueAs, keyAs: o.keyAs, human: o.human, quiet: o.quiet}
+	jsonFmt := JSONFormatter{timestamp: o.timestamp, fields: o.fields, valueAs: o.valueAs, keyAs: o.keyAs, page: o.page, partial: o.partial, mapNames: o.mapNames}
+
 	switch format {
 	case FormatJSON:
-		return &JSONFormatter{pretty: false}
+		return &jsonFmt
 	case FormatJSONPretty:
-		return &JSONFormatter{pretty: true}
+		jsonFmt.pretty = true
+		return &jsonFmt
+	case FormatYAML:
+		return &YAMLFormatter{json: jsonFmt}
+	case FormatTable:
+		return &TableFormatter{PlainFormatter: plain, columns: o.columns}
+	case FormatCSV:
+		return &CSVFormatter{plain}
 	default:
-		return &PlainFormatter{}
+		plain.color = o.color
+		return &plain
 	}
 }
@@ -1,13 +1,25 @@
 package output
 
 import (
+	"bytes"
 	"encoding/json"
 	"fmt"
+
+	"github.com/viveksb007/gobpftool/internal/utils"
+	bpferrors "github.com/viveksb007/gobpftool/pkg/errors"
 )
 
 // JSONFormatter formats output as JSON, compatible with bpftool JSON output.
 type JSONFormatter struct {
-	pretty bool
+	pretty    bool
+	timestamp TimestampFormat
+	fields    EntryFields
+	valueAs   string
+	keyAs     string
+	page      *Page
+	partial   *Partial
+	// mapNames, when set, turns map_ids into objects with the map names
+	mapNames map[uint32]string
 }
 
 // programJSON represents a program in bpftool-compatible JSON format.
@@ -18,45 +30,175 @@ type programJSON struct {
 	Tag           string   `json:"tag"`
 	GPLCompatible bool     `json:"gpl_compatible"`
 	LoadedAt      string   `json:"loaded_at"`
-	UID           uint32   `json:"uid"`
+	UID           *uint32  `json:"uid,omitempty"`
 	BytesXlated   uint32   `json:"bytes_xlated"`
 	BytesJited    uint32   `json:"bytes_jited"`
 	BytesMemlock  uint32   `json:"bytes_memlock"`
 	MapIDs        []uint32 `json:"map_ids,omitempty"`
+	BTFID         uint32   `json:"btf_id,omitempty"`
+	PinnedPaths   []string `json:"pinned_paths,omitempty"`
+	RunTimeNS     uint64   `json:"run_time_ns,omitempty"`
+	RunCount      uint64   `json:"run_cnt,omitempty"`
+	VerifiedInsns uint32   `json:"verified_insns,omitempty"`
+	// Maps holds a mapJSON, or a mapErrorJSON for maps that couldn't be read.
+	Maps     []interface{} `json:"maps,omitempty"`
+	Attach   *attachJSON   `json:"attach,omitempty"`
+	Metadata metadataJSON  `json:"metadata,omitempty"`
+
+	// mapRefs replaces MapIDs in the output when map names were resolved
+	mapRefs []mapRefJSON
+}
+
+// MarshalJSON encodes the program, with map_ids as objects holding the map
+// names when they were resolved.
+func (p programJSON) MarshalJSON() ([]byte, error) {
+	type program programJSON
+	if p.mapRefs == nil {
+		return json.Marshal(program(p))
+	}
+	// The outer map_ids shadows the one of the embedded program
+	return json.Marshal(struct {
+		program
+		MapIDs []mapRefJSON `json:"map_ids"`
+	}{program(p), p.mapRefs})
+}
+
+// metadataJSON represents a program's metadata as a JSON object with the
+// variables in declaration order.
+type metadataJSON []MetadataEntry
+
+// MarshalJSON encodes the metadata as a JSON object preserving order.
+func (m metadataJSON) MarshalJSON() ([]byte, error) {
+	var buf bytes.Buffer
+	buf.WriteByte('{')
+	for i, entry := range m {
+		if i > 0 {
+			buf.WriteByte(',')
+		}
+		name, err := json.Marshal(entry.Name)
+		if err != nil {
+			return nil, err
+		}
+		value, err := json.Marshal(entry.Value)
+		if err != nil {
+			return nil, err
+		}
+		buf.Write(name)
+		buf.WriteByte(':')
+		buf.Write(value)
+	}
+	buf.WriteByte('}')
+	return buf.Bytes(), nil
+}
+
+// attachJSON represents where a program is attached.
+type attachJSON struct {
+	Type     string `json:"type"`
+	Ifindex  uint32 `json:"ifindex,omitempty"`
+	BTFObjID uint32 `json:"btf_obj_id,omitempty"`
+	BTFID    uint32 `json:"btf_id,omitempty"`
+	Target   string `json:"target,omitempty"`
+}
+
+// mapRefJSON is a map used by a program, with the name resolved from its
+// ID. Name is left out when the map couldn't be resolved.
+type mapRefJSON struct {
+	ID   uint32  `json:"id"`
+	Name *string `json:"name,omitempty"`
+}
+
+// mapErrorJSON is the placeholder for a program's map that couldn't be read.
+type mapErrorJSON struct {
+	ID    uint32 `json:"id"`
+	Error string `json:"error"`
+}
+
+// partialJSON tells consumers whether a list or dump is incomplete. It is
+// embedded in the top-level wrappers; partial is always present so the
+// schema doesn't change with the result.
+type partialJSON struct {
+	Partial       bool   `json:"partial"`
+	PartialReason string `json:"partial_reason,omitempty"`
 }
 
 // programsJSON wraps programs for JSON output.
 type programsJSON struct {
 	Programs []programJSON `json:"programs"`
+	partialJSON
 }
 
 // mapJSON represents a map in bpftool-compatible JSON format.
 type mapJSON struct {
-	ID           uint32 `json:"id"`
-	Type         string `json:"type"`
-	Name         string `json:"name"`
-	KeySize      uint32 `json:"key_size"`
-	ValueSize    uint32 `json:"value_size"`
-	MaxEntries   uint32 `json:"max_entries"`
-	Flags        uint32 `json:"flags"`
-	BytesMemlock uint32 `json:"bytes_memlock"`
+	ID           uint32   `json:"id"`
+	Type         string   `json:"type"`
+	Name         string   `json:"name"`
+	KeySize      uint32   `json:"key_size"`
+	ValueSize    uint32   `json:"value_size"`
+	MaxEntries   uint32   `json:"max_entries"`
+	Flags        uint32   `json:"flags"`
+	FlagsNames   []string `json:"flags_names,omitempty"`
+	BytesMemlock uint32   `json:"bytes_memlock"`
+	PinnedPaths  []string `json:"pinned_paths,omitempty"`
+	// Entries is only set when the entries were counted
+	Entries       *int `json:"entries,omitempty"`
+	EntriesCapped bool `json:"entries_capped,omitempty"`
 }
 
 // mapsJSON wraps maps for JSON output.
 type mapsJSON struct {
 	Maps []mapJSON `json:"maps"`
+	partialJSON
 }
 
 // mapEntryJSON represents a map entry in JSON format.
 type mapEntryJSON struct {
-	Key   []byte `json:"key"`
-	Value []byte `json:"value"`
+	Key    []byte            `json:"key,omitempty"`
+	Value  []byte            `json:"value,omitzero"`
+	Values []perCPUValueJSON `json:"values,omitempty"`
+	// KeyIP is the key rendered as an IP address (used by --key-as ip).
+	KeyIP string `json:"key_ip,omitempty"`
+	// ValueInt is the value interpreted as an integer (used by --value-as).
+	ValueInt *uint64 `json:"value_int,omitempty"`
+	// Formatted holds the BTF-decoded key and value, like bpftool -p.
+	Formatted *formattedJSON `json:"formatted,omitempty"`
+}
+
+// formattedJSON represents the BTF-decoded form of a map entry.
+type formattedJSON struct {
+	Key   interface{} `json:"key,omitempty"`
+	Value interface{} `json:"value,omitzero"`
+}
+
+// perCPUValueJSON represents one CPU's value of a per-CPU map entry.
+type perCPUValueJSON struct {
+	CPU      int     `json:"cpu"`
+	Value    []byte  `json:"value"`
+	ValueInt *uint64 `json:"value_int,omitempty"`
 }
 
 // mapEntriesJSON wraps map entries for JSON output.
 type mapEntriesJSON struct {
 	Entries []mapEntryJSON `json:"entries"`
 	Count   int            `json:"count"`
+	// Offset and Limit are only set for paged dumps
+	Offset *int `json:"offset,omitempty"`
+	Limit  *int `json:"limit,omitempty"`
+	partialJSON
+}
+
+// mapDiffJSON represents the differences between two map dumps in JSON format.
+type mapDiffJSON struct {
+	Added   []mapEntryJSON       `json:"added"`
+	Removed []mapEntryJSON       `json:"removed"`
+	Changed []mapEntryChangeJSON `json:"changed"`
+}
+
+// mapEntryChangeJSON represents a changed map entry. Old and New carry only
+// the values.
+type mapEntryChangeJSON struct {
+	Key []byte       `json:"key"`
+	Old mapEntryJSON `json:"old"`
+	New mapEntryJSON `json:"new"`
 }
 
 // nextKeyJSON represents a next key result in JSON format.
@@ -65,74 +207,331 @@ type nextKeyJSON struct {
 	NextKey []byte `json:"next_key"`
 }
 
+// instructionJSON represents a translated instruction in JSON format.
+type instructionJSON struct {
+	Offset int    `json:"offset"`
+	Raw    []byte `json:"raw"`
+	Insn   string `json:"insn"`
+}
+
+// jitedJSON represents JIT-compiled machine code in JSON format.
+type jitedJSON struct {
+	Jited []byte `json:"jited"`
+}
+
+// statsJSON represents the run time statistics of a program in JSON format.
+type statsJSON struct {
+	ID        uint32 `json:"id"`
+	Type      string `json:"type"`
+	Name      string `json:"name"`
+	RunTimeNS uint64 `json:"run_time_ns"`
+	RunCount  uint64 `json:"run_cnt"`
+	AvgNS     uint64 `json:"avg_ns"`
+}
+
+// btfJSON represents a BTF object in JSON format.
+type btfJSON struct {
+	ID     uint32 `json:"id"`
+	Name   string `json:"name,omitempty"`
+	Size   uint32 `json:"size"`
+	Kernel bool   `json:"kernel"`
+}
+
+// btfsJSON wraps BTF objects for JSON output.
+type btfsJSON struct {
+	BTFs []btfJSON `json:"btfs"`
+}
+
+// btfTypeJSON represents a BTF type in JSON format.
+type btfTypeJSON struct {
+	ID   uint32 `json:"id"`
+	Kind string `json:"kind"`
+	Name string `json:"name"`
+	C    string `json:"c"`
+}
+
+// btfTypesJSON wraps BTF types for JSON output.
+type btfTypesJSON struct {
+	Types []btfTypeJSON `json:"types"`
+}
+
+// runResultJSON represents the outcome of a test run in JSON format.
+type runResultJSON struct {
+	Retval     uint32 `json:"retval"`
+	Repeat     uint32 `json:"repeat"`
+	DurationNS int64  `json:"duration_ns"`
+	DataOut    []byte `json:"data_out,omitempty"`
+	CtxOut     []byte `json:"ctx_out,omitempty"`
+}
+
+// countJSON represents an object count in JSON format.
+type countJSON struct {
+	Count int `json:"count"`
+}
+
+// versionJSON represents the build information in JSON format.
+type versionJSON struct {
+	Version   string `json:"version"`
+	GitCommit string `json:"git_commit"`
+	BuildDate string `json:"build_date"`
+}
+
+// systemInfoJSON represents an overview of the BPF environment in JSON
+// format.
+type systemInfoJSON struct {
+	KernelRelease string `json:"kernel_release"`
+	BPFFSRoot     string `json:"bpffs_root"`
+	BPFFSMounted  bool   `json:"bpffs_mounted"`
+	// Programs and Maps are omitted when they couldn't be counted
+	Programs    *int `json:"programs,omitempty"`
+	Maps        *int `json:"maps,omitempty"`
+	CapBPF      bool `json:"cap_bpf"`
+	CapSysAdmin bool `json:"cap_sys_admin"`
+}
+
+// deletedJSON represents the result of clearing a map in JSON format.
+type deletedJSON struct {
+	Deleted int `json:"deleted"`
+}
+
+// recordJSON represents a record streamed from a map in JSON format.
+type recordJSON struct {
+	CPU  *int   `json:"cpu,omitempty"`
+	Data []byte `json:"data"`
+}
+
+// updateResultJSON represents the outcome of several map updates in JSON
+// format.
+type updateResultJSON struct {
+	Updated int      `json:"updated"`
+	Failed  int      `json:"failed"`
+	Errors  []string `json:"errors"`
+}
+
+// unpinnedJSON represents the result of an unpin operation in JSON format.
+type unpinnedJSON struct {
+	Unpinned []string `json:"unpinned"`
+	Count    int      `json:"count"`
+}
+
 // errorJSON represents an error in JSON format.
 type errorJSON struct {
 	Error string `json:"error"`
+	// Code classifies the error, e.g. "permission" or "not_found", so
+	// scripts don't have to match on the message.
+	Code string `json:"code"`
 }
 
 // FormatPrograms formats programs as JSON.
 func (f *JSONFormatter) FormatPrograms(progs []ProgramInfo) string {
 	programs := make([]programJSON, len(progs))
 	for i, p := range progs {
-		programs[i] = programJSON{
-			ID:            p.ID,
-			Type:          p.Type,
-			Name:          p.Name,
-			Tag:           p.Tag,
-			GPLCompatible: p.GPL,
-			LoadedAt:      p.LoadedAt.Format("2006-01-02T15:04:05-0700"),
-			UID:           p.UID,
-			BytesXlated:   p.BytesXlat,
-			BytesJited:    p.BytesJIT,
-			BytesMemlock:  p.MemLock,
-			MapIDs:        p.MapIDs,
+		programs[i] = f.newProgramJSON(p)
+	}
+
+	return f.marshal(programsJSON{Programs: programs, partialJSON: f.newPartialJSON()})
+}
+
+// FormatProgram formats a single program as a bare JSON object, like
+// bpftool prints one program looked up by ID.
+func (f *JSONFormatter) FormatProgram(p ProgramInfo) string {
+	return f.marshal(f.newProgramJSON(p))
+}
+
+// newProgramJSON converts a ProgramInfo to its JSON representation.
+func (f *JSONFormatter) newProgramJSON(p ProgramInfo) programJSON {
+	// Omit uid entirely when unknown so it is not mistaken for root
+	var uid *uint32
+	if p.UIDKnown {
+		uid = &p.UID
+	}
+
+	program := programJSON{
+		ID:            p.ID,
+		Type:          p.Type,
+		Name:          p.Name,
+		Tag:           p.Tag,
+		GPLCompatible: p.GPL,
+		LoadedAt:      f.timestamp.format(p.LoadedAt),
+		UID:           uid,
+		BytesXlated:   p.BytesXlat,
+		BytesJited:    p.BytesJIT,
+		BytesMemlock:  p.MemLock,
+		MapIDs:        p.MapIDs,
+		BTFID:         p.BTFID,
+		PinnedPaths:   p.PinnedPaths,
+		VerifiedInsns: p.VerifiedInsns,
+		Metadata:      p.Metadata,
+		mapRefs:       f.mapRefs(p.MapIDs),
+	}
+	if p.RunTimeNS > 0 {
+		program.RunTimeNS = p.RunTimeNS
+		program.RunCount = p.RunCount
+	}
+	if a := p.Attach; a != nil {
+		program.Attach = &attachJSON{
+			Type:     a.Type,
+			Ifindex:  a.Ifindex,
+			BTFObjID: a.BTFObjID,
+			BTFID:    a.BTFID,
+			Target:   a.Target,
 		}
 	}
+	for _, m := range p.Maps {
+		if m.Info == nil {
+			program.Maps = append(program.Maps, mapErrorJSON{ID: m.ID, Error: m.Error})
+		} else {
+			program.Maps = append(program.Maps, newMapJSON(*m.Info))
+		}
+	}
+	return program
+}
 
-	return f.marshal(programsJSON{Programs: programs})
+// mapRefs pairs a program's map IDs with the resolved map names, or
+// returns nil when no names were given or the program uses no maps.
+func (f *JSONFormatter) mapRefs(ids []uint32) []mapRefJSON {
+	if f.mapNames == nil || len(ids) == 0 {
+		return nil
+	}
+	refs := make([]mapRefJSON, len(ids))
+	for i, id := range ids {
+		refs[i].ID = id
+		if name, ok := f.mapNames[id]; ok {
+			refs[i].Name = &name
+		}
+	}
+	return refs
 }
 
 // FormatMaps formats maps as JSON.
 func (f *JSONFormatter) FormatMaps(maps []MapInfo) string {
 	jsonMaps := make([]mapJSON, len(maps))
 	for i, m := range maps {
-		jsonMaps[i] = mapJSON{
-			ID:           m.ID,
-			Type:         m.Type,
-			Name:         m.Name,
-			KeySize:      m.KeySize,
-			ValueSize:    m.ValueSize,
-			MaxEntries:   m.MaxEntries,
-			Flags:        m.Flags,
-			BytesMemlock: m.MemLock,
-		}
+		jsonMaps[i] = newMapJSON(m)
 	}
 
-	return f.marshal(mapsJSON{Maps: jsonMaps})
+	return f.marshal(mapsJSON{Maps: jsonMaps, partialJSON: f.newPartialJSON()})
+}
+
+// FormatMap formats a single map as a bare JSON object, like bpftool
+// prints one map looked up by ID.
+func (f *JSONFormatter) FormatMap(m MapInfo) string {
+	return f.marshal(newMapJSON(m))
+}
+
+// newPartialJSON returns the partial fields of the results being formatted.
+func (f *JSONFormatter) newPartialJSON() partialJSON {
+	if f.partial == nil {
+		return partialJSON{}
+	}
+	return partialJSON{Partial: true, PartialReason: f.partial.Reason}
+}
+
+// newMapJSON converts a MapInfo to its JSON representation.
+func newMapJSON(m MapInfo) mapJSON {
+	// Omit entries when not counted so it is not mistaken for an empty map
+	var entries *int
+	if m.EntriesCounted {
+		entries = &m.Entries
+	}
+	return mapJSON{
+		ID:            m.ID,
+		Type:          m.Type,
+		Name:          m.Name,
+		KeySize:       m.KeySize,
+		ValueSize:     m.ValueSize,
+		MaxEntries:    m.MaxEntries,
+		Flags:         m.Flags,
+		FlagsNames:    m.FlagNames,
+		BytesMemlock:  m.MemLock,
+		PinnedPaths:   m.PinnedPaths,
+		Entries:       entries,
+		EntriesCapped: m.EntriesCapped,
+	}
 }
 
 // FormatMapEntries formats map entries as JSON.
 func (f *JSONFormatter) FormatMapEntries(entries []MapEntry, keySize, valueSize uint32) string {
 	jsonEntries := make([]mapEntryJSON, len(entries))
 	for i, e := range entries {
-		jsonEntries[i] = mapEntryJSON{
-			Key:   e.Key,
-			Value: e.Value,
-		}
+		jsonEntries[i] = f.newMapEntryJSON(f.fields.apply(e))
 	}
 
-	return f.marshal(mapEntriesJSON{
-		Entries: jsonEntries,
-		Count:   len(entries),
-	})
+	doc := mapEntriesJSON{
+		Entries:     jsonEntries,
+		Count:       len(entries),
+		partialJSON: f.newPartialJSON(),
+	}
+	if f.page != nil {
+		doc.Offset = &f.page.Offset
+		if f.page.Limit > 0 {
+			doc.Limit = &f.page.Limit
+		}
+	}
+	return f.marshal(doc)
 }
 
 // FormatMapEntry formats a single map entry as JSON.
 func (f *JSONFormatter) FormatMapEntry(entry MapEntry, keySize, valueSize uint32) string {
-	return f.marshal(mapEntryJSON{
-		Key:   entry.Key,
-		Value: entry.Value,
-	})
+	return f.marshal(f.newMapEntryJSON(f.fields.apply(entry)))
+}
+
+// newMapEntryJSON converts a MapEntry, expanding per-CPU values into an array.
+// Values are also interpreted as integers when an integer kind is set.
+func (f *JSONFormatter) newMapEntryJSON(e MapEntry) mapEntryJSON {
+	entry := mapEntryJSON{
+		Key:   e.Key,
+		Value: e.Value,
+	}
+	if e.Key != nil && f.keyAs == KeyAsIP {
+		entry.KeyIP, _ = utils.BytesToIP(e.Key)
+	}
+	if e.Value != nil {
+		entry.ValueInt = f.valueInt(e.Value)
+	}
+	for cpu, value := range e.PerCPUValues {
+		entry.Values = append(entry.Values, perCPUValueJSON{CPU: cpu, Value: value, ValueInt: f.valueInt(value)})
+	}
+	if e.FormattedKey != nil || e.FormattedValue != nil {
+		entry.Formatted = &formattedJSON{Key: e.FormattedKey, Value: e.FormattedValue}
+	}
+	return entry
+}
+
+// FormatMapDiff formats the differences between two map dumps as JSON.
+func (f *JSONFormatter) FormatMapDiff(diff MapDiff) string {
+	out := mapDiffJSON{
+		Added:   make([]mapEntryJSON, len(diff.Added)),
+		Removed: make([]mapEntryJSON, len(diff.Removed)),
+		Changed: make([]mapEntryChangeJSON, len(diff.Changed)),
+	}
+	for i, e := range diff.Added {
+		out.Added[i] = f.newMapEntryJSON(e)
+	}
+	for i, e := range diff.Removed {
+		out.Removed[i] = f.newMapEntryJSON(e)
+	}
+	for i, c := range diff.Changed {
+		out.Changed[i] = mapEntryChangeJSON{
+			Key: c.New.Key,
+			Old: f.newMapEntryJSON(EntryValuesOnly.apply(c.Old)),
+			New: f.newMapEntryJSON(EntryValuesOnly.apply(c.New)),
+		}
+	}
+	return f.marshal(out)
+}
+
+// valueInt interprets value as an integer of the configured kind, or
+// returns nil when no kind is set or value doesn't have its size.
+func (f *JSONFormatter) valueInt(value []byte) *uint64 {
+	if f.valueAs == "" {
+		return nil
+	}
+	n, err := utils.DecodeInt(value, f.valueAs)
+	if err != nil {
+		return nil
+	}
+	return &n
 }
 
 // FormatNextKey formats the next key result as JSON.
@@ -143,9 +542,126 @@ func (f *JSONFormatter) FormatNextKey(currentKey, nextKey []byte) string {
 	})
 }
 
+// FormatInstructions formats translated instructions as a JSON array.
+func (f *JSONFormatter) FormatInstructions(insns []Instruction) string {
+	jsonInsns := make([]instructionJSON, len(insns))
+	for i, ins := range insns {
+		jsonInsns[i] = instructionJSON{Offset: ins.Offset, Raw: ins.Raw, Insn: ins.Text}
+	}
+	return f.marshal(jsonInsns)
+}
+
+// FormatJited formats JIT-compiled machine code as JSON.
+func (f *JSONFormatter) FormatJited(code []byte) string {
+	return f.marshal(jitedJSON{Jited: code})
+}
+
+// FormatStats formats the run time statistics of a program as JSON.
+func (f *JSONFormatter) FormatStats(p ProgramInfo) string {
+	return f.marshal(statsJSON{
+		ID:        p.ID,
+		Type:      p.Type,
+		Name:      p.Name,
+		RunTimeNS: p.RunTimeNS,
+		RunCount:  p.RunCount,
+		AvgNS:     averageRunTime(p),
+	})
+}
+
+// FormatBTFs formats BTF objects as JSON.
+func (f *JSONFormatter) FormatBTFs(objects []BTFInfo) string {
+	out := btfsJSON{BTFs: make([]btfJSON, len(objects))}
+	for i, obj := range objects {
+		out.BTFs[i] = btfJSON{ID: obj.ID, Name: obj.Name, Size: obj.Size, Kernel: obj.Kernel}
+	}
+	return f.marshal(out)
+}
+
+// FormatBTFTypes formats BTF types as JSON.
+func (f *JSONFormatter) FormatBTFTypes(types []BTFType) string {
+	out := btfTypesJSON{Types: make([]btfTypeJSON, len(types))}
+	for i, t := range types {
+		out.Types[i] = btfTypeJSON{ID: t.ID, Kind: t.Kind, Name: t.Name, C: t.C}
+	}
+	return f.marshal(out)
+}
+
+// FormatRunResult formats the outcome of a test run as JSON.
+func (f *JSONFormatter) FormatRunResult(r RunResult) string {
+	return f.marshal(runResultJSON{
+		Retval:     r.ReturnValue,
+		Repeat:     r.Repeat,
+		DurationNS: r.Duration.Nanoseconds(),
+		DataOut:    r.DataOut,
+		CtxOut:     r.ContextOut,
+	})
+}
+
+// FormatCount formats an object count as JSON.
+func (f *JSONFormatter) FormatCount(count int, kind string) string {
+	return f.marshal(countJSON{Count: count})
+}
+
+// FormatVersion formats the build information as JSON.
+func (f *JSONFormatter) FormatVersion(info VersionInfo) string {
+	return f.marshal(versionJSON{
+		Version:   info.Version,
+		GitCommit: info.GitCommit,
+		BuildDate: info.BuildDate,
+	})
+}
+
+// FormatSystemInfo formats an overview of the BPF environment as JSON.
+func (f *JSONFormatter) FormatSystemInfo(info SystemInfo) string {
+	doc := systemInfoJSON{
+		KernelRelease: info.KernelRelease,
+		BPFFSRoot:     info.BPFFSRoot,
+		BPFFSMounted:  info.BPFFSMounted,
+		CapBPF:        info.CapBPF,
+		CapSysAdmin:   info.CapSysAdmin,
+	}
+	if info.CountsKnown {
+		doc.Programs, doc.Maps = &info.Programs, &info.Maps
+	}
+	return f.marshal(doc)
+}
+
+// FormatDeleted formats the number of entries removed from a map as JSON.
+func (f *JSONFormatter) FormatDeleted(count int) string {
+	return f.marshal(deletedJSON{Deleted: count})
+}
+
+// FormatRecord formats a record streamed from a map as JSON, with the data
+// base64-encoded.
+func (f *JSONFormatter) FormatRecord(r Record) string {
+	// Omit cpu for ring buffer records so it is not mistaken for CPU 0
+	var cpu *int
+	if r.CPUKnown {
+		cpu = &r.CPU
+	}
+	return f.marshal(recordJSON{CPU: cpu, Data: r.Data})
+}
+
+// FormatUpdateResult formats the outcome of several map updates as JSON.
+func (f *JSONFormatter) FormatUpdateResult(result UpdateResult) string {
+	return f.marshal(updateResultJSON{
+		Updated: result.Updated,
+		Failed:  result.Failed,
+		Errors:  append([]string{}, result.Errors...),
+	})
+}
+
+// FormatUnpinned formats the paths removed by an unpin operation as JSON.
+func (f *JSONFormatter) FormatUnpinned(paths []string) string {
+	return f.marshal(unpinnedJSON{
+		Unpinned: append([]string{}, paths...),
+		Count:    len(paths),
+	})
+}
+
 // FormatError formats an error as JSON.
 func (f *JSONFormatter) FormatError(err error) string {
-	return f.marshal(errorJSON{Error: err.Error()})
+	return f.marshal(errorJSON{Error: err.Error(), Code: bpferrors.Code(err)})
 }
 
 // marshal converts data to JSON string, with optional pretty printing.
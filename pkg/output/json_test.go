@@ -3,8 +3,11 @@ package output
 import (
 	"encoding/json"
 	"fmt"
+	"strings"
 	"testing"
 	"time"
+
+	bpferrors "github.com/viveksb007/gobpftool/pkg/errors"
 )
 
 func TestJSONFormatter_FormatPrograms(t *testing.T) {
@@ -21,7 +24,7 @@ func TestJSONFormatter_FormatPrograms(t *testing.T) {
 			pretty: false,
 			progs:  []ProgramInfo{},
 			check: func(t *testing.T, result string) {
-				expected := `{"programs":[]}`
+				expected := `{"programs":[],"partial":false}`
 				if result != expected {
 					t.Errorf("got %q, want %q", result, expected)
 				}
@@ -69,6 +72,15 @@ func TestJSONFormatter_FormatPrograms(t *testing.T) {
 				if len(p.MapIDs) != 3 {
 					t.Errorf("MapIDs length = %d, want 3", len(p.MapIDs))
 				}
+				if p.BytesXlated != 5200 {
+					t.Errorf("BytesXlated = %d, want 5200", p.BytesXlated)
+				}
+				if p.BytesJited != 3263 {
+					t.Errorf("BytesJited = %d, want 3263", p.BytesJited)
+				}
+				if p.BytesMemlock != 8192 {
+					t.Errorf("BytesMemlock = %d, want 8192", p.BytesMemlock)
+				}
 			},
 		},
 		{
@@ -101,6 +113,32 @@ func TestJSONFormatter_FormatPrograms(t *testing.T) {
 				if p.MapIDs != nil {
 					t.Errorf("MapIDs = %v, want nil", p.MapIDs)
 				}
+				if p.PinnedPaths != nil {
+					t.Errorf("PinnedPaths = %v, want nil", p.PinnedPaths)
+				}
+			},
+		},
+		{
+			name:   "pinned program",
+			pretty: false,
+			progs: []ProgramInfo{
+				{
+					ID:          5,
+					Type:        "xdp",
+					Name:        "pinned_prog",
+					LoadedAt:    loadedAt,
+					PinnedPaths: []string{"/sys/fs/bpf/a"},
+				},
+			},
+			check: func(t *testing.T, result string) {
+				var parsed programsJSON
+				if err := json.Unmarshal([]byte(result), &parsed); err != nil {
+					t.Fatalf("failed to parse JSON: %v", err)
+				}
+				p := parsed.Programs[0]
+				if len(p.PinnedPaths) != 1 || p.PinnedPaths[0] != "/sys/fs/bpf/a" {
+					t.Errorf("PinnedPaths = %v, want [/sys/fs/bpf/a]", p.PinnedPaths)
+				}
 			},
 		},
 	}
@@ -114,6 +152,43 @@ func TestJSONFormatter_FormatPrograms(t *testing.T) {
 	}
 }
 
+func TestJSONFormatter_FormatPrograms_UID(t *testing.T) {
+	formatter := &JSONFormatter{pretty: false}
+
+	tests := []struct {
+		name     string
+		prog     ProgramInfo
+		contains string
+		absent   bool
+	}{
+		{
+			name:     "known root uid",
+			prog:     ProgramInfo{ID: 1, UID: 0, UIDKnown: true},
+			contains: `"uid":0`,
+		},
+		{
+			name:     "known non-root uid",
+			prog:     ProgramInfo{ID: 1, UID: 1000, UIDKnown: true},
+			contains: `"uid":1000`,
+		},
+		{
+			name:     "unknown uid",
+			prog:     ProgramInfo{ID: 1},
+			contains: `"uid"`,
+			absent:   true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result := formatter.FormatPrograms([]ProgramInfo{tt.prog})
+			if got := strings.Contains(result, tt.contains); got == tt.absent {
+				t.Errorf("result %s: contains %s = %v, want %v", result, tt.contains, got, !tt.absent)
+			}
+		})
+	}
+}
+
 func TestJSONFormatter_FormatPrograms_Pretty(t *testing.T) {
 	loadedAt := time.Date(2025, 11, 24, 5, 50, 46, 0, time.UTC)
 	formatter := &JSONFormatter{pretty: true}
@@ -153,6 +228,141 @@ func TestJSONFormatter_FormatPrograms_Pretty(t *testing.T) {
 	}
 }
 
+func TestJSONFormatter_FormatPrograms_Maps(t *testing.T) {
+	formatter := &JSONFormatter{}
+	progs := []ProgramInfo{
+		{
+			ID: 10, MapIDs: []uint32{5, 6},
+			Maps: []ProgramMap{
+				{ID: 5, Info: &MapInfo{ID: 5, Type: "hash", Name: "counts"}},
+				{ID: 6, Error: "permission denied"},
+			},
+		},
+	}
+
+	var parsed struct {
+		Programs []struct {
+			Maps []map[string]interface{} `json:"maps"`
+		} `json:"programs"`
+	}
+	result := formatter.FormatPrograms(progs)
+	if err := json.Unmarshal([]byte(result), &parsed); err != nil {
+		t.Fatalf("failed to parse JSON: %v", err)
+	}
+
+	maps := parsed.Programs[0].Maps
+	if len(maps) != 2 {
+		t.Fatalf("got %d maps, want 2", len(maps))
+	}
+	if maps[0]["name"] != "counts" || maps[0]["type"] != "hash" {
+		t.Errorf("maps[0] = %v, want the counts map", maps[0])
+	}
+	if maps[1]["id"] != float64(6) || maps[1]["error"] != "permission denied" {
+		t.Errorf("maps[1] = %v, want an error placeholder", maps[1])
+	}
+}
+
+func TestJSONFormatter_FormatPrograms_Attach(t *testing.T) {
+	formatter := &JSONFormatter{}
+	progs := []ProgramInfo{
+		{ID: 10, Attach: &AttachInfo{Type: "xdp", Ifindex: 2, Target: "eth0"}},
+		{ID: 11},
+	}
+
+	var parsed programsJSON
+	result := formatter.FormatPrograms(progs)
+	if err := json.Unmarshal([]byte(result), &parsed); err != nil {
+		t.Fatalf("failed to parse JSON: %v", err)
+	}
+
+	want := &attachJSON{Type: "xdp", Ifindex: 2, Target: "eth0"}
+	if got := parsed.Programs[0].Attach; got == nil || *got != *want {
+		t.Errorf("attach = %+v, want %+v", got, want)
+	}
+	if strings.Count(result, `"attach"`) != 1 {
+		t.Errorf("attach printed for a program without attach info: %s", result)
+	}
+	if strings.Contains(result, "btf_id") {
+		t.Errorf("unset btf_id printed: %s", result)
+	}
+}
+
+func TestJSONFormatter_FormatProgram(t *testing.T) {
+	formatter := &JSONFormatter{}
+	p := ProgramInfo{ID: 185, Type: "sched_cls", Name: "my_prog", Tag: "f0055c08993fea1e", MapIDs: []uint32{85}}
+
+	single := formatter.FormatProgram(p)
+	var object map[string]interface{}
+	if err := json.Unmarshal([]byte(single), &object); err != nil {
+		t.Fatalf("failed to parse JSON: %v", err)
+	}
+	if _, ok := object["programs"]; ok {
+		t.Errorf("FormatProgram() = %s, want a bare program object", single)
+	}
+	if object["id"] != float64(185) || object["name"] != "my_prog" {
+		t.Errorf("FormatProgram() = %s, want program 185", single)
+	}
+
+	// The plural form wraps the same object
+	if plural, want := formatter.FormatPrograms([]ProgramInfo{p}), `{"programs":[`+single+`],"partial":false}`; plural != want {
+		t.Errorf("FormatPrograms() = %s, want %s", plural, want)
+	}
+}
+
+func TestJSONFormatter_AnonymousNames(t *testing.T) {
+	formatter := &JSONFormatter{}
+
+	// The <anon> placeholder is only for plain output; JSON keeps the
+	// empty name as the kernel reports it
+	results := []string{
+		formatter.FormatPrograms([]ProgramInfo{{ID: 1, Type: "xdp"}}),
+		formatter.FormatProgram(ProgramInfo{ID: 1, Type: "xdp"}),
+		formatter.FormatMaps([]MapInfo{{ID: 3, Type: "array"}}),
+		formatter.FormatMap(MapInfo{ID: 3, Type: "array"}),
+	}
+	for _, result := range results {
+		if !strings.Contains(result, `"name":""`) || strings.Contains(result, "<anon>") {
+			t.Errorf("JSON = %s, want an empty name", result)
+		}
+	}
+}
+
+func TestJSONFormatter_FormatRecord(t *testing.T) {
+	formatter := &JSONFormatter{}
+
+	// Ring buffers don't record the CPU, so it isn't reported as CPU 0
+	if got, want := formatter.FormatRecord(Record{Data: []byte{0xde, 0xad}}), `{"data":"3q0="}`; got != want {
+		t.Errorf("FormatRecord() = %s, want %s", got, want)
+	}
+	if got, want := formatter.FormatRecord(Record{CPU: 0, CPUKnown: true, Data: []byte{1}}), `{"cpu":0,"data":"AQ=="}`; got != want {
+		t.Errorf("FormatRecord() = %s, want %s", got, want)
+	}
+}
+
+func TestJSONFormatter_FormatPrograms_VerifiedInsns(t *testing.T) {
+	formatter := &JSONFormatter{}
+
+	if result := formatter.FormatProgram(ProgramInfo{ID: 1, VerifiedInsns: 42}); !strings.Contains(result, `"verified_insns":42`) {
+		t.Errorf("FormatProgram() = %s, want verified_insns 42", result)
+	}
+	// Kernels before 5.16 don't report it
+	if result := formatter.FormatProgram(ProgramInfo{ID: 1}); strings.Contains(result, "verified_insns") {
+		t.Errorf("FormatProgram() = %s, want no verified_insns", result)
+	}
+}
+
+func TestJSONFormatter_FormatPrograms_BTFID(t *testing.T) {
+	formatter := &JSONFormatter{}
+
+	if result := formatter.FormatProgram(ProgramInfo{ID: 1, BTFID: 7}); !strings.Contains(result, `"btf_id":7`) {
+		t.Errorf("FormatProgram() = %s, want btf_id 7", result)
+	}
+	// Programs loaded without BTF have none
+	if result := formatter.FormatProgram(ProgramInfo{ID: 1}); strings.Contains(result, "btf_id") {
+		t.Errorf("FormatProgram() = %s, want no btf_id", result)
+	}
+}
+
 func TestJSONFormatter_FormatMaps(t *testing.T) {
 	tests := []struct {
 		name   string
@@ -165,7 +375,7 @@ func TestJSONFormatter_FormatMaps(t *testing.T) {
 			pretty: false,
 			maps:   []MapInfo{},
 			check: func(t *testing.T, result string) {
-				expected := `{"maps":[]}`
+				expected := `{"maps":[],"partial":false}`
 				if result != expected {
 					t.Errorf("got %q, want %q", result, expected)
 				}
@@ -207,6 +417,46 @@ func TestJSONFormatter_FormatMaps(t *testing.T) {
 				if m.ValueSize != 8 {
 					t.Errorf("ValueSize = %d, want 8", m.ValueSize)
 				}
+				if m.PinnedPaths != nil {
+					t.Errorf("PinnedPaths = %v, want nil", m.PinnedPaths)
+				}
+			},
+		},
+		{
+			name:   "pinned map",
+			pretty: false,
+			maps: []MapInfo{
+				{
+					ID:          31,
+					Type:        "array",
+					Name:        "shared_map",
+					PinnedPaths: []string{"/sys/fs/bpf/a", "/sys/fs/bpf/b"},
+				},
+			},
+			check: func(t *testing.T, result string) {
+				var parsed mapsJSON
+				if err := json.Unmarshal([]byte(result), &parsed); err != nil {
+					t.Fatalf("failed to parse JSON: %v", err)
+				}
+				if got := parsed.Maps[0].PinnedPaths; len(got) != 2 {
+					t.Errorf("PinnedPaths = %v, want 2 paths", got)
+				}
+			},
+		},
+		{
+			name:   "flag names",
+			pretty: false,
+			maps: []MapInfo{
+				{ID: 1, Type: "hash", Name: "flagged", Flags: 0x1, FlagNames: []string{"BPF_F_NO_PREALLOC"}},
+				{ID: 2, Type: "hash", Name: "plain"},
+			},
+			check: func(t *testing.T, result string) {
+				if !strings.Contains(result, `"flags":1,"flags_names":["BPF_F_NO_PREALLOC"]`) {
+					t.Errorf("flags_names missing from %s", result)
+				}
+				if strings.Count(result, "flags_names") != 1 {
+					t.Errorf("flags_names printed for a map without flags: %s", result)
+				}
 			},
 		},
 	}
@@ -220,6 +470,57 @@ func TestJSONFormatter_FormatMaps(t *testing.T) {
 	}
 }
 
+func TestJSONFormatter_FormatMap(t *testing.T) {
+	formatter := &JSONFormatter{}
+	m := MapInfo{ID: 10, Type: "hash", Name: "some_map", KeySize: 4, ValueSize: 8, MaxEntries: 2048,
+		Flags: 1, FlagNames: []string{"no_prealloc"}, MemLock: 4096, PinnedPaths: []string{"/sys/fs/bpf/some_map"}}
+
+	var single map[string]interface{}
+	if err := json.Unmarshal([]byte(formatter.FormatMap(m)), &single); err != nil {
+		t.Fatalf("failed to parse FormatMap JSON: %v", err)
+	}
+	var plural struct {
+		Maps []map[string]interface{} `json:"maps"`
+	}
+	if err := json.Unmarshal([]byte(formatter.FormatMaps([]MapInfo{m})), &plural); err != nil {
+		t.Fatalf("failed to parse FormatMaps JSON: %v", err)
+	}
+
+	if _, ok := single["maps"]; ok {
+		t.Errorf("FormatMap() = %v, want a bare map object", single)
+	}
+	if len(plural.Maps) != 1 {
+		t.Fatalf("FormatMaps() = %v, want one map under \"maps\"", plural)
+	}
+	if got, want := fmt.Sprint(single), fmt.Sprint(plural.Maps[0]); got != want {
+		t.Errorf("FormatMap() fields = %s, want the FormatMaps fields %s", got, want)
+	}
+}
+
+func TestJSONFormatter_FormatMap_Entries(t *testing.T) {
+	formatter := &JSONFormatter{}
+
+	tests := []struct {
+		name    string
+		m       MapInfo
+		want    string
+		wantNot string
+	}{
+		{name: "not counted", m: MapInfo{ID: 1}, wantNot: `"entries"`},
+		{name: "empty", m: MapInfo{ID: 1, EntriesCounted: true}, want: `"entries":0}`},
+		{name: "capped", m: MapInfo{ID: 1, Entries: 10, EntriesCounted: true, EntriesCapped: true}, want: `"entries":10,"entries_capped":true}`},
+	}
+	for _, tt := range tests {
+		got := formatter.FormatMap(tt.m)
+		if tt.want != "" && !strings.HasSuffix(got, tt.want) {
+			t.Errorf("%s: FormatMap() = %s, want it to end with %s", tt.name, got, tt.want)
+		}
+		if tt.wantNot != "" && strings.Contains(got, tt.wantNot) {
+			t.Errorf("%s: FormatMap() = %s, want no %s", tt.name, got, tt.wantNot)
+		}
+	}
+}
+
 func TestJSONFormatter_FormatMapEntries(t *testing.T) {
 	formatter := &JSONFormatter{pretty: false}
 
@@ -249,6 +550,109 @@ func TestJSONFormatter_FormatMapEntries(t *testing.T) {
 	}
 }
 
+func TestJSONFormatter_FormatMapEntries_Fields(t *testing.T) {
+	entries := []MapEntry{
+		{Key: []byte{0x00, 0x01}, Value: []byte{0x0a, 0x0b}, FormattedKey: 1, FormattedValue: 2},
+		{Key: []byte{0x02, 0x03}, Value: []byte{0x0c, 0x0d}, FormattedKey: 3, FormattedValue: 4},
+	}
+
+	keys := (&JSONFormatter{fields: EntryKeysOnly}).FormatMapEntries(entries, 2, 2)
+	want := `{"entries":[{"key":"AAE=","formatted":{"key":1}},{"key":"AgM=","formatted":{"key":3}}],"count":2,"partial":false}`
+	if keys != want {
+		t.Errorf("keys only = %s, want %s", keys, want)
+	}
+
+	values := (&JSONFormatter{fields: EntryValuesOnly}).FormatMapEntries(entries, 2, 2)
+	want = `{"entries":[{"value":"Cgs=","formatted":{"value":2}},{"value":"DA0=","formatted":{"value":4}}],"count":2,"partial":false}`
+	if values != want {
+		t.Errorf("values only = %s, want %s", values, want)
+	}
+}
+
+func TestJSONFormatter_FormatMapEntries_EmptyValue(t *testing.T) {
+	// Empty and zero values are kept, so they can be told from missing ones
+	entries := []MapEntry{{Key: []byte{0x01}, Value: []byte{}, FormattedKey: 1, FormattedValue: 0}}
+	got := (&JSONFormatter{}).FormatMapEntries(entries, 1, 0)
+	want := `{"entries":[{"key":"AQ==","value":"","formatted":{"key":1,"value":0}}],"count":1,"partial":false}`
+	if got != want {
+		t.Errorf("FormatMapEntries() = %s, want %s", got, want)
+	}
+}
+
+func TestJSONFormatter_Partial(t *testing.T) {
+	complete := NewFormatter(FormatJSON)
+	partial := NewFormatter(FormatJSON, WithPartial("3 maps not accessible"))
+	entries := []MapEntry{{Key: []byte{1}, Value: []byte{2}}}
+
+	tests := []struct {
+		name string
+		got  string
+		want string
+	}{
+		{"complete programs", complete.FormatPrograms(nil), `{"programs":[],"partial":false}`},
+		{"complete maps", complete.FormatMaps(nil), `{"maps":[],"partial":false}`},
+		{"complete entries", complete.FormatMapEntries(entries, 1, 1), `{"entries":[{"key":"AQ==","value":"Ag=="}],"count":1,"partial":false}`},
+		{"partial programs", partial.FormatPrograms(nil), `{"programs":[],"partial":true,"partial_reason":"3 maps not accessible"}`},
+		{"partial maps", partial.FormatMaps(nil), `{"maps":[],"partial":true,"partial_reason":"3 maps not accessible"}`},
+		{"partial entries", partial.FormatMapEntries(entries, 1, 1), `{"entries":[{"key":"AQ==","value":"Ag=="}],"count":1,"partial":true,"partial_reason":"3 maps not accessible"}`},
+		{"no reason", NewFormatter(FormatJSON, WithPartial("")).FormatMaps(nil), `{"maps":[],"partial":true}`},
+	}
+	for _, tt := range tests {
+		if tt.got != tt.want {
+			t.Errorf("%s = %s, want %s", tt.name, tt.got, tt.want)
+		}
+	}
+
+	// Single objects aren't wrapped, so they carry no partial field
+	if got := partial.FormatMap(MapInfo{ID: 1}); strings.Contains(got, "partial") {
+		t.Errorf("FormatMap() = %s, want no partial field", got)
+	}
+}
+
+func TestJSONFormatter_FormatMapEntry_ValueAs(t *testing.T) {
+	formatter := &JSONFormatter{valueAs: "u64be"}
+	entry := MapEntry{Key: []byte{0x01}, Value: []byte{0, 0, 0, 0, 0, 0, 0x01, 0x00}}
+
+	result := formatter.FormatMapEntry(entry, 1, 8)
+	want := `{"key":"AQ==","value":"AAAAAAAAAQA=","value_int":256}`
+	if result != want {
+		t.Errorf("FormatMapEntry() = %s, want %s", result, want)
+	}
+}
+
+func TestJSONFormatter_FormatMapEntry_KeyAsIP(t *testing.T) {
+	formatter := &JSONFormatter{keyAs: KeyAsIP}
+
+	tests := []struct {
+		entry MapEntry
+		want  string
+	}{
+		{MapEntry{Key: []byte{10, 0, 0, 1}, Value: []byte{1}}, `{"key":"CgAAAQ==","value":"AQ==","key_ip":"10.0.0.1"}`},
+		{MapEntry{Key: []byte{0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 1}, Value: []byte{1}}, `{"key":"AAAAAAAAAAAAAAAAAAAAAQ==","value":"AQ==","key_ip":"::1"}`},
+		// Other key sizes have no address
+		{MapEntry{Key: []byte{1, 2}, Value: []byte{1}}, `{"key":"AQI=","value":"AQ=="}`},
+	}
+	for _, tt := range tests {
+		if result := formatter.FormatMapEntry(tt.entry, uint32(len(tt.entry.Key)), 1); result != tt.want {
+			t.Errorf("FormatMapEntry() = %s, want %s", result, tt.want)
+		}
+	}
+}
+
+func TestJSONFormatter_FormatMapDiff(t *testing.T) {
+	formatter := &JSONFormatter{}
+	diff := MapDiff{
+		Added:   []MapEntry{{Key: []byte{1}, Value: []byte{10}}},
+		Changed: []MapEntryChange{{Old: MapEntry{Key: []byte{2}, Value: []byte{20}}, New: MapEntry{Key: []byte{2}, Value: []byte{21}}}},
+	}
+
+	result := formatter.FormatMapDiff(diff)
+	want := `{"added":[{"key":"AQ==","value":"Cg=="}],"removed":[],"changed":[{"key":"Ag==","old":{"value":"FA=="},"new":{"value":"FQ=="}}]}`
+	if result != want {
+		t.Errorf("FormatMapDiff() = %s, want %s", result, want)
+	}
+}
+
 func TestJSONFormatter_FormatMapEntry(t *testing.T) {
 	formatter := &JSONFormatter{pretty: false}
 
@@ -272,6 +676,63 @@ func TestJSONFormatter_FormatMapEntry(t *testing.T) {
 	}
 }
 
+func TestJSONFormatter_FormatMapEntry_Formatted(t *testing.T) {
+	formatter := &JSONFormatter{pretty: false}
+
+	entry := MapEntry{
+		Key:            []byte{0x01, 0x00, 0x00, 0x00},
+		Value:          []byte{0x2a, 0x00, 0x00, 0x00},
+		FormattedKey:   uint64(1),
+		FormattedValue: map[string]uint64{"count": 42},
+	}
+
+	result := formatter.FormatMapEntry(entry, 4, 4)
+	want := `"formatted":{"key":1,"value":{"count":42}}`
+	if !strings.Contains(result, want) {
+		t.Errorf("result = %s, want it to contain %s", result, want)
+	}
+
+	// Undecoded entries have no formatted field
+	entry.FormattedKey, entry.FormattedValue = nil, nil
+	if result := formatter.FormatMapEntry(entry, 4, 4); strings.Contains(result, "formatted") {
+		t.Errorf("result = %s, want no formatted field", result)
+	}
+}
+
+func TestJSONFormatter_FormatMapEntry_PerCPU(t *testing.T) {
+	formatter := &JSONFormatter{pretty: false}
+
+	entry := MapEntry{
+		Key: []byte{0x01, 0x00, 0x00, 0x00},
+		PerCPUValues: [][]byte{
+			{0x0a, 0x00, 0x00, 0x00},
+			{0x0b, 0x00, 0x00, 0x00},
+		},
+	}
+
+	result := formatter.FormatMapEntry(entry, 4, 4)
+
+	var parsed mapEntryJSON
+	if err := json.Unmarshal([]byte(result), &parsed); err != nil {
+		t.Fatalf("failed to parse JSON: %v", err)
+	}
+
+	if parsed.Value != nil {
+		t.Errorf("Value = %v, want nil", parsed.Value)
+	}
+	if len(parsed.Values) != 2 {
+		t.Fatalf("Values length = %d, want 2", len(parsed.Values))
+	}
+	for i, v := range parsed.Values {
+		if v.CPU != i {
+			t.Errorf("Values[%d].CPU = %d, want %d", i, v.CPU, i)
+		}
+		if v.Value[0] != entry.PerCPUValues[i][0] {
+			t.Errorf("Values[%d].Value = %v, want %v", i, v.Value, entry.PerCPUValues[i])
+		}
+	}
+}
+
 func TestJSONFormatter_FormatNextKey(t *testing.T) {
 	tests := []struct {
 		name       string
@@ -338,6 +799,163 @@ func TestJSONFormatter_FormatError(t *testing.T) {
 	if parsed.Error != "something went wrong" {
 		t.Errorf("Error = %q, want %q", parsed.Error, "something went wrong")
 	}
+	if parsed.Code != "generic" {
+		t.Errorf("Code = %q, want %q", parsed.Code, "generic")
+	}
+}
+
+func TestJSONFormatter_FormatErrorCode(t *testing.T) {
+	formatter := &JSONFormatter{pretty: false}
+
+	tests := []struct {
+		err  error
+		want string
+	}{
+		{bpferrors.ErrPermission, "permission"},
+		{bpferrors.ErrNotFound, "not_found"},
+		{bpferrors.ErrKeyNotFound, "not_found"},
+		{bpferrors.ErrBpfFSNotMounted, "bpffs_not_mounted"},
+		{bpferrors.ErrInvalidKey, "invalid_key"},
+		{bpferrors.ErrMapFrozen, "map_frozen"},
+		{bpferrors.ErrDeleteNotSupported, "not_supported"},
+		{bpferrors.ErrInvalidValue, "generic"},
+		{fmt.Errorf("looking up map 5: %w", bpferrors.ErrNotFound), "not_found"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.err.Error(), func(t *testing.T) {
+			var parsed errorJSON
+			if err := json.Unmarshal([]byte(formatter.FormatError(tt.err)), &parsed); err != nil {
+				t.Fatalf("failed to parse JSON: %v", err)
+			}
+			if parsed.Code != tt.want {
+				t.Errorf("Code = %q, want %q", parsed.Code, tt.want)
+			}
+			if parsed.Error != tt.err.Error() {
+				t.Errorf("Error = %q, want %q", parsed.Error, tt.err.Error())
+			}
+		})
+	}
+}
+
+func TestJSONFormatter_FormatInstructions(t *testing.T) {
+	formatter := &JSONFormatter{pretty: false}
+
+	insns := []Instruction{
+		{Offset: 0, Raw: []byte{0x95, 0, 0, 0, 0, 0, 0, 0}, Text: "Exit"},
+	}
+
+	var parsed []instructionJSON
+	if err := json.Unmarshal([]byte(formatter.FormatInstructions(insns)), &parsed); err != nil {
+		t.Fatalf("failed to parse JSON: %v", err)
+	}
+	if len(parsed) != 1 {
+		t.Fatalf("got %d instructions, want 1", len(parsed))
+	}
+	if parsed[0].Offset != 0 || len(parsed[0].Raw) != 8 || parsed[0].Insn != "Exit" {
+		t.Errorf("parsed = %+v", parsed[0])
+	}
+}
+
+func TestJSONFormatter_FormatUnpinned(t *testing.T) {
+	formatter := &JSONFormatter{pretty: false}
+
+	if got, want := formatter.FormatUnpinned(nil), `{"unpinned":[],"count":0}`; got != want {
+		t.Errorf("FormatUnpinned(nil) = %s, want %s", got, want)
+	}
+	if got, want := formatter.FormatUnpinned([]string{"/sys/fs/bpf/a"}), `{"unpinned":["/sys/fs/bpf/a"],"count":1}`; got != want {
+		t.Errorf("FormatUnpinned() = %s, want %s", got, want)
+	}
+}
+
+func TestJSONFormatter_FormatRunResult(t *testing.T) {
+	formatter := &JSONFormatter{pretty: false}
+
+	got := formatter.FormatRunResult(RunResult{
+		ReturnValue: 2,
+		DataOut:     []byte{0xaa, 0xbb},
+		Repeat:      10,
+		Duration:    150 * time.Nanosecond,
+	})
+	if want := `{"retval":2,"repeat":10,"duration_ns":150,"data_out":"qrs="}`; got != want {
+		t.Errorf("FormatRunResult() = %s, want %s", got, want)
+	}
+}
+
+func TestJSONFormatter_FormatBTFs(t *testing.T) {
+	formatter := &JSONFormatter{pretty: false}
+
+	got := formatter.FormatBTFs([]BTFInfo{{ID: 1, Name: "vmlinux", Size: 100, Kernel: true}, {ID: 42, Size: 8}})
+	want := `{"btfs":[{"id":1,"name":"vmlinux","size":100,"kernel":true},{"id":42,"size":8,"kernel":false}]}`
+	if got != want {
+		t.Errorf("FormatBTFs() = %s, want %s", got, want)
+	}
+
+	got = formatter.FormatBTFTypes([]BTFType{{ID: 3, Kind: "typedef", Name: "__u32", C: "typedef unsigned int __u32;"}})
+	want = `{"types":[{"id":3,"kind":"typedef","name":"__u32","c":"typedef unsigned int __u32;"}]}`
+	if got != want {
+		t.Errorf("FormatBTFTypes() = %s, want %s", got, want)
+	}
+}
+
+func TestJSONFormatter_MapNames(t *testing.T) {
+	programs := []ProgramInfo{
+		{ID: 1, Type: "xdp", Name: "fw", MapIDs: []uint32{85, 86}},
+		{ID: 2, Type: "kprobe", Name: "probe"},
+	}
+
+	// Bare IDs stay the default, like bpftool
+	got := (&JSONFormatter{}).FormatPrograms(programs)
+	if !strings.Contains(got, `"map_ids":[85,86]`) {
+		t.Errorf("FormatPrograms() = %s, want bare map_ids", got)
+	}
+
+	// Map 86 couldn't be resolved and keeps its ID only
+	f := NewFormatter(FormatJSON, WithMapNames(map[uint32]string{85: "some_map"}))
+	got = f.FormatPrograms(programs)
+	if !strings.Contains(got, `"map_ids":[{"id":85,"name":"some_map"},{"id":86}]`) {
+		t.Errorf("FormatPrograms() = %s, want map_ids objects", got)
+	}
+	if strings.Count(got, `"map_ids"`) != 1 {
+		t.Errorf("FormatPrograms() = %s, want map_ids only for the program using maps", got)
+	}
+	if !strings.Contains(got, `"id":1,"type":"xdp","name":"fw"`) {
+		t.Errorf("FormatPrograms() = %s, want the other fields unchanged", got)
+	}
+
+	got = f.FormatProgram(programs[0])
+	if !strings.Contains(got, `"map_ids":[{"id":85,"name":"some_map"},{"id":86}]`) {
+		t.Errorf("FormatProgram() = %s, want map_ids objects", got)
+	}
+
+	got = NewFormatter(FormatYAML, WithMapNames(map[uint32]string{85: "some_map"})).FormatProgram(programs[0])
+	if !strings.Contains(got, "- id: 85\n      name: some_map\n") {
+		t.Errorf("YAML FormatProgram() = %s, want map_ids objects", got)
+	}
+}
+
+func TestJSONFormatter_FormatSystemInfo(t *testing.T) {
+	formatter := &JSONFormatter{pretty: false}
+
+	got := formatter.FormatSystemInfo(SystemInfo{
+		KernelRelease: "6.8.0",
+		BPFFSRoot:     "/sys/fs/bpf",
+		BPFFSMounted:  true,
+		Programs:      0,
+		Maps:          3,
+		CountsKnown:   true,
+		CapSysAdmin:   true,
+	})
+	want := `{"kernel_release":"6.8.0","bpffs_root":"/sys/fs/bpf","bpffs_mounted":true,"programs":0,"maps":3,"cap_bpf":false,"cap_sys_admin":true}`
+	if got != want {
+		t.Errorf("FormatSystemInfo() = %s, want %s", got, want)
+	}
+
+	got = formatter.FormatSystemInfo(SystemInfo{KernelRelease: "6.8.0", BPFFSRoot: "/sys/fs/bpf"})
+	want = `{"kernel_release":"6.8.0","bpffs_root":"/sys/fs/bpf","bpffs_mounted":false,"cap_bpf":false,"cap_sys_admin":false}`
+	if got != want {
+		t.Errorf("FormatSystemInfo() without counts = %s, want %s", got, want)
+	}
 }
 
 func TestNewFormatter(t *testing.T) {
@@ -361,6 +979,16 @@ func TestNewFormatter(t *testing.T) {
 			format:   FormatJSONPretty,
 			wantType: "*output.JSONFormatter",
 		},
+		{
+			name:     "YAML format",
+			format:   FormatYAML,
+			wantType: "*output.YAMLFormatter",
+		},
+		{
+			name:     "table format",
+			format:   FormatTable,
+			wantType: "*output.TableFormatter",
+		},
 	}
 
 	for _, tt := range tests {
@@ -369,8 +997,9 @@ func TestNewFormatter(t *testing.T) {
 			if f == nil {
 				t.Fatal("NewFormatter returned nil")
 			}
-			// Just verify it implements the interface
-			var _ Formatter = f
+			if got := fmt.Sprintf("%T", f); got != tt.wantType {
+				t.Errorf("NewFormatter() type = %s, want %s", got, tt.wantType)
+			}
 		})
 	}
 }
@@ -1,19 +1,41 @@
 package output
 
 import (
+	"encoding/json"
 	"fmt"
 	"strings"
+
+	"github.com/viveksb007/gobpftool/internal/utils"
 )
 
 // PlainFormatter formats output as human-readable plain text matching bpftool format.
-type PlainFormatter struct{}
+type PlainFormatter struct {
+	timestamp TimestampFormat
+	fields    EntryFields
+	valueAs   string
+	keyAs     string
+	human     bool
+	// color highlights IDs, types and the gpl marker with ANSI escapes
+	color bool
+	// quiet drops the "Found <n> elements" trailer of map dumps
+	quiet bool
+}
 
 // FormatPrograms formats programs in bpftool-compatible plain text format.
 // Format:
 //
-//	<ID>: <type>  name <name>  tag <tag>  gpl
+//	<ID>: <type>  name <name>  tag <tag>  gpl  run_time_ns <ns>  run_cnt <count>
 //	        loaded_at <timestamp>  uid <uid>
-//	        xlated <bytes>B  jited <bytes>B  memlock <bytes>B  map_ids <id1>,<id2>,...
+//	        xlated <bytes>B  jited <bytes>B  memlock <bytes>B  map_ids <id1>,<id2>,...  btf_id <id>  verified_insns <count>
+//	        pinned <path1>,<path2>,...
+//	        maps:
+//	                <ID>: <type>  name <name>  key <size>B  value <size>B  max_entries <count>
+//
+// Unnamed programs and maps are shown as <anon>. The run statistics are
+// only printed when BPF stats were collected, btf_id only when the program
+// has BTF, verified_insns only when the kernel reports it, the pinned line
+// only when the program is pinned, and the maps lines only when its maps
+// were expanded.
 func (f *PlainFormatter) FormatPrograms(progs []ProgramInfo) string {
 	if len(progs) == 0 {
 		return ""
@@ -29,22 +51,33 @@ func (f *PlainFormatter) FormatPrograms(progs []ProgramInfo) string {
 	return sb.String()
 }
 
+// FormatProgram formats a single program like FormatPrograms.
+func (f *PlainFormatter) FormatProgram(p ProgramInfo) string {
+	var sb strings.Builder
+	f.formatProgram(&sb, p)
+	return sb.String()
+}
+
 func (f *PlainFormatter) formatProgram(sb *strings.Builder, p ProgramInfo) {
 	// First line: ID, type, name, tag, gpl
 	gplStr := ""
 	if p.GPL {
-		gplStr = "  gpl"
+		gplStr = "  " + f.paint(colorYellow, "gpl")
+	}
+	fmt.Fprintf(sb, "%s: %s  name %s  tag %s%s",
+		f.id(p.ID), f.paint(colorGreen, p.Type), displayName(p.Name), p.Tag, gplStr)
+	if p.RunTimeNS > 0 {
+		fmt.Fprintf(sb, "  run_time_ns %d  run_cnt %d", p.RunTimeNS, p.RunCount)
 	}
-	fmt.Fprintf(sb, "%d: %s  name %s  tag %s%s\n",
-		p.ID, p.Type, p.Name, p.Tag, gplStr)
+	sb.WriteString("\n")
 
 	// Second line: loaded_at, uid
-	loadedAt := p.LoadedAt.Format("2006-01-02T15:04:05-0700")
+	loadedAt := f.timestamp.format(p.LoadedAt)
 	fmt.Fprintf(sb, "\tloaded_at %s  uid %d\n", loadedAt, p.UID)
 
 	// Third line: xlated, jited, memlock, map_ids
-	fmt.Fprintf(sb, "\txlated %dB  jited %dB  memlock %dB",
-		p.BytesXlat, p.BytesJIT, p.MemLock)
+	fmt.Fprintf(sb, "\txlated %s  jited %s  memlock %s",
+		f.size(uint64(p.BytesXlat)), f.size(uint64(p.BytesJIT)), f.size(uint64(p.MemLock)))
 
 	if len(p.MapIDs) > 0 {
 		mapIDStrs := make([]string, len(p.MapIDs))
@@ -53,13 +86,66 @@ func (f *PlainFormatter) formatProgram(sb *strings.Builder, p ProgramInfo) {
 		}
 		fmt.Fprintf(sb, "  map_ids %s", strings.Join(mapIDStrs, ","))
 	}
+	if p.BTFID != 0 {
+		fmt.Fprintf(sb, "  btf_id %d", p.BTFID)
+	}
+	if p.VerifiedInsns > 0 {
+		fmt.Fprintf(sb, "  verified_insns %d", p.VerifiedInsns)
+	}
+
+	if a := p.Attach; a != nil {
+		fmt.Fprintf(sb, "\n\tattach %s", a.Type)
+		if a.Ifindex != 0 {
+			fmt.Fprintf(sb, "  ifindex %d", a.Ifindex)
+		}
+		if a.BTFID != 0 {
+			fmt.Fprintf(sb, "  btf_obj_id %d  btf_id %d", a.BTFObjID, a.BTFID)
+		}
+		if a.Target != "" {
+			fmt.Fprintf(sb, "  target %s", a.Target)
+		}
+	}
+
+	if len(p.PinnedPaths) > 0 {
+		fmt.Fprintf(sb, "\n\tpinned %s", strings.Join(p.PinnedPaths, ","))
+	}
+
+	// Expanded maps, one per line
+	if len(p.Maps) > 0 {
+		sb.WriteString("\n\tmaps:")
+	}
+	for _, m := range p.Maps {
+		if m.Info == nil {
+			fmt.Fprintf(sb, "\n\t\t%d: error: %s", m.ID, m.Error)
+			continue
+		}
+		fmt.Fprintf(sb, "\n\t\t%s: %s  name %s  key %dB  value %dB  max_entries %d",
+			f.id(m.ID), f.paint(colorGreen, m.Info.Type), displayName(m.Info.Name), m.Info.KeySize, m.Info.ValueSize, m.Info.MaxEntries)
+	}
+
+	// Metadata, one variable per line with strings quoted
+	if len(p.Metadata) > 0 {
+		sb.WriteString("\n\tmetadata:")
+	}
+	for _, m := range p.Metadata {
+		if s, ok := m.Value.(string); ok {
+			fmt.Fprintf(sb, "\n\t\t%s = %q", m.Name, s)
+		} else {
+			fmt.Fprintf(sb, "\n\t\t%s = %v", m.Name, m.Value)
+		}
+	}
 }
 
 // FormatMaps formats maps in bpftool-compatible plain text format.
 // Format:
 //
 //	<ID>: <type>  name <name>  flags 0x<flags>
-//	        key <size>B  value <size>B  max_entries <count>  memlock <bytes>B
+//	        key <size>B  value <size>B  max_entries <count>  memlock <bytes>B  entries <count>/<max>
+//	        pinned <path1>,<path2>,...
+//
+// Unnamed maps are shown as <anon>. The entries are only printed when they
+// were counted, as >=<count> when counting stopped early, and the pinned
+// line only when the map is pinned.
 func (f *PlainFormatter) FormatMaps(maps []MapInfo) string {
 	if len(maps) == 0 {
 		return ""
@@ -75,14 +161,35 @@ func (f *PlainFormatter) FormatMaps(maps []MapInfo) string {
 	return sb.String()
 }
 
+// FormatMap formats a single map like FormatMaps.
+func (f *PlainFormatter) FormatMap(m MapInfo) string {
+	var sb strings.Builder
+	f.formatMap(&sb, m)
+	return sb.String()
+}
+
 func (f *PlainFormatter) formatMap(sb *strings.Builder, m MapInfo) {
 	// First line: ID, type, name, flags
-	fmt.Fprintf(sb, "%d: %s  name %s  flags 0x%x\n",
-		m.ID, m.Type, m.Name, m.Flags)
+	fmt.Fprintf(sb, "%s: %s  name %s  flags 0x%x", f.id(m.ID), f.paint(colorGreen, m.Type), displayName(m.Name), m.Flags)
+	if len(m.FlagNames) > 0 {
+		fmt.Fprintf(sb, " (%s)", strings.Join(m.FlagNames, "|"))
+	}
+	sb.WriteString("\n")
 
 	// Second line: key, value, max_entries, memlock
-	fmt.Fprintf(sb, "\tkey %dB  value %dB  max_entries %d  memlock %dB",
-		m.KeySize, m.ValueSize, m.MaxEntries, m.MemLock)
+	fmt.Fprintf(sb, "\tkey %dB  value %dB  max_entries %d  memlock %s",
+		m.KeySize, m.ValueSize, m.MaxEntries, f.size(uint64(m.MemLock)))
+	if m.EntriesCounted {
+		atLeast := ""
+		if m.EntriesCapped {
+			atLeast = ">="
+		}
+		fmt.Fprintf(sb, "  entries %s%d/%d", atLeast, m.Entries, m.MaxEntries)
+	}
+
+	if len(m.PinnedPaths) > 0 {
+		fmt.Fprintf(sb, "\n\tpinned %s", strings.Join(m.PinnedPaths, ","))
+	}
 }
 
 // FormatMapEntries formats all map entries for dump output.
@@ -91,15 +198,37 @@ func (f *PlainFormatter) formatMap(sb *strings.Builder, m MapInfo) {
 //	key: <hex bytes>  value: <hex bytes>
 //	...
 //	Found <n> elements
+//
+// Per-CPU entries print each CPU's value on its own line:
+//
+//	key: <hex bytes>
+//		value (CPU 00): <hex bytes>
+//		value (CPU 01): <hex bytes>
+//
+// With WithQuiet the trailer is left out, so every line is an entry.
 func (f *PlainFormatter) FormatMapEntries(entries []MapEntry, keySize, valueSize uint32) string {
 	var sb strings.Builder
 
 	for _, entry := range entries {
-		keyHex := formatHexBytes(entry.Key)
-		valueHex := formatHexBytes(entry.Value)
-		fmt.Fprintf(&sb, "key: %s  value: %s\n", keyHex, valueHex)
+		switch {
+		case f.fields == EntryKeysOnly:
+			fmt.Fprintf(&sb, "key: %s", f.formatKey(entry.Key))
+		case f.fields == EntryValuesOnly:
+			f.formatValues(&sb, entry)
+		case entry.PerCPUValues != nil:
+			f.formatPerCPUEntry(&sb, entry)
+		default:
+			keyHex := f.formatKey(entry.Key)
+			valueHex := f.formatValue(entry.Value)
+			fmt.Fprintf(&sb, "key: %s  value: %s", keyHex, valueHex)
+		}
+		f.formatDecoded(&sb, f.fields.apply(entry))
+		sb.WriteString("\n")
 	}
 
+	if f.quiet {
+		return strings.TrimSuffix(sb.String(), "\n")
+	}
 	fmt.Fprintf(&sb, "Found %d element", len(entries))
 	if len(entries) != 1 {
 		sb.WriteString("s")
@@ -111,9 +240,119 @@ func (f *PlainFormatter) FormatMapEntries(entries []MapEntry, keySize, valueSize
 // FormatMapEntry formats a single map entry for lookup output.
 // Format: key: <hex bytes> value: <hex bytes>
 func (f *PlainFormatter) FormatMapEntry(entry MapEntry, keySize, valueSize uint32) string {
-	keyHex := formatHexBytes(entry.Key)
-	valueHex := formatHexBytes(entry.Value)
-	return fmt.Sprintf("key: %s value: %s", keyHex, valueHex)
+	var sb strings.Builder
+	switch {
+	case f.fields == EntryKeysOnly:
+		fmt.Fprintf(&sb, "key: %s", f.formatKey(entry.Key))
+	case f.fields == EntryValuesOnly:
+		f.formatValues(&sb, entry)
+	case entry.PerCPUValues != nil:
+		f.formatPerCPUEntry(&sb, entry)
+	default:
+		keyHex := f.formatKey(entry.Key)
+		valueHex := f.formatValue(entry.Value)
+		fmt.Fprintf(&sb, "key: %s value: %s", keyHex, valueHex)
+	}
+	f.formatDecoded(&sb, f.fields.apply(entry))
+	return sb.String()
+}
+
+// FormatMapDiff formats the differences between two map dumps, one line
+// per entry. Added entries are prefixed with "+", removed ones with "-" and
+// changed ones with "~":
+//
+//	~ key: <hex bytes>  value: <old hex bytes> -> <new hex bytes>
+//
+// Per-CPU values are separated by " | ".
+func (f *PlainFormatter) FormatMapDiff(diff MapDiff) string {
+	var lines []string
+	for _, e := range diff.Added {
+		lines = append(lines, fmt.Sprintf("+ key: %s  value: %s", formatHexBytes(e.Key), f.formatEntryValue(e)))
+	}
+	for _, e := range diff.Removed {
+		lines = append(lines, fmt.Sprintf("- key: %s  value: %s", formatHexBytes(e.Key), f.formatEntryValue(e)))
+	}
+	for _, c := range diff.Changed {
+		lines = append(lines, fmt.Sprintf("~ key: %s  value: %s -> %s",
+			formatHexBytes(c.New.Key), f.formatEntryValue(c.Old), f.formatEntryValue(c.New)))
+	}
+	return strings.Join(lines, "\n")
+}
+
+// formatEntryValue formats the value of an entry on a single line.
+func (f *PlainFormatter) formatEntryValue(entry MapEntry) string {
+	if entry.PerCPUValues == nil {
+		return f.formatValue(entry.Value)
+	}
+	values := make([]string, len(entry.PerCPUValues))
+	for cpu, value := range entry.PerCPUValues {
+		values[cpu] = f.formatValue(value)
+	}
+	return strings.Join(values, " | ")
+}
+
+// formatDecoded writes the BTF-decoded key and value, if any, as compact
+// JSON on an indented line without a trailing newline.
+func (f *PlainFormatter) formatDecoded(sb *strings.Builder, entry MapEntry) {
+	if entry.FormattedKey != nil {
+		if key, err := json.Marshal(entry.FormattedKey); err == nil {
+			fmt.Fprintf(sb, "\n\tformatted key: %s", key)
+		}
+	}
+	if entry.FormattedValue != nil {
+		if value, err := json.Marshal(entry.FormattedValue); err == nil {
+			fmt.Fprintf(sb, "\n\tformatted value: %s", value)
+		}
+	}
+}
+
+// formatValues writes the value of an entry, or one indented line per CPU
+// for per-CPU entries, without the key and a trailing newline.
+func (f *PlainFormatter) formatValues(sb *strings.Builder, entry MapEntry) {
+	if entry.PerCPUValues == nil {
+		fmt.Fprintf(sb, "value: %s", f.formatValue(entry.Value))
+		return
+	}
+	for cpu, value := range entry.PerCPUValues {
+		if cpu > 0 {
+			sb.WriteString("\n")
+		}
+		fmt.Fprintf(sb, "\tvalue (CPU %02d): %s", cpu, f.formatValue(value))
+	}
+}
+
+// formatPerCPUEntry writes a per-CPU entry without a trailing newline.
+func (f *PlainFormatter) formatPerCPUEntry(sb *strings.Builder, entry MapEntry) {
+	fmt.Fprintf(sb, "key: %s\n", f.formatKey(entry.Key))
+	f.formatValues(sb, entry)
+}
+
+// formatKey formats a key as hex bytes, followed by the key rendered as an
+// IP address in parentheses when WithKeyAs(KeyAsIP) is set.
+func (f *PlainFormatter) formatKey(key []byte) string {
+	hex := formatHexBytes(key)
+	if f.keyAs != KeyAsIP {
+		return hex
+	}
+	ip, ok := utils.BytesToIP(key)
+	if !ok {
+		return hex
+	}
+	return fmt.Sprintf("%s (%s)", hex, ip)
+}
+
+// formatValue formats a value as hex bytes, followed by its decimal
+// interpretation in parentheses when an integer kind is set.
+func (f *PlainFormatter) formatValue(value []byte) string {
+	hex := formatHexBytes(value)
+	if f.valueAs == "" {
+		return hex
+	}
+	n, err := utils.DecodeInt(value, f.valueAs)
+	if err != nil {
+		return hex
+	}
+	return fmt.Sprintf("%s (%d)", hex, n)
 }
 
 // FormatNextKey formats the next key result for getnext output.
@@ -138,6 +377,218 @@ func (f *PlainFormatter) FormatNextKey(currentKey, nextKey []byte) string {
 	return sb.String()
 }
 
+// FormatInstructions formats translated instructions one per line, with
+// the offset and opcode like bpftool.
+// Format: <offset>: (<opcode>) <instruction>
+func (f *PlainFormatter) FormatInstructions(insns []Instruction) string {
+	lines := make([]string, len(insns))
+	for i, ins := range insns {
+		var opcode byte
+		if len(ins.Raw) > 0 {
+			opcode = ins.Raw[0]
+		}
+		lines[i] = fmt.Sprintf("%4d: (%02x) %s", ins.Offset, opcode, ins.Text)
+	}
+	return strings.Join(lines, "\n")
+}
+
+// FormatJited formats JIT-compiled machine code as a hexdump of 16-byte rows.
+// Format: <offset>: <hex bytes>
+func (f *PlainFormatter) FormatJited(code []byte) string {
+	return utils.FormatHexDump(code, 16)
+}
+
+// FormatStats formats the run time statistics of a program.
+// Format: <ID>: <type>  name <name>  run_time_ns <ns>  run_cnt <count>  avg_ns <ns>
+func (f *PlainFormatter) FormatStats(p ProgramInfo) string {
+	return fmt.Sprintf("%d: %s  name %s  run_time_ns %d  run_cnt %d  avg_ns %d",
+		p.ID, p.Type, displayName(p.Name), p.RunTimeNS, p.RunCount, averageRunTime(p))
+}
+
+// anonName stands in for the name of unnamed objects in plain output, so a
+// missing name doesn't read like a formatting error.
+const anonName = "<anon>"
+
+// displayName returns name, or anonName when it is empty.
+func displayName(name string) string {
+	if name == "" {
+		return anonName
+	}
+	return name
+}
+
+// FormatBTFs formats BTF objects one per line, like bpftool.
+// Format: <ID>: name [<name>]  size <bytes>B  kernel
+func (f *PlainFormatter) FormatBTFs(objects []BTFInfo) string {
+	lines := make([]string, len(objects))
+	for i, obj := range objects {
+		name := anonName
+		if obj.Name != "" {
+			name = "[" + obj.Name + "]"
+		}
+		lines[i] = fmt.Sprintf("%d: name %s  size %dB", obj.ID, name, obj.Size)
+		if obj.Kernel {
+			lines[i] += "  kernel"
+		}
+	}
+	return strings.Join(lines, "\n")
+}
+
+// FormatBTFTypes formats BTF types as C declarations separated by blank
+// lines.
+func (f *PlainFormatter) FormatBTFTypes(types []BTFType) string {
+	decls := make([]string, len(types))
+	for i, t := range types {
+		decls[i] = t.C
+	}
+	return strings.Join(decls, "\n\n")
+}
+
+// FormatRunResult formats the outcome of a test run, with the output data
+// and context as hexdumps of 16-byte rows.
+// Format:
+//
+//	retval <n>  repeat <n>  duration <duration>
+//	data_out:
+//	<offset>: <hex bytes>
+//	ctx_out:
+//	<offset>: <hex bytes>
+func (f *PlainFormatter) FormatRunResult(r RunResult) string {
+	var sb strings.Builder
+
+	fmt.Fprintf(&sb, "retval %d  repeat %d  duration %s", r.ReturnValue, r.Repeat, r.Duration)
+	if r.DataOut != nil {
+		sb.WriteString("\ndata_out:")
+		if len(r.DataOut) > 0 {
+			sb.WriteString("\n" + utils.FormatHexDump(r.DataOut, 16))
+		}
+	}
+	if r.ContextOut != nil {
+		sb.WriteString("\nctx_out:")
+		if len(r.ContextOut) > 0 {
+			sb.WriteString("\n" + utils.FormatHexDump(r.ContextOut, 16))
+		}
+	}
+
+	return sb.String()
+}
+
+// FormatCount formats an object count.
+// Format: <n> <kind>(s)
+func (f *PlainFormatter) FormatCount(count int, kind string) string {
+	if count == 1 {
+		return fmt.Sprintf("1 %s", kind)
+	}
+	return fmt.Sprintf("%d %ss", count, kind)
+}
+
+// FormatVersion formats the build information. The commit and build date
+// are left out while unknown.
+// Format:
+//
+//	gobpftool version <version>
+//	  git commit: <commit>
+//	  build date: <date>
+func (f *PlainFormatter) FormatVersion(info VersionInfo) string {
+	var sb strings.Builder
+
+	fmt.Fprintf(&sb, "gobpftool version %s", info.Version)
+	if info.GitCommit != "unknown" {
+		fmt.Fprintf(&sb, "\n  git commit: %s", info.GitCommit)
+	}
+	if info.BuildDate != "unknown" {
+		fmt.Fprintf(&sb, "\n  build date: %s", info.BuildDate)
+	}
+
+	return sb.String()
+}
+
+// FormatSystemInfo formats an overview of the BPF environment. Counts that
+// couldn't be read are shown as unknown.
+// Format:
+//
+//	kernel: <release>
+//	bpffs: <root> (mounted|not mounted)
+//	programs: <count>
+//	maps: <count>
+//	capabilities: CAP_BPF (yes|no), CAP_SYS_ADMIN (yes|no)
+func (f *PlainFormatter) FormatSystemInfo(info SystemInfo) string {
+	yesNo := func(b bool) string {
+		if b {
+			return "yes"
+		}
+		return "no"
+	}
+	mounted := "mounted"
+	if !info.BPFFSMounted {
+		mounted = "not mounted"
+	}
+	programs, maps := "unknown", "unknown"
+	if info.CountsKnown {
+		programs, maps = fmt.Sprint(info.Programs), fmt.Sprint(info.Maps)
+	}
+
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "kernel: %s\n", info.KernelRelease)
+	fmt.Fprintf(&sb, "bpffs: %s (%s)\n", info.BPFFSRoot, mounted)
+	fmt.Fprintf(&sb, "programs: %s\n", programs)
+	fmt.Fprintf(&sb, "maps: %s\n", maps)
+	fmt.Fprintf(&sb, "capabilities: CAP_BPF %s, CAP_SYS_ADMIN %s", yesNo(info.CapBPF), yesNo(info.CapSysAdmin))
+	return sb.String()
+}
+
+// FormatDeleted formats the number of entries removed from a map.
+// Format: Deleted <n> element(s)
+func (f *PlainFormatter) FormatDeleted(count int) string {
+	if count == 1 {
+		return "Deleted 1 element"
+	}
+	return fmt.Sprintf("Deleted %d elements", count)
+}
+
+// FormatUpdateResult formats the outcome of several map updates. The
+// errors aren't included; they're reported as they happen.
+// Format: Updated <n> element(s)[, <m> failed]
+func (f *PlainFormatter) FormatUpdateResult(result UpdateResult) string {
+	s := fmt.Sprintf("Updated %d elements", result.Updated)
+	if result.Updated == 1 {
+		s = "Updated 1 element"
+	}
+	if result.Failed > 0 {
+		s += fmt.Sprintf(", %d failed", result.Failed)
+	}
+	return s
+}
+
+// FormatUnpinned formats the paths removed by an unpin operation.
+// Format:
+//
+//	unpinned <path>
+//	Removed <n> pin(s)
+func (f *PlainFormatter) FormatUnpinned(paths []string) string {
+	var sb strings.Builder
+
+	for _, path := range paths {
+		fmt.Fprintf(&sb, "unpinned %s\n", path)
+	}
+
+	fmt.Fprintf(&sb, "Removed %d pin", len(paths))
+	if len(paths) != 1 {
+		sb.WriteString("s")
+	}
+
+	return sb.String()
+}
+
+// FormatRecord formats a record streamed from a map as hex bytes, prefixed
+// with the CPU it was written on when known.
+func (f *PlainFormatter) FormatRecord(r Record) string {
+	if r.CPUKnown {
+		return fmt.Sprintf("cpu %d: %s", r.CPU, formatHexBytes(r.Data))
+	}
+	return formatHexBytes(r.Data)
+}
+
 // FormatError formats an error message for stderr output.
 func (f *PlainFormatter) FormatError(err error) string {
 	return fmt.Sprintf("Error: %v", err)
@@ -2,6 +2,7 @@ package output
 
 import (
 	"fmt"
+	"strings"
 	"testing"
 	"time"
 )
@@ -101,6 +102,26 @@ func TestPlainFormatter_FormatPrograms(t *testing.T) {
 				"\tloaded_at 2025-11-24T05:50:46+0000  uid 0\n" +
 				"\txlated 200B  jited 100B  memlock 8192B",
 		},
+		{
+			name: "pinned program",
+			progs: []ProgramInfo{
+				{
+					ID:          5,
+					Type:        "xdp",
+					Name:        "pinned_prog",
+					Tag:         "5555555555555555",
+					LoadedAt:    loadedAt,
+					BytesXlat:   100,
+					BytesJIT:    50,
+					MemLock:     4096,
+					PinnedPaths: []string{"/sys/fs/bpf/a", "/sys/fs/bpf/b"},
+				},
+			},
+			expected: "5: xdp  name pinned_prog  tag 5555555555555555\n" +
+				"\tloaded_at 2025-11-24T05:50:46+0000  uid 0\n" +
+				"\txlated 100B  jited 50B  memlock 4096B\n" +
+				"\tpinned /sys/fs/bpf/a,/sys/fs/bpf/b",
+		},
 	}
 
 	for _, tt := range tests {
@@ -113,6 +134,53 @@ func TestPlainFormatter_FormatPrograms(t *testing.T) {
 	}
 }
 
+func TestPlainFormatter_FormatPrograms_Maps(t *testing.T) {
+	formatter := &PlainFormatter{}
+	loadedAt := time.Date(2025, 11, 24, 5, 50, 46, 0, time.UTC)
+	progs := []ProgramInfo{
+		{
+			ID: 10, Type: "xdp", Name: "p", Tag: "00", LoadedAt: loadedAt, MapIDs: []uint32{5, 6},
+			Maps: []ProgramMap{
+				{ID: 5, Info: &MapInfo{ID: 5, Type: "hash", Name: "counts", KeySize: 4, ValueSize: 8, MaxEntries: 16}},
+				{ID: 6, Error: "permission denied"},
+			},
+		},
+	}
+
+	expected := "10: xdp  name p  tag 00\n" +
+		"\tloaded_at 2025-11-24T05:50:46+0000  uid 0\n" +
+		"\txlated 0B  jited 0B  memlock 0B  map_ids 5,6\n" +
+		"\tmaps:\n" +
+		"\t\t5: hash  name counts  key 4B  value 8B  max_entries 16\n" +
+		"\t\t6: error: permission denied"
+	if result := formatter.FormatPrograms(progs); result != expected {
+		t.Errorf("FormatPrograms() =\n%q\nwant:\n%q", result, expected)
+	}
+}
+
+func TestPlainFormatter_FormatPrograms_Attach(t *testing.T) {
+	formatter := &PlainFormatter{}
+	loadedAt := time.Date(2025, 11, 24, 5, 50, 46, 0, time.UTC)
+	progs := []ProgramInfo{
+		{ID: 10, Type: "xdp", Name: "fw", Tag: "00", LoadedAt: loadedAt,
+			Attach: &AttachInfo{Type: "xdp", Ifindex: 2, Target: "eth0"}},
+		{ID: 11, Type: "tracing", Name: "trace", Tag: "01", LoadedAt: loadedAt,
+			Attach: &AttachInfo{Type: "tracing", BTFObjID: 1, BTFID: 5432, Target: "tcp_connect"}},
+	}
+
+	expected := "10: xdp  name fw  tag 00\n" +
+		"\tloaded_at 2025-11-24T05:50:46+0000  uid 0\n" +
+		"\txlated 0B  jited 0B  memlock 0B\n" +
+		"\tattach xdp  ifindex 2  target eth0\n" +
+		"11: tracing  name trace  tag 01\n" +
+		"\tloaded_at 2025-11-24T05:50:46+0000  uid 0\n" +
+		"\txlated 0B  jited 0B  memlock 0B\n" +
+		"\tattach tracing  btf_obj_id 1  btf_id 5432  target tcp_connect"
+	if result := formatter.FormatPrograms(progs); result != expected {
+		t.Errorf("FormatPrograms() =\n%q\nwant:\n%q", result, expected)
+	}
+}
+
 func TestPlainFormatter_FormatMaps(t *testing.T) {
 	formatter := &PlainFormatter{}
 
@@ -160,6 +228,23 @@ func TestPlainFormatter_FormatMaps(t *testing.T) {
 			expected: "20: array  name my_array  flags 0x1\n" +
 				"\tkey 4B  value 16B  max_entries 100  memlock 8192B",
 		},
+		{
+			name: "map with flag names",
+			maps: []MapInfo{
+				{
+					ID:         21,
+					Type:       "hash",
+					Name:       "ro_map",
+					KeySize:    4,
+					ValueSize:  4,
+					MaxEntries: 10,
+					Flags:      0x9,
+					FlagNames:  []string{"BPF_F_NO_PREALLOC", "BPF_F_RDONLY"},
+				},
+			},
+			expected: "21: hash  name ro_map  flags 0x9 (BPF_F_NO_PREALLOC|BPF_F_RDONLY)\n" +
+				"\tkey 4B  value 4B  max_entries 10  memlock 0B",
+		},
 		{
 			name: "multiple maps",
 			maps: []MapInfo{
@@ -189,6 +274,42 @@ func TestPlainFormatter_FormatMaps(t *testing.T) {
 				"2: array  name map2  flags 0x0\n" +
 				"\tkey 4B  value 8B  max_entries 50  memlock 2048B",
 		},
+		{
+			name: "map with one pinned path",
+			maps: []MapInfo{
+				{
+					ID:          30,
+					Type:        "hash",
+					Name:        "pinned_map",
+					KeySize:     4,
+					ValueSize:   4,
+					MaxEntries:  10,
+					MemLock:     4096,
+					PinnedPaths: []string{"/sys/fs/bpf/pinned_map"},
+				},
+			},
+			expected: "30: hash  name pinned_map  flags 0x0\n" +
+				"\tkey 4B  value 4B  max_entries 10  memlock 4096B\n" +
+				"\tpinned /sys/fs/bpf/pinned_map",
+		},
+		{
+			name: "map with multiple pinned paths",
+			maps: []MapInfo{
+				{
+					ID:          31,
+					Type:        "array",
+					Name:        "shared_map",
+					KeySize:     4,
+					ValueSize:   4,
+					MaxEntries:  10,
+					MemLock:     4096,
+					PinnedPaths: []string{"/sys/fs/bpf/a", "/sys/fs/bpf/b"},
+				},
+			},
+			expected: "31: array  name shared_map  flags 0x0\n" +
+				"\tkey 4B  value 4B  max_entries 10  memlock 4096B\n" +
+				"\tpinned /sys/fs/bpf/a,/sys/fs/bpf/b",
+		},
 	}
 
 	for _, tt := range tests {
@@ -201,6 +322,15 @@ func TestPlainFormatter_FormatMaps(t *testing.T) {
 	}
 }
 
+func TestPlainFormatter_FormatMap(t *testing.T) {
+	formatter := &PlainFormatter{}
+	m := MapInfo{ID: 10, Type: "hash", Name: "some_map", KeySize: 4, ValueSize: 8, MaxEntries: 2048}
+
+	if got, want := formatter.FormatMap(m), formatter.FormatMaps([]MapInfo{m}); got != want {
+		t.Errorf("FormatMap() = %q, want %q", got, want)
+	}
+}
+
 func TestPlainFormatter_FormatMapEntries(t *testing.T) {
 	formatter := &PlainFormatter{}
 
@@ -248,6 +378,21 @@ func TestPlainFormatter_FormatMapEntries(t *testing.T) {
 				"key: 0d 00 07 00  value: 02 00 00 00 01 02 03 04\n" +
 				"Found 2 elements",
 		},
+		{
+			name: "per-CPU entry",
+			entries: []MapEntry{
+				{
+					Key:          []byte{0x01, 0x00, 0x00, 0x00},
+					PerCPUValues: [][]byte{{0x0a, 0x00}, {0x0b, 0x00}},
+				},
+			},
+			keySize:   4,
+			valueSize: 2,
+			expected: "key: 01 00 00 00\n" +
+				"\tvalue (CPU 00): 0a 00\n" +
+				"\tvalue (CPU 01): 0b 00\n" +
+				"Found 1 element",
+		},
 	}
 
 	for _, tt := range tests {
@@ -260,6 +405,148 @@ func TestPlainFormatter_FormatMapEntries(t *testing.T) {
 	}
 }
 
+func TestPlainFormatter_FormatMapEntries_Fields(t *testing.T) {
+	entries := []MapEntry{
+		{Key: []byte{0x00, 0x01}, Value: []byte{0x0a, 0x0b}},
+		{Key: []byte{0x02, 0x03}, PerCPUValues: [][]byte{{0x0c}, {0x0d}}},
+	}
+
+	tests := []struct {
+		name     string
+		fields   EntryFields
+		expected string
+	}{
+		{
+			name:   "keys only",
+			fields: EntryKeysOnly,
+			expected: "key: 00 01\n" +
+				"key: 02 03\n" +
+				"Found 2 elements",
+		},
+		{
+			name:   "values only",
+			fields: EntryValuesOnly,
+			expected: "value: 0a 0b\n" +
+				"\tvalue (CPU 00): 0c\n" +
+				"\tvalue (CPU 01): 0d\n" +
+				"Found 2 elements",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			formatter := &PlainFormatter{fields: tt.fields}
+			result := formatter.FormatMapEntries(entries, 2, 2)
+			if result != tt.expected {
+				t.Errorf("FormatMapEntries() =\n%q\nwant:\n%q", result, tt.expected)
+			}
+		})
+	}
+}
+
+func TestPlainFormatter_FormatMapEntries_Quiet(t *testing.T) {
+	entries := []MapEntry{
+		{Key: []byte{0x00, 0x01}, Value: []byte{0x0a, 0x0b}},
+		{Key: []byte{0x02, 0x03}, Value: []byte{0x0c, 0x0d}},
+	}
+
+	tests := []struct {
+		name     string
+		opts     []Option
+		entries  []MapEntry
+		expected string
+	}{
+		{
+			name:    "default keeps the trailer",
+			entries: entries,
+			expected: "key: 00 01  value: 0a 0b\n" +
+				"key: 02 03  value: 0c 0d\n" +
+				"Found 2 elements",
+		},
+		{
+			name:    "quiet",
+			opts:    []Option{WithQuiet()},
+			entries: entries,
+			expected: "key: 00 01  value: 0a 0b\n" +
+				"key: 02 03  value: 0c 0d",
+		},
+		{
+			name:     "quiet and empty",
+			opts:     []Option{WithQuiet()},
+			expected: "",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			formatter := NewFormatter(FormatPlain, tt.opts...)
+			if result := formatter.FormatMapEntries(tt.entries, 2, 2); result != tt.expected {
+				t.Errorf("FormatMapEntries() =\n%q\nwant:\n%q", result, tt.expected)
+			}
+		})
+	}
+
+	// JSON has no trailer to drop
+	quiet := NewFormatter(FormatJSON, WithQuiet()).FormatMapEntries(entries, 2, 2)
+	if want := NewFormatter(FormatJSON).FormatMapEntries(entries, 2, 2); quiet != want {
+		t.Errorf("quiet JSON = %q, want %q", quiet, want)
+	}
+}
+
+func TestPlainFormatter_FormatMapEntries_KeyAsIP(t *testing.T) {
+	formatter := NewFormatter(FormatPlain, WithKeyAs(KeyAsIP))
+	entries := []MapEntry{
+		{Key: []byte{192, 168, 0, 1}, Value: []byte{0x01}},
+		{Key: []byte{0x20, 0x01, 0x0d, 0xb8, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0x01}, Value: []byte{0x02}},
+		{Key: []byte{0x01, 0x02}, Value: []byte{0x03}},
+	}
+
+	expected := "key: c0 a8 00 01 (192.168.0.1)  value: 01\n" +
+		"key: 20 01 0d b8 00 00 00 00 00 00 00 00 00 00 00 01 (2001:db8::1)  value: 02\n" +
+		"key: 01 02  value: 03\n" +
+		"Found 3 elements"
+	if result := formatter.FormatMapEntries(entries, 0, 1); result != expected {
+		t.Errorf("FormatMapEntries() =\n%q\nwant:\n%q", result, expected)
+	}
+
+	expected = "key: 0a 00 00 01 (10.0.0.1) value: 01"
+	if result := formatter.FormatMapEntry(MapEntry{Key: []byte{10, 0, 0, 1}, Value: []byte{0x01}}, 4, 1); result != expected {
+		t.Errorf("FormatMapEntry() = %q, want %q", result, expected)
+	}
+}
+
+func TestPlainFormatter_FormatMapEntries_ValueAs(t *testing.T) {
+	formatter := &PlainFormatter{valueAs: "u32le"}
+	entries := []MapEntry{
+		{Key: []byte{0x01}, Value: []byte{0x2a, 0x00, 0x00, 0x00}},
+		{Key: []byte{0x02}, PerCPUValues: [][]byte{{0x01, 0x01, 0x00, 0x00}}},
+	}
+
+	expected := "key: 01  value: 2a 00 00 00 (42)\n" +
+		"key: 02\n" +
+		"\tvalue (CPU 00): 01 01 00 00 (257)\n" +
+		"Found 2 elements"
+	if result := formatter.FormatMapEntries(entries, 1, 4); result != expected {
+		t.Errorf("FormatMapEntries() =\n%q\nwant:\n%q", result, expected)
+	}
+}
+
+func TestPlainFormatter_FormatMapDiff(t *testing.T) {
+	formatter := &PlainFormatter{}
+	diff := MapDiff{
+		Added:   []MapEntry{{Key: []byte{0x01}, Value: []byte{0x0a}}},
+		Removed: []MapEntry{{Key: []byte{0x02}, PerCPUValues: [][]byte{{0x0b}, {0x0c}}}},
+		Changed: []MapEntryChange{{Old: MapEntry{Key: []byte{0x03}, Value: []byte{0x01}}, New: MapEntry{Key: []byte{0x03}, Value: []byte{0x02}}}},
+	}
+
+	expected := "+ key: 01  value: 0a\n" +
+		"- key: 02  value: 0b | 0c\n" +
+		"~ key: 03  value: 01 -> 02"
+	if result := formatter.FormatMapDiff(diff); result != expected {
+		t.Errorf("FormatMapDiff() =\n%q\nwant:\n%q", result, expected)
+	}
+}
+
 func TestPlainFormatter_FormatMapEntry(t *testing.T) {
 	formatter := &PlainFormatter{}
 
@@ -280,6 +567,16 @@ func TestPlainFormatter_FormatMapEntry(t *testing.T) {
 			valueSize: 8,
 			expected:  "key: 00 01 02 03 value: 00 01 02 03 04 05 06 07",
 		},
+		{
+			name: "per-CPU entry",
+			entry: MapEntry{
+				Key:          []byte{0x01, 0x00, 0x00, 0x00},
+				PerCPUValues: [][]byte{{0x0a, 0x00}, {0x0b, 0x00}},
+			},
+			keySize:   4,
+			valueSize: 2,
+			expected:  "key: 01 00 00 00\n\tvalue (CPU 00): 0a 00\n\tvalue (CPU 01): 0b 00",
+		},
 	}
 
 	for _, tt := range tests {
@@ -387,3 +684,245 @@ func TestFormatHexBytes(t *testing.T) {
 		})
 	}
 }
+
+func TestPlainFormatter_FormatInstructions(t *testing.T) {
+	formatter := &PlainFormatter{}
+
+	insns := []Instruction{
+		{Offset: 0, Raw: []byte{0xb7, 0x00, 0, 0, 0, 0, 0, 0}, Text: "MovImm dst: r0 imm: 0"},
+		{Offset: 1, Raw: []byte{0x95, 0x00, 0, 0, 0, 0, 0, 0}, Text: "Exit"},
+	}
+
+	want := "   0: (b7) MovImm dst: r0 imm: 0\n   1: (95) Exit"
+	if got := formatter.FormatInstructions(insns); got != want {
+		t.Errorf("FormatInstructions() = %q, want %q", got, want)
+	}
+}
+
+func TestPlainFormatter_FormatUnpinned(t *testing.T) {
+	formatter := &PlainFormatter{}
+
+	tests := []struct {
+		name  string
+		paths []string
+		want  string
+	}{
+		{
+			name: "nothing removed",
+			want: "Removed 0 pins",
+		},
+		{
+			name:  "single pin",
+			paths: []string{"/sys/fs/bpf/a"},
+			want:  "unpinned /sys/fs/bpf/a\nRemoved 1 pin",
+		},
+		{
+			name:  "multiple pins",
+			paths: []string{"/sys/fs/bpf/a", "/sys/fs/bpf/b"},
+			want:  "unpinned /sys/fs/bpf/a\nunpinned /sys/fs/bpf/b\nRemoved 2 pins",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := formatter.FormatUnpinned(tt.paths); got != tt.want {
+				t.Errorf("FormatUnpinned() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestPlainFormatter_FormatPrograms_RunStats(t *testing.T) {
+	formatter := &PlainFormatter{}
+	progs := []ProgramInfo{{ID: 1, Type: "xdp", Name: "p", Tag: "00", RunTimeNS: 1234, RunCount: 5}}
+
+	result := formatter.FormatPrograms(progs)
+	if want := "1: xdp  name p  tag 00  run_time_ns 1234  run_cnt 5\n"; !strings.HasPrefix(result, want) {
+		t.Errorf("FormatPrograms() = %q, want prefix %q", result, want)
+	}
+}
+
+func TestPlainFormatter_FormatProgram(t *testing.T) {
+	formatter := &PlainFormatter{}
+	p := ProgramInfo{ID: 1, Type: "xdp", Name: "p", Tag: "00", MapIDs: []uint32{3}}
+
+	if got, want := formatter.FormatProgram(p), formatter.FormatPrograms([]ProgramInfo{p}); got != want {
+		t.Errorf("FormatProgram() = %q, want %q", got, want)
+	}
+}
+
+func TestPlainFormatter_AnonymousNames(t *testing.T) {
+	formatter := &PlainFormatter{}
+	p := ProgramInfo{ID: 1, Type: "xdp", Tag: "00", Maps: []ProgramMap{{ID: 3, Info: &MapInfo{ID: 3, Type: "array"}}}}
+	m := MapInfo{ID: 3, Type: "array"}
+
+	tests := []struct {
+		name   string
+		result string
+		want   []string
+	}{
+		{"FormatPrograms", formatter.FormatPrograms([]ProgramInfo{p}), []string{"1: xdp  name <anon>  tag 00", "3: array  name <anon>  key"}},
+		{"FormatProgram", formatter.FormatProgram(p), []string{"1: xdp  name <anon>  tag 00"}},
+		{"FormatMaps", formatter.FormatMaps([]MapInfo{m}), []string{"3: array  name <anon>  flags 0x0"}},
+		{"FormatMap", formatter.FormatMap(m), []string{"3: array  name <anon>  flags 0x0"}},
+		{"FormatStats", formatter.FormatStats(p), []string{"1: xdp  name <anon>  run_time_ns"}},
+	}
+	for _, tt := range tests {
+		for _, want := range tt.want {
+			if !strings.Contains(tt.result, want) {
+				t.Errorf("%s() = %q, want it to contain %q", tt.name, tt.result, want)
+			}
+		}
+	}
+}
+
+func TestPlainFormatter_FormatMaps_Entries(t *testing.T) {
+	formatter := &PlainFormatter{}
+	maps := []MapInfo{
+		{ID: 1, Type: "hash", Name: "a", MaxEntries: 16, Entries: 3, EntriesCounted: true},
+		{ID: 2, Type: "hash", Name: "b", MaxEntries: 1024, Entries: 100, EntriesCounted: true, EntriesCapped: true},
+		{ID: 3, Type: "hash", Name: "c", MaxEntries: 16},
+	}
+
+	result := formatter.FormatMaps(maps)
+	for _, want := range []string{"memlock 0B  entries 3/16\n", "memlock 0B  entries >=100/1024\n"} {
+		if !strings.Contains(result, want) {
+			t.Errorf("FormatMaps() = %q, want it to contain %q", result, want)
+		}
+	}
+	if strings.Count(result, "  entries ") != 2 {
+		t.Errorf("FormatMaps() = %q, want no entries for the uncounted map", result)
+	}
+}
+
+func TestPlainFormatter_FormatRecord(t *testing.T) {
+	formatter := &PlainFormatter{}
+
+	if got, want := formatter.FormatRecord(Record{Data: []byte{0xde, 0xad}}), "de ad"; got != want {
+		t.Errorf("FormatRecord() = %q, want %q", got, want)
+	}
+	if got, want := formatter.FormatRecord(Record{CPU: 3, CPUKnown: true, Data: []byte{0xde, 0xad}}), "cpu 3: de ad"; got != want {
+		t.Errorf("FormatRecord() = %q, want %q", got, want)
+	}
+}
+
+func TestPlainFormatter_FormatPrograms_VerifiedInsns(t *testing.T) {
+	formatter := &PlainFormatter{}
+	progs := []ProgramInfo{
+		{ID: 1, Type: "xdp", Name: "p", Tag: "00", MapIDs: []uint32{3}, VerifiedInsns: 42},
+		{ID: 2, Type: "xdp", Name: "q", Tag: "00"},
+	}
+
+	result := formatter.FormatPrograms(progs)
+	if want := "memlock 0B  map_ids 3  verified_insns 42\n"; !strings.Contains(result, want) {
+		t.Errorf("FormatPrograms() = %q, want it to contain %q", result, want)
+	}
+	// Kernels before 5.16 don't report it
+	if strings.Count(result, "verified_insns") != 1 {
+		t.Errorf("FormatPrograms() = %q, want verified_insns only for the first program", result)
+	}
+}
+
+func TestPlainFormatter_FormatPrograms_BTFID(t *testing.T) {
+	formatter := &PlainFormatter{}
+	progs := []ProgramInfo{
+		{ID: 1, Type: "xdp", Name: "p", Tag: "00", MapIDs: []uint32{3}, BTFID: 7, VerifiedInsns: 42},
+		{ID: 2, Type: "xdp", Name: "q", Tag: "00"},
+	}
+
+	result := formatter.FormatPrograms(progs)
+	if want := "map_ids 3  btf_id 7  verified_insns 42\n"; !strings.Contains(result, want) {
+		t.Errorf("FormatPrograms() = %q, want it to contain %q", result, want)
+	}
+	// Programs loaded without BTF have none
+	if strings.Count(result, "btf_id") != 1 {
+		t.Errorf("FormatPrograms() = %q, want btf_id only for the first program", result)
+	}
+}
+
+func TestPlainFormatter_FormatCount(t *testing.T) {
+	formatter := &PlainFormatter{}
+
+	tests := []struct {
+		count int
+		kind  string
+		want  string
+	}{
+		{count: 0, kind: "program", want: "0 programs"},
+		{count: 1, kind: "map", want: "1 map"},
+		{count: 12, kind: "map", want: "12 maps"},
+	}
+
+	for _, tt := range tests {
+		if got := formatter.FormatCount(tt.count, tt.kind); got != tt.want {
+			t.Errorf("FormatCount(%d, %q) = %q, want %q", tt.count, tt.kind, got, tt.want)
+		}
+	}
+}
+
+func TestPlainFormatter_FormatVersion(t *testing.T) {
+	formatter := &PlainFormatter{}
+
+	got := formatter.FormatVersion(VersionInfo{Version: "1.0.0", GitCommit: "unknown", BuildDate: "2025-01-01"})
+	if want := "gobpftool version 1.0.0\n  build date: 2025-01-01"; got != want {
+		t.Errorf("FormatVersion() = %q, want %q", got, want)
+	}
+}
+
+func TestPlainFormatter_FormatRunResult(t *testing.T) {
+	formatter := &PlainFormatter{}
+
+	result := formatter.FormatRunResult(RunResult{
+		ReturnValue: 2,
+		DataOut:     []byte{0xaa, 0xbb},
+		ContextOut:  []byte{},
+		Repeat:      10,
+		Duration:    150 * time.Nanosecond,
+	})
+	want := "retval 2  repeat 10  duration 150ns\ndata_out:\n00000000: aa bb\nctx_out:"
+	if result != want {
+		t.Errorf("FormatRunResult() = %q, want %q", result, want)
+	}
+
+	result = formatter.FormatRunResult(RunResult{Repeat: 1})
+	if want := "retval 0  repeat 1  duration 0s"; result != want {
+		t.Errorf("FormatRunResult() without output = %q, want %q", result, want)
+	}
+}
+
+func TestPlainFormatter_FormatBTFs(t *testing.T) {
+	formatter := &PlainFormatter{}
+
+	result := formatter.FormatBTFs([]BTFInfo{
+		{ID: 1, Name: "vmlinux", Size: 5843164, Kernel: true},
+		{ID: 42, Size: 1024},
+	})
+	want := "1: name [vmlinux]  size 5843164B  kernel\n42: name <anon>  size 1024B"
+	if result != want {
+		t.Errorf("FormatBTFs() = %q, want %q", result, want)
+	}
+}
+
+func TestPlainFormatter_FormatSystemInfo(t *testing.T) {
+	formatter := &PlainFormatter{}
+
+	result := formatter.FormatSystemInfo(SystemInfo{
+		KernelRelease: "6.8.0",
+		BPFFSRoot:     "/sys/fs/bpf",
+		BPFFSMounted:  true,
+		Programs:      12,
+		Maps:          30,
+		CountsKnown:   true,
+		CapBPF:        true,
+	})
+	want := "kernel: 6.8.0\nbpffs: /sys/fs/bpf (mounted)\nprograms: 12\nmaps: 30\ncapabilities: CAP_BPF yes, CAP_SYS_ADMIN no"
+	if result != want {
+		t.Errorf("FormatSystemInfo() = %q, want %q", result, want)
+	}
+
+	result = formatter.FormatSystemInfo(SystemInfo{KernelRelease: "6.8.0", BPFFSRoot: "/sys/fs/bpf"})
+	want = "kernel: 6.8.0\nbpffs: /sys/fs/bpf (not mounted)\nprograms: unknown\nmaps: unknown\ncapabilities: CAP_BPF no, CAP_SYS_ADMIN no"
+	if result != want {
+		t.Errorf("FormatSystemInfo() without counts = %q, want %q", result, want)
+	}
+}
@@ -0,0 +1,73 @@
+package output
+
+import (
+	"fmt"
+	"slices"
+	"sync"
+)
+
+var (
+	registryMu sync.RWMutex
+	// formatNames maps the names accepted by ParseFormat to formats.
+	formatNames = map[string]Format{
+		"plain":       FormatPlain,
+		"json":        FormatJSON,
+		"json-pretty": FormatJSONPretty,
+		"yaml":        FormatYAML,
+		"table":       FormatTable,
+	}
+	// factories creates the formatters of registered formats.
+	factories = map[Format]func() Formatter{}
+	// nextFormat is the value given to the next registered format.
+	nextFormat = FormatCSV + 1
+)
+
+// RegisterFormatter adds a named output format created by factory, so
+// programs embedding gobpftool can select their own format by name, e.g.
+// with --format. Like database/sql.Register, it panics when the name is
+// already taken or factory is nil; it's meant to be called from init.
+func RegisterFormatter(name string, factory func() Formatter) {
+	registryMu.Lock()
+	defer registryMu.Unlock()
+
+	if factory == nil {
+		panic("output: RegisterFormatter factory is nil")
+	}
+	if _, dup := formatNames[name]; dup {
+		panic("output: RegisterFormatter called twice for format " + name)
+	}
+	formatNames[name] = nextFormat
+	factories[nextFormat] = factory
+	nextFormat++
+}
+
+// ParseFormat returns the format with the given name: one of the built-in
+// "plain", "json", "json-pretty", "yaml" and "table", or a name added with
+// RegisterFormatter.
+func ParseFormat(name string) (Format, error) {
+	registryMu.RLock()
+	defer registryMu.RUnlock()
+
+	if f, ok := formatNames[name]; ok {
+		return f, nil
+	}
+	names := make([]string, 0, len(formatNames))
+	for n := range formatNames {
+		names = append(names, n)
+	}
+	slices.Sort(names)
+	return FormatPlain, fmt.Errorf("invalid output format %q, must be one of %v", name, names)
+}
+
+// registeredFormatter creates the formatter of a format added with
+// RegisterFormatter, reporting false for the built-in formats.
+func registeredFormatter(format Format) (Formatter, bool) {
+	registryMu.RLock()
+	factory, ok := factories[format]
+	registryMu.RUnlock()
+
+	if !ok {
+		return nil, false
+	}
+	return factory(), true
+}
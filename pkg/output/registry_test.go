@@ -0,0 +1,69 @@
+package output
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+)
+
+// countFormatter is a custom format that only prints how many programs
+// and maps there are
+type countFormatter struct {
+	PlainFormatter
+}
+
+func (countFormatter) FormatPrograms(progs []ProgramInfo) string {
+	return fmt.Sprintf("programs=%d", len(progs))
+}
+
+func init() {
+	RegisterFormatter("test-count", func() Formatter { return &countFormatter{} })
+}
+
+func TestRegisterFormatter(t *testing.T) {
+	format, err := ParseFormat("test-count")
+	if err != nil {
+		t.Fatalf("ParseFormat() error = %v", err)
+	}
+	for _, builtin := range []Format{FormatPlain, FormatJSON, FormatJSONPretty, FormatYAML, FormatTable, FormatCSV} {
+		if format == builtin {
+			t.Fatalf("registered format = %d, want a value of its own", format)
+		}
+	}
+
+	f := NewFormatter(format, WithTimestampFormat(TimestampEpoch))
+	if got := f.FormatPrograms(make([]ProgramInfo, 3)); got != "programs=3" {
+		t.Errorf("FormatPrograms() = %q, want the registered formatter's output", got)
+	}
+
+	defer func() {
+		if recover() == nil {
+			t.Error("registering a taken name didn't panic")
+		}
+	}()
+	RegisterFormatter("json", func() Formatter { return &countFormatter{} })
+}
+
+func TestParseFormat(t *testing.T) {
+	tests := []struct {
+		name string
+		want Format
+	}{
+		{"plain", FormatPlain},
+		{"json", FormatJSON},
+		{"json-pretty", FormatJSONPretty},
+		{"yaml", FormatYAML},
+		{"table", FormatTable},
+	}
+	for _, tt := range tests {
+		got, err := ParseFormat(tt.name)
+		if err != nil || got != tt.want {
+			t.Errorf("ParseFormat(%q) = %d, %v, want %d", tt.name, got, err, tt.want)
+		}
+	}
+
+	_, err := ParseFormat("xml")
+	if err == nil || !strings.Contains(err.Error(), "test-count") {
+		t.Errorf("ParseFormat(xml) error = %v, want it to list the formats", err)
+	}
+}
@@ -0,0 +1,231 @@
+package output
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+	"text/tabwriter"
+)
+
+// TableFormatter formats program and map lists as aligned columns, one
+// object per row. Map entries and next keys use the plain rendering since
+// a table layout doesn't apply to them.
+type TableFormatter struct {
+	PlainFormatter
+	// columns lists the columns to render by name, in order. Nil renders
+	// the default columns of each object type.
+	columns []string
+}
+
+// programColumn is a column of the program table.
+type programColumn struct {
+	name   string
+	header string
+	value  func(f *TableFormatter, p ProgramInfo) string
+}
+
+// mapColumn is a column of the map table.
+type mapColumn struct {
+	name   string
+	header string
+	value  func(m MapInfo) string
+}
+
+// programColumns are the columns known to the program table.
+var programColumns = []programColumn{
+	{"id", "ID", func(_ *TableFormatter, p ProgramInfo) string { return fmt.Sprintf("%d", p.ID) }},
+	{"type", "TYPE", func(_ *TableFormatter, p ProgramInfo) string { return p.Type }},
+	{"name", "NAME", func(_ *TableFormatter, p ProgramInfo) string { return p.Name }},
+	{"tag", "TAG", func(_ *TableFormatter, p ProgramInfo) string { return p.Tag }},
+	{"gpl", "GPL", func(_ *TableFormatter, p ProgramInfo) string { return fmt.Sprintf("%t", p.GPL) }},
+	{"loaded_at", "LOADED_AT", func(f *TableFormatter, p ProgramInfo) string { return f.timestamp.format(p.LoadedAt) }},
+	{"uid", "UID", func(_ *TableFormatter, p ProgramInfo) string {
+		if !p.UIDKnown {
+			return ""
+		}
+		return fmt.Sprintf("%d", p.UID)
+	}},
+	{"xlated", "XLATED", func(_ *TableFormatter, p ProgramInfo) string { return fmt.Sprintf("%dB", p.BytesXlat) }},
+	{"jited", "JITED", func(_ *TableFormatter, p ProgramInfo) string { return fmt.Sprintf("%dB", p.BytesJIT) }},
+	{"memlock", "MEMLOCK", func(_ *TableFormatter, p ProgramInfo) string { return fmt.Sprintf("%dB", p.MemLock) }},
+	{"maps", "MAPS", func(_ *TableFormatter, p ProgramInfo) string { return joinIDs(p.MapIDs) }},
+	{"pinned", "PINNED", func(_ *TableFormatter, p ProgramInfo) string { return strings.Join(p.PinnedPaths, ",") }},
+	{"run_time_ns", "RUN_TIME_NS", func(_ *TableFormatter, p ProgramInfo) string { return fmt.Sprintf("%d", p.RunTimeNS) }},
+	{"run_cnt", "RUN_CNT", func(_ *TableFormatter, p ProgramInfo) string { return fmt.Sprintf("%d", p.RunCount) }},
+}
+
+// mapColumns are the columns known to the map table.
+var mapColumns = []mapColumn{
+	{"id", "ID", func(m MapInfo) string { return fmt.Sprintf("%d", m.ID) }},
+	{"type", "TYPE", func(m MapInfo) string { return m.Type }},
+	{"name", "NAME", func(m MapInfo) string { return m.Name }},
+	{"key", "KEY", func(m MapInfo) string { return fmt.Sprintf("%dB", m.KeySize) }},
+	{"value", "VALUE", func(m MapInfo) string { return fmt.Sprintf("%dB", m.ValueSize) }},
+	{"max", "MAX", func(m MapInfo) string { return fmt.Sprintf("%d", m.MaxEntries) }},
+	{"flags", "FLAGS", func(m MapInfo) string { return fmt.Sprintf("0x%x", m.Flags) }},
+	{"memlock", "MEMLOCK", func(m MapInfo) string { return fmt.Sprintf("%dB", m.MemLock) }},
+	{"pinned", "PINNED", func(m MapInfo) string { return strings.Join(m.PinnedPaths, ",") }},
+}
+
+// DefaultProgramColumns are the program table columns shown without --columns.
+var DefaultProgramColumns = []string{"id", "type", "name", "tag", "maps"}
+
+// DefaultMapColumns are the map table columns shown without --columns.
+var DefaultMapColumns = []string{"id", "type", "name", "key", "value", "max"}
+
+// ProgramColumnNames returns the names of the program table columns.
+func ProgramColumnNames() []string {
+	names := make([]string, len(programColumns))
+	for i, c := range programColumns {
+		names[i] = c.name
+	}
+	return names
+}
+
+// MapColumnNames returns the names of the map table columns.
+func MapColumnNames() []string {
+	names := make([]string, len(mapColumns))
+	for i, c := range mapColumns {
+		names[i] = c.name
+	}
+	return names
+}
+
+// ParseColumns parses a comma-separated list of column names such as
+// "id,name,memlock" against the known names. Names are case-insensitive
+// and kept in the order given. Unknown or repeated names are an error.
+func ParseColumns(spec string, known []string) ([]string, error) {
+	var columns []string
+	seen := make(map[string]bool)
+	for _, field := range strings.Split(spec, ",") {
+		name := strings.ToLower(strings.TrimSpace(field))
+		if name == "" {
+			return nil, fmt.Errorf("empty column name in %q", spec)
+		}
+		if !slices.Contains(known, name) {
+			return nil, fmt.Errorf("unknown column %q, valid columns: %s", name, strings.Join(known, ", "))
+		}
+		if seen[name] {
+			return nil, fmt.Errorf("column %q listed twice", name)
+		}
+		seen[name] = true
+		columns = append(columns, name)
+	}
+	return columns, nil
+}
+
+// FormatPrograms formats programs as a table of the selected columns.
+// Format:
+//
+//	ID   TYPE       NAME     TAG               MAPS
+//	185  sched_cls  my_prog  f0055c08993fea1e  85,39
+func (f *TableFormatter) FormatPrograms(progs []ProgramInfo) string {
+	if len(progs) == 0 {
+		return ""
+	}
+
+	var columns []programColumn
+	for _, name := range f.columnsOr(DefaultProgramColumns) {
+		for _, c := range programColumns {
+			if c.name == name {
+				columns = append(columns, c)
+			}
+		}
+	}
+
+	header := make([]string, len(columns))
+	for i, c := range columns {
+		header[i] = c.header
+	}
+	rows := make([][]string, len(progs))
+	for i, p := range progs {
+		rows[i] = make([]string, len(columns))
+		for j, c := range columns {
+			rows[i][j] = c.value(f, p)
+		}
+	}
+
+	return formatTable(header, rows)
+}
+
+// FormatProgram formats a single program as a table with one row.
+func (f *TableFormatter) FormatProgram(p ProgramInfo) string {
+	return f.FormatPrograms([]ProgramInfo{p})
+}
+
+// FormatMaps formats maps as a table of the selected columns.
+// Format:
+//
+//	ID  TYPE  NAME      KEY  VALUE  MAX
+//	10  hash  some_map  4B   8B     2048
+func (f *TableFormatter) FormatMaps(maps []MapInfo) string {
+	if len(maps) == 0 {
+		return ""
+	}
+
+	var columns []mapColumn
+	for _, name := range f.columnsOr(DefaultMapColumns) {
+		for _, c := range mapColumns {
+			if c.name == name {
+				columns = append(columns, c)
+			}
+		}
+	}
+
+	header := make([]string, len(columns))
+	for i, c := range columns {
+		header[i] = c.header
+	}
+	rows := make([][]string, len(maps))
+	for i, m := range maps {
+		rows[i] = make([]string, len(columns))
+		for j, c := range columns {
+			rows[i][j] = c.value(m)
+		}
+	}
+
+	return formatTable(header, rows)
+}
+
+// FormatMap formats a single map as a table with one row.
+func (f *TableFormatter) FormatMap(m MapInfo) string {
+	return f.FormatMaps([]MapInfo{m})
+}
+
+// columnsOr returns the selected columns, or defaults when none were selected.
+func (f *TableFormatter) columnsOr(defaults []string) []string {
+	if f.columns == nil {
+		return defaults
+	}
+	return f.columns
+}
+
+// joinIDs joins IDs with commas.
+func joinIDs(ids []uint32) string {
+	strs := make([]string, len(ids))
+	for i, id := range ids {
+		strs[i] = fmt.Sprintf("%d", id)
+	}
+	return strings.Join(strs, ",")
+}
+
+// formatTable renders a header and rows as tab-aligned columns.
+func formatTable(header []string, rows [][]string) string {
+	var sb strings.Builder
+	tw := tabwriter.NewWriter(&sb, 0, 0, 2, ' ', 0)
+
+	fmt.Fprintln(tw, strings.Join(header, "\t"))
+	for _, row := range rows {
+		fmt.Fprintln(tw, strings.Join(row, "\t"))
+	}
+	tw.Flush()
+
+	// Drop the padding tabwriter leaves after empty trailing cells
+	lines := strings.Split(strings.TrimRight(sb.String(), "\n"), "\n")
+	for i, line := range lines {
+		lines[i] = strings.TrimRight(line, " ")
+	}
+
+	// Match the plain formatter, which leaves the final newline to the caller
+	return strings.Join(lines, "\n")
+}
@@ -0,0 +1,157 @@
+package output
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestTableFormatter_FormatPrograms(t *testing.T) {
+	formatter := &TableFormatter{}
+
+	tests := []struct {
+		name     string
+		progs    []ProgramInfo
+		expected string
+	}{
+		{
+			name:     "empty list",
+			progs:    []ProgramInfo{},
+			expected: "",
+		},
+		{
+			name: "multiple programs",
+			progs: []ProgramInfo{
+				{ID: 1, Type: "xdp", Name: "prog1", Tag: "1111111111111111", MapIDs: []uint32{1, 2}},
+				{ID: 185, Type: "sched_cls", Name: "my_prog", Tag: "f0055c08993fea1e"},
+			},
+			expected: "ID   TYPE       NAME     TAG               MAPS\n" +
+				"1    xdp        prog1    1111111111111111  1,2\n" +
+				"185  sched_cls  my_prog  f0055c08993fea1e",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result := formatter.FormatPrograms(tt.progs)
+			if result != tt.expected {
+				t.Errorf("FormatPrograms() =\n%q\nwant:\n%q", result, tt.expected)
+			}
+		})
+	}
+}
+
+func TestTableFormatter_FormatMaps(t *testing.T) {
+	formatter := &TableFormatter{}
+
+	result := formatter.FormatMaps([]MapInfo{
+		{ID: 10, Type: "hash", Name: "some_map", KeySize: 4, ValueSize: 8, MaxEntries: 2048},
+		{ID: 2, Type: "percpu_array", Name: "m", KeySize: 4, ValueSize: 16, MaxEntries: 1},
+	})
+
+	lines := strings.Split(result, "\n")
+	if len(lines) != 3 {
+		t.Fatalf("expected 3 lines, got %d:\n%s", len(lines), result)
+	}
+	if !strings.HasPrefix(lines[0], "ID  TYPE") {
+		t.Errorf("missing header, got %q", lines[0])
+	}
+
+	// Every column starts at the same offset on every row
+	for _, column := range []string{"NAME", "KEY", "VALUE", "MAX"} {
+		offset := strings.Index(lines[0], column)
+		for _, line := range lines[1:] {
+			if offset >= len(line) || line[offset-1] != ' ' || line[offset] == ' ' {
+				t.Errorf("column %s not aligned at offset %d in %q", column, offset, line)
+			}
+		}
+	}
+}
+
+func TestTableFormatter_Columns(t *testing.T) {
+	progs := []ProgramInfo{
+		{ID: 1, Type: "xdp", Name: "prog1", MemLock: 4096, UID: 0, UIDKnown: true},
+		{ID: 185, Type: "sched_cls", Name: "my_prog", MemLock: 8192},
+	}
+	maps := []MapInfo{
+		{ID: 10, Type: "hash", Name: "some_map", Flags: 1, PinnedPaths: []string{"/sys/fs/bpf/a", "/sys/fs/bpf/b"}},
+	}
+
+	tests := []struct {
+		name    string
+		columns []string
+		format  func(f *TableFormatter) string
+		want    string
+	}{
+		{
+			name:    "programs in the requested order",
+			columns: []string{"memlock", "name", "id"},
+			format:  func(f *TableFormatter) string { return f.FormatPrograms(progs) },
+			want: "MEMLOCK  NAME     ID\n" +
+				"4096B    prog1    1\n" +
+				"8192B    my_prog  185",
+		},
+		{
+			name:    "unknown uid is left blank",
+			columns: []string{"id", "uid"},
+			format:  func(f *TableFormatter) string { return f.FormatPrograms(progs) },
+			want:    "ID   UID\n1    0\n185",
+		},
+		{
+			name:    "maps",
+			columns: []string{"name", "flags", "pinned"},
+			format:  func(f *TableFormatter) string { return f.FormatMaps(maps) },
+			want:    "NAME      FLAGS  PINNED\nsome_map  0x1    /sys/fs/bpf/a,/sys/fs/bpf/b",
+		},
+		{
+			name:   "default map columns",
+			format: func(f *TableFormatter) string { return f.FormatMaps(maps) },
+			want:   "ID  TYPE  NAME      KEY  VALUE  MAX\n10  hash  some_map  0B   0B     0",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			formatter := NewFormatter(FormatTable, WithColumns(tt.columns)).(*TableFormatter)
+			if got := tt.format(formatter); got != tt.want {
+				t.Errorf("got:\n%q\nwant:\n%q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseColumns(t *testing.T) {
+	tests := []struct {
+		spec    string
+		want    []string
+		wantErr string
+	}{
+		{spec: "id,name,memlock", want: []string{"id", "name", "memlock"}},
+		{spec: " Name , ID", want: []string{"name", "id"}},
+		{spec: "id,bogus", wantErr: `unknown column "bogus"`},
+		{spec: "id,,name", wantErr: "empty column name"},
+		{spec: "id,name,id", wantErr: `column "id" listed twice`},
+	}
+
+	for _, tt := range tests {
+		got, err := ParseColumns(tt.spec, ProgramColumnNames())
+		if tt.wantErr != "" {
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("ParseColumns(%q) error = %v, want %q", tt.spec, err, tt.wantErr)
+			}
+			continue
+		}
+		if err != nil || strings.Join(got, ",") != strings.Join(tt.want, ",") {
+			t.Errorf("ParseColumns(%q) = %v, %v, want %v", tt.spec, got, err, tt.want)
+		}
+	}
+}
+
+func TestTableFormatter_FormatMapEntriesFallsBackToPlain(t *testing.T) {
+	entries := []MapEntry{{Key: []byte{0x01}, Value: []byte{0x02}}}
+
+	got := (&TableFormatter{}).FormatMapEntries(entries, 1, 1)
+	want := (&PlainFormatter{}).FormatMapEntries(entries, 1, 1)
+	if got != want {
+		t.Errorf("FormatMapEntries() = %q, want %q", got, want)
+	}
+}
@@ -0,0 +1,60 @@
+package output
+
+import (
+	"fmt"
+	"strconv"
+	"time"
+)
+
+// TimestampFormat selects how timestamps such as a program's loaded_at are rendered.
+type TimestampFormat int
+
+const (
+	// TimestampISO renders timestamps as 2006-01-02T15:04:05-0700, matching bpftool.
+	TimestampISO TimestampFormat = iota
+	// TimestampRFC3339Nano renders timestamps as RFC 3339 with nanoseconds.
+	TimestampRFC3339Nano
+	// TimestampEpoch renders timestamps as seconds since the Unix epoch.
+	TimestampEpoch
+)
+
+// isoLayout is the layout bpftool uses for loaded_at.
+const isoLayout = "2006-01-02T15:04:05-0700"
+
+// ParseTimestampFormat parses a timestamp format name: "iso", "rfc3339nano" or "epoch".
+func ParseTimestampFormat(s string) (TimestampFormat, error) {
+	switch s {
+	case "iso":
+		return TimestampISO, nil
+	case "rfc3339nano":
+		return TimestampRFC3339Nano, nil
+	case "epoch":
+		return TimestampEpoch, nil
+	default:
+		return TimestampISO, fmt.Errorf("invalid timestamp format %q, must be one of iso, rfc3339nano, epoch", s)
+	}
+}
+
+// String returns the name accepted by ParseTimestampFormat.
+func (tf TimestampFormat) String() string {
+	switch tf {
+	case TimestampRFC3339Nano:
+		return "rfc3339nano"
+	case TimestampEpoch:
+		return "epoch"
+	default:
+		return "iso"
+	}
+}
+
+// format renders t in the timestamp format.
+func (tf TimestampFormat) format(t time.Time) string {
+	switch tf {
+	case TimestampRFC3339Nano:
+		return t.Format(time.RFC3339Nano)
+	case TimestampEpoch:
+		return strconv.FormatInt(t.Unix(), 10)
+	default:
+		return t.Format(isoLayout)
+	}
+}
@@ -0,0 +1,65 @@
+package output
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestFormatPrograms_TimestampFormat(t *testing.T) {
+	loadedAt := time.Date(2025, 11, 24, 5, 50, 46, 123456789, time.FixedZone("", 2*60*60))
+	progs := []ProgramInfo{{ID: 1, Type: "xdp", Name: "p", Tag: "00", LoadedAt: loadedAt}}
+
+	tests := []struct {
+		name     string
+		format   string
+		expected string
+	}{
+		{name: "iso", format: "iso", expected: "2025-11-24T05:50:46+0200"},
+		{name: "rfc3339nano", format: "rfc3339nano", expected: "2025-11-24T05:50:46.123456789+02:00"},
+		{name: "epoch", format: "epoch", expected: "1763956246"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tf, err := ParseTimestampFormat(tt.format)
+			if err != nil {
+				t.Fatalf("ParseTimestampFormat(%q) error: %v", tt.format, err)
+			}
+			if tf.String() != tt.format {
+				t.Errorf("String() = %q, want %q", tf.String(), tt.format)
+			}
+
+			plain := NewFormatter(FormatPlain, WithTimestampFormat(tf)).FormatPrograms(progs)
+			if want := "\tloaded_at " + tt.expected + "  uid 0\n"; !strings.Contains(plain, want) {
+				t.Errorf("plain output = %q, want it to contain %q", plain, want)
+			}
+
+			out := NewFormatter(FormatJSON, WithTimestampFormat(tf)).FormatPrograms(progs)
+			var parsed programsJSON
+			if err := json.Unmarshal([]byte(out), &parsed); err != nil {
+				t.Fatalf("invalid JSON %q: %v", out, err)
+			}
+			if parsed.Programs[0].LoadedAt != tt.expected {
+				t.Errorf("JSON loaded_at = %q, want %q", parsed.Programs[0].LoadedAt, tt.expected)
+			}
+		})
+	}
+}
+
+func TestFormatPrograms_DefaultTimestampFormat(t *testing.T) {
+	loadedAt := time.Date(2025, 11, 24, 5, 50, 46, 0, time.UTC)
+	progs := []ProgramInfo{{ID: 1, LoadedAt: loadedAt}}
+
+	got := NewFormatter(FormatPlain).FormatPrograms(progs)
+	if !strings.Contains(got, "loaded_at 2025-11-24T05:50:46+0000") {
+		t.Errorf("default output = %q, want ISO loaded_at", got)
+	}
+}
+
+func TestParseTimestampFormat_Invalid(t *testing.T) {
+	if _, err := ParseTimestampFormat("unix"); err == nil {
+		t.Error("expected error for unknown timestamp format, got nil")
+	}
+}
@@ -0,0 +1,149 @@
+package output
+
+import (
+	"fmt"
+
+	"gopkg.in/yaml.v3"
+)
+
+// YAMLFormatter formats output as YAML. The document structure mirrors the
+// JSON output, including field names and ordering.
+type YAMLFormatter struct {
+	json JSONFormatter
+}
+
+// FormatPrograms formats programs as YAML.
+func (f *YAMLFormatter) FormatPrograms(progs []ProgramInfo) string {
+	return f.fromJSON(f.json.FormatPrograms(progs))
+}
+
+// FormatProgram formats a single program as a YAML mapping.
+func (f *YAMLFormatter) FormatProgram(p ProgramInfo) string {
+	return f.fromJSON(f.json.FormatProgram(p))
+}
+
+// FormatMaps formats maps as YAML.
+func (f *YAMLFormatter) FormatMaps(maps []MapInfo) string {
+	return f.fromJSON(f.json.FormatMaps(maps))
+}
+
+// FormatMap formats a single map as a YAML mapping.
+func (f *YAMLFormatter) FormatMap(m MapInfo) string {
+	return f.fromJSON(f.json.FormatMap(m))
+}
+
+// FormatMapEntries formats map entries as YAML.
+func (f *YAMLFormatter) FormatMapEntries(entries []MapEntry, keySize, valueSize uint32) string {
+	return f.fromJSON(f.json.FormatMapEntries(entries, keySize, valueSize))
+}
+
+// FormatMapEntry formats a single map entry as YAML.
+func (f *YAMLFormatter) FormatMapEntry(entry MapEntry, keySize, valueSize uint32) string {
+	return f.fromJSON(f.json.FormatMapEntry(entry, keySize, valueSize))
+}
+
+// FormatNextKey formats the next key result as YAML.
+func (f *YAMLFormatter) FormatNextKey(currentKey, nextKey []byte) string {
+	return f.fromJSON(f.json.FormatNextKey(currentKey, nextKey))
+}
+
+// FormatMapDiff formats the differences between two map dumps as YAML.
+func (f *YAMLFormatter) FormatMapDiff(diff MapDiff) string {
+	return f.fromJSON(f.json.FormatMapDiff(diff))
+}
+
+// FormatInstructions formats translated instructions as YAML.
+func (f *YAMLFormatter) FormatInstructions(insns []Instruction) string {
+	return f.fromJSON(f.json.FormatInstructions(insns))
+}
+
+// FormatJited formats JIT-compiled machine code as YAML.
+func (f *YAMLFormatter) FormatJited(code []byte) string {
+	return f.fromJSON(f.json.FormatJited(code))
+}
+
+// FormatStats formats the run time statistics of a program as YAML.
+func (f *YAMLFormatter) FormatStats(prog ProgramInfo) string {
+	return f.fromJSON(f.json.FormatStats(prog))
+}
+
+// FormatBTFs formats BTF objects as YAML.
+func (f *YAMLFormatter) FormatBTFs(objects []BTFInfo) string {
+	return f.fromJSON(f.json.FormatBTFs(objects))
+}
+
+// FormatBTFTypes formats BTF types as YAML.
+func (f *YAMLFormatter) FormatBTFTypes(types []BTFType) string {
+	return f.fromJSON(f.json.FormatBTFTypes(types))
+}
+
+// FormatRunResult formats the outcome of a test run as YAML.
+func (f *YAMLFormatter) FormatRunResult(result RunResult) string {
+	return f.fromJSON(f.json.FormatRunResult(result))
+}
+
+// FormatCount formats an object count as YAML.
+func (f *YAMLFormatter) FormatCount(count int, kind string) string {
+	return f.fromJSON(f.json.FormatCount(count, kind))
+}
+
+// FormatVersion formats the build information as YAML.
+func (f *YAMLFormatter) FormatVersion(info VersionInfo) string {
+	return f.fromJSON(f.json.FormatVersion(info))
+}
+
+// FormatSystemInfo formats an overview of the BPF environment as YAML.
+func (f *YAMLFormatter) FormatSystemInfo(info SystemInfo) string {
+	return f.fromJSON(f.json.FormatSystemInfo(info))
+}
+
+// FormatRecord formats a record streamed from a map as YAML.
+func (f *YAMLFormatter) FormatRecord(r Record) string {
+	return f.fromJSON(f.json.FormatRecord(r))
+}
+
+// FormatDeleted formats the number of entries removed from a map as YAML.
+func (f *YAMLFormatter) FormatDeleted(count int) string {
+	return f.fromJSON(f.json.FormatDeleted(count))
+}
+
+// FormatUpdateResult formats the outcome of several map updates as YAML.
+func (f *YAMLFormatter) FormatUpdateResult(result UpdateResult) string {
+	return f.fromJSON(f.json.FormatUpdateResult(result))
+}
+
+// FormatUnpinned formats the paths removed by an unpin operation as YAML.
+func (f *YAMLFormatter) FormatUnpinned(paths []string) string {
+	return f.fromJSON(f.json.FormatUnpinned(paths))
+}
+
+// FormatError formats an error as YAML.
+func (f *YAMLFormatter) FormatError(err error) string {
+	return f.fromJSON(f.json.FormatError(err))
+}
+
+// fromJSON re-encodes a JSON document as block-style YAML. JSON is valid
+// YAML, so parsing it into a yaml.Node keeps the key order intact.
+func (f *YAMLFormatter) fromJSON(data string) string {
+	var node yaml.Node
+	if err := yaml.Unmarshal([]byte(data), &node); err != nil {
+		return fmt.Sprintf("error: failed to convert to YAML: %v\n", err)
+	}
+	clearStyle(&node)
+
+	out, err := yaml.Marshal(&node)
+	if err != nil {
+		return fmt.Sprintf("error: failed to marshal YAML: %v\n", err)
+	}
+
+	return string(out)
+}
+
+// clearStyle resets the flow and quoting styles inherited from the JSON
+// input so the YAML is emitted in block style.
+func clearStyle(node *yaml.Node) {
+	node.Style = 0
+	for _, child := range node.Content {
+		clearStyle(child)
+	}
+}
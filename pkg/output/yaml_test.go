@@ -0,0 +1,117 @@
+package output
+
+import (
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+func TestYAMLFormatter_FormatPrograms(t *testing.T) {
+	formatter := &YAMLFormatter{}
+	loadedAt := time.Date(2025, 11, 24, 5, 50, 46, 0, time.UTC)
+
+	result := formatter.FormatPrograms([]ProgramInfo{
+		{
+			ID:        185,
+			Type:      "sched_cls",
+			Name:      "my_prog",
+			Tag:       "f0055c08993fea1e",
+			GPL:       true,
+			LoadedAt:  loadedAt,
+			BytesXlat: 5200,
+			MapIDs:    []uint32{85, 39},
+		},
+	})
+
+	var parsed struct {
+		Programs []struct {
+			ID            uint32   `yaml:"id"`
+			Name          string   `yaml:"name"`
+			GPLCompatible bool     `yaml:"gpl_compatible"`
+			BytesXlated   uint32   `yaml:"bytes_xlated"`
+			MapIDs        []uint32 `yaml:"map_ids"`
+		} `yaml:"programs"`
+	}
+	if err := yaml.Unmarshal([]byte(result), &parsed); err != nil {
+		t.Fatalf("failed to parse YAML: %v", err)
+	}
+	if len(parsed.Programs) != 1 {
+		t.Fatalf("expected 1 program, got %d", len(parsed.Programs))
+	}
+	p := parsed.Programs[0]
+	if p.ID != 185 || p.Name != "my_prog" || !p.GPLCompatible || p.BytesXlated != 5200 {
+		t.Errorf("unexpected program: %+v", p)
+	}
+	if len(p.MapIDs) != 2 {
+		t.Errorf("MapIDs length = %d, want 2", len(p.MapIDs))
+	}
+
+	// Block style, field order follows the JSON output
+	if !strings.HasPrefix(result, "programs:\n    - id: 185\n      type: sched_cls\n") {
+		t.Errorf("unexpected YAML layout:\n%s", result)
+	}
+}
+
+func TestYAMLFormatter_FormatMapEntries(t *testing.T) {
+	formatter := &YAMLFormatter{}
+
+	tests := []struct {
+		name      string
+		entries   []MapEntry
+		wantCount int
+	}{
+		{
+			name:      "empty entries",
+			entries:   []MapEntry{},
+			wantCount: 0,
+		},
+		{
+			name: "two entries",
+			entries: []MapEntry{
+				{Key: []byte{0x01}, Value: []byte{0x02}},
+				{Key: []byte{0x03}, Value: []byte{0x04}},
+			},
+			wantCount: 2,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result := formatter.FormatMapEntries(tt.entries, 1, 1)
+
+			var parsed struct {
+				Entries []map[string]interface{} `yaml:"entries"`
+				Count   int                      `yaml:"count"`
+			}
+			if err := yaml.Unmarshal([]byte(result), &parsed); err != nil {
+				t.Fatalf("failed to parse YAML: %v", err)
+			}
+			if parsed.Count != tt.wantCount {
+				t.Errorf("count = %d, want %d", parsed.Count, tt.wantCount)
+			}
+			if len(parsed.Entries) != tt.wantCount {
+				t.Errorf("entries length = %d, want %d", len(parsed.Entries), tt.wantCount)
+			}
+			for _, e := range parsed.Entries {
+				if _, ok := e["key"]; !ok {
+					t.Error("entry missing key field")
+				}
+				if _, ok := e["value"]; !ok {
+					t.Error("entry missing value field")
+				}
+			}
+		})
+	}
+}
+
+func TestYAMLFormatter_FormatError(t *testing.T) {
+	formatter := &YAMLFormatter{}
+
+	result := formatter.FormatError(errors.New("boom"))
+	if want := "error: boom\ncode: generic\n"; result != want {
+		t.Errorf("FormatError() = %q, want %q", result, want)
+	}
+}
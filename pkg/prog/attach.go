@@ -0,0 +1,69 @@
+package prog
+
+import (
+	"net"
+
+	"github.com/cilium/ebpf"
+	"github.com/cilium/ebpf/btf"
+)
+
+// attachInfo returns the attach target the kernel reported in objInfo, or
+// nil if it reported none. Device-bound programs such as offloaded XDP carry
+// an ifindex; tracing programs carry the BTF ID of the function they trace.
+func attachInfo(progType ebpf.ProgramType, objInfo *progObjInfo) *AttachInfo {
+	attach := &AttachInfo{Type: progType.String()}
+
+	switch {
+	case objInfo.Ifindex != 0:
+		attach.Ifindex = objInfo.Ifindex
+		if iface, err := net.InterfaceByIndex(int(objInfo.Ifindex)); err == nil {
+			attach.Target = iface.Name
+		}
+
+	case objInfo.AttachBTFID != 0:
+		attach.BTFObjID = objInfo.AttachBTFObjID
+		attach.BTFID = objInfo.AttachBTFID
+		attach.Target = btfTypeName(objInfo.AttachBTFObjID, objInfo.AttachBTFID)
+
+	default:
+		return nil
+	}
+
+	return attach
+}
+
+// btfTypeName resolves a type in a kernel BTF object to its name. It returns
+// an empty string if the object or type can't be read.
+func btfTypeName(objID, typeID uint32) string {
+	handle, err := btf.NewHandleFromID(btf.ID(objID))
+	if err != nil {
+		return ""
+	}
+	defer handle.Close()
+
+	info, err := handle.Info()
+	if err != nil {
+		return ""
+	}
+
+	// Kernel specs are cached by cilium/ebpf, so resolving the targets of
+	// many programs only parses vmlinux once
+	var spec *btf.Spec
+	switch {
+	case info.IsVmlinux():
+		spec, err = btf.LoadKernelSpec()
+	case info.IsModule():
+		spec, err = btf.LoadKernelModuleSpec(info.Name)
+	default:
+		spec, err = handle.Spec(nil)
+	}
+	if err != nil {
+		return ""
+	}
+
+	typ, err := spec.TypeByID(btf.TypeID(typeID))
+	if err != nil {
+		return ""
+	}
+	return typ.TypeName()
+}
@@ -0,0 +1,96 @@
+package prog
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"os"
+	"unsafe"
+
+	"github.com/cilium/ebpf"
+	"github.com/cilium/ebpf/asm"
+	bpferrors "github.com/viveksb007/gobpftool/pkg/errors"
+)
+
+// DumpXlated returns the translated instructions of a program.
+func (s *EBPFService) DumpXlated(id uint32) ([]Instruction, error) {
+	info, err := programInfoByID(id)
+	if err != nil {
+		return nil, err
+	}
+
+	// The kernel leaves the instructions empty without CAP_BPF or when
+	// restricted by sysctl; cilium/ebpf reports that as an error.
+	insns, err := info.Instructions()
+	if err != nil {
+		return nil, fmt.Errorf("translated instructions of program %d %w: %v", id, bpferrors.ErrNotAvailable, err)
+	}
+
+	return convertInstructions(insns)
+}
+
+// DumpJited returns the JIT-compiled machine code of a program.
+func (s *EBPFService) DumpJited(id uint32) ([]byte, error) {
+	info, err := programInfoByID(id)
+	if err != nil {
+		return nil, err
+	}
+
+	// Empty when the JIT is disabled, unsupported or without CAP_BPF
+	code, ok := info.JitedInsns()
+	if !ok {
+		return nil, fmt.Errorf("JITed code of program %d %w", id, bpferrors.ErrNotAvailable)
+	}
+
+	return code, nil
+}
+
+// programInfoByID opens a program by ID and returns its info.
+func programInfoByID(id uint32) (*ebpf.ProgramInfo, error) {
+	prog, err := openProgram(ebpf.ProgramID(id))
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return nil, fmt.Errorf("program with ID %d: %w", id, bpferrors.ErrNotFound)
+		}
+		return nil, fmt.Errorf("failed to get program %d: %w", id, err)
+	}
+	defer prog.Close()
+
+	info, err := prog.Info()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get program info: %w", err)
+	}
+
+	return info, nil
+}
+
+// convertInstructions encodes instructions and records their offsets.
+func convertInstructions(insns asm.Instructions) ([]Instruction, error) {
+	result := make([]Instruction, 0, len(insns))
+	offset := 0
+	for _, ins := range insns {
+		var buf bytes.Buffer
+		if _, err := ins.Marshal(&buf, hostEndian()); err != nil {
+			return nil, fmt.Errorf("failed to encode instruction %d: %w", offset, err)
+		}
+
+		result = append(result, Instruction{
+			Offset: offset,
+			Raw:    buf.Bytes(),
+			Text:   fmt.Sprint(ins),
+		})
+		offset += int(ins.Size() / asm.InstructionSize)
+	}
+	return result, nil
+}
+
+// hostEndian returns the host byte order. asm only accepts the concrete
+// binary.LittleEndian and binary.BigEndian values, not binary.NativeEndian.
+func hostEndian() binary.ByteOrder {
+	x := uint16(1)
+	if *(*byte)(unsafe.Pointer(&x)) == 1 {
+		return binary.LittleEndian
+	}
+	return binary.BigEndian
+}
@@ -0,0 +1,126 @@
+package prog
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/cilium/ebpf"
+
+	"github.com/viveksb007/gobpftool/internal/utils"
+)
+
+// tagLength is the number of hex digits in a program tag.
+const tagLength = 16
+
+// TypeNames returns the lowercase names of all program types known to
+// cilium/ebpf, in kernel enum order.
+func TypeNames() []string {
+	var names []string
+	for t := ebpf.ProgramType(1); t < 256; t++ {
+		if isKnownType(t) {
+			names = append(names, strings.ToLower(ProgTypeName(t)))
+		}
+	}
+	return names
+}
+
+// normalizeType folds a type name so "sched_cls", "SchedCLS" and "schedcls"
+// all compare equal.
+func normalizeType(typ string) string {
+	return strings.ToLower(strings.ReplaceAll(typ, "_", ""))
+}
+
+// ParseType returns the program type named typ, accepting the same
+// spellings as ProgTypeFromString. An unknown type returns an error
+// listing the recognized type names.
+func ParseType(typ string) (ebpf.ProgramType, error) {
+	t, ok := ProgTypeFromString(typ)
+	if !ok {
+		return ebpf.UnspecifiedProgram, fmt.Errorf("unknown program type %q, valid types: %s", typ, strings.Join(TypeNames(), ", "))
+	}
+	return t, nil
+}
+
+// ValidateType returns an error listing the recognized type names if typ
+// is not a known program type.
+func ValidateType(typ string) error {
+	_, err := ParseType(typ)
+	return err
+}
+
+// FilterByType returns the programs of the type named typ, accepting the
+// same spellings as ProgTypeFromString. An unknown type returns an error
+// listing the recognized type names.
+func FilterByType(progs []ProgramInfo, typ string) ([]ProgramInfo, error) {
+	t, err := ParseType(typ)
+	if err != nil {
+		return nil, err
+	}
+
+	want := normalizeType(ProgTypeName(t))
+	var matched []ProgramInfo
+	for _, p := range progs {
+		if normalizeType(p.Type) == want {
+			matched = append(matched, p)
+		}
+	}
+
+	return matched, nil
+}
+
+// FilterByMemlock returns the programs locking at least min bytes of memory.
+func FilterByMemlock(progs []ProgramInfo, min uint64) []ProgramInfo {
+	var matched []ProgramInfo
+	for _, p := range progs {
+		if uint64(p.MemLock) >= min {
+			matched = append(matched, p)
+		}
+	}
+	return matched
+}
+
+// FilterByIDRange returns the programs whose ID falls in r.
+func FilterByIDRange(progs []ProgramInfo, r utils.IDRange) []ProgramInfo {
+	var matched []ProgramInfo
+	for _, p := range progs {
+		if r.Contains(p.ID) {
+			matched = append(matched, p)
+		}
+	}
+	return matched
+}
+
+// ValidateTagPrefix returns an error if prefix isn't the start of a
+// program tag: 1 to 16 hex digits. Unlike full tags, odd lengths are
+// accepted.
+func ValidateTagPrefix(prefix string) error {
+	if prefix == "" || len(prefix) > tagLength {
+		return fmt.Errorf("invalid tag prefix %q: must be 1 to %d hex digits", prefix, tagLength)
+	}
+	// Pad to a whole byte so odd-length prefixes parse as hex
+	padded := prefix
+	if len(padded)%2 != 0 {
+		padded += "0"
+	}
+	if _, err := utils.ParseHexString(padded); err != nil || strings.ContainsAny(prefix, " \t\n") {
+		return fmt.Errorf("invalid tag prefix %q: must be 1 to %d hex digits", prefix, tagLength)
+	}
+	return nil
+}
+
+// FilterByTagPrefix returns the programs whose tag starts with prefix,
+// compared case-insensitively. An invalid prefix returns an error.
+func FilterByTagPrefix(progs []ProgramInfo, prefix string) ([]ProgramInfo, error) {
+	if err := ValidateTagPrefix(prefix); err != nil {
+		return nil, err
+	}
+
+	want := strings.ToLower(prefix)
+	var matched []ProgramInfo
+	for _, p := range progs {
+		if strings.HasPrefix(strings.ToLower(p.Tag), want) {
+			matched = append(matched, p)
+		}
+	}
+	return matched, nil
+}
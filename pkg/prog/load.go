@@ -0,0 +1,122 @@
+package prog
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/cilium/ebpf"
+)
+
+// Load loads the programs of a BPF ELF object and pins them. A single
+// program is pinned at pinPath; several are pinned as pinPath/<name>.
+// The object's maps are created as needed and live as long as the programs
+// using them. Verifier rejections are returned as *ebpf.VerifierError.
+func (s *EBPFService) Load(file, pinPath string, opts LoadOptions) ([]ProgramInfo, error) {
+	spec, err := ebpf.LoadCollectionSpec(file)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read object %s: %w", file, err)
+	}
+
+	names, err := selectPrograms(spec, opts)
+	if err != nil {
+		return nil, err
+	}
+
+	coll, err := ebpf.NewCollection(spec)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load %s: %w", file, err)
+	}
+	defer coll.Close()
+
+	var pinned []*ebpf.Program
+	unpinAll := func() {
+		for _, p := range pinned {
+			_ = p.Unpin()
+		}
+	}
+
+	programs := make([]ProgramInfo, 0, len(names))
+	for _, name := range names {
+		prog := coll.Programs[name]
+		path := pinPath
+		if len(names) > 1 {
+			path = filepath.Join(pinPath, name)
+		}
+
+		if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+			unpinAll()
+			return nil, fmt.Errorf("failed to create directory for %s: %w", path, err)
+		}
+		if err := prog.Pin(path); err != nil {
+			unpinAll()
+			return nil, fmt.Errorf("failed to pin program %s at %s: %w", name, path, err)
+		}
+		pinned = append(pinned, prog)
+
+		info, err := extractProgramInfo(prog)
+		if err != nil {
+			unpinAll()
+			return nil, err
+		}
+		programs = append(programs, *info)
+	}
+
+	s.refreshPinnedPaths()
+	for i := range programs {
+		programs[i].PinnedPaths = s.pinnedPaths(programs[i].ID)
+	}
+
+	return programs, nil
+}
+
+// selectPrograms drops the programs of spec that opts doesn't select,
+// applies the type override and returns the names of the remaining
+// programs in order.
+func selectPrograms(spec *ebpf.CollectionSpec, opts LoadOptions) ([]string, error) {
+	var progType ebpf.ProgramType
+	if opts.Type != "" {
+		t, err := ParseType(opts.Type)
+		if err != nil {
+			return nil, err
+		}
+		progType = t
+	}
+
+	var names, sections []string
+	for name, ps := range spec.Programs {
+		sections = append(sections, ps.SectionName)
+		if opts.Section != "" && ps.SectionName != opts.Section {
+			delete(spec.Programs, name)
+			continue
+		}
+		if progType != ebpf.UnspecifiedProgram {
+			ps.Type = progType
+		}
+		names = append(names, name)
+	}
+
+	if len(names) == 0 {
+		if opts.Section == "" {
+			return nil, errors.New("object contains no programs")
+		}
+		sort.Strings(sections)
+		return nil, fmt.Errorf("no programs in section %q, sections: %s", opts.Section, strings.Join(sections, ", "))
+	}
+
+	sort.Strings(names)
+	return names, nil
+}
+
+// VerifierLog returns the full verifier log when err is a program rejected
+// by the verifier.
+func VerifierLog(err error) (string, bool) {
+	var ve *ebpf.VerifierError
+	if !errors.As(err, &ve) {
+		return "", false
+	}
+	return fmt.Sprintf("%+v", ve), true
+}
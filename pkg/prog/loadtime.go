@@ -0,0 +1,27 @@
+package prog
+
+import (
+	"fmt"
+	"time"
+
+	"golang.org/x/sys/unix"
+)
+
+// bootTime returns the wall-clock time at which the system booted, computed
+// like bpftool does from CLOCK_REALTIME and CLOCK_BOOTTIME.
+func bootTime() (time.Time, error) {
+	var real, boot unix.Timespec
+	if err := unix.ClockGettime(unix.CLOCK_REALTIME, &real); err != nil {
+		return time.Time{}, fmt.Errorf("reading CLOCK_REALTIME: %w", err)
+	}
+	if err := unix.ClockGettime(unix.CLOCK_BOOTTIME, &boot); err != nil {
+		return time.Time{}, fmt.Errorf("reading CLOCK_BOOTTIME: %w", err)
+	}
+	return time.Unix(0, real.Nano()-boot.Nano()), nil
+}
+
+// loadTimeToWallClock converts a program's load time, which the kernel
+// reports relative to boot, to a wall-clock time.
+func loadTimeToWallClock(boot time.Time, sinceBoot time.Duration) time.Time {
+	return boot.Add(sinceBoot)
+}
@@ -0,0 +1,180 @@
+package prog
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/cilium/ebpf"
+	"github.com/cilium/ebpf/btf"
+
+	"github.com/viveksb007/gobpftool/internal/utils"
+	bpferrors "github.com/viveksb007/gobpftool/pkg/errors"
+)
+
+// metadataPrefix marks the global variables libbpf programs declare as
+// metadata, e.g. `const char bpf_metadata_version[] SEC(".rodata") = "1.2";`.
+const metadataPrefix = "bpf_metadata_"
+
+// metadataSection is the data section holding the metadata variables.
+const metadataSection = ".rodata"
+
+// MetadataEntry is a metadata variable declared by a program. Name has the
+// bpf_metadata_ prefix removed; Value is a string, an int64 or a uint64.
+type MetadataEntry struct {
+	Name  string
+	Value interface{}
+}
+
+// Metadata returns the metadata the program declares, in declaration
+// order, or nil when it has none. The metadata is read from the program's
+// .rodata map using the map's BTF, like bpftool does.
+func (s *EBPFService) Metadata(id uint32) ([]MetadataEntry, error) {
+	prog, err := openProgram(ebpf.ProgramID(id))
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return nil, fmt.Errorf("program with ID %d: %w", id, bpferrors.ErrNotFound)
+		}
+		return nil, fmt.Errorf("failed to get program %d: %w", id, err)
+	}
+	defer prog.Close()
+
+	info, err := prog.Info()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get info of program %d: %w", id, err)
+	}
+
+	mapIDs, _ := info.MapIDs()
+	for _, mapID := range mapIDs {
+		metadata, found, err := readMetadataMap(mapID)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read metadata of program %d: %w", id, err)
+		}
+		if found {
+			return metadata, nil
+		}
+	}
+
+	return nil, nil
+}
+
+// readMetadataMap decodes the metadata in a map, reporting false when the
+// map isn't a .rodata map with BTF.
+func readMetadataMap(id ebpf.MapID) ([]MetadataEntry, bool, error) {
+	m, err := utils.OpenByID(ebpf.NewMapFromID, id)
+	if err != nil {
+		// Maps freed since the program info was read can't hold metadata
+		if errors.Is(err, os.ErrNotExist) {
+			return nil, false, nil
+		}
+		return nil, false, fmt.Errorf("failed to get map %d: %w", id, err)
+	}
+	defer m.Close()
+
+	info, err := m.Info()
+	if err != nil {
+		return nil, false, fmt.Errorf("failed to get info of map %d: %w", id, err)
+	}
+	// libbpf keeps the section suffix when it truncates the map name
+	if info.Type != ebpf.Array || info.KeySize != 4 || info.MaxEntries != 1 ||
+		!strings.HasSuffix(info.Name, metadataSection) {
+		return nil, false, nil
+	}
+	btfID, ok := info.BTFID()
+	if !ok {
+		return nil, false, nil
+	}
+
+	handle, err := btf.NewHandleFromID(btfID)
+	if err != nil {
+		return nil, false, fmt.Errorf("failed to get BTF %d: %w", btfID, err)
+	}
+	defer handle.Close()
+
+	spec, err := handle.Spec(nil)
+	if err != nil {
+		return nil, false, fmt.Errorf("failed to load BTF %d: %w", btfID, err)
+	}
+	var sec *btf.Datasec
+	if err := spec.TypeByName(metadataSection, &sec); err != nil {
+		return nil, false, nil
+	}
+
+	value := make([]byte, info.ValueSize)
+	if err := m.Lookup(uint32(0), &value); err != nil {
+		return nil, false, fmt.Errorf("failed to read map %d: %w", id, err)
+	}
+
+	return DecodeMetadata(sec, value), true, nil
+}
+
+// DecodeMetadata decodes the bpf_metadata_ variables of a .rodata section
+// from its contents. Only integer and string (char array) variables are
+// decoded; variables of other types are left out.
+func DecodeMetadata(sec *btf.Datasec, data []byte) []MetadataEntry {
+	var entries []MetadataEntry
+	for _, v := range sec.Vars {
+		variable, ok := v.Type.(*btf.Var)
+		if !ok || !strings.HasPrefix(variable.Name, metadataPrefix) {
+			continue
+		}
+		end := uint64(v.Offset) + uint64(v.Size)
+		if end > uint64(len(data)) {
+			continue
+		}
+
+		value, ok := decodeMetadataValue(variable.Type, data[v.Offset:end])
+		if !ok {
+			continue
+		}
+		entries = append(entries, MetadataEntry{
+			Name:  strings.TrimPrefix(variable.Name, metadataPrefix),
+			Value: value,
+		})
+	}
+	return entries
+}
+
+// decodeMetadataValue decodes a metadata variable of type typ, reporting
+// false for types other than integers and char arrays.
+func decodeMetadataValue(typ btf.Type, raw []byte) (interface{}, bool) {
+	switch t := btf.UnderlyingType(typ).(type) {
+	case *btf.Int:
+		if int(t.Size) != len(raw) {
+			return nil, false
+		}
+		var u uint64
+		switch t.Size {
+		case 1:
+			u = uint64(raw[0])
+		case 2:
+			u = uint64(binary.NativeEndian.Uint16(raw))
+		case 4:
+			u = uint64(binary.NativeEndian.Uint32(raw))
+		case 8:
+			u = binary.NativeEndian.Uint64(raw)
+		default:
+			return nil, false
+		}
+		if t.Encoding&btf.Signed != 0 {
+			// Sign-extend from the integer's width
+			shift := 64 - 8*t.Size
+			return int64(u<<shift) >> shift, true
+		}
+		return u, true
+
+	case *btf.Array:
+		elem, ok := btf.UnderlyingType(t.Type).(*btf.Int)
+		if !ok || elem.Size != 1 {
+			return nil, false
+		}
+		if i := strings.IndexByte(string(raw), 0); i >= 0 {
+			raw = raw[:i]
+		}
+		return string(raw), true
+	}
+
+	return nil, false
+}
@@ -0,0 +1,63 @@
+package prog
+
+import "github.com/viveksb007/gobpftool/internal/utils"
+
+// progObjInfo mirrors the kernel's struct bpf_prog_info up to and including
+// attach_btf_id. The kernel only fills in as many bytes as info_len allows
+// and zeroes what it doesn't know, so older kernels leave the tail empty.
+type progObjInfo struct {
+	Type                 uint32
+	ID                   uint32
+	Tag                  [8]byte
+	JitedProgLen         uint32
+	XlatedProgLen        uint32
+	JitedProgInsns       uint64
+	XlatedProgInsns      uint64
+	LoadTime             uint64
+	CreatedByUID         uint32
+	NrMapIDs             uint32
+	MapIDs               uint64
+	Name                 [16]byte
+	Ifindex              uint32
+	Flags                uint32 // bit 0: gpl_compatible
+	NetnsDev             uint64
+	NetnsIno             uint64
+	NrJitedKsyms         uint32
+	NrJitedFuncLens      uint32
+	JitedKsyms           uint64
+	JitedFuncLens        uint64
+	BTFID                uint32
+	FuncInfoRecSize      uint32
+	FuncInfo             uint64
+	NrFuncInfo           uint32
+	NrLineInfo           uint32
+	LineInfo             uint64
+	JitedLineInfo        uint64
+	NrJitedLineInfo      uint32
+	LineInfoRecSize      uint32
+	JitedLineInfoRecSize uint32
+	NrProgTags           uint32
+	ProgTags             uint64
+	RunTimeNS            uint64
+	RunCnt               uint64
+	RecursionMisses      uint64
+	VerifiedInsns        uint32
+	AttachBTFObjID       uint32
+	AttachBTFID          uint32
+}
+
+// gplCompatible reports whether the gpl_compatible bit is set.
+func (i *progObjInfo) gplCompatible() bool {
+	return i.Flags&1 != 0
+}
+
+// getProgObjInfo queries BPF_OBJ_GET_INFO_BY_FD for the program fd.
+// cilium/ebpf does not expose every field (e.g. gpl_compatible), so this
+// reads them directly from the kernel.
+func getProgObjInfo(fd int) (*progObjInfo, error) {
+	var info progObjInfo
+	if err := utils.ObjGetInfo(fd, &info); err != nil {
+		return nil, err
+	}
+	return &info, nil
+}
@@ -0,0 +1,201 @@
+package prog
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"runtime"
+	"time"
+	"unsafe"
+
+	"github.com/cilium/ebpf"
+	"golang.org/x/sys/unix"
+
+	bpferrors "github.com/viveksb007/gobpftool/pkg/errors"
+)
+
+// ErrNotTestRunnable is returned by Run for program types the kernel can't
+// run through BPF_PROG_TEST_RUN.
+var ErrNotTestRunnable = errors.New("program type can't be test-run")
+
+// testRunTypes are the program types that implement BPF_PROG_TEST_RUN.
+var testRunTypes = map[ebpf.ProgramType]bool{
+	ebpf.SocketFilter:  true,
+	ebpf.SchedCLS:      true,
+	ebpf.SchedACT:      true,
+	ebpf.XDP:           true,
+	ebpf.CGroupSKB:     true,
+	ebpf.LWTIn:         true,
+	ebpf.LWTOut:        true,
+	ebpf.LWTXmit:       true,
+	ebpf.LWTSeg6Local:  true,
+	ebpf.FlowDissector: true,
+	ebpf.RawTracepoint: true,
+	ebpf.Tracing:       true,
+	ebpf.StructOps:     true,
+	ebpf.SkLookup:      true,
+	ebpf.Syscall:       true,
+	ebpf.Netfilter:     true,
+}
+
+// outputPad is the room left in the output buffer for programs that grow
+// the packet, e.g. with bpf_xdp_adjust_head. Older kernels ignore the
+// buffer size, so it has to be large enough up front.
+const outputPad = 256 + 2
+
+// RunOptions holds the input of a test run.
+type RunOptions struct {
+	// Data is the packet or buffer the program runs on. XDP and skb
+	// programs need at least an Ethernet header (14 bytes).
+	Data []byte
+	// Context is the program's context, e.g. a struct __sk_buff, in the
+	// kernel's layout. It's optional for most program types.
+	Context []byte
+	// Repeat is how many times the program runs. Zero runs it once.
+	Repeat uint32
+}
+
+// RunResult is the outcome of a test run.
+type RunResult struct {
+	// ReturnValue is what the program returned on its last run.
+	ReturnValue uint32
+	// DataOut is the data after the program ran, nil without input data.
+	DataOut []byte
+	// ContextOut is the context after the program ran, nil without an
+	// input context.
+	ContextOut []byte
+	// Repeat is how many times the program ran.
+	Repeat uint32
+	// Duration is the average time of a single run, as measured by the
+	// kernel.
+	Duration time.Duration
+}
+
+// CanTestRun reports whether programs of type t can be test-run.
+func CanTestRun(t ebpf.ProgramType) bool {
+	return testRunTypes[t]
+}
+
+// Run executes the program with the given ID in the kernel against
+// opts.Data and opts.Context without attaching it. Program types that
+// don't support test runs return an error wrapping ErrNotTestRunnable.
+func (s *EBPFService) Run(id uint32, opts RunOptions) (*RunResult, error) {
+	prog, err := openProgram(ebpf.ProgramID(id))
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return nil, fmt.Errorf("program with ID %d: %w", id, bpferrors.ErrNotFound)
+		}
+		return nil, fmt.Errorf("failed to get program %d: %w", id, err)
+	}
+	defer prog.Close()
+
+	if !CanTestRun(prog.Type()) {
+		return nil, fmt.Errorf("program %d of type %s: %w", id, prog.Type(), ErrNotTestRunnable)
+	}
+
+	repeat := opts.Repeat
+	if repeat == 0 {
+		repeat = 1
+	}
+	attr := progTestRunAttr{
+		ProgFd:     uint32(prog.FD()),
+		DataSizeIn: uint32(len(opts.Data)),
+		DataIn:     sliceAddr(opts.Data),
+		Repeat:     repeat,
+		CtxSizeIn:  uint32(len(opts.Context)),
+		CtxIn:      sliceAddr(opts.Context),
+	}
+	var dataOut, ctxOut []byte
+	if len(opts.Data) > 0 {
+		dataOut = make([]byte, len(opts.Data)+outputPad)
+		attr.DataSizeOut = uint32(len(dataOut))
+		attr.DataOut = sliceAddr(dataOut)
+	}
+	if len(opts.Context) > 0 {
+		ctxOut = make([]byte, len(opts.Context))
+		attr.CtxSizeOut = uint32(len(ctxOut))
+		attr.CtxOut = sliceAddr(ctxOut)
+	}
+
+	if err := progTestRun(&attr); err != nil {
+		if errors.Is(err, errNotSupp) {
+			return nil, fmt.Errorf("program %d of type %s: %w", id, prog.Type(), ErrNotTestRunnable)
+		}
+		return nil, fmt.Errorf("failed to run program %d: %w", id, err)
+	}
+	runtime.KeepAlive(opts.Data)
+	runtime.KeepAlive(opts.Context)
+
+	if dataOut != nil {
+		if int(attr.DataSizeOut) > len(dataOut) {
+			return nil, fmt.Errorf("program %d wrote %d bytes of output data, more than the %d allocated",
+				id, attr.DataSizeOut, len(dataOut))
+		}
+		dataOut = dataOut[:attr.DataSizeOut]
+	}
+	if ctxOut != nil && int(attr.CtxSizeOut) < len(ctxOut) {
+		ctxOut = ctxOut[:attr.CtxSizeOut]
+	}
+
+	return &RunResult{
+		ReturnValue: attr.Retval,
+		DataOut:     dataOut,
+		ContextOut:  ctxOut,
+		Repeat:      repeat,
+		Duration:    time.Duration(attr.Duration),
+	}, nil
+}
+
+// errNotSupp is the kernel's ENOTSUPP, returned by BPF_PROG_TEST_RUN for
+// program types without a test runner. It's missing from x/sys/unix since
+// it isn't meant to reach user space.
+const errNotSupp = unix.Errno(524)
+
+// progTestRunAttr mirrors the test member of union bpf_attr used by
+// BPF_PROG_TEST_RUN.
+type progTestRunAttr struct {
+	ProgFd      uint32
+	Retval      uint32
+	DataSizeIn  uint32
+	DataSizeOut uint32
+	DataIn      uint64
+	DataOut     uint64
+	Repeat      uint32
+	// Duration is the average run time in nanoseconds.
+	Duration   uint32
+	CtxSizeIn  uint32
+	CtxSizeOut uint32
+	CtxIn      uint64
+	CtxOut     uint64
+	Flags      uint32
+	CPU        uint32
+	BatchSize  uint32
+	_          uint32
+}
+
+// progTestRun runs BPF_PROG_TEST_RUN. cilium/ebpf's Program.Run drops the
+// duration the kernel reports, so this makes the call directly. A run
+// interrupted by a signal is restarted, like cilium/ebpf does.
+func progTestRun(attr *progTestRunAttr) error {
+	for {
+		_, _, errno := unix.Syscall(unix.SYS_BPF, unix.BPF_PROG_TEST_RUN,
+			uintptr(unsafe.Pointer(attr)), unsafe.Sizeof(*attr))
+		switch errno {
+		case 0:
+			return nil
+		case unix.EINTR:
+			continue
+		}
+		return fmt.Errorf("BPF_PROG_TEST_RUN: %w", errno)
+	}
+}
+
+// sliceAddr returns the address of b's data for a bpf_attr pointer field,
+// or 0 for an empty slice. The caller keeps b alive until the syscall
+// returned.
+func sliceAddr(b []byte) uint64 {
+	if len(b) == 0 {
+		return 0
+	}
+	return uint64(uintptr(unsafe.Pointer(&b[0])))
+}
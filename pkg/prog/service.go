@@ -1,7 +1,10 @@
 // Package prog provides services for inspecting eBPF programs.
 package prog
 
-import "time"
+import (
+	"context"
+	"time"
+)
 
 // ProgramInfo contains information about a loaded eBPF program.
 type ProgramInfo struct {
@@ -19,32 +22,132 @@ type ProgramInfo struct {
 	LoadedAt time.Time
 	// UID is the user ID that loaded the program.
 	UID uint32
+	// UIDKnown indicates whether UID was reported by the kernel, distinguishing
+	// an unknown creator from root.
+	UIDKnown bool
 	// BytesXlated is the number of bytes in the translated eBPF bytecode.
 	BytesXlated uint32
 	// BytesJIT is the number of bytes in the JIT-compiled code.
 	BytesJIT uint32
 	// MemLock is the amount of memory locked for the program.
 	MemLock uint32
+	// VerifiedInsns is the number of instructions the verifier processed.
+	// It's zero on kernels that don't report it (before 5.16).
+	VerifiedInsns uint32
 	// MapIDs is the list of map IDs associated with this program.
 	MapIDs []uint32
+	// BTFID is the ID of the program's BTF object, for btf dump id. It's
+	// zero when the program was loaded without BTF.
+	BTFID uint32
+	// RunTimeNS is the total time the program ran, in nanoseconds.
+	// It's only collected while BPF stats are enabled.
+	RunTimeNS uint64
+	// RunCount is the number of times the program ran.
+	// It's only collected while BPF stats are enabled.
+	RunCount uint64
+	// Attach describes where the program is attached. It's nil when the
+	// kernel doesn't expose it.
+	Attach *AttachInfo
 	// PinnedPaths contains the paths where this program is pinned in bpffs.
 	PinnedPaths []string `json:"pinned_paths,omitempty"`
 }
 
+// AttachInfo describes where a program is attached, as far as the kernel
+// reports it in the program's info.
+type AttachInfo struct {
+	// Type is the kind of attachment, named after the program type
+	// (e.g., "xdp", "tracing").
+	Type string
+	// Ifindex is the network device an XDP program is bound to, or zero.
+	Ifindex uint32
+	// BTFObjID is the ID of the BTF object that holds the attach target.
+	BTFObjID uint32
+	// BTFID is the BTF type ID of the function a tracing program attaches
+	// to, or zero.
+	BTFID uint32
+	// Target is the name of the interface or function, when it resolves.
+	Target string
+}
+
+// ListResult is the outcome of enumerating the loaded programs.
+type ListResult struct {
+	// Programs are the programs that could be read.
+	Programs []ProgramInfo
+	// Skipped is the number of programs that couldn't be opened or read,
+	// usually because of missing privileges.
+	Skipped int
+}
+
+// Instruction is a single translated eBPF instruction.
+type Instruction struct {
+	// Offset is the instruction's position in 8-byte instruction slots.
+	Offset int
+	// Raw is the encoded instruction as it was returned by the kernel.
+	Raw []byte
+	// Text is the human-readable form of the instruction.
+	Text string
+}
+
 // Service defines the interface for inspecting eBPF programs.
 type Service interface {
 	// List returns all loaded eBPF programs.
 	List() ([]ProgramInfo, error)
 
+	// ListContext is like List but returns ctx's error as soon as ctx is
+	// cancelled.
+	ListContext(ctx context.Context) ([]ProgramInfo, error)
+
+	// ListWithSkipped is like ListContext but also reports how many
+	// programs exist that couldn't be read, e.g. for lack of privileges.
+	ListWithSkipped(ctx context.Context) (*ListResult, error)
+
 	// GetByID returns program info by ID.
 	GetByID(id uint32) (*ProgramInfo, error)
 
 	// GetByTag returns programs matching the tag.
 	GetByTag(tag string) ([]ProgramInfo, error)
 
+	// GetByTagContext is like GetByTag but stops early when ctx is cancelled.
+	GetByTagContext(ctx context.Context, tag string) ([]ProgramInfo, error)
+
 	// GetByName returns programs matching the name.
 	GetByName(name string) ([]ProgramInfo, error)
 
+	// GetByNameContext is like GetByName but stops early when ctx is cancelled.
+	GetByNameContext(ctx context.Context, name string) ([]ProgramInfo, error)
+
 	// GetByPinnedPath returns program at the pinned path.
 	GetByPinnedPath(path string) (*ProgramInfo, error)
+
+	// DumpXlated returns the translated instructions of a program.
+	DumpXlated(id uint32) ([]Instruction, error)
+
+	// DumpJited returns the JIT-compiled machine code of a program.
+	DumpJited(id uint32) ([]byte, error)
+
+	// Pin pins a program to path, creating parent directories as needed.
+	Pin(id uint32, path string) error
+
+	// Unpin removes the program pinned at path.
+	Unpin(path string) error
+
+	// Load loads the programs of a BPF ELF object and pins them at
+	// pinPath, returning the loaded programs.
+	Load(file, pinPath string, opts LoadOptions) ([]ProgramInfo, error)
+
+	// Run test-runs a program against the input in opts without
+	// attaching it.
+	Run(id uint32, opts RunOptions) (*RunResult, error)
+
+	// Metadata returns the bpf_metadata_ variables a program declares, or
+	// nil when it declares none.
+	Metadata(id uint32) ([]MetadataEntry, error)
+}
+
+// LoadOptions selects what Load loads from an object file.
+type LoadOptions struct {
+	// Section only loads the programs in this ELF section when set.
+	Section string
+	// Type overrides the program type inferred from the section name.
+	Type string
 }
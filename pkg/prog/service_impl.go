@@ -1,33 +1,96 @@
 package prog
 
 import (
+	"context"
+	"errors"
 	"fmt"
 	"os"
+	"path/filepath"
 	"time"
 
 	"github.com/cilium/ebpf"
 	"github.com/viveksb007/gobpftool/internal/bpffs"
+	"github.com/viveksb007/gobpftool/internal/utils"
+	bpferrors "github.com/viveksb007/gobpftool/pkg/errors"
 )
 
+// pinnedPathResolver looks up the bpffs paths a program is pinned at.
+type pinnedPathResolver interface {
+	GetProgramPinnedPaths(id uint32) []string
+}
+
 // EBPFService implements the Service interface using cilium/ebpf.
-type EBPFService struct{}
+type EBPFService struct {
+	// pinned resolves pinned paths; nil disables the bpffs scan.
+	pinned pinnedPathResolver
+}
+
+// Option configures an EBPFService.
+type Option func(*EBPFService)
+
+// WithoutPinnedPaths disables the bpffs scan used to populate PinnedPaths,
+// which can be slow when many objects are pinned.
+func WithoutPinnedPaths() Option {
+	return func(s *EBPFService) {
+		s.pinned = nil
+	}
+}
 
 // NewService creates a new program service.
-func NewService() Service {
-	return &EBPFService{}
+func NewService(opts ...Option) Service {
+	s := &EBPFService{pinned: bpffs.GetScanner()}
+	for _, opt := range opts {
+		opt(s)
+	}
+	return s
 }
 
 // List returns all loaded eBPF programs.
 func (s *EBPFService) List() ([]ProgramInfo, error) {
-	var programs []ProgramInfo
+	return s.ListContext(context.Background())
+}
+
+// ListContext returns all loaded eBPF programs, checking ctx between programs.
+func (s *EBPFService) ListContext(ctx context.Context) ([]ProgramInfo, error) {
+	result, err := s.findPrograms(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	return result.Programs, nil
+}
+
+// ListWithSkipped returns all loaded eBPF programs along with the number of
+// programs that couldn't be read.
+func (s *EBPFService) ListWithSkipped(ctx context.Context) (*ListResult, error) {
+	return s.findPrograms(ctx, nil)
+}
+
+// newProgramFromID opens a program by ID. Tests replace it to simulate
+// programs that can't be opened.
+var newProgramFromID = ebpf.NewProgramFromID
+
+// openProgram opens a program by ID, retrying briefly when the kernel
+// reports it doesn't exist, see utils.OpenByID.
+func openProgram(id ebpf.ProgramID) (*ebpf.Program, error) {
+	return utils.OpenByID(newProgramFromID, id)
+}
+
+// findPrograms enumerates the loaded programs once and returns those
+// matching match, or all of them when match is nil. Programs unloaded
+// meanwhile are dropped; programs that exist but can't be opened or read
+// are counted in Skipped. Pinned paths are only resolved for matching
+// programs.
+func (s *EBPFService) findPrograms(ctx context.Context, match func(ProgramInfo) bool) (*ListResult, error) {
+	result := &ListResult{}
 
 	var id ebpf.ProgramID
 	firstIteration := true
 
-	// Get the scanner for pinned paths
-	scanner := bpffs.GetScanner()
-
 	for {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+
 		nextID, err := ebpf.ProgramGetNextID(id)
 		if err != nil {
 			// If this is the first iteration and we get an error, it's likely a permission issue
@@ -40,33 +103,42 @@ func (s *EBPFService) List() ([]ProgramInfo, error) {
 		firstIteration = false
 		id = nextID
 
-		prog, err := ebpf.NewProgramFromID(id)
+		prog, err := openProgram(id)
 		if err != nil {
-			// Skip programs we can't access
+			// Skip programs we can't access, and count them unless they
+			// were unloaded since they were enumerated
+			if !errors.Is(err, os.ErrNotExist) {
+				result.Skipped++
+			}
 			continue
 		}
 
 		info, err := extractProgramInfo(prog)
 		prog.Close()
 		if err != nil {
+			result.Skipped++
+			continue
+		}
+
+		if match != nil && !match(*info) {
 			continue
 		}
 
 		// Add pinned paths
-		info.PinnedPaths = scanner.GetProgramPinnedPaths(info.ID)
+		info.PinnedPaths = s.pinnedPaths(info.ID)
 
-		programs = append(programs, *info)
+		result.Programs = append(result.Programs, *info)
 	}
 
-	return programs, nil
+	return result, nil
 }
 
 // GetByID returns program info by ID.
 func (s *EBPFService) GetByID(id uint32) (*ProgramInfo, error) {
-	prog, err := ebpf.NewProgramFromID(ebpf.ProgramID(id))
+	prog, err := openProgram(ebpf.ProgramID(id))
 	if err != nil {
-		if os.IsNotExist(err) {
-			return nil, fmt.Errorf("program with ID %d not found", id)
+		if errors.Is(err, os.ErrNotExist) {
+			return nil, fmt.Errorf("program with ID %d: %w", id, bpferrors.ErrNotFound)
 		}
 		return nil, fmt.Errorf("failed to get program %d: %w", id, err)
 	}
@@ -78,44 +150,39 @@ func (s *EBPFService) GetByID(id uint32) (*ProgramInfo, error) {
 	}
 
 	// Add pinned paths
-	scanner := bpffs.GetScanner()
-	info.PinnedPaths = scanner.GetProgramPinnedPaths(info.ID)
+	info.PinnedPaths = s.pinnedPaths(info.ID)
 
 	return info, nil
 }
 
 // GetByTag returns programs matching the tag.
 func (s *EBPFService) GetByTag(tag string) ([]ProgramInfo, error) {
-	allProgs, err := s.List()
+	return s.GetByTagContext(context.Background(), tag)
+}
+
+// GetByTagContext returns programs matching the tag, stopping early when ctx
+// is cancelled.
+func (s *EBPFService) GetByTagContext(ctx context.Context, tag string) ([]ProgramInfo, error) {
+	result, err := s.findPrograms(ctx, func(p ProgramInfo) bool { return p.Tag == tag })
 	if err != nil {
 		return nil, err
 	}
-
-	var matched []ProgramInfo
-	for _, p := range allProgs {
-		if p.Tag == tag {
-			matched = append(matched, p)
-		}
-	}
-
-	return matched, nil
+	return result.Programs, nil
 }
 
 // GetByName returns programs matching the name.
 func (s *EBPFService) GetByName(name string) ([]ProgramInfo, error) {
-	allProgs, err := s.List()
+	return s.GetByNameContext(context.Background(), name)
+}
+
+// GetByNameContext returns programs matching the name, stopping early when
+// ctx is cancelled.
+func (s *EBPFService) GetByNameContext(ctx context.Context, name string) ([]ProgramInfo, error) {
+	result, err := s.findPrograms(ctx, func(p ProgramInfo) bool { return p.Name == name })
 	if err != nil {
 		return nil, err
 	}
-
-	var matched []ProgramInfo
-	for _, p := range allProgs {
-		if p.Name == name {
-			matched = append(matched, p)
-		}
-	}
-
-	return matched, nil
+	return result.Programs, nil
 }
 
 // GetByPinnedPath returns program at the pinned path.
@@ -123,13 +190,79 @@ func (s *EBPFService) GetByPinnedPath(path string) (*ProgramInfo, error) {
 	prog, err := ebpf.LoadPinnedProgram(path, nil)
 	if err != nil {
 		if os.IsNotExist(err) {
-			return nil, fmt.Errorf("no program pinned at %s", path)
+			return nil, fmt.Errorf("no program pinned at %s: %w", path, bpferrors.ErrNotFound)
 		}
 		return nil, fmt.Errorf("failed to load pinned program at %s: %w", path, err)
 	}
 	defer prog.Close()
 
-	return extractProgramInfo(prog)
+	info, err := extractProgramInfo(prog)
+	if err != nil {
+		return nil, err
+	}
+
+	// Add pinned paths
+	info.PinnedPaths = s.pinnedPaths(info.ID)
+
+	return info, nil
+}
+
+// Pin pins a program to path, creating parent directories as needed.
+func (s *EBPFService) Pin(id uint32, path string) error {
+	prog, err := openProgram(ebpf.ProgramID(id))
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return fmt.Errorf("program with ID %d: %w", id, bpferrors.ErrNotFound)
+		}
+		return fmt.Errorf("failed to get program %d: %w", id, err)
+	}
+	defer prog.Close()
+
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return fmt.Errorf("failed to create directory for %s: %w", path, err)
+	}
+	if err := prog.Pin(path); err != nil {
+		return fmt.Errorf("failed to pin program %d at %s: %w", id, path, err)
+	}
+
+	s.refreshPinnedPaths()
+	return nil
+}
+
+// Unpin removes the program pinned at path. It returns an error wrapping
+// ErrNotFound if nothing is pinned there.
+func (s *EBPFService) Unpin(path string) error {
+	prog, err := ebpf.LoadPinnedProgram(path, nil)
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return fmt.Errorf("no program pinned at %s: %w", path, bpferrors.ErrNotFound)
+		}
+		return fmt.Errorf("failed to load pinned program at %s: %w", path, err)
+	}
+	defer prog.Close()
+
+	if err := prog.Unpin(); err != nil {
+		return fmt.Errorf("failed to unpin program at %s: %w", path, err)
+	}
+
+	s.refreshPinnedPaths()
+	return nil
+}
+
+// refreshPinnedPaths rescans bpffs so later lookups see pin changes.
+func (s *EBPFService) refreshPinnedPaths() {
+	if r, ok := s.pinned.(interface{ Refresh() }); ok {
+		r.Refresh()
+	}
+}
+
+// pinnedPaths returns the pinned paths for a program, or nil when the
+// bpffs scan is disabled.
+func (s *EBPFService) pinnedPaths(id uint32) []string {
+	if s.pinned == nil {
+		return nil
+	}
+	return s.pinned.GetProgramPinnedPaths(id)
 }
 
 // extractProgramInfo extracts ProgramInfo from an ebpf.Program.
@@ -157,22 +290,71 @@ func extractProgramInfo(prog *ebpf.Program) (*ProgramInfo, error) {
 
 	// Get loaded time - LoadTime returns a duration since boot
 	var loadedAt time.Time
-	if loadTime, ok := info.LoadTime(); ok {
-		// Convert duration since boot to actual time
-		loadedAt = time.Now().Add(-loadTime)
+	loadTime, loadTimeKnown := info.LoadTime()
+	if loadTimeKnown {
+		if boot, err := bootTime(); err == nil {
+			loadedAt = loadTimeToWallClock(boot, loadTime)
+		}
+	}
+
+	// Sizes are unavailable on restricted kernels or without CAP_BPF; report zero then
+	var bytesXlated, bytesJIT uint32
+	if size, err := info.TranslatedSize(); err == nil {
+		bytesXlated = uint32(size)
+	}
+	if size, err := info.JitedSize(); err == nil {
+		bytesJIT = size
+	}
+
+	// Memlock comes from fdinfo; read it ourselves if cilium/ebpf couldn't
+	memlock, ok := info.Memlock()
+	if !ok {
+		memlock, _ = utils.ReadFdInfoUint(prog.FD(), "memlock")
+	}
+
+	// cilium/ebpf exposes neither gpl_compatible nor the attach target, so
+	// query the kernel directly. The reply also carries created_by_uid for
+	// when cilium/ebpf couldn't read it.
+	var gpl bool
+	var attach *AttachInfo
+	uid, uidKnown := info.CreatedByUID()
+	if objInfo, err := getProgObjInfo(prog.FD()); err == nil {
+		gpl = objInfo.gplCompatible()
+		attach = attachInfo(info.Type, objInfo)
+		if !uidKnown && loadTimeKnown {
+			// created_by_uid was added alongside load_time (4.15)
+			uid, uidKnown = objInfo.CreatedByUID, true
+		}
+	}
+
+	// Zero when the kernel doesn't report it
+	verifiedInsns, _ := info.VerifiedInstructions()
+	btfID, _ := info.BTFID()
+
+	// Run statistics stay zero unless BPF stats are enabled (5.8+)
+	var runTimeNS, runCount uint64
+	if stats, err := prog.Stats(); err == nil {
+		runTimeNS = uint64(stats.Runtime.Nanoseconds())
+		runCount = stats.RunCount
 	}
 
 	return &ProgramInfo{
-		ID:          uint32(id),
-		Type:        info.Type.String(),
-		Name:        info.Name,
-		Tag:         tag,
-		GPL:         false, // GPL info not directly exposed in this version
-		LoadedAt:    loadedAt,
-		UID:         0, // UID is not directly exposed by cilium/ebpf
-		BytesXlated: 0, // Not directly exposed in this API version
-		BytesJIT:    0, // Not directly exposed in this API version
-		MemLock:     0, // Not directly exposed in this API version
-		MapIDs:      mapIDsUint32,
+		ID:            uint32(id),
+		Type:          ProgTypeName(info.Type),
+		Name:          info.Name,
+		Tag:           tag,
+		GPL:           gpl,
+		LoadedAt:      loadedAt,
+		UID:           uid,
+		UIDKnown:      uidKnown,
+		BytesXlated:   bytesXlated,
+		BytesJIT:      bytesJIT,
+		MemLock:       uint32(memlock),
+		VerifiedInsns: verifiedInsns,
+		MapIDs:        mapIDsUint32,
+		BTFID:         uint32(btfID),
+		RunTimeNS:     runTimeNS,
+		RunCount:      runCount,
+		Attach:        attach,
 	}, nil
 }
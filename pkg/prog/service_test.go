@@ -1,8 +1,24 @@
 package prog
 
 import (
+	"bytes"
+	"context"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"os"
+	"reflect"
+	"strings"
 	"testing"
 	"time"
+	"unsafe"
+
+	"github.com/cilium/ebpf"
+	"github.com/cilium/ebpf/asm"
+	"github.com/cilium/ebpf/btf"
+	"github.com/viveksb007/gobpftool/internal/utils"
+	bpferrors "github.com/viveksb007/gobpftool/pkg/errors"
+	"golang.org/x/sys/unix"
 )
 
 // TestProgramInfoStruct tests that ProgramInfo struct has all required fields.
@@ -194,3 +210,703 @@ func TestMockServiceGetByName(t *testing.T) {
 		t.Errorf("expected 2 programs named my_prog, got %d", len(progs))
 	}
 }
+
+// TestProgObjInfoLayout tests that progObjInfo matches the kernel's bpf_prog_info prefix.
+func TestProgObjInfoLayout(t *testing.T) {
+	if size := unsafe.Sizeof(progObjInfo{}); size != 232 {
+		t.Errorf("expected progObjInfo size 232, got %d", size)
+	}
+	if off := unsafe.Offsetof(progObjInfo{}.Ifindex); off != 80 {
+		t.Errorf("expected ifindex at offset 80, got %d", off)
+	}
+	if off := unsafe.Offsetof(progObjInfo{}.AttachBTFID); off != 224 {
+		t.Errorf("expected attach_btf_id at offset 224, got %d", off)
+	}
+
+	info := progObjInfo{Flags: 1}
+	if !info.gplCompatible() {
+		t.Error("expected gplCompatible to be true")
+	}
+}
+
+// fakePinnedPaths is an in-memory pinnedPathResolver for testing.
+type fakePinnedPaths map[uint32][]string
+
+func (f fakePinnedPaths) GetProgramPinnedPaths(id uint32) []string {
+	return f[id]
+}
+
+// TestPinnedPaths tests that pinned paths come from the resolver unless disabled.
+func TestPinnedPaths(t *testing.T) {
+	svc := &EBPFService{pinned: fakePinnedPaths{
+		1: {"/sys/fs/bpf/a", "/sys/fs/bpf/b"},
+	}}
+
+	if paths := svc.pinnedPaths(1); len(paths) != 2 {
+		t.Errorf("expected 2 pinned paths, got %d", len(paths))
+	}
+	if paths := svc.pinnedPaths(2); len(paths) != 0 {
+		t.Errorf("expected 0 pinned paths, got %d", len(paths))
+	}
+
+	WithoutPinnedPaths()(svc)
+	if paths := svc.pinnedPaths(1); paths != nil {
+		t.Errorf("expected nil pinned paths when disabled, got %v", paths)
+	}
+}
+
+// TestFilterByType tests filtering MockService results by program type.
+func TestFilterByType(t *testing.T) {
+	mock := &MockService{
+		programs: []ProgramInfo{
+			{ID: 1, Name: "prog1", Type: "XDP"},
+			{ID: 2, Name: "prog2", Type: "Kprobe"},
+			{ID: 3, Name: "prog3", Type: "SchedCLS"},
+			{ID: 4, Name: "prog4", Type: "XDP"},
+		},
+	}
+
+	progs, err := mock.List()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	tests := []struct {
+		name    string
+		typ     string
+		wantIDs []uint32
+		wantErr bool
+	}{
+		{name: "lowercase", typ: "xdp", wantIDs: []uint32{1, 4}},
+		{name: "uppercase", typ: "KPROBE", wantIDs: []uint32{2}},
+		{name: "snake case", typ: "sched_cls", wantIDs: []uint32{3}},
+		{name: "alias", typ: "classifier", wantIDs: []uint32{3}},
+		{name: "known type without matches", typ: "tracepoint", wantIDs: nil},
+		{name: "unknown type", typ: "bogus", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			matched, err := FilterByType(progs, tt.typ)
+			if tt.wantErr {
+				if err == nil {
+					t.Fatal("expected error, got nil")
+				}
+				if !strings.Contains(err.Error(), "xdp") {
+					t.Errorf("expected error to list valid types, got %v", err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if len(matched) != len(tt.wantIDs) {
+				t.Fatalf("expected %d programs, got %d", len(tt.wantIDs), len(matched))
+			}
+			for i, p := range matched {
+				if p.ID != tt.wantIDs[i] {
+					t.Errorf("expected ID %d at %d, got %d", tt.wantIDs[i], i, p.ID)
+				}
+			}
+		})
+	}
+}
+
+// TestProgTypeTable tests that every known program type name round-trips
+// and that aliases resolve.
+func TestProgTypeTable(t *testing.T) {
+	seen := make(map[string]ebpf.ProgramType)
+	for typ := ebpf.ProgramType(1); typ < 256; typ++ {
+		if !isKnownType(typ) {
+			continue
+		}
+		name := ProgTypeName(typ)
+		if other, dup := seen[normalizeType(name)]; dup {
+			t.Errorf("ProgTypeName(%d) = %q, also the name of %v", typ, name, other)
+		}
+		seen[normalizeType(name)] = typ
+
+		for _, s := range []string{name, strings.ToLower(name), strings.ToUpper(name)} {
+			if got, ok := ProgTypeFromString(s); !ok || got != typ {
+				t.Errorf("ProgTypeFromString(%q) = %v, %v, want %v", s, got, ok, typ)
+			}
+		}
+	}
+	if len(seen) != len(TypeNames()) {
+		t.Errorf("round-tripped %d types, TypeNames() has %d", len(seen), len(TypeNames()))
+	}
+
+	aliases := map[string]ebpf.ProgramType{
+		"classifier":     ebpf.SchedCLS,
+		"cls":            ebpf.SchedCLS,
+		"sched_cls":      ebpf.SchedCLS,
+		"action":         ebpf.SchedACT,
+		"raw_tracepoint": ebpf.RawTracepoint,
+		"ext":            ebpf.Extension,
+	}
+	for s, want := range aliases {
+		if got, ok := ProgTypeFromString(s); !ok || got != want {
+			t.Errorf("ProgTypeFromString(%q) = %v, %v, want %v", s, got, ok, want)
+		}
+	}
+
+	for _, s := range []string{"", "bogus", "ProgramType(250)", "unspecifiedprogram"} {
+		if got, ok := ProgTypeFromString(s); ok {
+			t.Errorf("ProgTypeFromString(%q) = %v, want not found", s, got)
+		}
+	}
+}
+
+// TestFilterByMemlock tests filtering programs by a minimum memlock size.
+func TestFilterByMemlock(t *testing.T) {
+	progs := []ProgramInfo{
+		{ID: 1, MemLock: 4096},
+		{ID: 2, MemLock: 1 << 20},
+		{ID: 3},
+		{ID: 4, MemLock: 10 << 20},
+	}
+
+	tests := []struct {
+		name    string
+		min     uint64
+		wantIDs []uint32
+	}{
+		{name: "zero keeps everything", min: 0, wantIDs: []uint32{1, 2, 3, 4}},
+		{name: "threshold is inclusive", min: 1 << 20, wantIDs: []uint32{2, 4}},
+		{name: "above every program", min: 1 << 30, wantIDs: nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			matched := FilterByMemlock(progs, tt.min)
+			if len(matched) != len(tt.wantIDs) {
+				t.Fatalf("expected %d programs, got %d", len(tt.wantIDs), len(matched))
+			}
+			for i, p := range matched {
+				if p.ID != tt.wantIDs[i] {
+					t.Errorf("expected ID %d at %d, got %d", tt.wantIDs[i], i, p.ID)
+				}
+			}
+		})
+	}
+}
+
+// TestFilterByTagPrefix tests filtering programs by the start of their tag.
+func TestFilterByTagPrefix(t *testing.T) {
+	progs := []ProgramInfo{
+		{ID: 1, Tag: "f0055c08993fea1e"},
+		{ID: 2, Tag: "f00a1b2c3d4e5f60"},
+		{ID: 3, Tag: "0123456789abcdef"},
+	}
+
+	tests := []struct {
+		name    string
+		prefix  string
+		wantIDs []uint32
+		wantErr bool
+	}{
+		{name: "exact tag", prefix: "f0055c08993fea1e", wantIDs: []uint32{1}},
+		{name: "shared prefix", prefix: "f00", wantIDs: []uint32{1, 2}},
+		{name: "even prefix", prefix: "f005", wantIDs: []uint32{1}},
+		{name: "case-insensitive", prefix: "F00A", wantIDs: []uint32{2}},
+		{name: "no match", prefix: "abc", wantIDs: nil},
+		{name: "invalid hex", prefix: "f0g", wantErr: true},
+		{name: "whitespace", prefix: "f0 0", wantErr: true},
+		{name: "empty", prefix: "", wantErr: true},
+		{name: "longer than a tag", prefix: "f0055c08993fea1e0", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			matched, err := FilterByTagPrefix(progs, tt.prefix)
+			if tt.wantErr {
+				if err == nil {
+					t.Fatal("expected error, got nil")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if len(matched) != len(tt.wantIDs) {
+				t.Fatalf("expected %d programs, got %d", len(tt.wantIDs), len(matched))
+			}
+			for i, p := range matched {
+				if p.ID != tt.wantIDs[i] {
+					t.Errorf("expected ID %d at %d, got %d", tt.wantIDs[i], i, p.ID)
+				}
+			}
+		})
+	}
+}
+
+// TestSort tests stable sorting of programs by each field.
+func TestSort(t *testing.T) {
+	base := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+	newProgs := func() []ProgramInfo {
+		return []ProgramInfo{
+			{ID: 1, Name: "b", MemLock: 4096, LoadedAt: base.Add(2 * time.Second)},
+			{ID: 2, Name: "a", MemLock: 8192, LoadedAt: base},
+			{ID: 3, Name: "b", MemLock: 4096, LoadedAt: base.Add(time.Second)},
+		}
+	}
+
+	tests := []struct {
+		field   string
+		reverse bool
+		wantIDs []uint32
+	}{
+		{field: "id", wantIDs: []uint32{1, 2, 3}},
+		{field: "id", reverse: true, wantIDs: []uint32{3, 2, 1}},
+		{field: "name", wantIDs: []uint32{2, 1, 3}},
+		{field: "name", reverse: true, wantIDs: []uint32{1, 3, 2}},
+		{field: "memlock", wantIDs: []uint32{1, 3, 2}},
+		{field: "memlock", reverse: true, wantIDs: []uint32{2, 1, 3}},
+		{field: "loaded", wantIDs: []uint32{2, 3, 1}},
+	}
+
+	for _, tt := range tests {
+		progs := newProgs()
+		if err := Sort(progs, tt.field, tt.reverse); err != nil {
+			t.Fatalf("Sort(%s) unexpected error: %v", tt.field, err)
+		}
+		for i, p := range progs {
+			if p.ID != tt.wantIDs[i] {
+				t.Errorf("Sort(%s, reverse=%v): expected ID %d at %d, got %d", tt.field, tt.reverse, tt.wantIDs[i], i, p.ID)
+			}
+		}
+	}
+
+	if err := Sort(newProgs(), "size", false); err == nil || !strings.Contains(err.Error(), "memlock") {
+		t.Errorf("expected error listing valid fields, got %v", err)
+	}
+}
+
+func TestConvertInstructions(t *testing.T) {
+	insns := asm.Instructions{
+		asm.LoadImm(asm.R0, 1<<40, asm.DWord),
+		asm.Mov.Imm(asm.R1, 2),
+		asm.Return(),
+	}
+
+	got, err := convertInstructions(insns)
+	if err != nil {
+		t.Fatalf("convertInstructions() error = %v", err)
+	}
+	if len(got) != 3 {
+		t.Fatalf("got %d instructions, want 3", len(got))
+	}
+
+	// The 64-bit immediate load occupies two instruction slots
+	wantOffsets := []int{0, 2, 3}
+	wantRaw := []int{16, 8, 8}
+	for i, ins := range got {
+		if ins.Offset != wantOffsets[i] {
+			t.Errorf("insns[%d].Offset = %d, want %d", i, ins.Offset, wantOffsets[i])
+		}
+		if len(ins.Raw) != wantRaw[i] {
+			t.Errorf("insns[%d] raw length = %d, want %d", i, len(ins.Raw), wantRaw[i])
+		}
+		if ins.Text == "" {
+			t.Errorf("insns[%d].Text is empty", i)
+		}
+	}
+	if got[2].Raw[0] != 0x95 {
+		t.Errorf("exit opcode = %#x, want 0x95", got[2].Raw[0])
+	}
+}
+
+func TestLoadTimeToWallClock(t *testing.T) {
+	boot := time.Date(2025, 11, 24, 5, 0, 0, 0, time.UTC)
+	sinceBoot := 50*time.Minute + 46*time.Second
+
+	got := loadTimeToWallClock(boot, sinceBoot)
+	want := time.Date(2025, 11, 24, 5, 50, 46, 0, time.UTC)
+	if !got.Equal(want) {
+		t.Errorf("loadTimeToWallClock() = %v, want %v", got, want)
+	}
+}
+
+func TestBootTime(t *testing.T) {
+	boot, err := bootTime()
+	if err != nil {
+		t.Fatalf("bootTime() error: %v", err)
+	}
+	if !boot.Before(time.Now()) {
+		t.Errorf("bootTime() = %v, want a time in the past", boot)
+	}
+}
+
+// TestListContext_Cancelled tests that a cancelled context stops listing
+// before any program is read.
+func TestListContext_Cancelled(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	svc := NewService()
+	if _, err := svc.ListContext(ctx); !errors.Is(err, context.Canceled) {
+		t.Errorf("ListContext() error = %v, want %v", err, context.Canceled)
+	}
+	if _, err := svc.GetByNameContext(ctx, "any"); !errors.Is(err, context.Canceled) {
+		t.Errorf("GetByNameContext() error = %v, want %v", err, context.Canceled)
+	}
+}
+
+// loadTestPrograms loads XDP programs with the given names for the duration
+// of a test, skipping it when programs can't be loaded.
+func loadTestPrograms(tb testing.TB, names ...string) {
+	tb.Helper()
+	for _, name := range names {
+		p, err := ebpf.NewProgram(&ebpf.ProgramSpec{
+			Name:         name,
+			Type:         ebpf.XDP,
+			Instructions: asm.Instructions{asm.Mov.Imm(asm.R0, 2), asm.Return()},
+			License:      "GPL",
+		})
+		if err != nil {
+			tb.Skipf("can't load programs: %v", err)
+		}
+		tb.Cleanup(func() { p.Close() })
+	}
+}
+
+// TestFindPrograms_MatchesListFilter tests that GetByName and GetByTag return
+// the same programs as filtering the full list.
+func TestFindPrograms_MatchesListFilter(t *testing.T) {
+	loadTestPrograms(t, "gbt_find_a", "gbt_find_a", "gbt_find_b")
+
+	svc := NewService(WithoutPinnedPaths())
+	all, err := svc.List()
+	if err != nil {
+		t.Fatalf("List() error = %v", err)
+	}
+	filter := func(match func(ProgramInfo) bool) []uint32 {
+		var ids []uint32
+		for _, p := range all {
+			if match(p) {
+				ids = append(ids, p.ID)
+			}
+		}
+		return ids
+	}
+	ids := func(progs []ProgramInfo) []uint32 {
+		var ids []uint32
+		for _, p := range progs {
+			ids = append(ids, p.ID)
+		}
+		return ids
+	}
+
+	byName, err := svc.GetByName("gbt_find_a")
+	if err != nil {
+		t.Fatalf("GetByName() error = %v", err)
+	}
+	want := filter(func(p ProgramInfo) bool { return p.Name == "gbt_find_a" })
+	if len(want) != 2 || fmt.Sprint(ids(byName)) != fmt.Sprint(want) {
+		t.Errorf("GetByName() = %v, want %v", ids(byName), want)
+	}
+
+	// All three programs share the same instructions and so the same tag
+	byTag, err := svc.GetByTag(byName[0].Tag)
+	if err != nil {
+		t.Fatalf("GetByTag() error = %v", err)
+	}
+	want = filter(func(p ProgramInfo) bool { return p.Tag == byName[0].Tag })
+	if len(want) < 3 || fmt.Sprint(ids(byTag)) != fmt.Sprint(want) {
+		t.Errorf("GetByTag() = %v, want %v", ids(byTag), want)
+	}
+}
+
+// TestListWithSkipped tests that programs which can't be opened are counted,
+// unless they disappeared since they were enumerated.
+func TestListWithSkipped(t *testing.T) {
+	loadTestPrograms(t, "gbt_skip_a", "gbt_skip_b")
+
+	progs, err := NewService(WithoutPinnedPaths()).GetByName("gbt_skip_a")
+	if err != nil || len(progs) != 1 {
+		t.Fatalf("GetByName() = %v, %v", progs, err)
+	}
+	denied := ebpf.ProgramID(progs[0].ID)
+	progs, err = NewService(WithoutPinnedPaths()).GetByName("gbt_skip_b")
+	if err != nil || len(progs) != 1 {
+		t.Fatalf("GetByName() = %v, %v", progs, err)
+	}
+	gone := ebpf.ProgramID(progs[0].ID)
+
+	baseline, err := NewService(WithoutPinnedPaths()).ListWithSkipped(context.Background())
+	if err != nil {
+		t.Fatalf("ListWithSkipped() error = %v", err)
+	}
+
+	orig := newProgramFromID
+	t.Cleanup(func() { newProgramFromID = orig })
+	newProgramFromID = func(id ebpf.ProgramID) (*ebpf.Program, error) {
+		switch id {
+		case denied:
+			return nil, fmt.Errorf("open program %d: %w", id, unix.EPERM)
+		case gone:
+			return nil, fmt.Errorf("open program %d: %w", id, os.ErrNotExist)
+		}
+		return orig(id)
+	}
+
+	result, err := NewService(WithoutPinnedPaths()).ListWithSkipped(context.Background())
+	if err != nil {
+		t.Fatalf("ListWithSkipped() error = %v", err)
+	}
+	if result.Skipped != baseline.Skipped+1 {
+		t.Errorf("Skipped = %d, want %d", result.Skipped, baseline.Skipped+1)
+	}
+	for _, p := range result.Programs {
+		if ebpf.ProgramID(p.ID) == denied || ebpf.ProgramID(p.ID) == gone {
+			t.Errorf("program %d listed although it couldn't be opened", p.ID)
+		}
+	}
+}
+
+// TestGetByID_Retry tests that a program that isn't found at first is opened
+// again before GetByID gives up.
+func TestGetByID_Retry(t *testing.T) {
+	loadTestPrograms(t, "gbt_retry")
+	progs, err := NewService(WithoutPinnedPaths()).GetByName("gbt_retry")
+	if err != nil || len(progs) != 1 {
+		t.Fatalf("GetByName() = %v, %v", progs, err)
+	}
+	id := progs[0].ID
+
+	orig := newProgramFromID
+	t.Cleanup(func() { newProgramFromID = orig })
+
+	tests := []struct {
+		name      string
+		failures  int
+		wantCalls int
+		wantErr   error
+	}{
+		{name: "fails once", failures: 1, wantCalls: 2},
+		{name: "always fails", failures: utils.OpenRetries + 1, wantCalls: utils.OpenRetries + 1, wantErr: bpferrors.ErrNotFound},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			calls := 0
+			newProgramFromID = func(id ebpf.ProgramID) (*ebpf.Program, error) {
+				calls++
+				if calls <= tt.failures {
+					return nil, fmt.Errorf("open program %d: %w", id, os.ErrNotExist)
+				}
+				return orig(id)
+			}
+
+			_, err := NewService(WithoutPinnedPaths()).GetByID(id)
+			if !errors.Is(err, tt.wantErr) {
+				t.Errorf("GetByID() error = %v, want %v", err, tt.wantErr)
+			}
+			if calls != tt.wantCalls {
+				t.Errorf("opened %d times, want %d", calls, tt.wantCalls)
+			}
+		})
+	}
+}
+
+// BenchmarkGetByName measures looking up programs by name, which enumerates
+// all loaded programs once.
+func BenchmarkGetByName(b *testing.B) {
+	loadTestPrograms(b, "gbt_bench")
+	svc := NewService(WithoutPinnedPaths())
+
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		if _, err := svc.GetByName("gbt_bench"); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+// TestAttachInfo tests that attach info is only reported when the kernel set a target.
+func TestAttachInfo(t *testing.T) {
+	if attach := attachInfo(ebpf.XDP, &progObjInfo{}); attach != nil {
+		t.Errorf("expected nil attach info without a target, got %+v", attach)
+	}
+
+	attach := attachInfo(ebpf.XDP, &progObjInfo{Ifindex: 1})
+	if attach == nil || attach.Ifindex != 1 || attach.Type != ebpf.XDP.String() {
+		t.Fatalf("expected XDP attach info for ifindex 1, got %+v", attach)
+	}
+
+	// A BTF object that doesn't exist leaves the target unresolved
+	attach = attachInfo(ebpf.Tracing, &progObjInfo{AttachBTFObjID: 1 << 30, AttachBTFID: 7})
+	if attach == nil || attach.BTFID != 7 || attach.Target != "" {
+		t.Errorf("expected unresolved tracing attach info, got %+v", attach)
+	}
+}
+
+// testObject is a minimal XDP object built from testdata/xdp_pass.ll.
+const testObject = "testdata/xdp_pass.o"
+
+// TestSelectPrograms tests section selection and type overrides.
+func TestSelectPrograms(t *testing.T) {
+	load := func(t *testing.T) *ebpf.CollectionSpec {
+		t.Helper()
+		spec, err := ebpf.LoadCollectionSpec(testObject)
+		if err != nil {
+			t.Fatalf("LoadCollectionSpec() error: %v", err)
+		}
+		return spec
+	}
+
+	names, err := selectPrograms(load(t), LoadOptions{})
+	if err != nil || len(names) != 1 || names[0] != "xdp_pass" {
+		t.Errorf("expected [xdp_pass], got %v, %v", names, err)
+	}
+
+	if _, err := selectPrograms(load(t), LoadOptions{Section: "kprobe/sys_open"}); err == nil || !strings.Contains(err.Error(), "xdp") {
+		t.Errorf("expected an error listing the xdp section, got %v", err)
+	}
+
+	spec := load(t)
+	if _, err := selectPrograms(spec, LoadOptions{Section: "xdp", Type: "sched_cls"}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if typ := spec.Programs["xdp_pass"].Type; typ != ebpf.SchedCLS {
+		t.Errorf("expected type override to SchedCLS, got %v", typ)
+	}
+
+	if _, err := selectPrograms(load(t), LoadOptions{Type: "bogus"}); err == nil {
+		t.Error("expected an error for an unknown type")
+	}
+}
+
+// TestLoad tests loading and pinning the test object. Pinning needs a BPF
+// filesystem at /sys/fs/bpf.
+func TestLoad(t *testing.T) {
+	var fs unix.Statfs_t
+	if err := unix.Statfs("/sys/fs/bpf", &fs); err != nil || fs.Type != unix.BPF_FS_MAGIC {
+		t.Skip("no BPF filesystem mounted at /sys/fs/bpf")
+	}
+
+	pinPath := fmt.Sprintf("/sys/fs/bpf/gobpftool-test-%d", os.Getpid())
+	t.Cleanup(func() { os.Remove(pinPath) })
+
+	progs, err := NewService().Load(testObject, pinPath, LoadOptions{})
+	if err != nil {
+		t.Skipf("can't load programs: %v", err)
+	}
+	if len(progs) != 1 || progs[0].Type != ebpf.XDP.String() || progs[0].Name != "xdp_pass" {
+		t.Fatalf("expected the xdp_pass program, got %+v", progs)
+	}
+	if _, err := os.Stat(pinPath); err != nil {
+		t.Errorf("program not pinned: %v", err)
+	}
+}
+
+// TestVerifierLog tests that a verifier rejection exposes the full log.
+func TestVerifierLog(t *testing.T) {
+	// Returning without setting R0 is rejected by the verifier
+	_, err := ebpf.NewProgram(&ebpf.ProgramSpec{
+		Type:         ebpf.XDP,
+		Instructions: asm.Instructions{asm.Return()},
+		License:      "GPL",
+	})
+	if err == nil {
+		t.Fatal("expected the verifier to reject the program")
+	}
+	if errors.Is(err, unix.EPERM) {
+		t.Skipf("can't load programs: %v", err)
+	}
+
+	log, ok := VerifierLog(fmt.Errorf("loading: %w", err))
+	if !ok || !strings.Contains(log, "R0") {
+		t.Errorf("expected a verifier log mentioning R0, got %q, %v", log, ok)
+	}
+
+	if _, ok := VerifierLog(errors.New("other")); ok {
+		t.Error("expected no verifier log for other errors")
+	}
+}
+
+// TestRun tests a test run of a program that passes every packet.
+func TestRun(t *testing.T) {
+	p, err := ebpf.NewProgram(&ebpf.ProgramSpec{
+		Type:         ebpf.XDP,
+		Instructions: asm.Instructions{asm.Mov.Imm(asm.R0, 2), asm.Return()},
+		License:      "GPL",
+	})
+	if err != nil {
+		t.Skipf("can't load programs: %v", err)
+	}
+	defer p.Close()
+	info, err := p.Info()
+	if err != nil {
+		t.Fatalf("Info() error = %v", err)
+	}
+	id, _ := info.ID()
+
+	data := make([]byte, 14)
+	data[12] = 0x08
+	result, err := NewService().Run(uint32(id), RunOptions{Data: data, Repeat: 3})
+	if err != nil {
+		t.Fatalf("Run() error = %v", err)
+	}
+	if result.ReturnValue != 2 || result.Repeat != 3 || !bytes.Equal(result.DataOut, data) {
+		t.Errorf("Run() = %+v, want XDP_PASS with the input unchanged", result)
+	}
+}
+
+// TestRun_NotTestRunnable tests that kprobes are rejected before running.
+func TestRun_NotTestRunnable(t *testing.T) {
+	p, err := ebpf.NewProgram(&ebpf.ProgramSpec{
+		Type:         ebpf.Kprobe,
+		Instructions: asm.Instructions{asm.Mov.Imm(asm.R0, 0), asm.Return()},
+		License:      "GPL",
+	})
+	if err != nil {
+		t.Skipf("can't load programs: %v", err)
+	}
+	defer p.Close()
+	info, err := p.Info()
+	if err != nil {
+		t.Fatalf("Info() error = %v", err)
+	}
+	id, _ := info.ID()
+
+	if _, err := NewService().Run(uint32(id), RunOptions{}); !errors.Is(err, ErrNotTestRunnable) {
+		t.Errorf("Run() error = %v, want %v", err, ErrNotTestRunnable)
+	}
+}
+
+// TestDecodeMetadata tests decoding the bpf_metadata_ variables of a
+// .rodata section.
+func TestDecodeMetadata(t *testing.T) {
+	char := &btf.Int{Name: "char", Size: 1, Encoding: btf.Char}
+	u32 := &btf.Typedef{Name: "__u32", Type: &btf.Int{Name: "unsigned int", Size: 4}}
+	s16 := &btf.Int{Name: "short", Size: 2, Encoding: btf.Signed}
+
+	data := make([]byte, 32)
+	copy(data[0:], "xdp_fw\x00\x00")
+	binary.NativeEndian.PutUint32(data[8:], 3)
+	binary.NativeEndian.PutUint16(data[12:], uint16(0xfffe))
+	binary.NativeEndian.PutUint32(data[16:], 7)
+
+	sec := &btf.Datasec{Name: ".rodata", Vars: []btf.VarSecinfo{
+		{Type: &btf.Var{Name: "bpf_metadata_name", Type: &btf.Const{Type: &btf.Array{Type: char, Nelems: 8}}}, Offset: 0, Size: 8},
+		{Type: &btf.Var{Name: "bpf_metadata_version", Type: &btf.Const{Type: u32}}, Offset: 8, Size: 4},
+		{Type: &btf.Var{Name: "bpf_metadata_delta", Type: s16}, Offset: 12, Size: 2},
+		// Not metadata
+		{Type: &btf.Var{Name: "max_port", Type: u32}, Offset: 16, Size: 4},
+		// Neither an integer nor a string
+		{Type: &btf.Var{Name: "bpf_metadata_ptr", Type: &btf.Pointer{Target: char}}, Offset: 20, Size: 8},
+		// Past the end of the data
+		{Type: &btf.Var{Name: "bpf_metadata_late", Type: u32}, Offset: 30, Size: 4},
+	}}
+
+	got := DecodeMetadata(sec, data)
+	want := []MetadataEntry{
+		{Name: "name", Value: "xdp_fw"},
+		{Name: "version", Value: uint64(3)},
+		{Name: "delta", Value: int64(-2)},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("DecodeMetadata() = %#v, want %#v", got, want)
+	}
+}
@@ -0,0 +1,37 @@
+package prog
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// SortFields lists the fields accepted by Sort.
+var SortFields = []string{"id", "name", "memlock", "loaded"}
+
+// Sort orders programs in place by the given field. The sort is stable, so
+// programs with equal keys keep their kernel (ID) order, also when reversed.
+func Sort(progs []ProgramInfo, field string, reverse bool) error {
+	var less func(a, b ProgramInfo) bool
+	switch field {
+	case "id":
+		less = func(a, b ProgramInfo) bool { return a.ID < b.ID }
+	case "name":
+		less = func(a, b ProgramInfo) bool { return a.Name < b.Name }
+	case "memlock":
+		less = func(a, b ProgramInfo) bool { return a.MemLock < b.MemLock }
+	case "loaded":
+		less = func(a, b ProgramInfo) bool { return a.LoadedAt.Before(b.LoadedAt) }
+	default:
+		return fmt.Errorf("unknown sort field %q, valid fields: %s", field, strings.Join(SortFields, ", "))
+	}
+
+	sort.SliceStable(progs, func(i, j int) bool {
+		if reverse {
+			return less(progs[j], progs[i])
+		}
+		return less(progs[i], progs[j])
+	})
+
+	return nil
+}
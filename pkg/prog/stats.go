@@ -0,0 +1,29 @@
+package prog
+
+import (
+	"io"
+	"os"
+	"strings"
+
+	"github.com/cilium/ebpf"
+	"golang.org/x/sys/unix"
+)
+
+// statsSysctl controls whether the kernel collects run_time_ns and run_cnt.
+const statsSysctl = "/proc/sys/kernel/bpf_stats_enabled"
+
+// StatsEnabled reports whether the kernel.bpf_stats_enabled sysctl is set.
+// Statistics are also collected while an EnableStats handle is open anywhere.
+func StatsEnabled() bool {
+	data, err := os.ReadFile(statsSysctl)
+	if err != nil {
+		return false
+	}
+	return strings.TrimSpace(string(data)) != "0"
+}
+
+// EnableStats turns on run time statistics for all programs until the
+// returned handle is closed.
+func EnableStats() (io.Closer, error) {
+	return ebpf.EnableStats(uint32(unix.BPF_STATS_RUN_TIME))
+}
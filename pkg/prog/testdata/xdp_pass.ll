@@ -0,0 +1,11 @@
+; A minimal XDP program that passes every packet, used by the load tests.
+; Rebuild with: llc -march=bpfel -filetype=obj -o xdp_pass.o xdp_pass.ll
+target datalayout = "e-m:e-p:64:64-i64:64-i128:128-n32:64-S128"
+target triple = "bpfel"
+
+@_license = dso_local global [4 x i8] c"GPL\00", section "license", align 1
+@llvm.used = appending global [2 x i8*] [i8* getelementptr inbounds ([4 x i8], [4 x i8]* @_license, i32 0, i32 0), i8* bitcast (i32 (i8*)* @xdp_pass to i8*)], section "llvm.metadata"
+
+define dso_local i32 @xdp_pass(i8* nocapture readnone %ctx) section "xdp" {
+  ret i32 2
+}
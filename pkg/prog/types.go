@@ -0,0 +1,50 @@
+package prog
+
+import (
+	"strings"
+
+	"github.com/cilium/ebpf"
+)
+
+// progTypeAliases maps other common spellings of program types, such as
+// bpftool's and tc's, to the type they name. Keys are normalized.
+var progTypeAliases = map[string]ebpf.ProgramType{
+	"classifier": ebpf.SchedCLS,
+	"cls":        ebpf.SchedCLS,
+	"action":     ebpf.SchedACT,
+	"act":        ebpf.SchedACT,
+	"socket":     ebpf.SocketFilter,
+	"ext":        ebpf.Extension,
+}
+
+// ProgTypeFromString returns the program type named s, ignoring case and
+// underscores, so "sched_cls", "SchedCLS" and "schedcls" all name the same
+// type. Aliases such as "classifier" and "cls" are accepted too. It
+// reports false for unknown names.
+func ProgTypeFromString(s string) (ebpf.ProgramType, bool) {
+	want := normalizeType(s)
+	if want == "" {
+		return ebpf.UnspecifiedProgram, false
+	}
+	for t := ebpf.ProgramType(1); t < 256; t++ {
+		if isKnownType(t) && normalizeType(ProgTypeName(t)) == want {
+			return t, true
+		}
+	}
+	if t, ok := progTypeAliases[want]; ok {
+		return t, true
+	}
+	return ebpf.UnspecifiedProgram, false
+}
+
+// ProgTypeName returns the name ProgramInfo.Type uses for t: cilium/ebpf's
+// name, e.g. "SchedCLS".
+func ProgTypeName(t ebpf.ProgramType) string {
+	return t.String()
+}
+
+// isKnownType reports whether cilium/ebpf has a name for t rather than
+// the "ProgramType(N)" fallback.
+func isKnownType(t ebpf.ProgramType) bool {
+	return !strings.HasPrefix(t.String(), "ProgramType(")
+}